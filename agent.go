@@ -0,0 +1,210 @@
+package tfclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// AgentServer serves the provider protocol on behalf of remote clients
+// configured with WithRemoteAgent. Providers are downloaded and launched by the
+// wrapped Client on the agent host, so credentials never leave it.
+//
+// Providers are shared by every remote caller targeting the same
+// namespace/name@version, including their configuration.
+type AgentServer struct {
+	tfplugin6.UnimplementedProviderServer
+	client *Client
+}
+
+// NewAgentServer returns an AgentServer launching providers with client.
+func NewAgentServer(client *Client) *AgentServer {
+	return &AgentServer{client: client}
+}
+
+// Register registers the provider and health services on s.
+func (a *AgentServer) Register(s *grpc.Server) {
+	tfplugin6.RegisterProviderServer(s, a)
+	grpc_health_v1.RegisterHealthServer(s, &agentHealthServer{agent: a})
+}
+
+// provider returns the provider targeted by the incoming call, launching it if needed.
+func (a *AgentServer) provider(ctx context.Context) (*provider, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	get := func(key string) string {
+		if v := md.Get(key); len(v) > 0 {
+			return v[0]
+		}
+		return ""
+	}
+
+	cfg := ProviderConfig{
+		Namespace: get(agentNamespaceKey),
+		Name:      get(agentNameKey),
+		Version:   get(agentVersionKey),
+		Alias:     get(agentAliasKey),
+	}
+	if cfg.Namespace == "" || cfg.Name == "" || cfg.Version == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing provider metadata")
+	}
+
+	p, err := a.client.CreateProvider(ctx, cfg)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	impl, ok := p.(*provider)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unexpected provider type %T", p)
+	}
+	return impl, nil
+}
+
+// forward resolves the target provider and invokes fn on one of its processes.
+func forward[Resp any](ctx context.Context, a *AgentServer, fn func(ctx context.Context, client tfplugin6.ProviderClient) (Resp, error)) (Resp, error) {
+	var resp Resp
+
+	p, err := a.provider(ctx)
+	if err != nil {
+		return resp, err
+	}
+
+	err = p.call(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		var err error
+		resp, err = fn(ctx, client)
+		return err
+	})
+	return resp, callError(err)
+}
+
+// callError returns err for a remote client: calls that timed out on the
+// agent are reported as DeadlineExceeded, so that the client reports them as
+// ErrCallTimeout too.
+func callError(err error) error {
+	var timeout *ErrCallTimeout
+	if errors.As(err, &timeout) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return err
+}
+
+func (a *AgentServer) GetMetadata(ctx context.Context, req *tfplugin6.GetMetadata_Request) (*tfplugin6.GetMetadata_Response, error) {
+	return forward(ctx, a, func(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.GetMetadata_Response, error) {
+		return client.GetMetadata(ctx, req)
+	})
+}
+
+func (a *AgentServer) GetProviderSchema(ctx context.Context, req *tfplugin6.GetProviderSchema_Request) (*tfplugin6.GetProviderSchema_Response, error) {
+	return forward(ctx, a, func(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.GetProviderSchema_Response, error) {
+		return client.GetProviderSchema(ctx, req)
+	})
+}
+
+func (a *AgentServer) ValidateProviderConfig(ctx context.Context, req *tfplugin6.ValidateProviderConfig_Request) (*tfplugin6.ValidateProviderConfig_Response, error) {
+	return forward(ctx, a, func(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.ValidateProviderConfig_Response, error) {
+		return client.ValidateProviderConfig(ctx, req)
+	})
+}
+
+func (a *AgentServer) ValidateDataResourceConfig(ctx context.Context, req *tfplugin6.ValidateDataResourceConfig_Request) (*tfplugin6.ValidateDataResourceConfig_Response, error) {
+	return forward(ctx, a, func(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.ValidateDataResourceConfig_Response, error) {
+		return client.ValidateDataResourceConfig(ctx, req)
+	})
+}
+
+func (a *AgentServer) ConfigureProvider(ctx context.Context, req *tfplugin6.ConfigureProvider_Request) (*tfplugin6.ConfigureProvider_Response, error) {
+	p, err := a.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.configureMu.Lock()
+	defer p.configureMu.Unlock()
+
+	// Providers are shared by remote callers: refuse to reconfigure one with
+	// a different configuration, e.g. other credentials, as Configure does.
+	p.mu.Lock()
+	current := p.configureReq
+	p.mu.Unlock()
+	if current != nil {
+		if bytes.Equal(current.Config.GetMsgpack(), req.Config.GetMsgpack()) {
+			return &tfplugin6.ConfigureProvider_Response{}, nil
+		}
+		err := &ErrProviderAlreadyConfigured{Namespace: p.namespace, Name: p.name, Version: p.version, Alias: p.alias}
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	// Configure every pooled process on the agent, not just one. Diagnostics
+	// fail the broadcast too, so that the processes configured are rolled
+	// back, and are returned to the caller.
+	var (
+		mu   sync.Mutex
+		resp *tfplugin6.ConfigureProvider_Response
+	)
+	err = p.broadcast(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		r, err := client.ConfigureProvider(ctx, req)
+		if err != nil {
+			return err
+		}
+		derr := checkDiagnostics(r.Diagnostics)
+		mu.Lock()
+		if resp == nil || derr != nil {
+			resp = r
+		}
+		mu.Unlock()
+		return derr
+	})
+	if resp != nil && checkDiagnostics(resp.Diagnostics) != nil {
+		return resp, nil
+	}
+	if err != nil {
+		return nil, callError(err)
+	}
+
+	p.mu.Lock()
+	p.configured = true
+	p.configureReq = req
+	p.mu.Unlock()
+	return resp, nil
+}
+
+func (a *AgentServer) ReadDataSource(ctx context.Context, req *tfplugin6.ReadDataSource_Request) (*tfplugin6.ReadDataSource_Response, error) {
+	return forward(ctx, a, func(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.ReadDataSource_Response, error) {
+		return client.ReadDataSource(ctx, req)
+	})
+}
+
+// agentHealthServer reports the health of agent-managed providers. The service
+// name is the provider key (namespace/name@version); an empty name checks the
+// agent itself.
+type agentHealthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	agent *AgentServer
+}
+
+func (h *agentHealthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if req.Service == "" {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+	}
+
+	h.agent.client.mu.Lock()
+	p, ok := h.agent.client.providers[req.Service]
+	h.agent.client.mu.Unlock()
+	if !ok {
+		return nil, status.Error(codes.NotFound, fmt.Sprintf("provider %s is not running", req.Service))
+	}
+
+	if err := p.Ping(ctx); err != nil {
+		return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
@@ -0,0 +1,184 @@
+package tfclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"time"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+)
+
+// AuditHook receives an event for every Configure and ReadDataSource call, so
+// security teams can trace what data was read from which provider.
+//
+// Audit is called synchronously once the call returns; implementations should
+// be fast and must be safe for concurrent use.
+type AuditHook interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// AuditFunc adapts a function to the AuditHook interface.
+type AuditFunc func(ctx context.Context, event AuditEvent)
+
+// Audit calls f(ctx, event).
+func (f AuditFunc) Audit(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
+
+// AuditEvent describes a single provider operation.
+type AuditEvent struct {
+	Operation  string // "Configure", "Reconfigure" or "ReadDataSource"
+	Namespace  string
+	Name       string
+	Version    string
+	DataSource string // data source type name, ReadDataSource only
+
+	// ConfigHash is the hex SHA-256 of the JSON-encoded configuration, with
+	// attributes the schema marks sensitive replaced by a placeholder. Equal
+	// configurations have equal hashes without the secrets being recoverable.
+	ConfigHash string
+
+	Caller   string // set with WithAuditCaller, empty otherwise
+	Start    time.Time
+	Duration time.Duration
+	Err      error // nil if the call succeeded
+}
+
+const redactedValue = "(sensitive)"
+
+type auditCallerKey struct{}
+
+// WithAuditCaller returns a context recording caller, e.g. a user or service
+// identity, in the audit events of calls made with it.
+func WithAuditCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, auditCallerKey{}, caller)
+}
+
+// auditCaller returns the caller recorded in ctx by WithAuditCaller.
+func auditCaller(ctx context.Context) string {
+	caller, _ := ctx.Value(auditCallerKey{}).(string)
+	return caller
+}
+
+// audit sends an event for an operation that started at start to the audit
+// hook, if any. block is the schema of the configuration, nil if unknown.
+func (p *provider) audit(ctx context.Context, op, dataSource string, block *tfplugin6.Schema_Block, config map[string]any, start time.Time, err error) {
+	if p.auditHook == nil {
+		return
+	}
+	p.auditHook.Audit(ctx, AuditEvent{
+		Operation:  op,
+		Namespace:  p.namespace,
+		Name:       p.name,
+		Version:    p.version,
+		DataSource: dataSource,
+		ConfigHash: configHash(block, config),
+		Caller:     auditCaller(ctx),
+		Start:      start,
+		Duration:   time.Since(start),
+		Err:        err,
+	})
+}
+
+// configHash hashes config with its sensitive attributes redacted.
+func configHash(block *tfplugin6.Schema_Block, config map[string]any) string {
+	// encoding/json sorts map keys, so the encoding is deterministic.
+	data, err := json.Marshal(redactBlock(block, config))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// redactBlock returns a copy of config with the values of sensitive
+// attributes, in the block and its nested blocks, replaced.
+func redactBlock(block *tfplugin6.Schema_Block, config map[string]any) map[string]any {
+	if block == nil || config == nil {
+		return config
+	}
+
+	out := redactAttributes(block.Attributes, config)
+	for _, nested := range block.BlockTypes {
+		v, ok := out[nested.TypeName]
+		if !ok {
+			continue
+		}
+		redact := func(m map[string]any) map[string]any { return redactBlock(nested.Block, m) }
+		switch nested.Nesting {
+		case tfplugin6.Schema_NestedBlock_SINGLE, tfplugin6.Schema_NestedBlock_GROUP:
+			out[nested.TypeName] = redactObject(v, redact)
+		case tfplugin6.Schema_NestedBlock_LIST, tfplugin6.Schema_NestedBlock_SET:
+			out[nested.TypeName] = redactList(v, redact)
+		case tfplugin6.Schema_NestedBlock_MAP:
+			out[nested.TypeName] = redactMap(v, redact)
+		}
+	}
+	return out
+}
+
+// redactAttributes returns a copy of config with sensitive attributes
+// replaced, recursing into nested attribute types.
+func redactAttributes(attrs []*tfplugin6.Schema_Attribute, config map[string]any) map[string]any {
+	out := maps.Clone(config)
+
+	for _, attr := range attrs {
+		v, ok := out[attr.Name]
+		if !ok || v == nil {
+			continue
+		}
+		if attr.Sensitive {
+			out[attr.Name] = redactedValue
+			continue
+		}
+		if attr.NestedType == nil {
+			continue
+		}
+
+		obj := attr.NestedType
+		redact := func(m map[string]any) map[string]any { return redactAttributes(obj.Attributes, m) }
+		switch obj.Nesting {
+		case tfplugin6.Schema_Object_SINGLE:
+			out[attr.Name] = redactObject(v, redact)
+		case tfplugin6.Schema_Object_LIST, tfplugin6.Schema_Object_SET:
+			out[attr.Name] = redactList(v, redact)
+		case tfplugin6.Schema_Object_MAP:
+			out[attr.Name] = redactMap(v, redact)
+		}
+	}
+	return out
+}
+
+func redactObject(v any, redact func(map[string]any) map[string]any) any {
+	if m, ok := v.(map[string]any); ok {
+		return redact(m)
+	}
+	return v
+}
+
+func redactList(v any, redact func(map[string]any) map[string]any) any {
+	list, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	out := make([]any, len(list))
+	for i, elem := range list {
+		out[i] = redactObject(elem, redact)
+	}
+	return out
+}
+
+func redactMap(v any, redact func(map[string]any) map[string]any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	out := make(map[string]any, len(m))
+	for k, elem := range m {
+		out[k] = redactObject(elem, redact)
+	}
+	return out
+}
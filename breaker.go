@@ -0,0 +1,171 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CircuitBreaker configures the circuit breaker of providers, see
+// WithCircuitBreaker.
+type CircuitBreaker struct {
+	// Failures is the number of consecutive failed RPCs opening the circuit.
+	Failures int
+	// Cooldown is how long calls fail fast once the circuit is open. After
+	// it, a single call is let through: the circuit closes if it succeeds,
+	// and opens again if it fails.
+	Cooldown time.Duration
+	// Restart relaunches the provider's processes when the circuit opens,
+	// replaying the last Configure call, so that the call after the cooldown
+	// is made against fresh processes.
+	Restart bool
+}
+
+// breakerState is the state of a circuit breaker.
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // calls go through
+	breakerOpen                         // calls fail fast until the cooldown ends
+	breakerHalfOpen                     // a single call probes the provider
+)
+
+// circuitBreaker tracks the consecutive RPC failures of a provider. Methods
+// on a nil breaker let every call through.
+type circuitBreaker struct {
+	config CircuitBreaker
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int       // consecutive failed RPCs
+	openedAt time.Time // when the circuit last opened
+	lastErr  error     // that opened the circuit
+}
+
+func newCircuitBreaker(config *CircuitBreaker) *circuitBreaker {
+	if config == nil {
+		return nil
+	}
+	return &circuitBreaker{config: *config}
+}
+
+// allow reports whether a call may be made, or returns ErrCircuitOpen. Once
+// the cooldown has passed, the first call is let through as a probe.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) >= b.config.Cooldown {
+			b.state = breakerHalfOpen
+			return nil
+		}
+	case breakerHalfOpen:
+	default:
+		return nil
+	}
+	return &ErrCircuitOpen{Failures: b.failures, RetryAt: b.openedAt.Add(b.config.Cooldown), Err: b.lastErr}
+}
+
+// record records the outcome of a call let through by allow, returning
+// whether it opened the circuit, or closed it.
+func (b *circuitBreaker) record(err error) (opened, closed bool) {
+	if b == nil {
+		return false, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		closed = b.state != breakerClosed
+		b.state, b.failures, b.lastErr = breakerClosed, 0, nil
+		return false, closed
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.state == breakerClosed && b.failures >= b.config.Failures {
+		b.state, b.openedAt, b.lastErr = breakerOpen, time.Now(), err
+		return true, false
+	}
+	return false, false
+}
+
+// abandon records that a call let through by allow ended without telling
+// whether the provider works, e.g. its caller canceled it: a probe is let
+// through again.
+func (b *circuitBreaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+// rpcFailure reports whether err, returned by a call its caller didn't cancel
+// nor let run out of time, counts as a failure of the provider: an RPC error,
+// the call timeout of the provider expiring or a crash. Errors the provider
+// reported in diagnostics don't, as it answered.
+func rpcFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var (
+		crashed *ErrProviderCrashed
+		killed  *ErrProviderKilled
+	)
+	if errors.As(err, &crashed) || errors.As(err, &killed) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	s, ok := status.FromError(err)
+	return ok && s.Code() != codes.Canceled && s.Code() != codes.OK
+}
+
+// guard makes a call through the circuit breaker of the provider: it fails
+// fast with ErrProviderUnhealthy while the circuit is open, and otherwise
+// records the outcome of call. Calls whose ctx, that of the caller, was
+// canceled or ran out of time tell nothing of the provider, and are
+// abandoned; the provider answering, even with diagnostics, is a success.
+func (p *provider) guard(ctx context.Context, call func() error) error {
+	if err := p.breaker.allow(); err != nil {
+		return &ErrProviderUnhealthy{Namespace: p.namespace, Name: p.name, Version: p.version, Err: err}
+	}
+	err := call()
+	switch {
+	case err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)):
+		p.breaker.abandon()
+	case err == nil || !rpcFailure(err):
+		if _, closed := p.breaker.record(nil); closed {
+			p.setHealthy(nil)
+			p.events.emit(&CircuitClosed{Provider: p.Config()})
+		}
+	default:
+		if opened, _ := p.breaker.record(err); opened {
+			p.setHealthy(err)
+			p.events.emit(&CircuitOpened{Provider: p.Config(), Err: err})
+			if p.breaker.config.Restart {
+				go p.restartAll()
+			}
+		}
+	}
+	return err
+}
+
+// restartAll relaunches every process of the provider, for a circuit that
+// opened.
+func (p *provider) restartAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), max(p.breaker.config.Cooldown, time.Minute))
+	defer cancel()
+	for _, inst := range p.instances() {
+		if _, err := p.restart(ctx, inst); err != nil {
+			p.logger.Error(err, "failed to restart provider", "provider", p.Config().String(), "slot", inst.slot)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package tfclient
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/infracollect/tf-data-client/cache"
+)
+
+// bundleManifestName is the name of the manifest, the first entry of a bundle.
+const bundleManifestName = "manifest.json"
+
+type bundleManifest struct {
+	Providers []bundleEntry `json:"providers"`
+}
+
+// bundleEntry describes a provider package in a bundle.
+type bundleEntry struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Archive   string `json:"archive"` // path of the zip archive in the bundle
+	SHA256    string `json:"sha256"`  // of the zip archive
+	Hash      string `json:"hash"`    // "h1:" package hash, as in lock files
+}
+
+// ExportBundle writes a gzipped tarball of the cached packages of providers,
+// downloading those that aren't cached yet, with a manifest of their versions,
+// platforms and checksums. ImportBundle seeds the cache of another machine
+// with it, e.g. one without network access (see WithOfflineMode). Versions are
+// resolved as CreateProvider does; packages are for the current platform.
+func (c *Client) ExportBundle(ctx context.Context, providers []ProviderConfig, w io.Writer) error {
+	var manifest bundleManifest
+	var archives []string
+	defer func() {
+		for _, archive := range archives {
+			os.Remove(archive)
+		}
+	}()
+
+	for _, cfg := range providers {
+		key := cfg.Namespace + "/" + cfg.Name
+		if _, ok := c.devOverrides[key]; ok {
+			return fmt.Errorf("cannot bundle %s: it is a development override", key)
+		}
+		if _, ok := c.inProcess[key]; ok {
+			return fmt.Errorf("cannot bundle %s: it is an in-process provider", key)
+		}
+
+		version, _, err := c.resolveRequest(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		execPath, _, err := c.getOrDownloadProvider(ctx, cfg.Namespace, cfg.Name, version)
+		if err != nil {
+			return &ErrDownloadFailed{Namespace: cfg.Namespace, Name: cfg.Name, Version: version, Err: err}
+		}
+
+		dir := filepath.Dir(execPath)
+		h1, err := packageHash(dir)
+		if err != nil {
+			return err
+		}
+		archive, sum, err := zipPackage(dir)
+		if err != nil {
+			return fmt.Errorf("failed to archive %s: %w", key, err)
+		}
+		archives = append(archives, archive)
+
+		manifest.Providers = append(manifest.Providers, bundleEntry{
+			Namespace: cfg.Namespace,
+			Name:      cfg.Name,
+			Version:   version,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			Archive:   fmt.Sprintf("providers/%s/%s/terraform-provider-%s_%s_%s_%s.zip", cfg.Namespace, cfg.Name, cfg.Name, version, runtime.GOOS, runtime.GOARCH),
+			SHA256:    sum,
+			Hash:      h1,
+		})
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(data))}); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	for i, entry := range manifest.Providers {
+		if err := addTarFile(tw, entry.Archive, archives[i]); err != nil {
+			return fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+
+	if err := errors.Join(tw.Close(), gw.Close()); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// ImportBundle adds the providers of a bundle written by ExportBundle to the
+// cache, verifying the checksums of their archives and unpacked packages, and
+// returns them. Providers built for
+// another platform are skipped, and versions already cached are kept.
+func (c *Client) ImportBundle(ctx context.Context, r io.Reader) ([]ProviderConfig, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	if header.Name != bundleManifestName {
+		return nil, fmt.Errorf("invalid bundle: first entry is %q, not %s", header.Name, bundleManifestName)
+	}
+	var manifest bundleManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+
+	entries := make(map[string]bundleEntry, len(manifest.Providers))
+	for _, entry := range manifest.Providers {
+		entries[entry.Archive] = entry
+	}
+
+	var imported []ProviderConfig
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		entry, ok := entries[header.Name]
+		if !ok {
+			continue
+		}
+		delete(entries, header.Name)
+
+		cfg := ProviderConfig{Namespace: entry.Namespace, Name: entry.Name, Version: entry.Version}
+		if entry.OS != runtime.GOOS || entry.Arch != runtime.GOARCH {
+			c.logger.V(1).Info("skipping bundled provider built for another platform", "provider", cfg.String(), "platform", entry.OS+"_"+entry.Arch)
+			continue
+		}
+		if err := c.importBundleEntry(ctx, entry, tr); err != nil {
+			return imported, fmt.Errorf("failed to import %s: %w", cfg, err)
+		}
+		imported = append(imported, cfg)
+	}
+
+	if len(entries) > 0 {
+		missing := slices.Sorted(maps.Keys(entries))
+		return imported, fmt.Errorf("invalid bundle: missing %s", strings.Join(missing, ", "))
+	}
+	return imported, nil
+}
+
+// importBundleEntry adds a bundled provider archive read from r to the cache.
+func (c *Client) importBundleEntry(ctx context.Context, entry bundleEntry, r io.Reader) error {
+	id := cache.ProviderIdentifier{
+		Namespace: entry.Namespace,
+		Name:      entry.Name,
+		Version:   entry.Version,
+		OS:        entry.OS,
+		Arch:      entry.Arch,
+	}
+	// The identifier becomes a cache path: don't let it escape the cache
+	for _, part := range []string{entry.Namespace, entry.Name, entry.Version} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return fmt.Errorf("invalid bundle entry %s/%s %s", entry.Namespace, entry.Name, entry.Version)
+		}
+	}
+
+	tmpFile, err := os.CreateTemp("", "provider-*.zip")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmpFile, h), r)
+	if err := errors.Join(err, tmpFile.Close()); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, entry.SHA256) {
+		return &ErrChecksumMismatch{URL: entry.Archive, Expected: entry.SHA256, Actual: actual}
+	}
+
+	ctx = cache.ContextWithSource(ctx, "bundle:"+entry.Archive)
+	execPath, err := c.cache.GetOrPut(ctx, id, func(context.Context) (string, func(), error) {
+		return tmpFile.Name(), nil, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Check the unpacked package too, as lock files are
+	h1, err := packageHash(filepath.Dir(execPath))
+	if err != nil {
+		return err
+	}
+	if h1 != entry.Hash {
+		return fmt.Errorf("package hash mismatch for %s: expected %s, got %s", entry.Archive, entry.Hash, h1)
+	}
+	return nil
+}
+
+// zipPackage archives the files of an unpacked provider package into a
+// temporary zip file, returning its path and SHA-256.
+func zipPackage(dir string) (_ string, _ string, err error) {
+	tmpFile, err := os.CreateTemp("", "provider-*.zip")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmpFile.Name())
+		}
+	}()
+
+	h := sha256.New()
+	zw := zip.NewWriter(io.MultiWriter(tmpFile, h))
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err = errors.Join(err, zw.Close(), tmpFile.Close()); err != nil {
+		return "", "", err
+	}
+	return tmpFile.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addTarFile writes the file at src to tw as name.
+func addTarFile(tw *tar.Writer, name, src string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: path.Clean(name), Mode: 0644, Size: info.Size()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,119 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/infracollect/tf-data-client/cache"
+)
+
+// revalidateCached purges a cached provider whose entry is stale (see
+// WithCacheTTL) and no longer matches its recorded package hash or, if it was
+// downloaded from the registry, the registry's archive checksum, so that it is
+// downloaded again.
+func (c *Client) revalidateCached(ctx context.Context, id cache.ProviderIdentifier) error {
+	fsCache := c.filesystemCache()
+	if fsCache == nil || c.cacheTTL == 0 {
+		return nil
+	}
+	if cached, err := fsCache.Has(ctx, id); err != nil || !cached {
+		return err
+	}
+	meta, err := fsCache.Metadata(ctx, id)
+	if err != nil {
+		return err
+	}
+	if meta != nil && !meta.Stale(c.cacheTTL) {
+		return nil
+	}
+
+	log := c.logger.WithValues("namespace", id.Namespace, "name", id.Name, "version", id.Version)
+	reason := ""
+	switch {
+	case meta == nil:
+		reason = "no metadata recorded"
+	case fsCache.Verify(ctx, id) != nil:
+		reason = "files were modified"
+	case meta.Source != c.providerAddress(id.Namespace, id.Name):
+		// Installed from a bundle or local archive, not from this registry
+	default:
+		info, err := c.downloadInfo(ctx, id.Namespace, id.Name, id.Version)
+		if err != nil {
+			log.V(1).Info("could not revalidate cached provider against the registry, using it as is", "error", err.Error())
+			return nil
+		}
+		if info.SHA256Sum != "" && !strings.EqualFold(info.SHA256Sum, meta.ArchiveSHA256) {
+			reason = "registry checksum changed"
+		}
+	}
+
+	if reason == "" {
+		log.V(1).Info("revalidated cached provider")
+		return fsCache.MarkValidated(ctx, id)
+	}
+	log.Info("purging stale cached provider", "reason", reason)
+	return fsCache.Evict(ctx, id)
+}
+
+// CacheInfo returns the content of the provider cache, with its size, the
+// versions cached and when they were last used, and its hit and miss counts,
+// to monitor and size it. It fails if the cache doesn't implement
+// cache.StatsReporter, as the built-in caches do.
+func (c *Client) CacheInfo(ctx context.Context) (*cache.Stats, error) {
+	reporter, ok := c.cache.(cache.StatsReporter)
+	if !ok {
+		return nil, fmt.Errorf("cache %T does not report statistics", c.cache)
+	}
+	return reporter.Stats(ctx)
+}
+
+// filesystemCache returns the client's filesystem cache, including one used
+// as the fallback of a Terraform plugin cache, or nil if there is none.
+func (c *Client) filesystemCache() *cache.FilesystemCache {
+	cached := c.cache
+	if plugin, ok := cached.(*cache.TerraformPluginCache); ok {
+		cached = plugin.Fallback()
+	}
+	fsCache, _ := cached.(*cache.FilesystemCache)
+	return fsCache
+}
+
+// setExtractLimits applies WithExtractLimits to the caches extracting
+// archives, including the fallback of a plugin cache.
+func setExtractLimits(c cache.Cache, limits cache.ExtractLimits) error {
+	switch c := c.(type) {
+	case *cache.FilesystemCache:
+		c.ExtractLimits = limits
+	case *cache.MemoryCache:
+		c.ExtractLimits = limits
+	case *cache.ObjectStoreCache:
+		c.Local().ExtractLimits = limits
+	case *cache.TerraformPluginCache:
+		c.ExtractLimits = limits
+		return setExtractLimits(c.Fallback(), limits)
+	default:
+		return fmt.Errorf("WithExtractLimits requires a cache provided by this package, not %T", c)
+	}
+	return nil
+}
+
+// setLockTimeout applies WithCacheLockTimeout to the caches taking file
+// locks, including the fallback of a plugin cache.
+func setLockTimeout(c cache.Cache, timeout time.Duration) error {
+	switch c := c.(type) {
+	case *cache.FilesystemCache:
+		c.LockTimeout = timeout
+	case *cache.MemoryCache:
+		// Private to the process: no file locks
+	case *cache.ObjectStoreCache:
+		c.Local().LockTimeout = timeout
+	case *cache.TerraformPluginCache:
+		c.LockTimeout = timeout
+		return setLockTimeout(c.Fallback(), timeout)
+	default:
+		return fmt.Errorf("WithCacheLockTimeout requires a cache provided by this package, not %T", c)
+	}
+	return nil
+}
@@ -21,3 +21,10 @@ type Cache interface {
 	GetOrPut(ctx context.Context, id ProviderIdentifier,
 		downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (executablePath string, err error)
 }
+
+// VersionLister is implemented by caches that can list the versions of a
+// provider they hold, which offline mode resolves versions from.
+type VersionLister interface {
+	// Versions returns the cached versions of a provider.
+	Versions(ctx context.Context, namespace, name string) ([]string, error)
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// objectsDir is the content-addressed store of a deduplicating
+// FilesystemCache, holding one hard link to each distinct file.
+func (c *FilesystemCache) objectsDir() string {
+	return filepath.Join(c.baseDir, ".objects")
+}
+
+// dedup replaces the files under dir by hard links to identical files of
+// other cached versions, keyed by SHA-256 and permissions in the objects
+// directory. It is best effort: a file that can't be linked is kept as is.
+func (c *FilesystemCache) dedup(dir string) {
+	if !c.Dedup || !hardLinksSupported {
+		return
+	}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return nil
+		}
+		key := fmt.Sprintf("%s-%o", sum, info.Mode().Perm())
+		object := filepath.Join(c.objectsDir(), key[:2], key)
+		if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+			return nil
+		}
+
+		// New content: the file becomes the object
+		err = os.Link(path, object)
+		if err == nil || !errors.Is(err, fs.ErrExist) {
+			return nil
+		}
+		// Known content: replace the file by a link to the object
+		tmp := path + ".dedup"
+		if err := os.Link(object, tmp); err != nil {
+			return nil
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+		}
+		return nil
+	})
+}
+
+// pruneObjects removes the objects no cached version links to anymore.
+func (c *FilesystemCache) pruneObjects() error {
+	if !hardLinksSupported {
+		return nil
+	}
+	err := filepath.WalkDir(c.objectsDir(), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if n, ok := linkCount(info); ok && n <= 1 {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to prune cache objects: %w", err)
+	}
+	return nil
+}
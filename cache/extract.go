@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractLimits bounds what extracting a provider archive may write, as
+// archives are fetched over the network. A zero field uses the value of
+// DefaultExtractLimits; a negative one disables the limit.
+type ExtractLimits struct {
+	MaxBytes int64 // total uncompressed size of the files
+	MaxFiles int   // number of files
+}
+
+// DefaultExtractLimits are the limits of caches whose ExtractLimits are
+// unset, well above the size of the largest published providers.
+var DefaultExtractLimits = ExtractLimits{
+	MaxBytes: 4 << 30,
+	MaxFiles: 10000,
+}
+
+// ErrUnsafeArchive is returned when a provider archive exceeds the extraction
+// limits, or holds an entry that would be written outside of the provider
+// directory or that isn't a regular file or directory, such as a symlink.
+type ErrUnsafeArchive struct {
+	Archive string
+	Entry   string // offending entry, if any
+	Reason  string
+}
+
+func (e *ErrUnsafeArchive) Error() string {
+	if e.Entry != "" {
+		return fmt.Sprintf("unsafe provider archive %s: entry %q: %s", e.Archive, e.Entry, e.Reason)
+	}
+	return fmt.Sprintf("unsafe provider archive %s: %s", e.Archive, e.Reason)
+}
+
+// withDefaults fills in the unset limits from DefaultExtractLimits.
+func (l ExtractLimits) withDefaults() ExtractLimits {
+	if l.MaxBytes == 0 {
+		l.MaxBytes = DefaultExtractLimits.MaxBytes
+	}
+	if l.MaxFiles == 0 {
+		l.MaxFiles = DefaultExtractLimits.MaxFiles
+	}
+	return l
+}
+
+// extractZip extracts a zip file to a destination directory, within limits.
+// Entries are checked before anything is written, and sizes again while
+// extracting, as the sizes recorded in an archive can't be trusted.
+func extractZip(zipPath, destDir string, limits ExtractLimits) error {
+	limits = limits.withDefaults()
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer r.Close()
+
+	unsafe := func(entry, format string, args ...any) error {
+		return &ErrUnsafeArchive{Archive: filepath.Base(zipPath), Entry: entry, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	var files int
+	var declared uint64
+	for _, f := range r.File {
+		name := strings.ReplaceAll(f.Name, `\`, "/")
+		if path.IsAbs(name) || filepath.IsAbs(f.Name) || filepath.VolumeName(f.Name) != "" {
+			return unsafe(f.Name, "absolute path")
+		}
+		if clean := path.Clean(name); clean == ".." || strings.HasPrefix(clean, "../") {
+			return unsafe(f.Name, "path escapes the provider directory")
+		}
+		mode := f.Mode()
+		if mode.IsDir() {
+			continue
+		}
+		if mode&fs.ModeSymlink != 0 {
+			return unsafe(f.Name, "symlink")
+		}
+		if !mode.IsRegular() {
+			return unsafe(f.Name, "not a regular file")
+		}
+		files++
+		declared += f.UncompressedSize64
+	}
+	if limits.MaxFiles > 0 && files > limits.MaxFiles {
+		return unsafe("", "%d files, more than the limit of %d", files, limits.MaxFiles)
+	}
+	if limits.MaxBytes > 0 && declared > uint64(limits.MaxBytes) {
+		return unsafe("", "%d bytes uncompressed, more than the limit of %d", declared, limits.MaxBytes)
+	}
+
+	remaining := limits.MaxBytes
+	for _, f := range r.File {
+		fpath := filepath.Join(destDir, f.Name)
+
+		// Check for ZipSlip vulnerability
+		if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
+			return unsafe(f.Name, "path escapes the provider directory")
+		}
+
+		if f.FileInfo().IsDir() {
+			os.MkdirAll(fpath, 0755)
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory: %w", err)
+		}
+
+		// Permission bits only: no setuid, setgid or sticky bits
+		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			outFile.Close()
+			return fmt.Errorf("failed to open zip entry: %w", err)
+		}
+
+		var src io.Reader = rc
+		if limits.MaxBytes > 0 {
+			src = io.LimitReader(rc, remaining+1)
+		}
+		n, err := io.Copy(outFile, src)
+		outFile.Close()
+		rc.Close()
+
+		if err != nil {
+			return fmt.Errorf("failed to extract file: %w", err)
+		}
+		if limits.MaxBytes > 0 {
+			if remaining -= n; remaining < 0 {
+				return unsafe(f.Name, "more than the limit of %d bytes uncompressed", limits.MaxBytes)
+			}
+		}
+	}
+
+	return nil
+}
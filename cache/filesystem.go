@@ -1,21 +1,42 @@
 package cache
 
 import (
-	"archive/zip"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
 )
 
 // FilesystemCache implements Cache using the local filesystem.
+//
+// Without limits, the cache grows unboundedly. When MaxSizeBytes, MaxEntries
+// or MaxAge is set, the least recently used providers are evicted after each
+// install (see GC).
+//
+// When Dedup is set, files identical across cached versions, such as bundled
+// documentation or a binary republished unchanged, are stored once and hard
+// linked into each version directory. Modifying a linked file in place
+// modifies it in every version; Verify catches it. Sizes reported by Entries
+// and Stats count linked files in each version. Dedup has no effect on
+// Windows and other platforms without link counts.
 type FilesystemCache struct {
 	baseDir string
 	locker  *Locker
+
+	MaxSizeBytes int64         // total size of the unpacked providers; 0 for no limit
+	MaxEntries   int           // number of provider versions; 0 for no limit
+	MaxAge       time.Duration // evict providers unused for longer; 0 for no limit
+	Dedup        bool          // hard link files identical across versions
+
+	ExtractLimits ExtractLimits // bounds of extracted archives; DefaultExtractLimits if unset
+	LockTimeout   time.Duration // fail with ErrLockTimeout after waiting this long for a lock; 0 for no limit
+
+	counters counters
 }
 
 // NewFilesystemCache creates a new filesystem-based cache at the given directory.
@@ -27,6 +48,11 @@ func NewFilesystemCache(baseDir string) *FilesystemCache {
 	}
 }
 
+// Dir returns the directory of the cache.
+func (c *FilesystemCache) Dir() string {
+	return c.baseDir
+}
+
 // providerDir returns the directory path for a provider.
 func (c *FilesystemCache) providerDir(id ProviderIdentifier) string {
 	return filepath.Join(c.baseDir, id.Namespace, id.Name, id.Version)
@@ -39,12 +65,32 @@ func (c *FilesystemCache) Get(ctx context.Context, id ProviderIdentifier) (strin
 	execPath := findProviderExecutable(dir, id.Name)
 	if execPath != "" {
 		if _, err := os.Stat(execPath); err == nil {
+			c.touch(id)
 			return execPath, nil
 		}
 	}
 	return "", nil
 }
 
+// Versions returns the cached versions of a provider.
+func (c *FilesystemCache) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.baseDir, namespace, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		if entry.IsDir() && findProviderExecutable(filepath.Join(c.baseDir, namespace, name, entry.Name()), name) != "" {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions, nil
+}
+
 // Put stores a provider archive and returns the path to the extracted executable.
 func (c *FilesystemCache) Put(ctx context.Context, id ProviderIdentifier, archivePath string) (string, error) {
 	dir := c.providerDir(id)
@@ -55,7 +101,9 @@ func (c *FilesystemCache) Put(ctx context.Context, id ProviderIdentifier, archiv
 	}
 
 	// Extract the zip file
-	if err := extractZip(archivePath, dir); err != nil {
+	if err := extractZip(archivePath, dir, c.ExtractLimits); err != nil {
+		// Don't leave a partial package behind for Get to find
+		os.RemoveAll(dir)
 		return "", fmt.Errorf("failed to extract provider: %w", err)
 	}
 
@@ -69,7 +117,14 @@ func (c *FilesystemCache) Put(ctx context.Context, id ProviderIdentifier, archiv
 	if err := os.Chmod(execPath, 0755); err != nil {
 		return "", fmt.Errorf("failed to make provider executable: %w", err)
 	}
+	c.dedup(dir)
 
+	if err := c.recordInstall(ctx, id, archivePath); err != nil {
+		return "", err
+	}
+
+	// Best effort: the provider is installed either way
+	c.gc(ctx, &id)
 	return execPath, nil
 }
 
@@ -87,19 +142,39 @@ func (c *FilesystemCache) Has(ctx context.Context, id ProviderIdentifier) (bool,
 func (c *FilesystemCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
 	downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (string, error) {
 
+	// Look the provider up under a shared lock first, so that processes
+	// starting the same cached provider don't wait on each other
+	execPath, err := c.getShared(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if execPath != "" {
+		c.counters.hits.Add(1)
+		return execPath, nil
+	}
+
 	// Acquire exclusive lock for this provider
-	unlock, err := c.locker.AcquireExclusive(ctx, id)
+	unlock, err := c.locker.acquire(ctx, id, false, c.LockTimeout)
 	if err != nil {
 		return "", fmt.Errorf("failed to acquire cache lock: %w", err)
 	}
 	defer unlock()
+	installed := false
+	defer func() {
+		// Leave no lock file behind for a provider that isn't cached
+		if !installed {
+			c.locker.Remove(id)
+		}
+	}()
 
 	// Re-check cache - another process may have populated it while we waited for the lock
-	execPath, err := c.Get(ctx, id)
+	execPath, err = c.Get(ctx, id)
 	if err != nil {
 		return "", err
 	}
 	if execPath != "" {
+		installed = true
+		c.counters.hits.Add(1)
 		return execPath, nil
 	}
 
@@ -119,7 +194,7 @@ func (c *FilesystemCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
 	}
 
 	// Extract the zip file to temp directory
-	if err := extractZip(archivePath, tmpDir); err != nil {
+	if err := extractZip(archivePath, tmpDir, c.ExtractLimits); err != nil {
 		os.RemoveAll(tmpDir)
 		return "", fmt.Errorf("failed to extract provider: %w", err)
 	}
@@ -135,6 +210,7 @@ func (c *FilesystemCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
 		os.RemoveAll(tmpDir)
 		return "", fmt.Errorf("failed to make provider executable: %w", err)
 	}
+	c.dedup(tmpDir)
 
 	// Create parent directories for final location
 	finalDir := c.providerDir(id)
@@ -148,11 +224,32 @@ func (c *FilesystemCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
 		os.RemoveAll(tmpDir)
 		return "", fmt.Errorf("failed to move provider to cache: %w", err)
 	}
+	if err := c.recordInstall(ctx, id, archivePath); err != nil {
+		os.RemoveAll(finalDir)
+		return "", err
+	}
+
+	installed = true
+	c.touch(id)
+	c.counters.misses.Add(1)
+	// Best effort: the provider is installed either way
+	c.gc(ctx, &id)
 
 	// Return the executable path in the final location
 	return findProviderExecutable(finalDir, id.Name), nil
 }
 
+// getShared looks a provider up under a shared lock, waiting for any process
+// installing or evicting it.
+func (c *FilesystemCache) getShared(ctx context.Context, id ProviderIdentifier) (string, error) {
+	unlock, err := c.locker.acquire(ctx, id, true, c.LockTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire cache lock: %w", err)
+	}
+	defer unlock()
+	return c.Get(ctx, id)
+}
+
 // createTempDir creates a unique temporary directory under the cache's .tmp directory.
 func (c *FilesystemCache) createTempDir() (string, error) {
 	tmpBase := filepath.Join(c.baseDir, ".tmp")
@@ -184,51 +281,3 @@ func findProviderExecutable(dir, name string) string {
 	}
 	return matches[0]
 }
-
-// extractZip extracts a zip file to a destination directory.
-func extractZip(zipPath, destDir string) error {
-	r, err := zip.OpenReader(zipPath)
-	if err != nil {
-		return fmt.Errorf("failed to open zip: %w", err)
-	}
-	defer r.Close()
-
-	for _, f := range r.File {
-		fpath := filepath.Join(destDir, f.Name)
-
-		// Check for ZipSlip vulnerability
-		if !strings.HasPrefix(fpath, filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("invalid file path: %s", fpath)
-		}
-
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(fpath, 0755)
-			continue
-		}
-
-		if err := os.MkdirAll(filepath.Dir(fpath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory: %w", err)
-		}
-
-		outFile, err := os.OpenFile(fpath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
-		}
-
-		rc, err := f.Open()
-		if err != nil {
-			outFile.Close()
-			return fmt.Errorf("failed to open zip entry: %w", err)
-		}
-
-		_, err = io.Copy(outFile, rc)
-		outFile.Close()
-		rc.Close()
-
-		if err != nil {
-			return fmt.Errorf("failed to extract file: %w", err)
-		}
-	}
-
-	return nil
-}
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Entry is a provider version held by a FilesystemCache.
+type Entry struct {
+	ID         ProviderIdentifier // OS and Arch are not recorded, and empty
+	Path       string             // directory of the unpacked package
+	Size       int64              // bytes
+	LastAccess time.Time          // last time the entry was installed or looked up
+}
+
+// Entries lists the providers in the cache, least recently used first.
+func (c *FilesystemCache) Entries(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	namespaces, err := readDirs(c.baseDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, namespace := range namespaces {
+		names, err := readDirs(filepath.Join(c.baseDir, namespace))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			versions, err := readDirs(filepath.Join(c.baseDir, namespace, name))
+			if err != nil {
+				return nil, err
+			}
+			for _, version := range versions {
+				dir := filepath.Join(c.baseDir, namespace, name, version)
+				if findProviderExecutable(dir, name) == "" {
+					continue
+				}
+				entry, err := cacheEntry(dir, ProviderIdentifier{Namespace: namespace, Name: name, Version: version})
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LastAccess.Before(entries[j].LastAccess)
+	})
+	return entries, nil
+}
+
+// Evict removes a provider version from the cache, waiting for the lock of
+// any process installing it.
+func (c *FilesystemCache) Evict(ctx context.Context, id ProviderIdentifier) error {
+	unlock, err := c.locker.acquire(ctx, id, false, c.LockTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to acquire cache lock: %w", err)
+	}
+	defer unlock()
+	if err := c.remove(id); err != nil {
+		return err
+	}
+	return c.pruneObjects()
+}
+
+// GC evicts the least recently used providers until the cache is within
+// MaxSizeBytes and MaxEntries, as well as those unused for longer than
+// MaxAge, and returns the evicted entries. Providers being installed by
+// another process are left alone. It also deletes the lock files no process
+// holds, and only that if no limit is set.
+func (c *FilesystemCache) GC(ctx context.Context) ([]Entry, error) {
+	if err := c.locker.Clean(); err != nil {
+		return nil, err
+	}
+	return c.gc(ctx, nil)
+}
+
+// gc is GC, never evicting keep, whose lock the caller holds.
+func (c *FilesystemCache) gc(ctx context.Context, keep *ProviderIdentifier) ([]Entry, error) {
+	if c.MaxSizeBytes <= 0 && c.MaxEntries <= 0 && c.MaxAge <= 0 {
+		return nil, nil
+	}
+
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var size int64
+	for _, entry := range entries {
+		size += entry.Size
+	}
+	count := len(entries)
+	overLimit := func(entry Entry) bool {
+		return (c.MaxSizeBytes > 0 && size > c.MaxSizeBytes) || (c.MaxEntries > 0 && count > c.MaxEntries) ||
+			(c.MaxAge > 0 && time.Since(entry.LastAccess) > c.MaxAge)
+	}
+
+	var evicted []Entry
+	for _, entry := range entries {
+		if !overLimit(entry) {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return evicted, err
+		}
+		if keep != nil && entry.ID.Namespace == keep.Namespace && entry.ID.Name == keep.Name && entry.ID.Version == keep.Version {
+			continue
+		}
+
+		unlock, ok, err := c.locker.TryAcquireExclusive(entry.ID)
+		if err != nil {
+			return evicted, err
+		}
+		if !ok {
+			continue
+		}
+		err = c.remove(entry.ID)
+		unlock()
+		if err != nil {
+			return evicted, err
+		}
+		evicted = append(evicted, entry)
+		size -= entry.Size
+		count--
+	}
+	if len(evicted) > 0 {
+		return evicted, c.pruneObjects()
+	}
+	return evicted, nil
+}
+
+// remove deletes a provider version and the directories it leaves empty. The
+// caller holds its lock.
+func (c *FilesystemCache) remove(id ProviderIdentifier) error {
+	dir := c.providerDir(id)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s/%s %s from cache: %w", id.Namespace, id.Name, id.Version, err)
+	}
+	os.Remove(c.metadataPath(id))
+	c.locker.Remove(id)
+	// Fails harmlessly if other versions remain
+	os.Remove(filepath.Dir(dir))
+	os.Remove(filepath.Dir(filepath.Dir(dir)))
+	return nil
+}
+
+// touch records an access to a cache entry, in its directory's modification
+// time, for least recently used eviction.
+func (c *FilesystemCache) touch(id ProviderIdentifier) {
+	now := time.Now()
+	os.Chtimes(c.providerDir(id), now, now)
+}
+
+// cacheEntry describes the cache entry unpacked in dir.
+func cacheEntry(dir string, id ProviderIdentifier) (Entry, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+	entry := Entry{ID: id, Path: dir, LastAccess: stat.ModTime()}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry.Size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to read cache: %w", err)
+	}
+	return entry, nil
+}
+
+// readDirs returns the names of the subdirectories of dir, skipping the
+// cache's own dot directories such as .locks and .tmp.
+func readDirs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package cache
+
+import "io/fs"
+
+// Objects can't be pruned without link counts, so files aren't deduplicated.
+const hardLinksSupported = false
+
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
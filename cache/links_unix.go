@@ -0,0 +1,19 @@
+//go:build unix
+
+package cache
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+const hardLinksSupported = true
+
+// linkCount returns the number of hard links to a file.
+func linkCount(info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Nlink), true
+}
@@ -2,17 +2,42 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/gofrs/flock"
 )
 
+// ErrLockTimeout is returned when a cache lock isn't acquired within the
+// lock timeout, e.g. because another process is stuck installing the same
+// provider.
+var ErrLockTimeout = errors.New("timed out waiting for cache lock")
+
+// lockRetryInterval is how often a busy lock is retried.
+const lockRetryInterval = 100 * time.Millisecond
+
+// staleCheckInterval is how often the holder of a busy lock is checked for
+// being alive.
+const staleCheckInterval = 2 * time.Second
+
 // Locker manages file-based locks for cache operations.
+//
+// Locks are released by the operating system when their holder exits. On
+// filesystems where that is unreliable, such as some network filesystems, a
+// lock whose recorded holder is a dead process of this host is considered
+// stale and broken.
 type Locker struct {
 	locksDir string
+
+	// Timeout bounds how long AcquireExclusive waits for a lock, failing with
+	// ErrLockTimeout; 0 waits until the context is done.
+	Timeout time.Duration
 }
 
 // NewLocker creates a new Locker that stores lock files in the given directory.
@@ -20,6 +45,19 @@ func NewLocker(locksDir string) *Locker {
 	return &Locker{locksDir: locksDir}
 }
 
+// lockHolder is recorded in a lock file by the process holding it
+// exclusively, for stale lock detection and error messages. Shared locks
+// record no holder.
+type lockHolder struct {
+	PID      int       `json:"pid"`
+	Host     string    `json:"host"`
+	Acquired time.Time `json:"acquired"`
+}
+
+func (h *lockHolder) String() string {
+	return fmt.Sprintf("process %d on %s since %s", h.PID, h.Host, h.Acquired.Format(time.RFC3339))
+}
+
 // lockPath returns the path to the lock file for a provider.
 func (l *Locker) lockPath(id ProviderIdentifier) string {
 	// Use flat naming to avoid nested directories
@@ -33,24 +71,209 @@ func (l *Locker) lockPath(id ProviderIdentifier) string {
 
 // AcquireExclusive acquires an exclusive lock for the given provider.
 // The returned function releases the lock and should be called when done.
-// Returns an error if the context is cancelled while waiting for the lock.
+// Returns an error if the context is cancelled while waiting for the lock,
+// or ErrLockTimeout once Timeout has elapsed.
 func (l *Locker) AcquireExclusive(ctx context.Context, id ProviderIdentifier) (unlock func() error, err error) {
+	return l.acquire(ctx, id, false, l.Timeout)
+}
+
+// AcquireShared acquires a shared lock for the given provider, held along
+// with other shared locks but not with an exclusive one, e.g. to read a cached
+// provider while no process installs or evicts it. It waits as
+// AcquireExclusive does.
+func (l *Locker) AcquireShared(ctx context.Context, id ProviderIdentifier) (unlock func() error, err error) {
+	return l.acquire(ctx, id, true, l.Timeout)
+}
+
+// acquire acquires a shared or exclusive lock, waiting up to timeout if it
+// is positive.
+func (l *Locker) acquire(ctx context.Context, id ProviderIdentifier, shared bool, timeout time.Duration) (func() error, error) {
 	// Ensure locks directory exists
 	if err := os.MkdirAll(l.locksDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create locks directory: %w", err)
 	}
+	lockPath := l.lockPath(id)
+
+	waitCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	retry := time.NewTicker(lockRetryInterval)
+	defer retry.Stop()
+	lastStaleCheck := time.Now()
+	for {
+		fl, ok, err := tryLock(lockPath, shared)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		}
+		if ok && shared {
+			return fl.Unlock, nil
+		}
+		if ok {
+			writeHolder(lockPath)
+			return release(fl), nil
+		}
+
+		if time.Since(lastStaleCheck) >= staleCheckInterval {
+			lastStaleCheck = time.Now()
+			breakStale(lockPath)
+		}
+
+		select {
+		case <-retry.C:
+		case <-waitCtx.Done():
+			if ctx.Err() == nil {
+				holder := "another process"
+				if h := readHolder(lockPath); h != nil {
+					holder = h.String()
+				}
+				return nil, fmt.Errorf("%w on %s/%s %s after %s: held by %s", ErrLockTimeout, id.Namespace, id.Name, id.Version, timeout, holder)
+			}
+			return nil, fmt.Errorf("failed to acquire lock: %v", ctx.Err())
+		}
+	}
+}
+
+// TryAcquireExclusive acquires an exclusive lock for the given provider if it
+// is free, without waiting. ok is false if another process holds the lock.
+func (l *Locker) TryAcquireExclusive(id ProviderIdentifier) (unlock func() error, ok bool, err error) {
+	if err := os.MkdirAll(l.locksDir, 0755); err != nil {
+		return nil, false, fmt.Errorf("failed to create locks directory: %w", err)
+	}
 
 	lockPath := l.lockPath(id)
-	fl := flock.New(lockPath)
+	fl, ok, err := tryLock(lockPath, false)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	writeHolder(lockPath)
+	return release(fl), true, nil
+}
 
-	// TryLockContext will retry with backoff until context is cancelled or lock is acquired
-	locked, err := fl.TryLockContext(ctx, 100_000_000) // 100ms retry interval
+// tryLock locks the file at path if it is free, or only held shared when
+// shared is set. The lock only counts if the file is still at path once
+// locked: Remove and Clean delete lock files, and a process locking a deleted
+// file must start over.
+func tryLock(path string, shared bool) (*flock.Flock, bool, error) {
+	fl := flock.New(path)
+	lock := fl.TryLock
+	if shared {
+		lock = fl.TryRLock
+	}
+	locked, err := lock()
+	if err != nil || !locked {
+		return nil, false, err
+	}
+	if !lockedFileAt(fl, path) {
+		fl.Unlock()
+		return nil, false, nil
+	}
+	return fl, true, nil
+}
+
+// release returns a function clearing the holder recorded in an exclusive
+// lock and releasing it, so that a recorded holder always holds the lock.
+func release(fl *flock.Flock) func() error {
+	return func() error {
+		if lockedFileAt(fl, fl.Path()) {
+			os.Truncate(fl.Path(), 0)
+		}
+		return fl.Unlock()
+	}
+}
+
+// lockedFileAt reports whether the file fl locked is still the one at path.
+func lockedFileAt(fl *flock.Flock, path string) bool {
+	locked, err := fl.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(locked, current)
+}
+
+// Remove deletes the lock file of a provider whose lock the caller holds,
+// e.g. once it is evicted; the caller then releases the lock as usual.
+func (l *Locker) Remove(id ProviderIdentifier) error {
+	if err := os.Remove(l.lockPath(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// Clean deletes the lock files no process holds, which otherwise accumulate
+// as providers are installed and evicted.
+func (l *Locker) Clean() error {
+	entries, err := os.ReadDir(l.locksDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to acquire lock: %w", err)
+		return fmt.Errorf("failed to read locks directory: %w", err)
 	}
-	if !locked {
-		return nil, fmt.Errorf("failed to acquire lock: %v", ctx.Err())
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".lock") {
+			continue
+		}
+		path := filepath.Join(l.locksDir, entry.Name())
+		fl, ok, err := tryLock(path, false)
+		if err != nil || !ok {
+			continue
+		}
+		os.Remove(path)
+		fl.Unlock()
 	}
+	return nil
+}
+
+// writeHolder records the current process in the lock file at path. It is
+// best effort: some platforms don't allow writing a locked file.
+func writeHolder(path string) {
+	host, _ := os.Hostname()
+	data, err := json.Marshal(lockHolder{PID: os.Getpid(), Host: host, Acquired: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return
+	}
+	f.Write(data)
+	f.Close()
+}
 
-	return fl.Unlock, nil
+// readHolder returns the holder recorded in the lock file at path, or nil if
+// there is none.
+func readHolder(path string) *lockHolder {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var holder lockHolder
+	if err := json.Unmarshal(data, &holder); err != nil || holder.PID == 0 {
+		return nil
+	}
+	return &holder
+}
+
+// breakStale deletes the busy lock file at path if its recorded holder is a
+// process of this host that no longer runs, so that waiters start over with a
+// fresh lock file.
+func breakStale(path string) {
+	holder := readHolder(path)
+	if holder == nil {
+		return
+	}
+	if host, err := os.Hostname(); err != nil || host != holder.Host || holder.PID == os.Getpid() {
+		return
+	}
+	if !processAlive(holder.PID) {
+		os.Remove(path)
+	}
 }
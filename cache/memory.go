@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryCache implements Cache for a single process: providers are extracted
+// into a temporary directory, which may be on a tmpfs, and tracked in memory.
+// Close removes the directory. Use it for ephemeral jobs and tests that must
+// not touch the user's home directory.
+type MemoryCache struct {
+	parent string // where the temporary directory is created
+
+	ExtractLimits ExtractLimits // bounds of extracted archives; DefaultExtractLimits if unset
+
+	mu       sync.Mutex
+	dir      string // created on first install
+	entries  map[ProviderIdentifier]string
+	used     map[ProviderIdentifier]time.Time // last install or lookup
+	inflight map[ProviderIdentifier]*memoryInstall
+	closed   bool
+	counters counters
+}
+
+// memoryInstall is a GetOrPut in progress, waited on by concurrent callers
+// for the same provider.
+type memoryInstall struct {
+	done     chan struct{}
+	execPath string
+	err      error
+}
+
+// NewMemoryCache creates a MemoryCache extracting providers under parent,
+// e.g. "/dev/shm", or the default temporary directory if parent is empty.
+func NewMemoryCache(parent string) *MemoryCache {
+	return &MemoryCache{
+		parent:   parent,
+		entries:  make(map[ProviderIdentifier]string),
+		used:     make(map[ProviderIdentifier]time.Time),
+		inflight: make(map[ProviderIdentifier]*memoryInstall),
+	}
+}
+
+// Get retrieves the executable path for a cached provider.
+// Returns empty string and nil error if the provider is not cached.
+func (c *MemoryCache) Get(ctx context.Context, id ProviderIdentifier) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if execPath := c.entries[id]; execPath != "" {
+		c.used[id] = time.Now()
+		return execPath, nil
+	}
+	return "", nil
+}
+
+// Has checks if a provider is cached.
+func (c *MemoryCache) Has(ctx context.Context, id ProviderIdentifier) (bool, error) {
+	execPath, err := c.Get(ctx, id)
+	return execPath != "", err
+}
+
+// Versions returns the cached versions of a provider.
+func (c *MemoryCache) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var versions []string
+	for id := range c.entries {
+		if id.Namespace == namespace && id.Name == name {
+			versions = append(versions, id.Version)
+		}
+	}
+	return versions, nil
+}
+
+// Put stores a provider archive and returns the path to the extracted executable.
+func (c *MemoryCache) Put(ctx context.Context, id ProviderIdentifier, archivePath string) (string, error) {
+	execPath, err := c.extract(id, archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[id] = execPath
+	c.used[id] = time.Now()
+	return execPath, nil
+}
+
+// GetOrPut retrieves a cached provider or invokes downloadFn to populate it.
+// Concurrent calls for the same provider wait for a single download.
+func (c *MemoryCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
+	downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (string, error) {
+
+	c.mu.Lock()
+	if execPath := c.entries[id]; execPath != "" {
+		c.used[id] = time.Now()
+		c.mu.Unlock()
+		c.counters.hits.Add(1)
+		return execPath, nil
+	}
+	if install, ok := c.inflight[id]; ok {
+		c.mu.Unlock()
+		select {
+		case <-install.done:
+			if install.err == nil {
+				c.counters.hits.Add(1)
+			}
+			return install.execPath, install.err
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	install := &memoryInstall{done: make(chan struct{})}
+	c.inflight[id] = install
+	c.mu.Unlock()
+
+	install.execPath, install.err = c.download(ctx, id, downloadFn)
+
+	c.mu.Lock()
+	delete(c.inflight, id)
+	if install.err == nil {
+		c.entries[id] = install.execPath
+		c.used[id] = time.Now()
+		c.counters.misses.Add(1)
+	}
+	c.mu.Unlock()
+	close(install.done)
+	return install.execPath, install.err
+}
+
+func (c *MemoryCache) download(ctx context.Context, id ProviderIdentifier,
+	downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (string, error) {
+
+	archivePath, cleanup, err := downloadFn(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	return c.extract(id, archivePath)
+}
+
+// extract unpacks a provider archive into its own directory.
+func (c *MemoryCache) extract(id ProviderIdentifier, archivePath string) (string, error) {
+	base, err := c.baseDir()
+	if err != nil {
+		return "", err
+	}
+
+	parent := filepath.Join(base, id.Namespace, id.Name)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(parent, id.Version+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := extractZip(archivePath, dir, c.ExtractLimits); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to extract provider: %w", err)
+	}
+	execPath := findProviderExecutable(dir, id.Name)
+	if execPath == "" {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("provider executable not found after extraction")
+	}
+	if err := os.Chmod(execPath, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", fmt.Errorf("failed to make provider executable: %w", err)
+	}
+	return execPath, nil
+}
+
+// baseDir returns the cache's temporary directory, creating it on first use.
+func (c *MemoryCache) baseDir() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return "", fmt.Errorf("cache is closed")
+	}
+	if c.dir == "" {
+		dir, err := os.MkdirTemp(c.parent, "tf-data-client-cache-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		c.dir = dir
+	}
+	return c.dir, nil
+}
+
+// Close removes every cached provider. The cache can't be used afterwards.
+func (c *MemoryCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	clear(c.entries)
+	clear(c.used)
+	if c.dir == "" {
+		return nil
+	}
+	dir := c.dir
+	c.dir = ""
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove cache directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// Metadata describes how a FilesystemCache entry was installed. It is stored
+// next to the unpacked package, in <version>.meta.json, so that it doesn't
+// change the package's hash.
+type Metadata struct {
+	InstalledAt   time.Time `json:"installed_at"`
+	ValidatedAt   time.Time `json:"validated_at"`             // last revalidation, InstalledAt at first
+	Source        string    `json:"source,omitempty"`         // where the archive came from, see ContextWithSource
+	ArchiveSHA256 string    `json:"archive_sha256,omitempty"` // hex SHA-256 of the installed archive
+	PackageHash   string    `json:"package_hash"`             // "h1:" hash of the unpacked package
+}
+
+// Stale reports whether the entry was last validated more than ttl ago. It is
+// never stale if ttl is 0.
+func (m *Metadata) Stale(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(m.ValidatedAt) > ttl
+}
+
+type sourceKey struct{}
+
+// ContextWithSource returns a context recording source, e.g. a provider's
+// registry address, in the Metadata of entries installed with it.
+func ContextWithSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+func (c *FilesystemCache) metadataPath(id ProviderIdentifier) string {
+	return c.providerDir(id) + ".meta.json"
+}
+
+// Metadata returns the metadata of a cached provider, or nil if it isn't
+// cached or was cached before metadata was recorded.
+func (c *FilesystemCache) Metadata(ctx context.Context, id ProviderIdentifier) (*Metadata, error) {
+	data, err := os.ReadFile(c.metadataPath(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache metadata: %w", err)
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("invalid cache metadata for %s/%s %s: %w", id.Namespace, id.Name, id.Version, err)
+	}
+	return &meta, nil
+}
+
+// Verify checks that a cached provider's files still match the package hash
+// recorded when it was installed, detecting tampering or corruption.
+func (c *FilesystemCache) Verify(ctx context.Context, id ProviderIdentifier) error {
+	meta, err := c.Metadata(ctx, id)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return fmt.Errorf("no metadata recorded for %s/%s %s", id.Namespace, id.Name, id.Version)
+	}
+	h1, err := dirhash.HashDir(c.providerDir(id), "", dirhash.Hash1)
+	if err != nil {
+		return fmt.Errorf("failed to hash cached provider: %w", err)
+	}
+	if h1 != meta.PackageHash {
+		return fmt.Errorf("cached provider %s/%s %s was modified: expected %s, got %s", id.Namespace, id.Name, id.Version, meta.PackageHash, h1)
+	}
+	return nil
+}
+
+// MarkValidated records that a cached provider was revalidated now.
+func (c *FilesystemCache) MarkValidated(ctx context.Context, id ProviderIdentifier) error {
+	meta, err := c.Metadata(ctx, id)
+	if err != nil || meta == nil {
+		return err
+	}
+	meta.ValidatedAt = time.Now()
+	return c.writeMetadata(id, meta)
+}
+
+// recordInstall writes the metadata of a provider just installed from
+// archivePath.
+func (c *FilesystemCache) recordInstall(ctx context.Context, id ProviderIdentifier, archivePath string) error {
+	h1, err := dirhash.HashDir(c.providerDir(id), "", dirhash.Hash1)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider: %w", err)
+	}
+	archiveSum, err := fileSHA256(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to hash provider archive: %w", err)
+	}
+
+	now := time.Now()
+	source, _ := ctx.Value(sourceKey{}).(string)
+	return c.writeMetadata(id, &Metadata{
+		InstalledAt:   now,
+		ValidatedAt:   now,
+		Source:        source,
+		ArchiveSHA256: archiveSum,
+		PackageHash:   h1,
+	})
+}
+
+func (c *FilesystemCache) writeMetadata(id ProviderIdentifier, meta *Metadata) error {
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename, so readers never see a partial file
+	path := c.metadataPath(id)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write cache metadata: %w", err)
+	}
+	return nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// ErrObjectNotFound is returned by ObjectStore.Get for missing objects.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore is a bucket of an object store such as S3, GCS or Azure Blob
+// Storage. Adapt the store's SDK client to it to share a provider cache
+// between machines with ObjectStoreCache.
+type ObjectStore interface {
+	// Get opens the object at key, or returns an error wrapping
+	// ErrObjectNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Put uploads size bytes read from r to key, replacing any object there.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+}
+
+// ObjectStoreCache implements Cache on top of an ObjectStore holding provider
+// archives, shared by a fleet of workers, and a FilesystemCache in a local
+// spill directory holding the providers extracted on this machine. A provider
+// missing locally is fetched from the store; only if it is missing there too
+// is it downloaded from the registry, then uploaded for the other workers.
+// Workers missing the same provider at the same time each download it. The
+// store is trusted: archives fetched from it are not checked against the
+// registry's checksums.
+type ObjectStoreCache struct {
+	store    ObjectStore
+	prefix   string
+	local    *FilesystemCache
+	counters counters
+}
+
+// NewObjectStoreCache creates an ObjectStoreCache storing archives under
+// prefix in store (e.g. "providers"), and extracting them in spillDir.
+func NewObjectStoreCache(store ObjectStore, prefix, spillDir string) *ObjectStoreCache {
+	return &ObjectStoreCache{
+		store:  store,
+		prefix: prefix,
+		local:  NewFilesystemCache(spillDir),
+	}
+}
+
+// Local returns the cache of the spill directory, e.g. to set its size
+// limits.
+func (c *ObjectStoreCache) Local() *FilesystemCache {
+	return c.local
+}
+
+// objectKey returns the key of a provider archive in the store.
+func (c *ObjectStoreCache) objectKey(id ProviderIdentifier) string {
+	filename := fmt.Sprintf("terraform-provider-%s_%s_%s_%s.zip", id.Name, id.Version, id.OS, id.Arch)
+	return path.Join(c.prefix, id.Namespace, id.Name, id.Version, filename)
+}
+
+// Get retrieves the executable path of a provider extracted locally.
+// Returns empty string and nil error if the provider is not cached locally.
+func (c *ObjectStoreCache) Get(ctx context.Context, id ProviderIdentifier) (string, error) {
+	return c.local.Get(ctx, id)
+}
+
+// Has checks if a provider is extracted locally.
+func (c *ObjectStoreCache) Has(ctx context.Context, id ProviderIdentifier) (bool, error) {
+	return c.local.Has(ctx, id)
+}
+
+// Versions returns the versions of a provider extracted locally.
+func (c *ObjectStoreCache) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	return c.local.Versions(ctx, namespace, name)
+}
+
+// Put uploads a provider archive to the store and extracts it locally.
+func (c *ObjectStoreCache) Put(ctx context.Context, id ProviderIdentifier, archivePath string) (string, error) {
+	if err := c.upload(ctx, id, archivePath); err != nil {
+		return "", err
+	}
+	return c.local.Put(ctx, id, archivePath)
+}
+
+// GetOrPut retrieves a provider extracted locally, or from the store, or
+// invokes downloadFn and uploads the archive to the store. A failed upload
+// doesn't fail the install: the next worker downloads the provider again.
+func (c *ObjectStoreCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
+	downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (string, error) {
+
+	missed := false
+	execPath, err := c.local.GetOrPut(ctx, id, func(ctx context.Context) (string, func(), error) {
+		archivePath, err := c.fetch(ctx, id)
+		if err == nil {
+			return archivePath, func() { os.Remove(archivePath) }, nil
+		}
+		if !errors.Is(err, ErrObjectNotFound) {
+			return "", nil, err
+		}
+
+		archivePath, cleanup, err := downloadFn(ctx)
+		if err != nil {
+			return "", nil, err
+		}
+		missed = true
+		// Best effort: the provider can be installed either way
+		c.upload(ctx, id, archivePath)
+		return archivePath, cleanup, nil
+	})
+	if err == nil {
+		if missed {
+			c.counters.misses.Add(1)
+		} else {
+			c.counters.hits.Add(1)
+		}
+	}
+	return execPath, err
+}
+
+// fetch downloads a provider archive from the store to a temporary file.
+func (c *ObjectStoreCache) fetch(ctx context.Context, id ProviderIdentifier) (string, error) {
+	r, err := c.store.Get(ctx, c.objectKey(id))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	tmpFile, err := os.CreateTemp("", "provider-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	_, err = io.Copy(tmpFile, r)
+	if err := errors.Join(err, tmpFile.Close()); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to fetch provider from object store: %w", err)
+	}
+	return tmpFile.Name(), nil
+}
+
+// upload copies a provider archive to the store.
+func (c *ObjectStoreCache) upload(ctx context.Context, id ProviderIdentifier, archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := c.store.Put(ctx, c.objectKey(id), f, stat.Size()); err != nil {
+		return fmt.Errorf("failed to upload provider to object store: %w", err)
+	}
+	return nil
+}
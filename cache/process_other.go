@@ -0,0 +1,16 @@
+//go:build !unix
+
+package cache
+
+import "os"
+
+// processAlive reports whether a process of this host is running. Finding a
+// process fails on Windows if it doesn't exist.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,14 @@
+//go:build unix
+
+package cache
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether a process of this host is running.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
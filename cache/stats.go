@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
+
+// StatsReporter is implemented by caches that report Stats.
+type StatsReporter interface {
+	// Stats returns the content and usage statistics of the cache.
+	Stats(ctx context.Context) (*Stats, error)
+}
+
+// Stats describes the content of a cache and how it was used.
+type Stats struct {
+	Entries   int   // cached provider versions
+	TotalSize int64 // bytes of unpacked providers
+	Providers []ProviderStats
+
+	// GetOrPut calls since the cache was created, served from the cache (hits)
+	// or installing a provider (misses).
+	Hits   int64
+	Misses int64
+}
+
+// ProviderStats describes the cached versions of a provider.
+type ProviderStats struct {
+	Namespace string
+	Name      string
+	Versions  []VersionStats
+}
+
+// VersionStats describes a cached provider version.
+type VersionStats struct {
+	Version  string
+	Size     int64
+	LastUsed time.Time
+}
+
+// counters counts GetOrPut hits and misses.
+type counters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// newStats aggregates cache entries into Stats, sorting providers by name.
+func newStats(entries []Entry, c *counters) *Stats {
+	stats := &Stats{Entries: len(entries), Hits: c.hits.Load(), Misses: c.misses.Load()}
+
+	providers := make(map[[2]string]*ProviderStats)
+	for _, entry := range entries {
+		stats.TotalSize += entry.Size
+		key := [2]string{entry.ID.Namespace, entry.ID.Name}
+		p, ok := providers[key]
+		if !ok {
+			p = &ProviderStats{Namespace: entry.ID.Namespace, Name: entry.ID.Name}
+			providers[key] = p
+		}
+		p.Versions = append(p.Versions, VersionStats{Version: entry.ID.Version, Size: entry.Size, LastUsed: entry.LastAccess})
+	}
+
+	for _, p := range providers {
+		sort.Slice(p.Versions, func(i, j int) bool { return versionLess(p.Versions[i].Version, p.Versions[j].Version) })
+		stats.Providers = append(stats.Providers, *p)
+	}
+	sort.Slice(stats.Providers, func(i, j int) bool {
+		a, b := stats.Providers[i], stats.Providers[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	return stats
+}
+
+// Stats returns the content of the cache, and the hits and misses of this
+// FilesystemCache value.
+func (c *FilesystemCache) Stats(ctx context.Context) (*Stats, error) {
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newStats(entries, &c.counters), nil
+}
+
+// Stats returns the content of the cache and its hits and misses.
+func (c *MemoryCache) Stats(ctx context.Context) (*Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var entries []Entry
+	for id, execPath := range c.entries {
+		entry, err := cacheEntry(filepath.Dir(execPath), id)
+		if err != nil {
+			return nil, err
+		}
+		entry.LastAccess = c.used[id]
+		entries = append(entries, entry)
+	}
+	return newStats(entries, &c.counters), nil
+}
+
+// Stats returns the providers extracted in the spill directory, and the hits
+// and misses of this cache, where providers fetched from the object store
+// count as hits.
+func (c *ObjectStoreCache) Stats(ctx context.Context) (*Stats, error) {
+	entries, err := c.local.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newStats(entries, &c.counters), nil
+}
+
+// Stats returns the statistics of the fallback cache, if it reports any, with
+// the hits and misses of this cache, where providers found in the plugin
+// cache directory count as hits.
+func (c *TerraformPluginCache) Stats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{}
+	if reporter, ok := c.fallback.(StatsReporter); ok {
+		var err error
+		if stats, err = reporter.Stats(ctx); err != nil {
+			return nil, err
+		}
+	}
+	stats.Hits, stats.Misses = c.counters.hits.Load(), c.counters.misses.Load()
+	return stats, nil
+}
+
+// versionLess orders versions by semver precedence, and invalid ones
+// lexically after valid ones.
+func versionLess(a, b string) bool {
+	va, errA := version.NewVersion(a)
+	vb, errB := version.NewVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.LessThan(vb)
+	case errA == nil || errB == nil:
+		return errA == nil
+	}
+	return a < b
+}
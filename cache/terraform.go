@@ -0,0 +1,217 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// TerraformPluginCache implements Cache on top of a Terraform plugin cache
+// directory (TF_PLUGIN_CACHE_DIR) or a .terraform/providers directory, laid
+// out as:
+//
+//	<dir>/<host>/<namespace>/<name>/<version>/<os>_<arch>/terraform-provider-<name>_v<version>
+//
+// Providers found there are used as they are. Other providers are installed
+// into the directory if it is writable, so that Terraform reuses them too, or
+// else into a fallback cache.
+type TerraformPluginCache struct {
+	dir      string
+	host     string
+	writable bool
+	fallback Cache
+	locker   *Locker
+	counters counters
+
+	ExtractLimits ExtractLimits // bounds of extracted archives; DefaultExtractLimits if unset
+	LockTimeout   time.Duration // fail with ErrLockTimeout after waiting this long for a lock; 0 for no limit
+}
+
+// NewTerraformPluginCache creates a TerraformPluginCache reading the providers
+// of host (e.g. "registry.terraform.io") from dir. If writable is false,
+// providers missing from dir are installed into fallback instead.
+func NewTerraformPluginCache(dir, host string, writable bool, fallback Cache) *TerraformPluginCache {
+	// Terraform reads every directory under dir as a hostname: keep locks out
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = dir
+	}
+	sum := sha256.Sum256([]byte(abs))
+	locksDir := filepath.Join(os.TempDir(), "tf-data-client", "plugin-cache-locks", hex.EncodeToString(sum[:8]))
+
+	return &TerraformPluginCache{
+		dir:      dir,
+		host:     host,
+		writable: writable,
+		fallback: fallback,
+		locker:   NewLocker(locksDir),
+	}
+}
+
+// Fallback returns the cache used for providers not in the plugin cache
+// directory, when it isn't writable.
+func (c *TerraformPluginCache) Fallback() Cache {
+	return c.fallback
+}
+
+// Close closes the fallback cache, if it is an io.Closer.
+func (c *TerraformPluginCache) Close() error {
+	if closer, ok := c.fallback.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// platformDir returns the directory holding a provider build.
+func (c *TerraformPluginCache) platformDir(id ProviderIdentifier) string {
+	return filepath.Join(c.dir, c.host, id.Namespace, id.Name, id.Version, id.OS+"_"+id.Arch)
+}
+
+// Get retrieves the executable path for a cached provider, from the plugin
+// cache directory or the fallback cache.
+// Returns empty string and nil error if the provider is not cached.
+func (c *TerraformPluginCache) Get(ctx context.Context, id ProviderIdentifier) (string, error) {
+	if execPath := findProviderExecutable(c.platformDir(id), id.Name); execPath != "" {
+		return execPath, nil
+	}
+	if c.fallback == nil {
+		return "", nil
+	}
+	return c.fallback.Get(ctx, id)
+}
+
+// Has checks if a provider is cached.
+func (c *TerraformPluginCache) Has(ctx context.Context, id ProviderIdentifier) (bool, error) {
+	execPath, err := c.Get(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return execPath != "", nil
+}
+
+// Put stores a provider archive and returns the path to the extracted
+// executable.
+func (c *TerraformPluginCache) Put(ctx context.Context, id ProviderIdentifier, archivePath string) (string, error) {
+	if !c.writable {
+		if c.fallback == nil {
+			return "", fmt.Errorf("plugin cache directory %s is read-only", c.dir)
+		}
+		return c.fallback.Put(ctx, id, archivePath)
+	}
+	return c.install(id, archivePath)
+}
+
+// GetOrPut retrieves a provider from the plugin cache directory or the
+// fallback cache, or invokes downloadFn to populate the plugin cache directory
+// if it is writable, or else the fallback cache. Terraform doesn't lock its
+// plugin cache: concurrent installs by Terraform itself are not excluded.
+func (c *TerraformPluginCache) GetOrPut(ctx context.Context, id ProviderIdentifier,
+	downloadFn func(ctx context.Context) (archivePath string, cleanup func(), err error)) (string, error) {
+
+	execPath, err := c.Get(ctx, id)
+	if err != nil || execPath != "" {
+		if execPath != "" {
+			c.counters.hits.Add(1)
+		}
+		return execPath, err
+	}
+	c.counters.misses.Add(1)
+	if !c.writable {
+		if c.fallback == nil {
+			return "", fmt.Errorf("provider %s/%s %s is not in read-only plugin cache directory %s", id.Namespace, id.Name, id.Version, c.dir)
+		}
+		return c.fallback.GetOrPut(ctx, id, downloadFn)
+	}
+
+	unlock, err := c.locker.acquire(ctx, id, false, c.LockTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire cache lock: %w", err)
+	}
+	defer unlock()
+
+	// Re-check cache - another process may have populated it while we waited for the lock
+	if execPath := findProviderExecutable(c.platformDir(id), id.Name); execPath != "" {
+		return execPath, nil
+	}
+
+	archivePath, cleanup, err := downloadFn(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+	return c.install(id, archivePath)
+}
+
+// install extracts a provider archive into the plugin cache directory.
+func (c *TerraformPluginCache) install(id ProviderIdentifier, archivePath string) (string, error) {
+	finalDir := c.platformDir(id)
+	versionDir := filepath.Dir(finalDir)
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Extract next to the final location, then rename it into place
+	tmpDir, err := os.MkdirTemp(versionDir, ".tmp-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	if err := extractZip(archivePath, tmpDir, c.ExtractLimits); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to extract provider: %w", err)
+	}
+	execPath := findProviderExecutable(tmpDir, id.Name)
+	if execPath == "" {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("provider executable not found after extraction")
+	}
+	if err := os.Chmod(execPath, 0755); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to make provider executable: %w", err)
+	}
+	if err := os.Chmod(tmpDir, 0755); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := os.Rename(tmpDir, finalDir); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to move provider to cache: %w", err)
+	}
+	return findProviderExecutable(finalDir, id.Name), nil
+}
+
+// Versions returns the versions of a provider built for the current platform
+// in the plugin cache directory, and those in the fallback cache.
+func (c *TerraformPluginCache) Versions(ctx context.Context, namespace, name string) ([]string, error) {
+	all, err := readDirs(filepath.Join(c.dir, c.host, namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, v := range all {
+		id := ProviderIdentifier{Namespace: namespace, Name: name, Version: v, OS: runtime.GOOS, Arch: runtime.GOARCH}
+		if findProviderExecutable(c.platformDir(id), name) != "" {
+			versions = append(versions, v)
+		}
+	}
+	if lister, ok := c.fallback.(VersionLister); ok {
+		more, err := lister.Versions(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range more {
+			if !slices.Contains(versions, v) {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions, nil
+}
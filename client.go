@@ -4,14 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/infracollect/tf-data-client/cache"
 	"github.com/infracollect/tf-data-client/registry"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 )
 
 // ProviderConfig identifies a provider. Used as input to CreateProvider/StopProvider
@@ -19,39 +28,178 @@ import (
 type ProviderConfig struct {
 	Namespace string // e.g., "hashicorp"
 	Name      string // e.g., "kubernetes"
-	Version   string // CreateProvider: optional (empty = latest). Config(): always resolved version.
+	Version   string // CreateProvider: optional (empty = latest), exact or a constraint like "~> 2.1". Config(): always resolved version.
+
+	// Alias distinguishes configurations of the same provider version, like
+	// provider aliases in Terraform (e.g. "us-east-1"). Each alias gets its
+	// own process, configured independently; without it CreateProvider
+	// returns the running provider of the same version.
+	Alias string
+
+	// Env sets environment variables for the provider process (e.g. AWS_PROFILE,
+	// KUBECONFIG, HTTPS_PROXY). They take precedence over WithProviderEnv.
+	Env map[string]string
+
+	// Sandbox overrides the client's sandbox policy (WithSandbox) for this provider.
+	Sandbox *SandboxPolicy
+
+	// AllowPrereleases overrides the client's prerelease policy
+	// (WithAllowPrereleases) when resolving the latest version or a constraint.
+	AllowPrereleases *bool
+
+	// TerraformVersion overrides the Terraform version the provider is told
+	// it runs under (WithTerraformVersion).
+	TerraformVersion string
+
+	// CallTimeout overrides the client's call timeout
+	// (WithDefaultCallTimeout) for this provider.
+	CallTimeout time.Duration
+
+	// Launch customizes the provider process: working directory, extra
+	// arguments, stdin and OS-specific attributes. Ignored with WithRemoteAgent.
+	Launch *LaunchOptions
 }
 
-// String returns a unique key for a provider including version.
+// aliasRegex matches valid provider aliases, which are identifiers as in
+// Terraform.
+var aliasRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// String returns a unique key for a provider including version and alias,
+// e.g. "hashicorp/aws@5.0.0" or "hashicorp/aws.us-east-1@5.0.0".
 // This allows running multiple versions of the same provider simultaneously.
 func (c ProviderConfig) String() string {
-	return fmt.Sprintf("%s/%s@%s", c.Namespace, c.Name, c.Version)
+	return providerKey(c.Namespace, c.Name, c.Alias, c.Version)
 }
 
-// providerKey returns the map key for a provider by resolved version.
-func providerKey(namespace, name, resolvedVersion string) string {
+// providerKey returns the map key for a provider by alias and resolved
+// version. Provider names can't contain dots, so the alias is unambiguous.
+func providerKey(namespace, name, alias, resolvedVersion string) string {
+	if alias != "" {
+		return fmt.Sprintf("%s/%s.%s@%s", namespace, name, alias, resolvedVersion)
+	}
 	return fmt.Sprintf("%s/%s@%s", namespace, name, resolvedVersion)
 }
 
+// requestKey returns the map key for a provider by requested version, which
+// may be empty or a constraint.
+func requestKey(cfg ProviderConfig) string {
+	return providerKey(cfg.Namespace, cfg.Name, cfg.Alias, cfg.Version)
+}
+
+// ProviderInfo describes a running provider, as returned by
+// Client.ListProviders.
+type ProviderInfo struct {
+	ProviderConfig // with the resolved version only
+
+	Configured bool
+	Healthy    bool      // result of the last health check (WithHealthCheck)
+	LaunchedAt time.Time // when CreateProvider launched it
+	LastUsed   time.Time // when it was last returned by CreateProvider or called
+	// PIDs are the process IDs of its processes, one per pool slot, 0 for
+	// processes that don't run locally, e.g. behind a remote agent.
+	PIDs []int
+
+	Calls      int64 // Configure, ReadDataSource and validation RPCs, including retries
+	Reads      int64 // ReadDataSource calls, including cached reads
+	ReadErrors int64 // failed ReadDataSource calls
+}
+
+// devOverrideVersion is the version reported by providers launched from a
+// development override when no version is requested.
+const devOverrideVersion = "0.0.0-dev"
+
+// ProviderManager creates and stops providers. It is implemented by *Client;
+// code depending on it can be tested with tfclienttest.Client instead.
+type ProviderManager interface {
+	CreateProvider(ctx context.Context, cfg ProviderConfig) (Provider, error)
+	StopProvider(ctx context.Context, cfg ProviderConfig) error
+	Close() error
+}
+
+var _ ProviderManager = (*Client)(nil)
+
 // Client orchestrates provider lifecycle management.
 type Client struct {
-	registry   registry.Registry
-	cache      cache.Cache
-	logger     logr.Logger
-	providers  map[string]*provider   // key = providerKey(ns, name, resolvedVersion)
-	latestKeys map[string]string      // "namespace/name" -> resolved key, when created with Version ""
-	mu         sync.Mutex
+	registry            registry.Registry
+	httpClient          *http.Client         // for the default registry
+	registryHost        string               // default registry host, registry.terraform.io if empty
+	registryCredentials registry.Credentials // nil loads the Terraform CLI credentials
+	mirrorDir           string               // serve providers from this filesystem mirror
+	registryOpts        []registry.Option    // for the default registry
+	cache               cache.Cache
+	logger              logr.Logger
+	providers           map[string]*provider       // key = providerKey(ns, name, resolvedVersion)
+	resolvedKeys        map[string]string          // requested key -> resolved key, when created with Version "" or a constraint
+	launching           map[string]*providerLaunch // key = providerKey(ns, name, resolvedVersion), while starting
+	mu                  sync.Mutex
+
+	healthCheckInterval time.Duration // 0 disables background health checks
+	maxProviders        int           // running providers, 0 for no limit
+	stopGrace           time.Duration // WithStopGracePeriod
+	crashDir            string        // WithCrashBundleDir
+	schemasDir          string        // cached provider schemas, "" with other caches than the filesystem one
+	downloadsDir        string        // partial provider downloads, "" with other caches than the filesystem one
+	refreshSchemas      bool          // WithSchemaRefresh
+	lazySchemas         bool          // WithLazySchemas
+	values              valueOptions  // WithExactNumbers, WithSortedSets
+	processesDir        string        // records of running provider processes, "" with other caches than the filesystem one
+	autoRestart         bool
+	circuitBreaker      *CircuitBreaker // WithCircuitBreaker
+	callTimeout         time.Duration   // WithDefaultCallTimeout
+	poolSize            int             // processes launched per provider
+	agent               *remoteAgent    // when set, providers run on a remote agent
+	processLimits       *ProcessLimits
+	sandbox             *SandboxPolicy
+	env                 map[string]string // added to every provider's environment
+	cleanEnv            bool              // don't inherit the host environment
+	providerOutput      io.Writer         // raw copy of provider stdout/stderr
+	unaryInterceptors   []grpc.UnaryClientInterceptor
+	streamInterceptors  []grpc.StreamClientInterceptor
+	tracer              trace.Tracer
+	auditHook           AuditHook
+	resolvers           []ConfigResolver              // rewrite configurations before checking them
+	resultCache         *ResultCache                  // reused data source results
+	events              *eventHooks                   // WithHooks and Subscribe
+	recordDir           string                        // record provider interactions here
+	replayDir           string                        // serve recorded interactions from here
+	inProcess           map[string]inProcessProvider  // "namespace/name" -> server
+	devOverrides        map[string]string             // "namespace/name" -> local binary
+	readLimiters        map[string]*rate.Limiter      // "namespace/name" -> WithReadRateLimit
+	allowPrereleases    bool                          // consider prereleases when resolving versions
+	terraformVersion    string                        // sent to providers in ConfigureProvider
+	lockFile            map[string]*lockedProvider    // source address -> pinned version and hashes
+	selections          map[string]*providerSelection // source address -> installed provider, for WriteLockFile
+	policy              *ProviderPolicy               // restricts which providers may run
+	offline             bool                          // forbid network access
+	prefetchConcurrency int                           // providers fetched at once by Prefetch
+	downloadProgress    func(DownloadProgress)        // reports provider download progress
+	cacheMaxBytes       int64                         // size limit of the filesystem cache, 0 for none
+	cacheMaxEntries     int                           // entry limit of the filesystem cache, 0 for none
+	cacheTTL            time.Duration                 // revalidate cached providers after this long, 0 never
+	cacheDedup          bool                          // hard link identical files across cached versions
+	extractLimits       *cache.ExtractLimits          // nil for the cache's own limits
+	cacheLockTimeout    time.Duration                 // 0 to wait for cache locks until the context is done
+	pluginCacheDir      string                        // Terraform plugin cache directory to reuse, if set
+	pluginCacheWritable bool                          // install new providers into pluginCacheDir
 }
 
 // New creates a new Client with the given options.
 // If no options are provided, it uses default settings:
 // - Filesystem cache at ~/.opentofu-data-client/providers
-// - Terraform registry
+// - Terraform registry (see WithRegistryHost) with Terraform CLI credentials
 func New(opts ...Option) (*Client, error) {
 	c := &Client{
-		providers:  make(map[string]*provider),
-		latestKeys: make(map[string]string),
-		logger:     logr.Discard(),
+		providers:    make(map[string]*provider),
+		resolvedKeys: make(map[string]string),
+		launching:    make(map[string]*providerLaunch),
+		selections:   make(map[string]*providerSelection),
+		logger:       logr.Discard(),
+		poolSize:     1,
+		tracer:       noopTracer,
+		events:       &eventHooks{},
+
+		terraformVersion: defaultTerraformVersion,
+		stopGrace:        defaultStopGracePeriod,
 	}
 
 	for _, opt := range opts {
@@ -60,71 +208,321 @@ func New(opts ...Option) (*Client, error) {
 		}
 	}
 
-	if c.registry == nil {
-		c.registry = registry.NewTerraformRegistry(nil)
+	if c.recordDir != "" && c.replayDir != "" {
+		return nil, fmt.Errorf("WithRecording and WithReplay are mutually exclusive")
+	}
+	if c.offline && c.agent != nil {
+		return nil, fmt.Errorf("WithOfflineMode and WithRemoteAgent are mutually exclusive")
+	}
+
+	if c.registry == nil && c.mirrorDir != "" {
+		c.registry = registry.NewFilesystemMirror(c.mirrorDir, c.registryHost)
+	}
+	if c.registry == nil && !c.offline {
+		creds := c.registryCredentials
+		if creds == nil {
+			var err error
+			if creds, err = registry.LoadCredentials(); err != nil {
+				return nil, fmt.Errorf("failed to load registry credentials: %w", err)
+			}
+		}
+		c.registry = registry.NewRegistry(registry.AuthenticatedClient(c.httpClient, creds), c.registryHost, c.registryOpts...)
 	}
 
 	if c.cache == nil {
-		homeDir, err := os.UserHomeDir()
+		cacheDir, err := DefaultCacheDir()
 		if err != nil {
-			return nil, fmt.Errorf("failed to get home directory: %w", err)
+			return nil, err
 		}
-		cacheDir := filepath.Join(homeDir, ".tf-data-client", "providers")
 		c.cache = cache.NewFilesystemCache(cacheDir)
 	}
+	if c.cacheMaxBytes > 0 || c.cacheMaxEntries > 0 {
+		fsCache, ok := c.cache.(*cache.FilesystemCache)
+		if !ok {
+			return nil, fmt.Errorf("WithCacheLimits requires the filesystem cache, not %T", c.cache)
+		}
+		fsCache.MaxSizeBytes = c.cacheMaxBytes
+		fsCache.MaxEntries = c.cacheMaxEntries
+	}
+	if c.cacheDedup {
+		fsCache, ok := c.cache.(*cache.FilesystemCache)
+		if !ok {
+			return nil, fmt.Errorf("WithCacheDedup requires the filesystem cache, not %T", c.cache)
+		}
+		fsCache.Dedup = true
+	}
+	if fsCache, ok := c.cache.(*cache.FilesystemCache); ok && processTracking {
+		c.processesDir = processesDir(fsCache.Dir())
+	}
+	if fsCache, ok := c.cache.(*cache.FilesystemCache); ok {
+		c.schemasDir = schemasDir(fsCache.Dir())
+		c.downloadsDir = filepath.Join(fsCache.Dir(), ".downloads")
+	}
+	if _, ok := c.cache.(*cache.FilesystemCache); c.cacheTTL > 0 && !ok {
+		return nil, fmt.Errorf("WithCacheTTL requires the filesystem cache, not %T", c.cache)
+	}
+	if c.pluginCacheDir != "" {
+		host := c.registryHost
+		if host == "" {
+			host = registry.TerraformRegistryHost
+		}
+		c.cache = cache.NewTerraformPluginCache(c.pluginCacheDir, host, c.pluginCacheWritable, c.cache)
+	}
+	if c.extractLimits != nil {
+		if err := setExtractLimits(c.cache, *c.extractLimits); err != nil {
+			return nil, err
+		}
+	}
+	if c.cacheLockTimeout > 0 {
+		if err := setLockTimeout(c.cache, c.cacheLockTimeout); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.offline {
+		// Only a filesystem mirror may stay in use: other registries are remote
+		offline := &offlineRegistry{cache: c.cache}
+		if mirror, ok := c.registry.(*registry.FilesystemMirror); ok {
+			offline.mirror = mirror
+		}
+		c.registry = offline
+	}
 
 	return c, nil
 }
 
+// DefaultCacheDir returns the directory of the default filesystem cache,
+// ~/.tf-data-client/providers.
+func DefaultCacheDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".tf-data-client", "providers"), nil
+}
+
 // CreateProvider downloads (if needed), launches, and fetches schema for a provider.
 // If cfg.Version is empty, fetches and uses the latest version from registry.
 // The returned Provider.Config() has the actual resolved version (use it for StopProvider if you passed "").
-func (c *Client) CreateProvider(ctx context.Context, cfg ProviderConfig) (Provider, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	// Resolve version if not specified
-	version := cfg.Version
-	if version == "" {
-		latest, err := c.registry.GetLatestVersion(ctx, cfg.Namespace, cfg.Name)
-		if err != nil {
-			return nil, &ErrProviderNotFound{
-				Namespace: cfg.Namespace,
-				Name:      cfg.Name,
-				Err:       err,
-			}
+// Different providers are created concurrently; concurrent calls for the same
+// provider share a single launch.
+func (c *Client) CreateProvider(ctx context.Context, cfg ProviderConfig) (_ Provider, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.CreateProvider", providerAttrs(cfg.Namespace, cfg.Name, cfg.Version)...)
+	defer func() { endSpan(span, err) }()
+
+	if cfg.Alias != "" && !aliasRegex.MatchString(cfg.Alias) {
+		return nil, fmt.Errorf("invalid alias %q for provider %s/%s: must start with a letter or underscore and contain only letters, digits, underscores and dashes", cfg.Alias, cfg.Namespace, cfg.Name)
+	}
+	terraformVersion := c.terraformVersion
+	if cfg.TerraformVersion != "" {
+		if err := checkTerraformVersion(cfg.TerraformVersion); err != nil {
+			return nil, fmt.Errorf("provider %s/%s: %w", cfg.Namespace, cfg.Name, err)
 		}
-		version = latest
+		terraformVersion = cfg.TerraformVersion
 	}
 
-	key := providerKey(cfg.Namespace, cfg.Name, version)
+	rule, err := c.policy.rule(cfg.Namespace, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
 
-	// Check if provider is already running (match "" or specific version)
+	// Resolution and launch happen without holding c.mu, so that a slow
+	// provider doesn't hold up others; concurrent calls for the same provider
+	// wait for a single launch.
+	version, locked, err := c.resolveRequest(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if version != cfg.Version {
+		span.SetAttributes(attribute.String("provider.version", version))
+	}
+
+	key := providerKey(cfg.Namespace, cfg.Name, cfg.Alias, version)
+
+	c.mu.Lock()
+	// Check if provider is already running (match "", constraint or specific version)
 	if existing, ok := c.providers[key]; ok {
-		if cfg.Version == "" {
-			c.latestKeys[cfg.Namespace+"/"+cfg.Name] = key
+		if version != cfg.Version {
+			c.resolvedKeys[requestKey(cfg)] = key
 		}
+		c.mu.Unlock()
+		existing.touch()
 		return existing, nil
 	}
+	if l, ok := c.launching[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-l.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if l.err != nil {
+			return nil, l.err
+		}
+		if version != cfg.Version {
+			c.mu.Lock()
+			if c.providers[key] == l.provider {
+				c.resolvedKeys[requestKey(cfg)] = key
+			}
+			c.mu.Unlock()
+		}
+		return l.provider, nil
+	}
+	if err := c.makeRoom(); err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	l := &providerLaunch{done: make(chan struct{})}
+	c.launching[key] = l
+	c.mu.Unlock()
+
+	provider, selection, err := c.startProvider(ctx, cfg, rule, version, locked, terraformVersion)
+
+	c.mu.Lock()
+	delete(c.launching, key)
+	if err == nil && l.abandoned {
+		provider.Close()
+		provider, err = nil, fmt.Errorf("client closed while launching provider %s", key)
+	}
+	if err == nil {
+		c.providers[key] = provider
+		if selection != nil {
+			c.selections[c.providerAddress(cfg.Namespace, cfg.Name)] = selection
+		}
+		if version != cfg.Version {
+			c.resolvedKeys[requestKey(cfg)] = key
+		}
+	}
+	c.mu.Unlock()
 
-	// Get executable path (from cache or download) using resolved version
-	execPath, err := c.getOrDownloadProvider(ctx, cfg.Namespace, cfg.Name, version)
+	l.provider, l.err = provider, err
+	close(l.done)
 	if err != nil {
-		return nil, &ErrDownloadFailed{
-			Namespace: cfg.Namespace,
-			Name:      cfg.Name,
-			Version:   version,
-			Err:       err,
+		return nil, err
+	}
+	return provider, nil
+}
+
+// providerLaunch is a provider being started by CreateProvider, waited on by
+// concurrent callers for the same provider.
+type providerLaunch struct {
+	done      chan struct{}
+	provider  *provider
+	err       error
+	abandoned bool // set by Close, guarded by Client.mu
+}
+
+// startProvider downloads (if needed), launches and fetches the schema of the
+// provider resolved to version, returning what to record in the lock file.
+func (c *Client) startProvider(ctx context.Context, cfg ProviderConfig, rule *ProviderRule, version string, locked *lockedProvider, terraformVersion string) (*provider, *providerSelection, error) {
+	devPath, devOverride := c.devOverrides[cfg.Namespace+"/"+cfg.Name]
+
+	resolved := ProviderConfig{Namespace: cfg.Namespace, Name: cfg.Name, Version: version, Alias: cfg.Alias}
+
+	if err := c.checkPolicyRule(ctx, rule, resolved); err != nil {
+		return nil, nil, err
+	}
+
+	var selection *providerSelection
+	var schemas *schemaFile
+	var launch func() (*pluginInstance, error)
+	if ip, ok := c.inProcess[cfg.Namespace+"/"+cfg.Name]; ok && ip.version == version {
+		c.logger.V(1).Info("serving in-process provider", "provider", resolved.String())
+		launch = func() (*pluginInstance, error) {
+			return serveInMemory(&inProcessServer{server: ip.server}, c.dialOptions())
+		}
+	} else if c.replayDir != "" && !devOverride {
+		c.logger.V(1).Info("replaying provider", "provider", resolved.String(), "dir", c.replayDir)
+		launch = func() (*pluginInstance, error) {
+			return launchReplay(c.replayDir, resolved, c.dialOptions())
+		}
+	} else if c.agent != nil && !devOverride {
+		// The agent downloads and launches the provider on its own host.
+		c.logger.V(1).Info("using remote provider", "provider", resolved.String(), "agent", c.agent.target)
+		launch = func() (*pluginInstance, error) {
+			return launchRemote(c.agent, resolved, c.dialOptions())
+		}
+	} else {
+		execPath := devPath
+		if devOverride {
+			// Like Terraform, make it obvious that a local build is in use.
+			c.logger.Info("provider development override in effect", "provider", resolved.String(), "path", devPath)
+		} else {
+			// Get executable path (from cache or download) using resolved version
+			var archiveSum string
+			var err error
+			execPath, archiveSum, err = c.getOrDownloadProvider(ctx, cfg.Namespace, cfg.Name, version)
+			if err != nil {
+				return nil, nil, &ErrDownloadFailed{
+					Namespace: cfg.Namespace,
+					Name:      cfg.Name,
+					Version:   version,
+					Err:       err,
+				}
+			}
+			if locked != nil {
+				if err := verifyLocked(cfg, locked, filepath.Dir(execPath), archiveSum); err != nil {
+					return nil, nil, err
+				}
+			}
+			selection = &providerSelection{
+				version:    version,
+				packageDir: filepath.Dir(execPath),
+				archiveSum: archiveSum,
+			}
+			if isVersionConstraint(cfg.Version) {
+				selection.constraints = cfg.Version
+			}
+			if c.schemasDir != "" {
+				schemas = newSchemaFile(c.schemasDir, cfg.Namespace, cfg.Name, version, execPath, c.refreshSchemas)
+			}
+		}
+
+		sandbox := c.sandbox
+		if cfg.Sandbox != nil {
+			sandbox = cfg.Sandbox
+		}
+
+		env := c.providerEnv(cfg)
+
+		c.logger.V(1).Info("launching provider", "namespace", cfg.Namespace, "name", cfg.Name, "version", version, "path", execPath, "sandboxed", sandbox != nil)
+		launch = func() (*pluginInstance, error) {
+			return launchPlugin(launchConfig{
+				execPath: execPath,
+				env:      env,
+				logger:   c.logger.WithValues("namespace", cfg.Namespace, "name", cfg.Name, "version", version),
+				limits:   c.processLimits,
+				sandbox:  sandbox,
+				opts:     cfg.Launch,
+				output:   c.providerOutput,
+				dialOpts: c.dialOptions(),
+
+				provider:     resolved.String(),
+				processesDir: c.processesDir,
+			})
+		}
+	}
+
+	if c.recordDir != "" {
+		var err error
+		if launch, err = withRecording(c.recordDir, resolved, launch); err != nil {
+			return nil, nil, &ErrLaunchFailed{
+				Namespace: cfg.Namespace,
+				Name:      cfg.Name,
+				Version:   version,
+				Err:       err,
+			}
 		}
 	}
 
 	// Launch provider
-	c.logger.V(1).Info("launching provider", "namespace", cfg.Namespace, "name", cfg.Name, "version", version, "path", execPath)
-	provider, err := launchProvider(execPath, c.logger)
+	_, launchSpan := startSpan(ctx, c.tracer, "tfclient.LaunchProvider", providerAttrs(cfg.Namespace, cfg.Name, version)...)
+	insts, err := c.launchPool(c.poolSize, launch, resolved)
+	endSpan(launchSpan, err)
 	if err != nil {
 		var pm *errProtocolMismatch
 		if errors.As(err, &pm) {
-			return nil, &ErrProtocolUnsupported{
+			return nil, nil, &ErrProtocolUnsupported{
 				Namespace:       cfg.Namespace,
 				Name:            cfg.Name,
 				Version:         version,
@@ -132,7 +530,7 @@ func (c *Client) CreateProvider(ctx context.Context, cfg ProviderConfig) (Provid
 				ClientVersion:   pm.clientVersion,
 			}
 		}
-		return nil, &ErrLaunchFailed{
+		return nil, nil, &ErrLaunchFailed{
 			Namespace: cfg.Namespace,
 			Name:      cfg.Name,
 			Version:   version,
@@ -140,29 +538,56 @@ func (c *Client) CreateProvider(ctx context.Context, cfg ProviderConfig) (Provid
 		}
 	}
 
-	provider.namespace = cfg.Namespace
-	provider.name = cfg.Name
-	provider.version = version
+	provider := newProvider(cfg.Namespace, cfg.Name, version, insts, c.logger)
+	provider.alias = cfg.Alias
+	provider.terraformVersion = terraformVersion
+	provider.launch = launch
+	provider.autoRestart = c.autoRestart
+	provider.breaker = newCircuitBreaker(c.circuitBreaker)
+	provider.readLimiter = c.readLimiters[cfg.Namespace+"/"+cfg.Name]
+	provider.callTimeout = c.callTimeout
+	if cfg.CallTimeout > 0 {
+		provider.callTimeout = cfg.CallTimeout
+	}
+	provider.tracer = c.tracer
+	provider.auditHook = c.auditHook
+	provider.results = c.resultCache
+	provider.events = c.events
+	provider.launchedAt = time.Now()
+	provider.stopGrace = c.stopGrace
+	provider.crashDir = c.crashDir
+	provider.schemaFile = schemas
+	provider.values = c.values
+	provider.resolvers = c.resolvers
+	provider.touch()
+	for _, inst := range insts {
+		c.events.emit(&ProviderLaunched{Provider: resolved, Slot: inst.slot})
+	}
 
-	if err := provider.getSchema(ctx); err != nil {
+	getSchema := provider.getSchema
+	if c.lazySchemas {
+		getSchema = provider.discoverSchema
+	}
+	if err := getSchema(ctx); err != nil {
 		provider.Close()
-		return nil, &ErrSchemaFailed{
+		return nil, nil, &ErrSchemaFailed{
 			Namespace: cfg.Namespace,
 			Name:      cfg.Name,
 			Err:       err,
 		}
 	}
 
-	c.providers[key] = provider
-	if cfg.Version == "" {
-		c.latestKeys[cfg.Namespace+"/"+cfg.Name] = key
+	if c.healthCheckInterval > 0 {
+		provider.startHealthCheck(c.healthCheckInterval)
 	}
-	return provider, nil
+
+	return provider, selection, nil
 }
 
 // getOrDownloadProvider returns the path to a provider executable,
-// downloading it first if not cached.
-func (c *Client) getOrDownloadProvider(ctx context.Context, namespace, name, version string) (string, error) {
+// downloading it first if not cached, and the SHA-256 of the downloaded
+// archive ("" if it was cached).
+func (c *Client) getOrDownloadProvider(ctx context.Context, namespace, name, version string) (_ string, archiveSum string, err error) {
 	id := cache.ProviderIdentifier{
 		Namespace: namespace,
 		Name:      name,
@@ -171,71 +596,276 @@ func (c *Client) getOrDownloadProvider(ctx context.Context, namespace, name, ver
 		Arch:      runtime.GOARCH,
 	}
 
-	return c.cache.GetOrPut(ctx, id, func(ctx context.Context) (string, func(), error) {
-		downloadInfo, err := c.registry.GetDownloadInfo(ctx, namespace, name, version, runtime.GOOS, runtime.GOARCH)
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Cache.GetOrPut", providerAttrs(namespace, name, version)...)
+	defer func() { endSpan(span, err) }()
+
+	if err := c.revalidateCached(ctx, id); err != nil {
+		return "", "", err
+	}
+	ctx = cache.ContextWithSource(ctx, c.providerAddress(namespace, name))
+
+	span.SetAttributes(attribute.Bool("cache.hit", true))
+	execPath, err := c.cache.GetOrPut(ctx, id, func(ctx context.Context) (string, func(), error) {
+		span.SetAttributes(attribute.Bool("cache.hit", false))
+
+		downloadInfo, err := c.downloadInfo(ctx, namespace, name, version)
 		if err != nil {
 			return "", nil, fmt.Errorf("failed to get download info: %w", err)
 		}
+		// Never extract an archive that can't be verified
+		if downloadInfo.SHA256Sum == "" {
+			return "", nil, fmt.Errorf("registry published no SHA-256 checksum for %s/%s %s, refusing to install it", namespace, name, version)
+		}
 
-		tmpFile, err := os.CreateTemp("", "provider-*.zip")
+		tmpPath, cleanup, err := c.downloadPath(namespace, name, version)
 		if err != nil {
-			return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+			return "", nil, err
 		}
-		tmpPath := tmpFile.Name()
-		tmpFile.Close()
-		cleanup := func() { os.Remove(tmpPath) }
 
-		if err := c.registry.DownloadToPath(ctx, downloadInfo, tmpPath); err != nil {
+		if c.downloadProgress != nil {
+			provider := ProviderConfig{Namespace: namespace, Name: name, Version: version}
+			ctx = registry.ContextWithProgress(ctx, func(downloaded, total int64) {
+				c.downloadProgress(DownloadProgress{Provider: provider, Downloaded: downloaded, Total: total})
+			})
+		}
+		if err := c.downloadEmitting(ctx, ProviderConfig{Namespace: namespace, Name: name, Version: version}, downloadInfo, tmpPath); err != nil {
 			cleanup()
 			return "", nil, fmt.Errorf("failed to download provider: %w", err)
 		}
 
+		archiveSum = downloadInfo.SHA256Sum
 		return tmpPath, cleanup, nil
 	})
+	return execPath, archiveSum, err
 }
 
-// StopProvider stops a specific provider by namespace, name, and version.
-func (c *Client) StopProvider(ctx context.Context, cfg ProviderConfig) error {
+// downloadPath returns where to download the archive of a provider, and a
+// function removing it. With the filesystem cache, it is a stable path in a
+// private directory of the cache, so that an interrupted download is resumed
+// by the next attempt; the cache lock keeps other processes off it. Other
+// caches get a private temporary directory per download.
+func (c *Client) downloadPath(namespace, name, version string) (string, func(), error) {
+	file := fmt.Sprintf("%s_terraform-provider-%s_%s_%s_%s.zip", namespace, name, version, runtime.GOOS, runtime.GOARCH)
+	if c.downloadsDir == "" {
+		dir, err := os.MkdirTemp("", "tf-data-client-download-")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to create download directory: %w", err)
+		}
+		return filepath.Join(dir, file), func() { os.RemoveAll(dir) }, nil
+	}
+
+	if err := os.MkdirAll(c.downloadsDir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	// Refuse a directory planted by someone else, which could swap the
+	// archive between its verification and its extraction. Only its owner
+	// can make it private.
+	info, err := os.Lstat(c.downloadsDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check download directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", nil, fmt.Errorf("download directory %s is not a directory", c.downloadsDir)
+	}
+	if err := os.Chmod(c.downloadsDir, 0o700); err != nil {
+		return "", nil, fmt.Errorf("failed to make download directory private: %w", err)
+	}
+	path := filepath.Join(c.downloadsDir, file)
+	return path, func() { os.Remove(path) }, nil
+}
+
+// latestVersion looks up the latest version of a provider in the registry,
+// including prereleases if prereleases is set.
+func (c *Client) latestVersion(ctx context.Context, namespace, name string, prereleases bool) (_ string, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.GetLatestVersion", providerAttrs(namespace, name, "")...)
+	defer func() { endSpan(span, err) }()
+	if ip, ok := c.inProcess[namespace+"/"+name]; ok {
+		return ip.version, nil
+	}
+	if _, ok := c.devOverrides[namespace+"/"+name]; ok {
+		return devOverrideVersion, nil
+	}
+	if c.replayDir != "" {
+		return recordedVersion(c.replayDir, namespace, name)
+	}
+	if prereleases {
+		versions, err := c.availableVersions(ctx, namespace, name)
+		if err != nil {
+			return "", err
+		}
+		if latest := newestVersion(versions, nil, true); latest != "" {
+			return latest, nil
+		}
+		return "", fmt.Errorf("no versions found for provider %s/%s", namespace, name)
+	}
+	return c.registry.GetLatestVersion(ctx, namespace, name)
+}
+
+// downloadInfo looks up where to download a provider for this platform.
+func (c *Client) downloadInfo(ctx context.Context, namespace, name, version string) (*registry.DownloadInfo, error) {
+	return c.downloadInfoFor(ctx, namespace, name, version, runtime.GOOS, runtime.GOARCH)
+}
+
+// downloadInfoFor looks up where to download a provider for a platform.
+func (c *Client) downloadInfoFor(ctx context.Context, namespace, name, version, goos, goarch string) (_ *registry.DownloadInfo, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.GetDownloadInfo", providerAttrs(namespace, name, version)...)
+	defer func() { endSpan(span, err) }()
+	return c.registry.GetDownloadInfo(ctx, namespace, name, version, goos, goarch)
+}
+
+// download fetches a provider archive to path.
+func (c *Client) download(ctx context.Context, info *registry.DownloadInfo, path string) (err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.Download", attribute.String("download.url", info.DownloadURL))
+	defer func() { endSpan(span, err) }()
+	return c.registry.DownloadToPath(ctx, info, path)
+}
+
+// downloadEmitting downloads a provider archive with download, sending
+// ProviderDownloadStarted and ProviderDownloadFinished events.
+func (c *Client) downloadEmitting(ctx context.Context, provider ProviderConfig, info *registry.DownloadInfo, path string) error {
+	start := time.Now()
+	c.events.emit(&ProviderDownloadStarted{Provider: provider, Time: start})
+	err := c.download(ctx, info, path)
+	c.events.emit(&ProviderDownloadFinished{Provider: provider, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// dialOptions returns the gRPC dial options applying the configured
+// interceptors to provider connections.
+func (c *Client) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if len(c.unaryInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(c.unaryInterceptors...))
+	}
+	if len(c.streamInterceptors) > 0 {
+		opts = append(opts, grpc.WithChainStreamInterceptor(c.streamInterceptors...))
+	}
+	return opts
+}
+
+// GetProvider returns the running provider created with cfg, matching its
+// namespace, name and version as CreateProvider would without launching
+// anything: an exact version, or the empty version or constraint it was
+// created with. ok is false if there is none.
+func (c *Client) GetProvider(cfg ProviderConfig) (_ Provider, ok bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if _, provider := c.runningProvider(cfg); provider != nil {
+		return provider, true
+	}
+	return nil, false
+}
 
-	var key string
-	if cfg.Version == "" {
-		key = c.latestKeys[cfg.Namespace+"/"+cfg.Name]
-	} else {
-		key = providerKey(cfg.Namespace, cfg.Name, cfg.Version)
+// ListProviders describes the running providers, sorted by
+// namespace/name@version.
+func (c *Client) ListProviders() []ProviderInfo {
+	c.mu.Lock()
+	providers := make([]*provider, 0, len(c.providers))
+	for _, p := range c.providers {
+		providers = append(providers, p)
+	}
+	c.mu.Unlock()
+
+	infos := make([]ProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		info := ProviderInfo{
+			ProviderConfig: p.Config(),
+			Configured:     p.IsConfigured(),
+			Healthy:        p.Healthy(),
+			LaunchedAt:     p.launchedAt,
+			LastUsed:       p.lastUsedAt(),
+			Calls:          p.calls.Load(),
+			Reads:          p.reads.Load(),
+			ReadErrors:     p.readErrors.Load(),
+		}
+		for _, inst := range p.instances() {
+			info.PIDs = append(info.PIDs, inst.conn.Pid())
+		}
+		infos = append(infos, info)
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].String() < infos[j].String() })
+	return infos
+}
 
-	provider, ok := c.providers[key]
-	if !ok {
-		return nil
+// runningProvider returns the running provider created with cfg and its key
+// in c.providers, or nil. The caller must hold c.mu.
+func (c *Client) runningProvider(cfg ProviderConfig) (string, *provider) {
+	var key string
+	if cfg.Version == "" || isVersionConstraint(cfg.Version) {
+		key = c.resolvedKeys[requestKey(cfg)]
+	} else {
+		key = providerKey(cfg.Namespace, cfg.Name, cfg.Alias, cfg.Version)
 	}
+	return key, c.providers[key]
+}
 
-	if err := provider.Close(); err != nil {
-		return err
+// StopProvider stops a specific provider by namespace, name, and version.
+func (c *Client) StopProvider(ctx context.Context, cfg ProviderConfig) error {
+	c.mu.Lock()
+	key, provider := c.runningProvider(cfg)
+	if provider != nil {
+		c.forget(key)
 	}
+	c.mu.Unlock()
 
-	delete(c.providers, key)
-	if cfg.Version == "" {
-		delete(c.latestKeys, cfg.Namespace+"/"+cfg.Name)
+	// Stopping may take the grace period; don't hold up other providers
+	if provider == nil {
+		return nil
 	}
-	return nil
+	return provider.Close()
 }
 
-// Close stops all running providers.
+// Close stops all running providers at once, without waiting for calls in
+// progress, and closes the cache if it is an io.Closer, such as
+// cache.MemoryCache. Use Shutdown to let calls complete.
 func (c *Client) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	return c.shutdown(context.Background(), false)
+}
+
+// Shutdown stops all running providers in parallel like Close, but first
+// lets their calls in progress, such as reads, complete until ctx is done,
+// then kills the providers still busy. The returned error joins the errors of
+// every provider, including which were killed busy.
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.shutdown(ctx, true)
+}
 
-	var lastErr error
+func (c *Client) shutdown(ctx context.Context, drain bool) error {
+	c.mu.Lock()
+	// Providers still launching are closed once started
+	for _, l := range c.launching {
+		l.abandoned = true
+	}
+	providers := make([]*provider, 0, len(c.providers))
 	for key, provider := range c.providers {
-		if err := provider.Close(); err != nil {
-			lastErr = err
-		}
+		providers = append(providers, provider)
 		delete(c.providers, key)
 	}
-	for k := range c.latestKeys {
-		delete(c.latestKeys, k)
+	for k := range c.resolvedKeys {
+		delete(c.resolvedKeys, k)
+	}
+	c.mu.Unlock()
+
+	errs := make([]error, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if drain {
+				errs[i] = provider.shutdown(ctx)
+			} else {
+				errs[i] = provider.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Caches such as cache.MemoryCache only live as long as the client
+	if closer, ok := c.cache.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	return lastErr
+	return errors.Join(errs...)
 }
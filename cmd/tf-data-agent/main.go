@@ -0,0 +1,119 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/go-logr/logr"
+	tfclient "github.com/infracollect/tf-data-client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	listen := flag.String("listen", ":7443", "Address to listen on")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	clientCA := flag.String("client-ca", "", "CA bundle used to verify client certificates (required unless --insecure)")
+	insecure := flag.Bool("insecure", false, "Serve without TLS or client authentication, letting anyone reaching the port use the agent's credentials (for local testing only)")
+	cacheDir := flag.String("cache-dir", "", "Provider cache directory (optional)")
+	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+
+	flag.Parse()
+
+	logLevel := slog.LevelInfo
+	if *verbose {
+		logLevel = slog.LevelDebug
+	}
+	logger := logr.FromSlogHandler(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+
+	var serverOpts []grpc.ServerOption
+	if !*insecure {
+		creds, err := serverCredentials(*tlsCert, *tlsKey, *clientCA)
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, grpc.Creds(creds))
+	} else {
+		logger.Info("serving without TLS or client authentication, anyone reaching the agent can use its credentials")
+	}
+
+	opts := []tfclient.Option{tfclient.WithLogger(logger)}
+	if *cacheDir != "" {
+		opts = append(opts, tfclient.WithCacheDir(*cacheDir))
+	}
+
+	client, err := tfclient.New(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	server := grpc.NewServer(serverOpts...)
+	tfclient.NewAgentServer(client).Register(server)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		logger.Info("shutting down")
+		server.GracefulStop()
+	}()
+
+	logger.Info("agent listening", "address", lis.Addr().String(), "tls", !*insecure)
+	return server.Serve(lis)
+}
+
+// serverCredentials builds TLS credentials requiring client certificates
+// issued by clientCA: the agent runs providers with the credentials of its
+// host, so only authenticated clients may call it.
+func serverCredentials(certFile, keyFile, clientCA string) (credentials.TransportCredentials, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("--tls-cert and --tls-key are required (or pass --insecure)")
+	}
+	if clientCA == "" {
+		return nil, fmt.Errorf("--client-ca is required to authenticate clients with certificates (or pass --insecure)")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	pem, err := os.ReadFile(clientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", clientCA)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return credentials.NewTLS(cfg), nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/go-version"
+	"github.com/infracollect/tf-data-client/cache"
+)
+
+const cacheUsage = `Usage: %s cache <command> [flags]
+
+Commands:
+  list     List cached providers with their sizes
+  prune    Remove least recently used providers
+  verify   Check cached providers against the hashes recorded when installed
+  path     Print the path of a cached provider binary
+`
+
+// runCache implements "tf-data-client cache <command>", managing the
+// filesystem provider cache.
+func runCache(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, cacheUsage, os.Args[0])
+		return usageErrorf("a cache command is required")
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		fmt.Fprintf(os.Stderr, cacheUsage, os.Args[0])
+		return nil
+	case "list":
+		return runCacheList(args[1:])
+	case "prune":
+		return runCachePrune(args[1:])
+	case "verify":
+		return runCacheVerify(args[1:])
+	case "path":
+		return runCachePath(args[1:])
+	}
+	fmt.Fprintf(os.Stderr, cacheUsage, os.Args[0])
+	return usageErrorf("unknown cache command %q", args[0])
+}
+
+// cacheFlags registers the flags shared by cache commands, returning a
+// function opening the cache once they are parsed.
+func cacheFlags(fs *flag.FlagSet) func() (*cache.FilesystemCache, error) {
+	cacheDir := fs.String("cache-dir", settings.CacheDir, "Provider cache directory (optional, TFDC_CACHE_DIR)")
+	return func() (*cache.FilesystemCache, error) {
+		return openFilesystemCache(*cacheDir)
+	}
+}
+
+// runCacheList implements "tf-data-client cache list".
+func runCacheList(args []string) error {
+	fs := newFlagSet("cache list", "", "List cached providers with their sizes and when they were last used.")
+	openCache := cacheFlags(fs)
+	asJSON := fs.Bool("json", false, "Output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.Stats(context.Background())
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		out, err := json.MarshalIndent(stats.Providers, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal cache entries to JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tVERSION\tSIZE\tLAST USED")
+	for _, p := range stats.Providers {
+		for _, v := range p.Versions {
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", p.Namespace, p.Name, v.Version, formatSize(v.Size), v.LastUsed.Format(time.DateTime))
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d provider version(s), %s\n", stats.Entries, formatSize(stats.TotalSize))
+	return nil
+}
+
+// runCachePrune implements "tf-data-client cache prune", evicting the least
+// recently used providers until the cache is within the given limits.
+func runCachePrune(args []string) error {
+	fs := newFlagSet("cache prune", "", "Remove the least recently used providers until the cache is within limits.")
+	openCache := cacheFlags(fs)
+	maxSize := fs.String("max-size", "", "Maximum total size of cached providers, e.g. 2GB")
+	maxEntries := fs.Int("max-entries", 0, "Maximum number of cached provider versions")
+	olderThan := fs.String("older-than", "", "Remove providers not used for this long, e.g. 30d or 12h")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	c.MaxEntries = *maxEntries
+	if *maxSize != "" {
+		if c.MaxSizeBytes, err = parseSize(*maxSize); err != nil {
+			return usageErrorf("invalid --max-size: %w", err)
+		}
+	}
+	if *olderThan != "" {
+		if c.MaxAge, err = parseAge(*olderThan); err != nil {
+			return usageErrorf("invalid --older-than: %w", err)
+		}
+	}
+	if c.MaxSizeBytes <= 0 && c.MaxEntries <= 0 && c.MaxAge <= 0 {
+		return usageErrorf("--max-size, --max-entries or --older-than is required")
+	}
+
+	evicted, err := c.GC(context.Background())
+	for _, entry := range evicted {
+		fmt.Printf("Removed %s/%s %s (%s)\n", entry.ID.Namespace, entry.ID.Name, entry.ID.Version, formatSize(entry.Size))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune cache: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Removed %d provider(s)\n", len(evicted))
+	return nil
+}
+
+// runCacheVerify implements "tf-data-client cache verify", failing if a
+// cached provider was modified since it was installed.
+func runCacheVerify(args []string) error {
+	fs := newFlagSet("cache verify", "", "Check cached providers against the hashes recorded when they were installed.")
+	openCache := cacheFlags(fs)
+	remove := fs.Bool("remove", false, "Remove modified providers, to be reinstalled on next use")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, entry := range entries {
+		id := entry.ID
+		meta, err := c.Metadata(ctx, id)
+		switch {
+		case err != nil:
+			fmt.Printf("ERROR     %s/%s %s: %v\n", id.Namespace, id.Name, id.Version, err)
+			failed++
+		case meta == nil:
+			fmt.Printf("UNKNOWN   %s/%s %s: no hash recorded\n", id.Namespace, id.Name, id.Version)
+		default:
+			if err := c.Verify(ctx, id); err != nil {
+				fmt.Printf("MODIFIED  %s/%s %s: %v\n", id.Namespace, id.Name, id.Version, err)
+				if !*remove {
+					failed++
+				} else if err := c.Evict(ctx, id); err != nil {
+					return fmt.Errorf("failed to remove %s/%s %s: %w", id.Namespace, id.Name, id.Version, err)
+				}
+				continue
+			}
+			fmt.Printf("OK        %s/%s %s\n", id.Namespace, id.Name, id.Version)
+		}
+	}
+	if failed > 0 {
+		return withExitCode(exitDownload, fmt.Errorf("%d cached provider(s) failed verification; run with --remove to remove them", failed))
+	}
+	return nil
+}
+
+// runCachePath implements "tf-data-client cache path namespace/name", printing
+// the path of a cached provider binary, of the latest cached version unless
+// --version is set.
+func runCachePath(args []string) error {
+	fs := newFlagSet("cache path", "namespace/name", "Print the path of a cached provider binary, of the latest cached version by default.")
+	openCache := cacheFlags(fs)
+	versionFlag := fs.String("version", "", "Provider version (optional, defaults to the latest cached)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageErrorf("usage: %s cache path [flags] namespace/name", os.Args[0])
+	}
+	namespace, name, ok := strings.Cut(fs.Arg(0), "/")
+	if !ok {
+		return usageErrorf("provider must be in format namespace/name (e.g., hashicorp/kubernetes)")
+	}
+	c, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	v := *versionFlag
+	if v == "" {
+		if v, err = cachedVersion(ctx, c, namespace, name, ""); err != nil {
+			return err
+		}
+	}
+
+	execPath, err := c.Get(ctx, cache.ProviderIdentifier{Namespace: namespace, Name: name, Version: v, OS: runtime.GOOS, Arch: runtime.GOARCH})
+	if err != nil {
+		return err
+	}
+	if execPath == "" {
+		return withExitCode(exitNotFound, fmt.Errorf("provider %s/%s %s is not cached", namespace, name, v))
+	}
+	fmt.Println(execPath)
+	return nil
+}
+
+// cachedVersion returns the latest cached version of a provider matching a
+// version constraint, or of any version if it is empty.
+func cachedVersion(ctx context.Context, c *cache.FilesystemCache, namespace, name, constraint string) (string, error) {
+	var constraints version.Constraints
+	if constraint != "" {
+		var err error
+		if constraints, err = version.NewConstraint(constraint); err != nil {
+			return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+	}
+
+	versions, err := c.Versions(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	var latest *version.Version
+	for _, cached := range versions {
+		parsed, err := version.NewVersion(cached)
+		if err != nil || !constraints.Check(parsed) {
+			continue
+		}
+		if latest == nil || parsed.GreaterThan(latest) {
+			latest = parsed
+		}
+	}
+	if latest == nil {
+		if constraint != "" {
+			return "", fmt.Errorf("no cached version of %s/%s matches %q", namespace, name, constraint)
+		}
+		return "", fmt.Errorf("provider %s/%s is not cached", namespace, name)
+	}
+	return latest.Original(), nil
+}
+
+// parseSize parses a size in bytes, with an optional KB, MB or GB suffix
+// (powers of 1024).
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(upper, unit.suffix); ok {
+			n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("%q is not a size", s)
+			}
+			return int64(n * float64(unit.size)), nil
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a size", s)
+	}
+	return n, nil
+}
+
+// parseAge parses a duration, accepting a "d" suffix for days.
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a duration", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+	return d, nil
+}
+
+// formatSize formats a size in bytes for humans.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/cache"
+)
+
+// Completion scripts call "tf-data-client __complete" with the words of the
+// command line, the last one being completed, and print its candidates.
+// When there are none, they fall back to completing file names.
+
+const bashCompletion = `# bash completion for tf-data-client
+_tf_data_client() {
+    local IFS=$'\n'
+    local candidates
+    candidates=$("${COMP_WORDS[0]}" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+    COMPREPLY=($(compgen -W "$candidates" -- "${COMP_WORDS[COMP_CWORD]}"))
+}
+complete -o default -F _tf_data_client tf-data-client
+`
+
+const zshCompletion = `#compdef tf-data-client
+# zsh completion for tf-data-client
+_tf_data_client() {
+    local -a candidates
+    candidates=("${(@f)$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _tf_data_client tf-data-client
+`
+
+const fishCompletion = `# fish completion for tf-data-client
+function __tf_data_client_complete
+    set -l words (commandline -opc) (commandline -ct)
+    set -l candidates ($words[1] __complete $words[2..-1] 2>/dev/null)
+    if test (count $candidates) -gt 0
+        printf '%s\n' $candidates
+    else
+        __fish_complete_path (commandline -ct)
+    end
+end
+complete -c tf-data-client -f -a '(__tf_data_client_complete)'
+`
+
+// runCompletion implements "tf-data-client completion <shell>".
+func runCompletion(args []string) error {
+	fs := newFlagSet("completion", "bash|zsh|fish", `Print a shell completion script. Load it with, e.g.:
+
+  bash: source <(tf-data-client completion bash)
+  zsh:  source <(tf-data-client completion zsh)
+  fish: tf-data-client completion fish | source`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageErrorf("a shell is required")
+	}
+
+	scripts := map[string]string{"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}
+	script, ok := scripts[fs.Arg(0)]
+	if !ok {
+		return usageErrorf("unsupported shell %q, expected bash, zsh or fish", fs.Arg(0))
+	}
+	_, err := os.Stdout.WriteString(script)
+	return err
+}
+
+// subcommands lists the subcommands of the commands that have some.
+var subcommands = map[string][]string{
+	"cache":  {"list", "prune", "verify", "path"},
+	"schema": {"describe"},
+}
+
+// runComplete implements the hidden "tf-data-client __complete" command,
+// printing the candidates for the last word of args, one per line.
+func runComplete(args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	for _, candidate := range complete(args[:len(args)-1], args[len(args)-1]) {
+		fmt.Println(candidate)
+	}
+	return nil
+}
+
+// complete returns the candidates for current, following words.
+func complete(words []string, current string) []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	if len(words) == 0 {
+		return matching(names, current)
+	}
+
+	switch words[0] {
+	case "help":
+		if len(words) == 1 {
+			return matching(names, current)
+		}
+		return nil
+	case "completion":
+		if len(words) == 1 {
+			return matching([]string{"bash", "zsh", "fish"}, current)
+		}
+		return nil
+	}
+
+	path := words[:1]
+	if subs, ok := subcommands[words[0]]; ok {
+		if len(words) == 1 {
+			return matching(subs, current)
+		}
+		path = words[:2]
+	}
+	fs := commandFlags(path)
+	if fs == nil {
+		return nil
+	}
+	args := words[len(path):]
+
+	// The value of a flag
+	if len(args) > 0 {
+		prev := args[len(args)-1]
+		if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") {
+			if f := fs.Lookup(strings.TrimLeft(prev, "-")); f != nil && !isBoolFlag(f) {
+				fs.Parse(args[:len(args)-1])
+				return matching(flagValues(f.Name, fs), current)
+			}
+		}
+	}
+
+	if strings.HasPrefix(current, "-") {
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) { flags = append(flags, "--"+f.Name) })
+		return matching(flags, current)
+	}
+
+	// Positional arguments, once the flags typed so far are parsed
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		return nil
+	}
+	switch strings.Join(path, " ") {
+	case "read", "schema describe":
+		return matching(completeDataSources(fs), current)
+	case "cache path", "versions":
+		return matching(cachedProviders(fs.Lookup("cache-dir").Value.String()), current)
+	}
+	return nil
+}
+
+// commandFlags returns the flag set of a command, such as ["schema",
+// "describe"], or nil if there is no such command. The command is run with
+// -h, which returns once its flags are defined.
+func commandFlags(path []string) *flag.FlagSet {
+	for _, cmd := range commands {
+		if cmd.name != path[0] {
+			continue
+		}
+		completing = true
+		lastFlagSet = nil
+		cmd.run(append(append([]string(nil), path[1:]...), "-h"))
+		return lastFlagSet
+	}
+	return nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValues returns the candidate values of a flag, or nil to complete
+// file names.
+func flagValues(name string, fs *flag.FlagSet) []string {
+	switch name {
+	case "format":
+		return outputFormats
+	case "log-level":
+		return []string{"debug", "info", "warn", "error"}
+	case "provider":
+		cacheDir := ""
+		if f := fs.Lookup("cache-dir"); f != nil {
+			cacheDir = f.Value.String()
+		}
+		return cachedProviders(cacheDir)
+	}
+	return nil
+}
+
+// matching returns the candidates starting with prefix.
+func matching(candidates []string, prefix string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// openFilesystemCache opens the provider cache in dir, or the default one.
+func openFilesystemCache(dir string) (*cache.FilesystemCache, error) {
+	if dir == "" {
+		var err error
+		if dir, err = tfclient.DefaultCacheDir(); err != nil {
+			return nil, err
+		}
+	}
+	return cache.NewFilesystemCache(dir), nil
+}
+
+// cachedProviders returns the namespace/name of the cached providers.
+func cachedProviders(cacheDir string) []string {
+	c, err := openFilesystemCache(cacheDir)
+	if err != nil {
+		return nil
+	}
+	stats, err := c.Stats(context.Background())
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, p := range stats.Providers {
+		names = append(names, p.Namespace+"/"+p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeDataSources returns the data sources of the provider of the
+// --provider, --version and --cache-dir flags. Only cached providers are
+// considered, and their data sources are remembered in the user cache
+// directory, since starting large providers takes seconds.
+func completeDataSources(fs *flag.FlagSet) []string {
+	providerName := fs.Lookup("provider").Value.String()
+	namespace, name, ok := strings.Cut(providerName, "/")
+	if !ok {
+		return nil
+	}
+	cacheDir := fs.Lookup("cache-dir").Value.String()
+	constraint := fs.Lookup("version").Value.String()
+	if constraint == "" {
+		constraint = settings.Providers[providerName]
+	}
+
+	ctx := context.Background()
+	c, err := openFilesystemCache(cacheDir)
+	if err != nil {
+		return nil
+	}
+	v, err := cachedVersion(ctx, c, namespace, name, constraint)
+	if err != nil {
+		return nil
+	}
+
+	var listPath string
+	if dir, err := os.UserCacheDir(); err == nil {
+		listPath = filepath.Join(dir, "tf-data-client", "completion", fmt.Sprintf("%s-%s-%s", namespace, name, v))
+		if data, err := os.ReadFile(listPath); err == nil {
+			return strings.Fields(string(data))
+		}
+	}
+
+	pf := &providerFlags{
+		clientFlags: &clientFlags{cacheDir: cacheDir, offline: true, logLevel: "error"},
+		provider:    providerName,
+		version:     v,
+	}
+	client, provider, err := pf.startProvider(ctx)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+
+	dataSources := provider.ListDataSources()
+	sort.Strings(dataSources)
+	if listPath != "" && os.MkdirAll(filepath.Dir(listPath), 0755) == nil {
+		os.WriteFile(listPath, []byte(strings.Join(dataSources, "\n")+"\n"), 0644)
+	}
+	return dataSources
+}
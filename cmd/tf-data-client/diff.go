@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/diff"
+)
+
+// diffFormats are the values of diff --format.
+var diffFormats = []string{"json", "jsonl", "text"}
+
+// errStatesDiffer is returned with --exit-code when the states differ.
+var errStatesDiffer = errors.New("the states differ")
+
+// runDiff implements "tf-data-client diff", comparing two results, or a
+// baseline result with the state read now.
+func runDiff(args []string) error {
+	fs := newFlagSet("diff", "<old.json> [new.json]", "Compare two data source results, or a baseline result with the state of --request read now, printing the list of changes.")
+	cf := addClientFlags(fs)
+	requestFile := fs.String("request", "", "JSON or YAML request document with provider, version, config, data_source and data_config, read now and compared with the baseline <old.json> (optional)")
+	schemaFile := fs.String("schema", "", "Schema of the data source from \"schema describe --json\", to compare sets as sets and elide sensitive values (optional, defaults to the provider's with --request)")
+	format := fs.String("format", "json", "Output format: json, jsonl (one change per line) or text")
+	showSensitive := fs.Bool("show-sensitive", false, "With --request, compare the attributes the schema marks as sensitive instead of masking them as read does")
+	exitChanges := fs.Bool("exit-code", false, "Exit with code 8 when the states differ")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	wantArgs := 2
+	if *requestFile != "" {
+		wantArgs = 1
+	}
+	if fs.NArg() != wantArgs {
+		fs.Usage()
+		if wantArgs == 1 {
+			return usageErrorf("a baseline is required with --request, and only one")
+		}
+		return usageErrorf("two results to compare are required, or a baseline and --request")
+	}
+	if !slices.Contains(diffFormats, *format) {
+		return usageErrorf("unknown format %q, expected one of %s", *format, strings.Join(diffFormats, ", "))
+	}
+
+	old, err := loadState(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	var schema *tfclient.Schema
+	if *schemaFile != "" {
+		data, err := readInput(*schemaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		schema = &tfclient.Schema{}
+		if err := json.Unmarshal(data, schema); err != nil {
+			return fmt.Errorf("failed to parse schema %s: %w", *schemaFile, err)
+		}
+	}
+
+	var current map[string]any
+	if *requestFile == "" {
+		if current, err = loadState(fs.Arg(1)); err != nil {
+			return err
+		}
+	} else {
+		req, err := loadRequest(*requestFile)
+		if err != nil {
+			return err
+		}
+		if req.DataSource == "" {
+			return usageErrorf("request %s has no data_source", *requestFile)
+		}
+		pf := &providerFlags{clientFlags: cf, provider: req.Provider, version: req.Version}
+		ctx := context.Background()
+		client, provider, err := pf.startProvider(ctx)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if err := configureProvider(ctx, provider, req.Config); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Reading data source %s...\n", req.DataSource)
+		result, err := provider.ReadDataSource(ctx, req.DataSource, req.DataConfig)
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to read data source: %w", err))
+		}
+		if schema == nil {
+			if schema, err = provider.DataSourceSchema(req.DataSource); err != nil {
+				return fmt.Errorf("failed to get schema: %w", err)
+			}
+		}
+		// Mask sensitive values as read does, so that baselines it wrote
+		// compare equal
+		if !*showSensitive {
+			redactSchema(result.State, schema)
+		}
+		data, err := json.Marshal(result.State)
+		if err != nil {
+			return fmt.Errorf("failed to marshal result to JSON: %w", err)
+		}
+		if current, err = decodeState(data); err != nil {
+			return err
+		}
+	}
+
+	changes := diff.States(old, current, schema)
+	if err := writeChanges(changes, *format); err != nil {
+		return err
+	}
+	if *exitChanges && len(changes) > 0 {
+		return errStatesDiffer
+	}
+	return nil
+}
+
+// loadState reads a result document, as written by read with --format json,
+// from path, or stdin if path is "-".
+func loadState(path string) (map[string]any, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result: %w", err)
+	}
+	state, err := decodeState(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse result %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// decodeState decodes a JSON state, keeping the precision of numbers, so
+// that states read now and from files compare alike.
+func decodeState(data []byte) (map[string]any, error) {
+	var state map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// writeChanges prints changes to stdout in format.
+func writeChanges(changes []diff.Change, format string) error {
+	switch format {
+	case "jsonl":
+		enc := json.NewEncoder(os.Stdout)
+		for _, c := range changes {
+			if err := enc.Encode(c); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "text":
+		if len(changes) == 0 {
+			fmt.Fprintln(os.Stderr, "No changes.")
+		}
+		for _, c := range changes {
+			old, new := changeValue(c.Old, c.Sensitive), changeValue(c.New, c.Sensitive)
+			switch c.Op {
+			case diff.Added:
+				fmt.Printf("+ %s: %s\n", c.Path, new)
+			case diff.Removed:
+				fmt.Printf("- %s: %s\n", c.Path, old)
+			default:
+				fmt.Printf("~ %s: %s -> %s\n", c.Path, old, new)
+			}
+		}
+		return nil
+	}
+
+	if changes == nil {
+		changes = []diff.Change{}
+	}
+	data, err := json.MarshalIndent(changes, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal changes to JSON: %w", err)
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// changeValue formats a value of a change for the text format.
+func changeValue(v any, sensitive bool) string {
+	if sensitive {
+		return redacted
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// Exit codes, stable across releases so that scripts can branch on the class
+// of a failure.
+const (
+	exitFailure   = 1 // any other error
+	exitUsage     = 2 // invalid command, flag or argument
+	exitNotFound  = 3 // provider, version or data source not found
+	exitDownload  = 4 // provider download or verification failed
+	exitConfigure = 5 // provider configuration failed
+	exitRead      = 6 // data source read failed
+	exitEmpty     = 7 // empty result with --fail-on-empty
+	exitChanged   = 8 // states differ with diff --exit-code
+	exitTimeout   = 9 // provider call timed out
+)
+
+// codedError is an error with the exit code of its class.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// withExitCode returns err with an exit code, or nil if err is nil.
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code: code, err: err}
+}
+
+// usageErrorf returns an error exiting with exitUsage.
+func usageErrorf(format string, args ...any) error {
+	return &codedError{code: exitUsage, err: fmt.Errorf(format, args...)}
+}
+
+// exitCode returns the exit code of err. The not found, download and timeout
+// errors of the library are recognized wherever they occur, since the step
+// that failed, e.g. reading a data source that doesn't exist, is less
+// specific.
+func exitCode(err error) int {
+	var (
+		providerNotFound  *tfclient.ErrProviderNotFound
+		versionNotFound   *tfclient.ErrVersionNotFound
+		cacheMiss         *tfclient.ErrOfflineCacheMiss
+		dataNotFound      *tfclient.ErrDataSourceNotFound
+		downloadFailed    *tfclient.ErrDownloadFailed
+		checksumMismatch  *tfclient.ErrChecksumMismatch
+		signatureMismatch *tfclient.ErrSignatureVerification
+		unsafeArchive     *tfclient.ErrUnsafeArchive
+		lockMismatch      *tfclient.ErrLockMismatch
+		callTimeout       *tfclient.ErrCallTimeout
+		coded             *codedError
+	)
+	switch {
+	case errors.Is(err, errEmptyResult):
+		return exitEmpty
+	case errors.Is(err, errStatesDiffer):
+		return exitChanged
+	case errors.As(err, &providerNotFound), errors.As(err, &versionNotFound),
+		errors.As(err, &cacheMiss), errors.As(err, &dataNotFound):
+		return exitNotFound
+	case errors.As(err, &downloadFailed), errors.As(err, &checksumMismatch),
+		errors.As(err, &signatureMismatch), errors.As(err, &unsafeArchive),
+		errors.As(err, &lockMismatch):
+		return exitDownload
+	case errors.As(err, &callTimeout):
+		return exitTimeout
+	case errors.As(err, &coded):
+		return coded.code
+	}
+	return exitFailure
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.yaml.in/yaml/v3"
+)
+
+// exporterConfig declares the providers, reads and metrics of
+// "tf-data-client exporter". Providers are declared as in run manifests.
+type exporterConfig struct {
+	// Interval is how often reads without their own interval are run,
+	// defaulting to a minute.
+	Interval  time.Duration               `yaml:"interval"`
+	Providers map[string]manifestProvider `yaml:"providers"`
+	Reads     []exporterRead              `yaml:"reads"`
+}
+
+type exporterRead struct {
+	manifestRead `yaml:",inline"`
+
+	Interval time.Duration    `yaml:"interval"`
+	Metrics  []exporterMetric `yaml:"metrics"`
+}
+
+// exporterMetric maps the state of a read to the samples of a gauge.
+type exporterMetric struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+	// Query selects the values that are each a sample, e.g. ".items[]",
+	// defaulting to the state itself.
+	Query string `yaml:"query"`
+	// Value is the query of the value of a sample, relative to what Query
+	// selected: a number, a boolean, or a string holding a number or an
+	// RFC 3339 time, exported as a Unix timestamp. Samples are 1 without it,
+	// as for info metrics.
+	Value string `yaml:"value"`
+	// Labels maps label names to the query of their value, relative to what
+	// Query selected.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// loadExporterConfig reads and validates an exporter configuration.
+func loadExporterConfig(path string) (*exporterConfig, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exporter configuration: %w", err)
+	}
+	c := exporterConfig{Interval: time.Minute}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse exporter configuration %s: %w", path, err)
+	}
+
+	if len(c.Reads) == 0 {
+		return nil, fmt.Errorf("exporter configuration %s has no reads", path)
+	}
+	if c.Interval <= 0 {
+		return nil, fmt.Errorf("exporter configuration %s has a non-positive interval", path)
+	}
+	names := make(map[string]bool)
+	for i, read := range c.Reads {
+		switch {
+		case read.Name == "":
+			return nil, fmt.Errorf("read %d of exporter configuration %s has no name", i+1, path)
+		case names[read.Name]:
+			return nil, fmt.Errorf("exporter configuration %s has several reads named %q", path, read.Name)
+		case read.DataSource == "":
+			return nil, fmt.Errorf("read %q of exporter configuration %s has no data_source", read.Name, path)
+		case len(read.Metrics) == 0:
+			return nil, fmt.Errorf("read %q of exporter configuration %s has no metrics", read.Name, path)
+		case read.Interval < 0:
+			return nil, fmt.Errorf("read %q of exporter configuration %s has a negative interval", read.Name, path)
+		}
+		if _, ok := c.Providers[read.Provider]; !ok {
+			return nil, fmt.Errorf("read %q of exporter configuration %s refers to undeclared provider %q", read.Name, path, read.Provider)
+		}
+		if read.Interval == 0 {
+			c.Reads[i].Interval = c.Interval
+		}
+		names[read.Name] = true
+	}
+	return &c, nil
+}
+
+// runExporter implements "tf-data-client exporter", serving metrics from
+// data sources read on an interval.
+func runExporter(args []string) error {
+	fs := newFlagSet("exporter", "", `Serve Prometheus metrics on /metrics from data sources read on an interval, e.g.:
+
+  interval: 5m
+  providers:
+    tls:
+      source: hashicorp/tls
+      version: "~> 4.0"
+  reads:
+    - name: example_com
+      provider: tls
+      data_source: tls_certificate
+      config:
+        url: https://example.com
+      metrics:
+        - name: tls_certificate_not_after_timestamp_seconds
+          help: Expiry time of the certificates of a URL.
+          query: .certificates[]
+          value: .not_after
+          labels:
+            subject: .subject`)
+	cf := addClientFlags(fs)
+	configPath := fs.String("config", "", "Exporter configuration file (required)")
+	listen := fs.String("listen", "127.0.0.1:9464", "Address the metrics are served on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return usageErrorf("exporter takes no arguments")
+	}
+	if *configPath == "" {
+		fs.Usage()
+		return usageErrorf("--config is required")
+	}
+	c, err := loadExporterConfig(*configPath)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	e := newExporter(reg)
+	reads := make([]*exportedRead, len(c.Reads))
+	for i, read := range c.Reads {
+		if reads[i], err = e.newRead(read); err != nil {
+			return withExitCode(exitUsage, fmt.Errorf("read %s: %w", read.Name, err))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Providers run for the lifetime of the exporter
+	var names []string
+	for _, read := range c.Reads {
+		names = append(names, read.Provider)
+	}
+	providers, clients, err := startManifestProviders(ctx, cf, c.Providers, names)
+	for _, client := range clients {
+		defer client.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	lis, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{Registry: reg}))
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	errs := make(chan error, 1)
+	slog.Info("serving metrics", "address", lis.Addr().String(), "reads", len(reads))
+	go func() {
+		if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, read := range reads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			read.run(ctx, providers[read.Provider])
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+		stop()
+	}
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	srv.Shutdown(shutdownCtx)
+	wg.Wait()
+	return err
+}
+
+// exporter holds the metrics about the reads themselves.
+type exporter struct {
+	reg         prometheus.Registerer
+	success     *prometheus.GaugeVec
+	duration    *prometheus.GaugeVec
+	lastSuccess *prometheus.GaugeVec
+}
+
+func newExporter(reg prometheus.Registerer) *exporter {
+	e := &exporter{
+		reg: reg,
+		success: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tf_data_client_read_success",
+			Help: "Whether the last read of a data source succeeded.",
+		}, []string{"read"}),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tf_data_client_read_duration_seconds",
+			Help: "Duration of the last read of a data source.",
+		}, []string{"read"}),
+		lastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tf_data_client_read_last_success_timestamp_seconds",
+			Help: "Time of the last successful read of a data source.",
+		}, []string{"read"}),
+	}
+	reg.MustRegister(e.success, e.duration, e.lastSuccess)
+	return e
+}
+
+// exportedRead is a read with the gauges of its metrics.
+type exportedRead struct {
+	exporterRead
+	e       *exporter
+	metrics []*exportedMetric
+}
+
+type exportedMetric struct {
+	gauge  *prometheus.GaugeVec
+	query  query // nil for the state itself
+	value  query // nil for 1
+	labels []string
+	values []query // of labels
+}
+
+// newRead parses the queries of the metrics of read and registers their
+// gauges.
+func (e *exporter) newRead(read exporterRead) (*exportedRead, error) {
+	r := &exportedRead{exporterRead: read, e: e}
+	for _, m := range read.Metrics {
+		em := &exportedMetric{}
+		var err error
+		if m.Query != "" {
+			if em.query, err = parseQuery(m.Query); err != nil {
+				return nil, fmt.Errorf("metric %s: %w", m.Name, err)
+			}
+		}
+		if m.Value != "" {
+			if em.value, err = parseQuery(m.Value); err != nil {
+				return nil, fmt.Errorf("metric %s: %w", m.Name, err)
+			}
+		}
+		for label := range m.Labels {
+			em.labels = append(em.labels, label)
+		}
+		sort.Strings(em.labels)
+		for _, label := range em.labels {
+			q, err := parseQuery(m.Labels[label])
+			if err != nil {
+				return nil, fmt.Errorf("metric %s: label %s: %w", m.Name, label, err)
+			}
+			em.values = append(em.values, q)
+		}
+
+		help := m.Help
+		if help == "" {
+			help = fmt.Sprintf("From data source %s of read %s.", read.DataSource, read.Name)
+		}
+		em.gauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: m.Name, Help: help}, em.labels)
+		if err := e.reg.Register(em.gauge); err != nil {
+			return nil, fmt.Errorf("metric %s: %w", m.Name, err)
+		}
+		r.metrics = append(r.metrics, em)
+	}
+	return r, nil
+}
+
+// run reads the data source every interval until ctx is done.
+func (r *exportedRead) run(ctx context.Context, provider tfclient.Provider) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	for {
+		r.read(ctx, provider)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// read reads the data source and updates the gauges. They keep their
+// previous samples when the read fails.
+func (r *exportedRead) read(ctx context.Context, provider tfclient.Provider) {
+	start := time.Now()
+	result, err := provider.ReadDataSource(ctx, r.DataSource, r.Config)
+	r.e.duration.WithLabelValues(r.Name).Set(time.Since(start).Seconds())
+	if err == nil {
+		for _, m := range r.metrics {
+			if err = m.update(result.State); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("failed to read data source", "read", r.Name, "data_source", r.DataSource, "error", err)
+		}
+		r.e.success.WithLabelValues(r.Name).Set(0)
+		return
+	}
+	r.e.success.WithLabelValues(r.Name).Set(1)
+	r.e.lastSuccess.WithLabelValues(r.Name).SetToCurrentTime()
+}
+
+// update replaces the samples of the gauge with those of state.
+func (m *exportedMetric) update(state map[string]any) error {
+	selected := []any{state}
+	if m.query != nil {
+		var err error
+		if selected, err = m.query.eval(state); err != nil {
+			return fmt.Errorf("failed to apply query: %w", err)
+		}
+	}
+
+	type sample struct {
+		labels []string
+		value  float64
+	}
+	var samples []sample
+	for _, v := range selected {
+		value := 1.0
+		if m.value != nil {
+			values, err := m.value.eval(v)
+			if err != nil {
+				return fmt.Errorf("failed to apply value query: %w", err)
+			}
+			var ok bool
+			if len(values) == 0 || values[0] == nil {
+				continue
+			}
+			if value, ok = sampleValue(values[0]); !ok {
+				return fmt.Errorf("value %s is not a number, a boolean or a time", scalarString(values[0]))
+			}
+		}
+		labels := make([]string, len(m.values))
+		for i, q := range m.values {
+			values, err := q.eval(v)
+			if err != nil {
+				return fmt.Errorf("failed to apply label query: %w", err)
+			}
+			if len(values) > 0 && values[0] != nil {
+				labels[i] = scalarString(values[0])
+			}
+		}
+		samples = append(samples, sample{labels, value})
+	}
+
+	m.gauge.Reset()
+	for _, s := range samples {
+		m.gauge.WithLabelValues(s.labels...).Set(s.value)
+	}
+	return nil
+}
+
+// sampleValue converts a value of a state to a sample value.
+func sampleValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, true
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return float64(t.UnixNano()) / 1e9, true
+		}
+	}
+	return 0, false
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	tfclient "github.com/infracollect/tf-data-client"
 	"github.com/go-logr/logr"
@@ -16,132 +18,398 @@ import (
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// command is a tf-data-client subcommand.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+// commands lists the subcommands in the order of the usage message. It is
+// set in init, as the help command refers to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"read", "Read a data source", runRead},
+		{"list", "List the data sources of a provider", runList},
+		{"run", "Read the data sources of a manifest", runRun},
+		{"diff", "Compare two data source results", runDiff},
+		{"schema", "Describe the schema of a data source", runSchema},
+		{"search", "Search the registry for providers", runSearch},
+		{"versions", "List the versions of a provider", runVersions},
+		{"outdated", "Report providers with newer versions", runOutdated},
+		{"cache", "Manage the provider cache", runCache},
+		{"reap", "Stop provider processes left by crashed runs", runReap},
+		{"mirror", "Download providers into a mirror directory", runMirror},
+		{"serve", "Serve an HTTP API reading data sources", runServe},
+		{"exporter", "Serve Prometheus metrics from data sources", runExporter},
+		{"completion", "Print a shell completion script", runCompletion},
+		{"help", "Show the help of a command", runHelp},
 	}
 }
 
 func run() error {
-	// Parse command line flags
-	providerArg := flag.String("provider", "", "Provider to use (e.g., hashicorp/kubernetes)")
-	version := flag.String("version", "", "Provider version (optional, defaults to latest)")
-	dataSource := flag.String("data-source", "", "Data source to read (e.g., kubernetes_all_namespaces)")
-	configJSON := flag.String("config", "{}", "Provider configuration as JSON")
-	dataConfigJSON := flag.String("data-config", "{}", "Data source configuration as JSON")
-	output := flag.String("output", "", "Output file for JSON result (optional, defaults to stdout)")
-	listDataSources := flag.Bool("list-data-sources", false, "List available data sources and exit")
-	cacheDir := flag.String("cache-dir", "", "Provider cache directory (optional)")
-	verbose := flag.Bool("verbose", false, "Enable verbose logging")
+	args := os.Args[1:]
+	if len(args) == 0 {
+		usage()
+		return usageErrorf("a command is required")
+	}
+	if isHelpFlag(args[0]) {
+		usage()
+		return nil
+	}
 
-	flag.Parse()
+	var err error
+	if settings, err = loadSettings(); err != nil {
+		return err
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runLegacy(args)
+	}
+	if args[0] == "__complete" {
+		return runComplete(args[1:])
+	}
 
-	if *providerArg == "" {
-		return fmt.Errorf("--provider is required")
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(args[1:])
+		}
 	}
+	usage()
+	return usageErrorf("unknown command %q", args[0])
+}
 
-	// Parse provider argument (namespace/name)
-	parts := strings.Split(*providerArg, "/")
-	if len(parts) != 2 {
-		return fmt.Errorf("provider must be in format namespace/name (e.g., hashicorp/kubernetes)")
+// usage prints the list of commands.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"%s help <command>\" for the flags of a command.\n", os.Args[0])
+}
+
+// runHelp implements "tf-data-client help [command]".
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return nil
+	}
+	for _, cmd := range commands {
+		if cmd.name == args[0] && cmd.name != "help" {
+			return cmd.run([]string{"-h"})
+		}
+	}
+	usage()
+	return usageErrorf("unknown command %q", args[0])
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// completing is set while completing a command line, when flag sets return
+// parse errors and print nothing rather than exiting.
+var completing bool
+
+// lastFlagSet is the flag set newFlagSet returned last, which completion
+// inspects.
+var lastFlagSet *flag.FlagSet
+
+// newFlagSet returns the flag set of a command, whose -h prints its usage
+// line, description and flags.
+func newFlagSet(name, args, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	if completing {
+		fs.Init(name, flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+	}
+	lastFlagSet = fs
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] %s\n\n%s\n\nFlags:\n", os.Args[0], name, args, description)
+		fs.PrintDefaults()
 	}
-	namespace, name := parts[0], parts[1]
+	return fs
+}
+
+// clientFlags are the flags of commands creating a client. Their defaults
+// come from the settings.
+type clientFlags struct {
+	cacheDir     string
+	registryHost string
+	offline      bool
+	logLevel     string
+	verbose      bool
+	tfVersion    string
+	refresh      bool
+	exact        bool
+	sortSets     bool
+	interpolate  bool
+	secrets      bool
+	rateLimits   stringsFlag
+	callTimeout  time.Duration
 
-	// Create client with options
-	var opts []tfclient.Option
-	if *cacheDir != "" {
-		opts = append(opts, tfclient.WithCacheDir(*cacheDir))
+	options []tfclient.Option // set by commands, e.g. serve's result cache
+}
+
+// parseReadRateLimit parses a --read-rate-limit value, namespace/name=rps
+// with an optional :burst, which defaults to 1.
+func parseReadRateLimit(s string) (tfclient.Option, error) {
+	source, limit, ok := strings.Cut(s, "=")
+	if namespace, name, found := strings.Cut(source, "/"); !ok || !found || namespace == "" || name == "" {
+		return nil, usageErrorf("invalid --read-rate-limit %q, expected namespace/name=rps[:burst]", s)
+	}
+	rpsText, burstText, hasBurst := strings.Cut(limit, ":")
+	rps, err := strconv.ParseFloat(rpsText, 64)
+	if err != nil || rps <= 0 {
+		return nil, usageErrorf("invalid --read-rate-limit %q: rps must be a positive number", s)
+	}
+	burst := 1
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstText); err != nil || burst < 1 {
+			return nil, usageErrorf("invalid --read-rate-limit %q: burst must be a positive integer", s)
+		}
+	}
+	return tfclient.WithReadRateLimit(source, rps, burst), nil
+}
+
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	f := &clientFlags{}
+	fs.StringVar(&f.cacheDir, "cache-dir", settings.CacheDir, "Provider cache directory (optional, TFDC_CACHE_DIR)")
+	fs.StringVar(&f.registryHost, "registry", settings.Registry, "Provider registry host, e.g. registry.opentofu.org (optional, TFDC_REGISTRY, defaults to registry.terraform.io)")
+	fs.BoolVar(&f.offline, "offline", settings.Offline, "Use cached providers only, without network access (TFDC_OFFLINE)")
+	fs.StringVar(&f.logLevel, "log-level", settings.LogLevel, "Log level: debug, info, warn or error (optional, TFDC_LOG_LEVEL, defaults to info)")
+	fs.BoolVar(&f.verbose, "verbose", false, "Enable verbose logging, as --log-level debug")
+	fs.StringVar(&f.tfVersion, "terraform-version", settings.TerraformVersion, "Terraform version presented to providers, e.g. 1.9.8 (optional, TFDC_TERRAFORM_VERSION, defaults to 1.0.0)")
+	fs.BoolVar(&f.exact, "exact-numbers", false, "Keep the precision of big numbers in results instead of converting them to float64")
+	fs.BoolVar(&f.sortSets, "sort-sets", false, "Sort the elements of sets of objects in results by their JSON encoding, for stable diffs")
+	fs.BoolVar(&f.interpolate, "interpolate", false, "Substitute ${env:VAR} and ${file:/path} references in configuration strings")
+	fs.BoolVar(&f.secrets, "resolve-secrets", false, "Resolve vault://, awssm:// and sops:// references in configuration strings")
+	fs.BoolVar(&f.refresh, "refresh-schemas", false, "Fetch provider schemas from the providers instead of the cache")
+	fs.DurationVar(&f.callTimeout, "call-timeout", 0, "Maximum duration of each provider call, e.g. 30s; calls that time out exit with code 9 (optional)")
+	fs.Var(&f.rateLimits, "read-rate-limit", "Maximum data source reads per second of a provider, as namespace/name=rps or namespace/name=rps:burst, e.g. hashicorp/aws=10:20 (repeatable)")
+	return f
+}
+
+// newClient creates a client from the flags and extra options, drawing
+// download progress on bar when stderr is a terminal.
+func (f *clientFlags) newClient(bar *progressBar, extra ...tfclient.Option) (*tfclient.Client, error) {
+	opts := append(append([]tfclient.Option(nil), f.options...), extra...)
+	if f.cacheDir != "" {
+		opts = append(opts, tfclient.WithCacheDir(f.cacheDir))
+	}
+	if f.registryHost != "" {
+		opts = append(opts, tfclient.WithRegistryHost(f.registryHost))
+	}
+	if f.offline {
+		opts = append(opts, tfclient.WithOfflineMode())
+	}
+	if f.tfVersion != "" {
+		opts = append(opts, tfclient.WithTerraformVersion(f.tfVersion))
+	}
+	if f.refresh {
+		opts = append(opts, tfclient.WithSchemaRefresh())
+	}
+	if f.exact {
+		opts = append(opts, tfclient.WithExactNumbers())
+	}
+	if f.sortSets {
+		opts = append(opts, tfclient.WithSortedSets())
+	}
+	if f.interpolate {
+		opts = append(opts, tfclient.WithConfigResolver(tfclient.Interpolator))
+	}
+	if f.secrets {
+		opts = append(opts, tfclient.WithConfigResolver(tfclient.Secrets))
+	}
+	if f.callTimeout < 0 {
+		return nil, usageErrorf("invalid --call-timeout %s, expected a positive duration", f.callTimeout)
+	}
+	if f.callTimeout > 0 {
+		opts = append(opts, tfclient.WithDefaultCallTimeout(f.callTimeout))
+	}
+	for _, limit := range f.rateLimits {
+		opt, err := parseReadRateLimit(limit)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
 	}
 
 	// Configure logging: slog -> logr -> library
 	logLevel := slog.LevelInfo
-	if *verbose {
+	if f.logLevel != "" {
+		if err := logLevel.UnmarshalText([]byte(f.logLevel)); err != nil {
+			return nil, usageErrorf("invalid log level %q, expected debug, info, warn or error", f.logLevel)
+		}
+	}
+	if f.verbose {
 		logLevel = slog.LevelDebug
 	}
 	slogHandler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})
 	logger := logr.FromSlogHandler(slogHandler)
 	opts = append(opts, tfclient.WithLogger(logger))
 
+	// Show download progress when stderr is a terminal
+	if stat, err := os.Stderr.Stat(); bar != nil && err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		opts = append(opts, tfclient.WithDownloadProgress(bar.update))
+	}
+
 	client, err := tfclient.New(opts...)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
-	defer client.Close()
+	return client, nil
+}
 
-	ctx := context.Background()
+// providerFlags are the flags of commands running a provider.
+type providerFlags struct {
+	*clientFlags
+	provider string
+	version  string
+}
+
+func addProviderFlags(fs *flag.FlagSet) *providerFlags {
+	f := &providerFlags{clientFlags: addClientFlags(fs)}
+	fs.StringVar(&f.provider, "provider", "", "Provider to use (e.g., hashicorp/kubernetes)")
+	fs.StringVar(&f.version, "version", "", "Provider version or constraint such as \"~> 2.0\" (optional, defaults to the providers setting, then latest)")
+	return f
+}
 
-	// Create provider
-	fmt.Fprintf(os.Stderr, "Creating provider %s/%s", namespace, name)
-	if *version != "" {
-		fmt.Fprintf(os.Stderr, "@%s", *version)
+// providerConfig parses --provider and --version.
+func (f *providerFlags) providerConfig() (tfclient.ProviderConfig, error) {
+	if f.provider == "" {
+		return tfclient.ProviderConfig{}, usageErrorf("--provider is required")
 	}
-	fmt.Fprintln(os.Stderr, "...")
+	parts := strings.Split(f.provider, "/")
+	if len(parts) != 2 {
+		return tfclient.ProviderConfig{}, usageErrorf("provider must be in format namespace/name (e.g., hashicorp/kubernetes)")
+	}
+	version := f.version
+	if version == "" {
+		version = settings.Providers[f.provider]
+	}
+	return tfclient.ProviderConfig{Namespace: parts[0], Name: parts[1], Version: version}, nil
+}
 
-	provider, err := client.CreateProvider(ctx, tfclient.ProviderConfig{
-		Namespace: namespace,
-		Name:      name,
-		Version:   *version,
-	})
+// startProvider creates a client and starts the provider of the flags. The
+// caller closes the client, which stops the provider.
+func (f *providerFlags) startProvider(ctx context.Context) (*tfclient.Client, tfclient.Provider, error) {
+	cfg, err := f.providerConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	bar := &progressBar{}
+	client, err := f.newClient(bar)
 	if err != nil {
-		return fmt.Errorf("failed to create provider: %w", err)
+		return nil, nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Creating provider %s/%s", cfg.Namespace, cfg.Name)
+	if cfg.Version != "" {
+		fmt.Fprintf(os.Stderr, "@%s", cfg.Version)
 	}
+	fmt.Fprintln(os.Stderr, "...")
 
+	provider, err := client.CreateProvider(ctx, cfg)
+	bar.finish()
+	if err != nil {
+		client.Close()
+		return nil, nil, fmt.Errorf("failed to create provider: %w", err)
+	}
 	fmt.Fprintf(os.Stderr, "Provider %s ready\n", provider.Config().String())
+	return client, provider, nil
+}
+
+// runLegacy runs the flags-only command line of earlier releases, e.g.
+// "tf-data-client --provider hashicorp/http --data-source http ...".
+//
+// Deprecated: use the read and list commands.
+func runLegacy(args []string) error {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	pf := addProviderFlags(fs)
+	dataSource := fs.String("data-source", "", "Data source to read (e.g., kubernetes_all_namespaces)")
+	configJSON := fs.String("config", "{}", "Provider configuration as JSON")
+	dataConfigJSON := fs.String("data-config", "{}", "Data source configuration as JSON")
+	output := fs.String("output", "", "Output file for JSON result (optional, defaults to stdout)")
+	listDataSources := fs.Bool("list-data-sources", false, "List available data sources and exit")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	replacement := "read"
+	if *listDataSources {
+		replacement = "list"
+	}
+	fmt.Fprintf(os.Stderr, "Warning: running without a command is deprecated, use \"%s %s\" instead.\n", os.Args[0], replacement)
+
+	ctx := context.Background()
+	client, provider, err := pf.startProvider(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
 
-	// List data sources if requested
 	if *listDataSources {
-		dataSources := provider.ListDataSources()
 		fmt.Println("Available data sources:")
-		for _, ds := range dataSources {
+		for _, ds := range provider.ListDataSources() {
 			fmt.Printf("  - %s\n", ds)
 		}
 		return nil
 	}
 
-	// Parse provider config
-	var config map[string]interface{}
-	if err := json.Unmarshal([]byte(*configJSON), &config); err != nil {
-		return fmt.Errorf("failed to parse provider config JSON: %w", err)
+	config, err := parseConfig("provider", *configJSON, "", nil, nil)
+	if err != nil {
+		return err
 	}
-
-	// Configure provider
-	fmt.Fprintf(os.Stderr, "Configuring provider...\n")
-	if err := provider.Configure(ctx, config); err != nil {
-		return fmt.Errorf("failed to configure provider: %w", err)
+	if err := configureProvider(ctx, provider, config); err != nil {
+		return err
 	}
-
 	// If no data source specified, just exit
 	if *dataSource == "" {
 		fmt.Fprintf(os.Stderr, "Provider configured successfully. Use --data-source to read a data source.\n")
 		return nil
 	}
-
-	// Parse data source config
-	var dataConfig map[string]interface{}
-	if err := json.Unmarshal([]byte(*dataConfigJSON), &dataConfig); err != nil {
-		return fmt.Errorf("failed to parse data source config JSON: %w", err)
-	}
-
-	// Read data source
-	fmt.Fprintf(os.Stderr, "Reading data source %s...\n", *dataSource)
-	result, err := provider.ReadDataSource(ctx, *dataSource, dataConfig)
+	dataConfig, err := parseConfig("data source", *dataConfigJSON, "", nil, nil)
 	if err != nil {
-		return fmt.Errorf("failed to read data source: %w", err)
+		return err
 	}
+	return readDataSource(ctx, client, provider, *dataSource, dataConfig, &outputFlags{output: *output, format: "json", showSensitive: true})
+}
 
-	// Marshal result to JSON
-	resultJSON, err := json.MarshalIndent(result.State, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal result to JSON: %w", err)
+// progressBar renders provider download progress on a single stderr line.
+type progressBar struct {
+	last   time.Time
+	active bool
+}
+
+func (b *progressBar) update(p tfclient.DownloadProgress) {
+	// Redraw at most every 100ms, but always show completion
+	if !p.Done() && time.Since(b.last) < 100*time.Millisecond {
+		return
 	}
+	b.last = time.Now()
+	b.active = true
 
-	// Output result
-	if *output != "" {
-		if err := os.WriteFile(*output, resultJSON, 0644); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
-		}
-		fmt.Fprintf(os.Stderr, "Result written to %s\n", *output)
+	const mb = 1 << 20
+	if p.Total > 0 {
+		fmt.Fprintf(os.Stderr, "\rDownloading %s: %.1f/%.1f MB (%d%%)", p.Provider, float64(p.Downloaded)/mb, float64(p.Total)/mb, p.Downloaded*100/p.Total)
 	} else {
-		fmt.Println(string(resultJSON))
+		fmt.Fprintf(os.Stderr, "\rDownloading %s: %.1f MB", p.Provider, float64(p.Downloaded)/mb)
 	}
+	if p.Done() {
+		b.finish()
+	}
+}
 
-	return nil
+// finish ends the progress line, if one was drawn.
+func (b *progressBar) finish() {
+	if b.active {
+		fmt.Fprintln(os.Stderr)
+		b.active = false
+	}
 }
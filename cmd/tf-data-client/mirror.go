@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// stringsFlag is a flag that can be repeated, collecting its values.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// runMirror implements "tf-data-client mirror", downloading providers into a
+// filesystem mirror.
+func runMirror(args []string) error {
+	fs := newFlagSet("mirror", "<namespace/name[@version]>... <dir>", `Download providers for the platforms of --platform into dir, in the layout of
+"terraform providers mirror", for WithFilesystemMirror or Terraform's
+filesystem_mirror and network_mirror. Versions are exact versions or
+constraints such as "~> 5.0" or "5.x", and default to the latest.`)
+	cf := addClientFlags(fs)
+	var platforms stringsFlag
+	fs.Var(&platforms, "platform", "Platform to download, e.g. linux_amd64, repeatable (optional, defaults to the current platform)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return usageErrorf("at least one provider and a mirror directory are required")
+	}
+	if len(platforms) == 0 {
+		platforms = stringsFlag{runtime.GOOS + "_" + runtime.GOARCH}
+	}
+	dir := fs.Arg(fs.NArg() - 1)
+
+	var providers []tfclient.ProviderConfig
+	for _, arg := range fs.Args()[:fs.NArg()-1] {
+		cfg, err := parseProviderArg(arg)
+		if err != nil {
+			return err
+		}
+		providers = append(providers, cfg)
+	}
+
+	bar := &progressBar{}
+	client, err := cf.newClient(bar)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	for _, cfg := range providers {
+		fmt.Fprintf(os.Stderr, "Mirroring %s for %s...\n", cfg, strings.Join(platforms, ", "))
+		pkgs, err := client.MirrorProvider(ctx, cfg, dir, platforms)
+		bar.finish()
+		if err != nil {
+			return err
+		}
+		for _, pkg := range pkgs {
+			fmt.Println(pkg.Path)
+		}
+	}
+	return nil
+}
+
+// parseProviderArg parses a provider argument such as "hashicorp/aws",
+// "hashicorp/aws@5.31.0" or "hashicorp/aws@5.x".
+func parseProviderArg(arg string) (tfclient.ProviderConfig, error) {
+	source, version, _ := strings.Cut(arg, "@")
+	namespace, name, ok := strings.Cut(source, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return tfclient.ProviderConfig{}, usageErrorf("provider %q must be in format namespace/name[@version] (e.g., hashicorp/aws@5.x)", arg)
+	}
+	return tfclient.ProviderConfig{Namespace: namespace, Name: name, Version: wildcardConstraint(version)}, nil
+}
+
+// wildcardConstraint converts a version with a trailing wildcard, such as
+// "5.x" or "5.2.x", to the equivalent constraint "~> 5.0" or "~> 5.2.0".
+// Other versions and constraints are returned as is.
+func wildcardConstraint(v string) string {
+	prefix, ok := strings.CutSuffix(v, ".x")
+	if !ok || prefix == "" {
+		return v
+	}
+	return "~> " + prefix + ".0"
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"go.yaml.in/yaml/v3"
+)
+
+// outputFormats are the values of --format.
+var outputFormats = []string{"json", "yaml", "table", "jsonl", "csv", "parquet", "hcl", "tfstate", "import"}
+
+// errEmptyResult is returned with --fail-on-empty when the result is empty.
+var errEmptyResult = errors.New("the result is empty")
+
+// outputFlags are the flags of commands printing data source state.
+type outputFlags struct {
+	output        string
+	format        string
+	listAttribute string
+	stream        bool
+	queryExpr     string
+	failOnEmpty   bool
+	showSensitive bool
+	redactExtra   string
+	templateText  string
+	templateFile  string
+	tfName        string
+	importTo      string
+
+	query       query              // parsed from queryExpr by validate
+	redactPaths [][]string         // parsed from redactExtra by validate
+	template    *template.Template // parsed from templateText or templateFile by validate
+}
+
+func addOutputFlags(fs *flag.FlagSet) *outputFlags {
+	f := &outputFlags{}
+	fs.StringVar(&f.output, "output", "", "Output file for the result (optional, defaults to stdout)")
+	fs.StringVar(&f.format, "format", "json", "Output format: json, yaml, table (top-level scalar attributes), jsonl, csv or parquet (a row for each element of --list-attribute, or of the lists the result or --query yields), or for Terraform hcl (data blocks), tfstate (state JSON) or import (import blocks of the IDs the result or --query yields)")
+	fs.StringVar(&f.listAttribute, "list-attribute", "", "With --format jsonl, csv or parquet, list attribute whose elements are printed one per line or row (optional, defaults to the whole state)")
+	fs.BoolVar(&f.stream, "stream", false, "With --format jsonl and --list-attribute, write each element as it is decoded instead of decoding the whole result first, for results of tens of thousands of elements")
+	fs.StringVar(&f.queryExpr, "query", "", "jq-like expression applied to the result before printing, e.g. '.names[0]' or '.items[] | select(.enabled == true)' (optional)")
+	fs.BoolVar(&f.showSensitive, "show-sensitive", false, "Print the attributes the schema marks as sensitive, which are masked by default")
+	fs.StringVar(&f.redactExtra, "redact-extra", "", "Comma-separated attribute paths to mask as well, e.g. 'token,items[].password' (optional)")
+	fs.StringVar(&f.templateText, "template", "", "Go template rendering the result instead of --format, e.g. '{{range .names}}{{println .}}{{end}}', with sprig-like functions such as toYaml, join and default (optional)")
+	fs.StringVar(&f.templateFile, "template-file", "", "File holding the template of --template (optional)")
+	fs.StringVar(&f.tfName, "tf-name", "this", "Name of the data block or state resource of --format hcl and tfstate, for commands reading one data source")
+	fs.StringVar(&f.importTo, "import-to", "", "With --format import, resource type or address prefix to import into, e.g. aws_instance or module.app.aws_instance")
+	fs.BoolVar(&f.failOnEmpty, "fail-on-empty", false, "Exit with an error when the result, or what --query yields, is empty: nothing, null, false or an empty string, list or object")
+	return f
+}
+
+// validate checks the flags before any provider is started.
+func (f *outputFlags) validate() error {
+	if !slices.Contains(outputFormats, f.format) {
+		return usageErrorf("unknown format %q, expected one of %s", f.format, strings.Join(outputFormats, ", "))
+	}
+	if f.listAttribute != "" && f.format != "jsonl" && !f.tabularFormat() {
+		return usageErrorf("--list-attribute requires --format jsonl, csv or parquet")
+	}
+	if f.stream && (f.format != "jsonl" || f.listAttribute == "") {
+		return usageErrorf("--stream requires --format jsonl and --list-attribute")
+	}
+	if err := f.validateTerraform(); err != nil {
+		return err
+	}
+	if f.queryExpr != "" {
+		if f.listAttribute != "" {
+			return usageErrorf("--list-attribute can't be used with --query, use --query '.%s[]' instead", f.listAttribute)
+		}
+		var err error
+		if f.query, err = parseQuery(f.queryExpr); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+	}
+	if f.templateText != "" || f.templateFile != "" {
+		if f.templateText != "" && f.templateFile != "" {
+			return usageErrorf("--template and --template-file are mutually exclusive")
+		}
+		if f.listAttribute != "" {
+			return usageErrorf("--list-attribute can't be used with a template, range over .%s in the template instead", f.listAttribute)
+		}
+		name, text := "template", f.templateText
+		if f.templateFile != "" {
+			data, err := os.ReadFile(f.templateFile)
+			if err != nil {
+				return usageErrorf("failed to read template: %v", err)
+			}
+			name, text = filepath.Base(f.templateFile), string(data)
+		}
+		var err error
+		if f.template, err = parseTemplate(name, text); err != nil {
+			return withExitCode(exitUsage, err)
+		}
+	}
+	var err error
+	if f.redactPaths, err = parseRedactPaths(f.redactExtra); err != nil {
+		return err
+	}
+	return nil
+}
+
+// redact masks the attributes of a data source state that its schema marks
+// as sensitive, unless --show-sensitive is set, and the --redact-extra paths.
+func (f *outputFlags) redact(state map[string]any, schema func() (*tfclient.Schema, error)) error {
+	if !f.showSensitive {
+		s, err := schema()
+		if err != nil {
+			return fmt.Errorf("failed to get schema to mask sensitive attributes: %w", err)
+		}
+		redactSchema(state, s)
+	}
+	for _, path := range f.redactPaths {
+		redactPath(state, path)
+	}
+	return nil
+}
+
+// write applies the query to state, if any, then formats what it yields and
+// writes it to the output file, or stdout.
+func (f *outputFlags) write(state map[string]any) error {
+	values := []any{state}
+	if f.query != nil {
+		var err error
+		if values, err = f.query.eval(state); err != nil {
+			return fmt.Errorf("failed to apply query: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch {
+	case f.template != nil:
+		for _, v := range values {
+			if err := f.template.Execute(&buf, v); err != nil {
+				return fmt.Errorf("failed to render template: %w", err)
+			}
+		}
+	case f.format == "import":
+		if err := writeImports(&buf, values, f.importTo); err != nil {
+			return err
+		}
+	case f.tabularFormat():
+		if err := writeRows(&buf, values, f.format, f.listAttribute); err != nil {
+			return err
+		}
+	default:
+		for _, v := range values {
+			if err := formatState(&buf, v, f.format, f.listAttribute); err != nil {
+				return err
+			}
+		}
+	}
+	if err := f.writeOutput(buf.Bytes()); err != nil {
+		return err
+	}
+
+	if f.failOnEmpty && isEmpty(values) {
+		return errEmptyResult
+	}
+	return nil
+}
+
+// writeStream writes the elements of the list attribute of a raw state, one
+// JSON line each, to the output file, or stdout, as they are decoded, masking
+// sensitive values and the --redact-extra paths as redact does.
+func (f *outputFlags) writeStream(raw *tfclient.RawState, schema func() (*tfclient.Schema, error)) error {
+	var s *tfclient.Schema
+	if !f.showSensitive {
+		var err error
+		if s, err = schema(); err != nil {
+			return fmt.Errorf("failed to get schema to mask sensitive attributes: %w", err)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if f.output != "" {
+		file, err := os.Create(f.output)
+		if err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		defer file.Close()
+		w = file
+	}
+	bw := bufio.NewWriter(w)
+	count := 0
+	err := raw.EachElement(f.listAttribute, func(elem any) error {
+		if s != nil {
+			state := map[string]any{f.listAttribute: []any{elem}}
+			redactSchema(state, s)
+			if elems, ok := state[f.listAttribute].([]any); ok {
+				elem = elems[0]
+			} else {
+				elem = state[f.listAttribute]
+			}
+		}
+		for _, path := range f.redactPaths {
+			elem = redactListElem(elem, f.listAttribute, count, path)
+		}
+		count++
+		return writeJSONLine(bw, elem)
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	if f.output != "" {
+		fmt.Fprintf(os.Stderr, "Result written to %s\n", f.output)
+	}
+
+	if f.failOnEmpty && count == 0 {
+		return errEmptyResult
+	}
+	return nil
+}
+
+// isEmpty reports whether values holds nothing but empty values.
+func isEmpty(values []any) bool {
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+		case bool:
+			if v {
+				return false
+			}
+		case string:
+			if v != "" {
+				return false
+			}
+		case []any:
+			if len(v) > 0 {
+				return false
+			}
+		case map[string]any:
+			if len(v) > 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// writeOutput writes the formatted result to the output file, or stdout.
+func (f *outputFlags) writeOutput(data []byte) error {
+	if f.output != "" {
+		if err := os.WriteFile(f.output, data, 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Result written to %s\n", f.output)
+		return nil
+	}
+	_, err := os.Stdout.Write(data)
+	return err
+}
+
+// yamlNumbers returns v with its json.Number values, from --exact-numbers, as
+// YAML number nodes, which the YAML encoder would quote as strings otherwise.
+func yamlNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = yamlNumbers(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = yamlNumbers(e)
+		}
+		return out
+	}
+	return v
+}
+
+// formatState writes a state, or a value a query yielded, to w in format.
+func formatState(w io.Writer, state any, format, listAttribute string) error {
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlNumbers(state)); err != nil {
+			return fmt.Errorf("failed to marshal result to YAML: %w", err)
+		}
+		return enc.Close()
+
+	case "table":
+		m, ok := state.(map[string]any)
+		if !ok {
+			return fmt.Errorf("--format table requires an object, got %s", typeName(state))
+		}
+		return writeTable(w, m)
+
+	case "jsonl":
+		if listAttribute == "" {
+			return writeJSONLine(w, state)
+		}
+		value, ok := state.(map[string]any)[listAttribute]
+		if !ok {
+			return fmt.Errorf("attribute %q not found in result", listAttribute)
+		}
+		if value == nil {
+			return nil
+		}
+		elems, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("attribute %q is not a list or set", listAttribute)
+		}
+		for _, elem := range elems {
+			if err := writeJSONLine(w, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal result to JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+}
+
+func writeJSONLine(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result to JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeTable writes the top-level attributes of state as a two-column
+// table, summarizing collections by their size.
+func writeTable(w io.Writer, state map[string]any) error {
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ATTRIBUTE\tVALUE")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\n", name, scalarString(state[name]))
+	}
+	return tw.Flush()
+}
+
+// scalarString renders a JSON-decoded value on a single line.
+func scalarString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		// Quote strings that would break the table
+		if strings.ContainsAny(v, "\t\r\n") {
+			return strconv.Quote(v)
+		}
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		return fmt.Sprintf("[%d elements]", len(v))
+	case map[string]any:
+		return fmt.Sprintf("{%d attributes}", len(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// A query is a jq-like expression selecting parts of a result, e.g.
+// ".names[0]" or ".items[] | select(.state == \"available\") | .id". It
+// supports:
+//
+//   - paths: ".", ".a.b", ".\"key with spaces\"", ".a[0]", ".a[-1]",
+//     ".a[1:3]" and ".a[]", which yields each element of a list or object
+//   - pipes: "expr | expr"
+//   - select(cond), keeping the values for which cond holds, where cond is
+//     a path alone (not null or false) or compared with ==, !=, <, <=, > or
+//     >= to a JSON literal
+//   - length and keys
+type query []queryStage
+
+// queryStage maps a value to the values it yields.
+type queryStage func(v any) ([]any, error)
+
+// parseQuery parses a query expression.
+func parseQuery(expr string) (query, error) {
+	var q query
+	full := expr
+	for {
+		part, rest, more := expr, "", false
+		if i := indexUnquoted(expr, "|"); i >= 0 {
+			part, rest, more = expr[:i], expr[i+1:], true
+		}
+		stage, err := parseStage(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid query %q: %w", full, err)
+		}
+		q = append(q, stage)
+		if !more {
+			return q, nil
+		}
+		expr = rest
+	}
+}
+
+// indexUnquoted returns the index of the first sub of s outside double
+// quotes, or -1.
+func indexUnquoted(s, sub string) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && strings.HasPrefix(s[i:], sub):
+			return i
+		}
+	}
+	return -1
+}
+
+// eval applies the query to v, returning the values it yields.
+func (q query) eval(v any) ([]any, error) {
+	values := []any{v}
+	for _, stage := range q {
+		var next []any
+		for _, value := range values {
+			out, err := stage(value)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, out...)
+		}
+		values = next
+	}
+	return values, nil
+}
+
+func parseStage(s string) (queryStage, error) {
+	switch {
+	case s == "length":
+		return func(v any) ([]any, error) {
+			switch v := v.(type) {
+			case nil:
+				return []any{float64(0)}, nil
+			case string:
+				return []any{float64(len([]rune(v)))}, nil
+			case []any:
+				return []any{float64(len(v))}, nil
+			case map[string]any:
+				return []any{float64(len(v))}, nil
+			}
+			return nil, fmt.Errorf("%s has no length", typeName(v))
+		}, nil
+
+	case s == "keys":
+		return func(v any) ([]any, error) {
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s has no keys", typeName(v))
+			}
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := make([]any, len(keys))
+			for i, k := range keys {
+				out[i] = k
+			}
+			return []any{out}, nil
+		}, nil
+
+	case strings.HasPrefix(s, "select(") && strings.HasSuffix(s, ")"):
+		return parseSelect(strings.TrimSpace(s[len("select(") : len(s)-1]))
+
+	case strings.HasPrefix(s, "."):
+		return parsePath(s)
+	}
+	return nil, fmt.Errorf("unsupported expression %q", s)
+}
+
+// comparisons are the operators of select, longest first.
+var comparisons = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func parseSelect(cond string) (queryStage, error) {
+	pathExpr, op, literal := cond, "", ""
+	for _, candidate := range comparisons {
+		if i := indexUnquoted(cond, candidate); i >= 0 {
+			pathExpr, op, literal = strings.TrimSpace(cond[:i]), candidate, strings.TrimSpace(cond[i+len(candidate):])
+			break
+		}
+	}
+	path, err := parsePath(pathExpr)
+	if err != nil {
+		return nil, err
+	}
+	var want any
+	if op != "" {
+		if err := json.Unmarshal([]byte(literal), &want); err != nil {
+			return nil, fmt.Errorf("invalid literal %q in select", literal)
+		}
+	}
+
+	return func(v any) ([]any, error) {
+		values, err := path(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, got := range values {
+			if op == "" && got != nil && got != false {
+				return []any{v}, nil
+			}
+			if op != "" && compare(got, op, want) {
+				return []any{v}, nil
+			}
+		}
+		return nil, nil
+	}, nil
+}
+
+// compare reports whether "got op want" holds. Ordering only applies to two
+// numbers or two strings.
+func compare(got any, op string, want any) bool {
+	// Numbers are json.Number with --exact-numbers
+	if n, ok := got.(json.Number); ok {
+		if f, err := n.Float64(); err == nil {
+			got = f
+		}
+	}
+	switch op {
+	case "==":
+		return reflect.DeepEqual(got, want)
+	case "!=":
+		return !reflect.DeepEqual(got, want)
+	}
+
+	var c int
+	switch g := got.(type) {
+	case float64:
+		w, ok := want.(float64)
+		if !ok {
+			return false
+		}
+		c = cmpOrdered(g, w)
+	case string:
+		w, ok := want.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(g, w)
+	default:
+		return false
+	}
+	switch op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func cmpOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// parsePath parses a path such as ".a.b[0]" into a stage.
+func parsePath(s string) (queryStage, error) {
+	if !strings.HasPrefix(s, ".") {
+		return nil, fmt.Errorf("path %q must start with '.'", s)
+	}
+	var steps []queryStage
+	rest := s[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in %q", s)
+			}
+			step, err := parseIndex(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, step)
+			rest = rest[end+1:]
+
+		case rest[0] == '"':
+			key, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted key in %q", s)
+			}
+			name, _ := strconv.Unquote(key)
+			steps = append(steps, fieldStep(name))
+			rest = rest[len(key):]
+
+		case rest[0] == '.':
+			// Separates a field from the previous step, as in ".a[0].b"
+			rest = rest[1:]
+			if rest == "" || rest[0] == '.' {
+				return nil, fmt.Errorf("empty field in %q", s)
+			}
+
+		default:
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-'
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("unexpected %q in %q", rest[:1], s)
+			}
+			steps = append(steps, fieldStep(rest[:end]))
+			rest = rest[end:]
+		}
+	}
+
+	return func(v any) ([]any, error) {
+		return query(steps).eval(v)
+	}, nil
+}
+
+// fieldStep yields the value of a field of an object, null if it is absent.
+func fieldStep(name string) queryStage {
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case map[string]any:
+			return []any{v[name]}, nil
+		}
+		return nil, fmt.Errorf("cannot get field %q of %s", name, typeName(v))
+	}
+}
+
+// parseIndex parses the content of brackets: empty to iterate, an index, or
+// a slice.
+func parseIndex(s string) (queryStage, error) {
+	if s == "" {
+		return func(v any) ([]any, error) {
+			switch v := v.(type) {
+			case nil:
+				return nil, nil
+			case []any:
+				return v, nil
+			case map[string]any:
+				keys := make([]string, 0, len(v))
+				for k := range v {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				out := make([]any, len(keys))
+				for i, k := range keys {
+					out[i] = v[k]
+				}
+				return out, nil
+			}
+			return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+		}, nil
+	}
+
+	if from, to, ok := strings.Cut(s, ":"); ok {
+		var start, end *int
+		for _, bound := range []struct {
+			s string
+			p **int
+		}{{from, &start}, {to, &end}} {
+			if bound.s = strings.TrimSpace(bound.s); bound.s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(bound.s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid slice [%s]", s)
+			}
+			*bound.p = &n
+		}
+		return func(v any) ([]any, error) {
+			if v == nil {
+				return []any{nil}, nil
+			}
+			list, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot slice %s", typeName(v))
+			}
+			lo, hi := 0, len(list)
+			if start != nil {
+				lo = clampIndex(*start, len(list))
+			}
+			if end != nil {
+				hi = clampIndex(*end, len(list))
+			}
+			if lo > hi {
+				lo = hi
+			}
+			return []any{list[lo:hi]}, nil
+		}, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid index [%s]", s)
+	}
+	return func(v any) ([]any, error) {
+		switch v := v.(type) {
+		case nil:
+			return []any{nil}, nil
+		case []any:
+			i := index
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return []any{nil}, nil
+			}
+			return []any{v[i]}, nil
+		}
+		return nil, fmt.Errorf("cannot index %s with a number", typeName(v))
+	}, nil
+}
+
+// clampIndex resolves a negative index from the end and bounds it to a list
+// of length n.
+func clampIndex(i, n int) int {
+	if i < 0 {
+		i += n
+	}
+	return max(0, min(i, n))
+}
+
+// typeName names the type of a JSON-decoded value in error messages.
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64, json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
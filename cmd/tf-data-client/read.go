@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// runRead implements "tf-data-client read", configuring a provider and
+// reading one of its data sources.
+func runRead(args []string) error {
+	fs := newFlagSet("read", "<data-source>", "Configure a provider and read a data source, printing its state as JSON or in --format.")
+	pf := addProviderFlags(fs)
+	configJSON := fs.String("config", "{}", "Provider configuration as JSON, or - to read it from stdin")
+	dataConfigJSON := fs.String("data-config", "{}", "Data source configuration as JSON, or - to read it from stdin")
+	configFile := fs.String("config-file", "", "HCL or JSON (.json) file with the provider configuration, instead of --config")
+	dataConfigFile := fs.String("data-config-file", "", "HCL or JSON (.json) file with the data source configuration, instead of --data-config")
+	tfDir := fs.String("from-tf-dir", "", "Terraform directory whose provider block is the provider configuration, instead of --config, with variables from its tfvars files")
+	tfProvider := fs.String("tf-provider", "", "Provider block of --from-tf-dir, e.g. aws or aws.west for an alias (optional, defaults to the provider name)")
+	var varValues, varFiles stringsFlag
+	fs.Var(&varValues, "var", "Variable referenced as ${var.name} by the configuration files and --from-tf-dir, as name=value (repeatable)")
+	fs.Var(&varFiles, "var-file", "tfvars file of variables referenced as ${var.name} by the configuration files and --from-tf-dir (repeatable)")
+	requestFile := fs.String("request", "", "JSON or YAML request document with provider, version, config, data_source and data_config, or - to read it from stdin (optional, flags take precedence)")
+	validateOnly := fs.Bool("validate", false, "Only validate the provider and data source configurations against their schemas and with the provider, without configuring it or reading")
+	out := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := exclusiveFlags(fs, "config", "config-file"); err != nil {
+		return err
+	}
+	if err := exclusiveFlags(fs, "data-config", "data-config-file"); err != nil {
+		return err
+	}
+	for _, name := range []string{"config", "config-file"} {
+		if err := exclusiveFlags(fs, name, "from-tf-dir"); err != nil {
+			return err
+		}
+	}
+	stdinInputs := 0
+	for _, v := range []string{*configJSON, *dataConfigJSON, *configFile, *dataConfigFile, *requestFile} {
+		if v == "-" {
+			stdinInputs++
+		}
+	}
+	if stdinInputs > 1 {
+		return usageErrorf("only one of --config, --data-config, --config-file, --data-config-file and --request can read stdin")
+	}
+
+	req := &readRequest{}
+	if *requestFile != "" {
+		var err error
+		if req, err = loadRequest(*requestFile); err != nil {
+			return err
+		}
+	}
+	if pf.provider == "" {
+		pf.provider = req.Provider
+	}
+	if pf.version == "" {
+		pf.version = req.Version
+	}
+	dataSource := fs.Arg(0)
+	if dataSource == "" {
+		dataSource = req.DataSource
+	}
+	if fs.NArg() > 1 || dataSource == "" {
+		fs.Usage()
+		return usageErrorf("a data source is required, e.g. %s read --provider hashicorp/http http", os.Args[0])
+	}
+	if _, err := pf.providerConfig(); err != nil {
+		return err
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	vars, err := parseVars(varValues, varFiles)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	client, provider, err := pf.startProvider(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	config := req.Config
+	if *tfDir != "" {
+		if config, err = terraformProviderConfig(*tfDir, *tfProvider, provider, vars); err != nil {
+			return err
+		}
+	} else if config == nil || isFlagSet(fs, "config") || isFlagSet(fs, "config-file") {
+		if config, err = parseConfig("provider", *configJSON, *configFile, provider.ProviderSchema, vars); err != nil {
+			return err
+		}
+	}
+	dataConfig := req.DataConfig
+	if dataConfig == nil || isFlagSet(fs, "data-config") || isFlagSet(fs, "data-config-file") {
+		dataConfig, err = parseConfig("data source", *dataConfigJSON, *dataConfigFile, func() (*tfclient.Schema, error) {
+			return provider.DataSourceSchema(dataSource)
+		}, vars)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *validateOnly {
+		if err := validateConfigs(ctx, provider, config, dataSource, dataConfig); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Configuration is valid.")
+		return nil
+	}
+	if err := configureProvider(ctx, provider, config); err != nil {
+		return err
+	}
+	return readDataSource(ctx, client, provider, dataSource, dataConfig, out)
+}
+
+// runList implements "tf-data-client list", printing the data sources of a
+// provider.
+func runList(args []string) error {
+	fs := newFlagSet("list", "", "List the data sources of a provider, one per line.")
+	pf := addProviderFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if _, err := pf.providerConfig(); err != nil {
+		return err
+	}
+
+	client, provider, err := pf.startProvider(context.Background())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	dataSources := provider.ListDataSources()
+	sort.Strings(dataSources)
+	for _, ds := range dataSources {
+		fmt.Println(ds)
+	}
+	return nil
+}
+
+// isFlagSet reports whether a flag was set on the command line.
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// exclusiveFlags returns an error if both flags were set.
+func exclusiveFlags(fs *flag.FlagSet, a, b string) error {
+	if isFlagSet(fs, a) && isFlagSet(fs, b) {
+		return usageErrorf("--%s and --%s can't be used together", a, b)
+	}
+	return nil
+}
+
+// parseVars parses --var name=value flags and --var-file files, returning nil
+// if there are none.
+func parseVars(values, files []string) (*tfclient.TerraformVars, error) {
+	if len(values) == 0 && len(files) == 0 {
+		return nil, nil
+	}
+	vars := &tfclient.TerraformVars{Files: files, Values: map[string]string{}}
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return nil, usageErrorf("invalid --var %q, expected name=value", v)
+		}
+		vars.Values[name] = value
+	}
+	return vars, nil
+}
+
+// parseConfig parses a provider or data source configuration given as JSON,
+// or as an HCL or JSON file decoded against the schema if configFile is set,
+// whose expressions can refer to vars if not nil. Either is read from stdin
+// if it is "-".
+func parseConfig(what, configJSON, configFile string, schema func() (*tfclient.Schema, error), vars *tfclient.TerraformVars) (map[string]interface{}, error) {
+	if configFile == "" {
+		data := []byte(configJSON)
+		if configJSON == "-" {
+			var err error
+			if data, err = readInput("-"); err != nil {
+				return nil, fmt.Errorf("failed to read %s config: %w", what, err)
+			}
+		}
+		var config map[string]interface{}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, usageErrorf("failed to parse %s config JSON: %w", what, err)
+		}
+		return config, nil
+	}
+
+	src, err := readInput(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s config file: %w", what, err)
+	}
+	s, err := schema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s schema: %w", what, err)
+	}
+	if vars != nil {
+		return tfclient.DecodeHCLConfigWithVars(configFile, src, s, *vars)
+	}
+	return tfclient.DecodeHCLConfig(configFile, src, s)
+}
+
+// terraformProviderConfig decodes the configuration of a provider from the
+// provider block ref of the Terraform configuration in dir, by default the
+// one named after the provider, with vars if not nil.
+func terraformProviderConfig(dir, ref string, provider tfclient.Provider, vars *tfclient.TerraformVars) (map[string]interface{}, error) {
+	if ref == "" {
+		ref = provider.Config().Name
+	}
+	schema, err := provider.ProviderSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider schema: %w", err)
+	}
+	if vars == nil {
+		vars = &tfclient.TerraformVars{}
+	}
+	return tfclient.DecodeTerraformProviderConfig(dir, ref, schema, *vars)
+}
+
+// configureProvider configures a provider.
+func configureProvider(ctx context.Context, provider tfclient.Provider, config map[string]interface{}) error {
+	fmt.Fprintf(os.Stderr, "Configuring provider...\n")
+	if err := provider.Configure(ctx, config); err != nil {
+		return withExitCode(exitConfigure, fmt.Errorf("failed to configure provider: %w", err))
+	}
+	return nil
+}
+
+// validateConfigs validates a provider configuration and a data source
+// configuration, or only the former if dataSource is empty.
+func validateConfigs(ctx context.Context, provider tfclient.Provider, config map[string]interface{}, dataSource string, dataConfig map[string]interface{}) error {
+	fmt.Fprintf(os.Stderr, "Validating provider configuration...\n")
+	if err := provider.ValidateProviderConfig(ctx, config); err != nil {
+		return withExitCode(exitConfigure, err)
+	}
+	if dataSource == "" {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Validating data source %s configuration...\n", dataSource)
+	if err := provider.ValidateDataSourceConfig(ctx, dataSource, dataConfig); err != nil {
+		return withExitCode(exitRead, err)
+	}
+	return nil
+}
+
+// readDataSource reads a data source and writes its state as out requests.
+func readDataSource(ctx context.Context, client *tfclient.Client, provider tfclient.Provider, dataSource string, dataConfig map[string]interface{}, out *outputFlags) error {
+	fmt.Fprintf(os.Stderr, "Reading data source %s...\n", dataSource)
+	if out.stream {
+		result, err := provider.ReadDataSource(tfclient.WithRawResult(ctx), dataSource, dataConfig)
+		if err != nil {
+			return withExitCode(exitRead, fmt.Errorf("failed to read data source: %w", err))
+		}
+		return out.writeStream(result.Raw, func() (*tfclient.Schema, error) {
+			return provider.DataSourceSchema(dataSource)
+		})
+	}
+	result, err := provider.ReadDataSource(ctx, dataSource, dataConfig)
+	if err != nil {
+		return withExitCode(exitRead, fmt.Errorf("failed to read data source: %w", err))
+	}
+	err = out.redact(result.State, func() (*tfclient.Schema, error) {
+		return provider.DataSourceSchema(dataSource)
+	})
+	if err != nil {
+		return err
+	}
+
+	if out.terraformFormat() {
+		return out.writeTerraform(client, []tfclient.TerraformDataSource{{
+			Name:       out.tfName,
+			DataSource: dataSource,
+			Provider:   provider,
+			Result:     result,
+		}})
+	}
+	return out.write(result.State)
+}
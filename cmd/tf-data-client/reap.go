@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// runReap implements "tf-data-client reap", stopping provider processes left
+// running by client processes that exited without stopping them.
+func runReap(args []string) error {
+	fs := newFlagSet("reap", "", "Stop provider processes left running by tf-data-client processes that crashed or were killed, as recorded in the provider cache. Only supported on Linux.")
+	cf := addClientFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "Only list the orphaned processes")
+	grace := fs.Duration("grace", 5*time.Second, "How long processes may take to exit before they are killed")
+	asJSON := fs.Bool("json", false, "Output processes as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return usageErrorf("reap takes no arguments")
+	}
+	if *grace < 0 {
+		return usageErrorf("--grace must not be negative")
+	}
+
+	client, err := cf.newClient(nil, tfclient.WithStopGracePeriod(*grace))
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	var orphans []tfclient.OrphanProcess
+	if *dryRun {
+		orphans, err = client.FindOrphans(ctx)
+	} else {
+		orphans, err = client.ReapOrphans(ctx)
+	}
+
+	if *asJSON {
+		if orphans == nil {
+			orphans = []tfclient.OrphanProcess{}
+		}
+		out, merr := json.MarshalIndent(orphans, "", "  ")
+		if merr != nil {
+			return fmt.Errorf("failed to marshal processes to JSON: %w", merr)
+		}
+		fmt.Println(string(out))
+	} else if len(orphans) > 0 {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PID\tPROVIDER\tOWNER PID\tSTARTED")
+		for _, o := range orphans {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", o.PID, o.Provider, o.OwnerPID, o.Started.Format(time.DateTime))
+		}
+		if ferr := tw.Flush(); ferr != nil {
+			return ferr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "Found %d orphaned provider process(es)\n", len(orphans))
+	} else {
+		fmt.Fprintf(os.Stderr, "Stopped %d orphaned provider process(es)\n", len(orphans))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// redacted replaces masked values in the output, as in Terraform's plans.
+const redacted = "(sensitive)"
+
+// parseRedactPaths parses the comma-separated paths of --redact-extra, such
+// as "token,items[].password,tags.*", into their segments. "[]" and "*"
+// stand for every element of a list or object.
+func parseRedactPaths(s string) ([][]string, error) {
+	var paths [][]string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		var segs []string
+		for _, seg := range strings.Split(strings.ReplaceAll(p, "[]", ".*"), ".") {
+			if seg == "" {
+				return nil, usageErrorf("invalid --redact-extra path %q", p)
+			}
+			segs = append(segs, seg)
+		}
+		paths = append(paths, segs)
+	}
+	return paths, nil
+}
+
+// redactSchema masks the non-null values of the sensitive attributes of
+// state, including in nested attributes and blocks.
+func redactSchema(state map[string]any, schema *tfclient.Schema) {
+	for _, attr := range schema.Attributes {
+		v, ok := state[attr.Name]
+		if !ok || v == nil {
+			continue
+		}
+		if attr.Sensitive {
+			state[attr.Name] = redacted
+			continue
+		}
+		if attr.Nesting != "" {
+			nested := &tfclient.Schema{Attributes: attr.Attributes}
+			for _, obj := range nestedObjects(v, attr.Nesting) {
+				redactSchema(obj, nested)
+			}
+		}
+	}
+	for _, block := range schema.Blocks {
+		for _, obj := range nestedObjects(state[block.Name], block.Nesting) {
+			redactSchema(obj, &block.Schema)
+		}
+	}
+}
+
+// nestedObjects returns the objects of a nested attribute or block value.
+func nestedObjects(v any, nesting string) []map[string]any {
+	var objs []map[string]any
+	switch v := v.(type) {
+	case map[string]any:
+		if nesting != "map" {
+			return []map[string]any{v}
+		}
+		for _, elem := range v {
+			if obj, ok := elem.(map[string]any); ok {
+				objs = append(objs, obj)
+			}
+		}
+	case []any:
+		for _, elem := range v {
+			if obj, ok := elem.(map[string]any); ok {
+				objs = append(objs, obj)
+			}
+		}
+	}
+	return objs
+}
+
+// redactPath masks the non-null values at path in v.
+func redactPath(v any, path []string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			if path[0] == "*" || path[0] == key {
+				v[key] = redactElem(elem, path[1:])
+			}
+		}
+	case []any:
+		for i, elem := range v {
+			if path[0] == "*" || path[0] == strconv.Itoa(i) {
+				v[i] = redactElem(elem, path[1:])
+			}
+		}
+	}
+}
+
+// redactElem returns elem masked if rest is empty, or with the values at
+// rest in it masked.
+func redactElem(elem any, rest []string) any {
+	switch {
+	case elem == nil:
+		return nil
+	case len(rest) == 0:
+		return redacted
+	}
+	redactPath(elem, rest)
+	return elem
+}
+
+// redactListElem returns the element i of the list attribute with the values
+// path masks in it, as redactPath masks them in the whole state.
+func redactListElem(elem any, attribute string, i int, path []string) any {
+	if path[0] != "*" && path[0] != attribute {
+		return elem
+	}
+	if len(path) == 1 {
+		return redactElem(elem, nil)
+	}
+	if path[1] != "*" && path[1] != strconv.Itoa(i) {
+		return elem
+	}
+	return redactElem(elem, path[2:])
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// readRequest is a request document describing a read, given to --request as
+// JSON or YAML. Flags take precedence over its fields.
+type readRequest struct {
+	Provider   string         `yaml:"provider"`
+	Version    string         `yaml:"version"`
+	Config     map[string]any `yaml:"config"`
+	DataSource string         `yaml:"data_source"`
+	DataConfig map[string]any `yaml:"data_config"`
+}
+
+// loadRequest reads a request document from path, or stdin if path is "-".
+func loadRequest(path string) (*readRequest, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+
+	// YAML is a superset of JSON, so this reads both
+	var req readRequest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse request %s: %w", path, err)
+	}
+	return &req, nil
+}
+
+// stdinRead is set once stdin has been read by readInput.
+var stdinRead bool
+
+// readInput reads the file at path, or stdin if path is "-". Stdin can only
+// be read once, e.g. by either --config or --data-config.
+func readInput(path string) ([]byte, error) {
+	if path != "-" {
+		return os.ReadFile(path)
+	}
+	if stdinRead {
+		return nil, fmt.Errorf("standard input can only be read once")
+	}
+	stdinRead = true
+	return io.ReadAll(os.Stdin)
+}
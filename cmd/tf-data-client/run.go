@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"go.yaml.in/yaml/v3"
+)
+
+// manifest declares the providers and data source reads of "tf-data-client
+// run", run as a tfclient.Pipeline.
+type manifest struct {
+	// Providers are keyed by a local name that reads refer to, which is also
+	// their alias, so providers with the same source are configured
+	// independently.
+	Providers map[string]runManifestProvider `yaml:"providers"`
+	Reads     []runManifestRead              `yaml:"reads"`
+}
+
+type manifestProvider struct {
+	Source  string         `yaml:"source"` // e.g. hashicorp/aws
+	Version string         `yaml:"version"`
+	Config  map[string]any `yaml:"config"`
+}
+
+type manifestRead struct {
+	Name       string `yaml:"name"` // key of the state in the output
+	Provider   string `yaml:"provider"`
+	DataSource string `yaml:"data_source"`
+	// Config may refer to the states of other reads of run manifests, e.g.
+	// ${reads.vpc.state.id}, which are read first.
+	Config map[string]any `yaml:"config"`
+}
+
+// manifestNode holds the settings of the pipeline nodes of run manifests.
+type manifestNode struct {
+	DependsOn []string      `yaml:"depends_on"`
+	Retries   int           `yaml:"retries"`
+	Timeout   time.Duration `yaml:"timeout"`
+}
+
+type runManifestProvider struct {
+	manifestProvider `yaml:",inline"`
+	manifestNode     `yaml:",inline"`
+}
+
+type runManifestRead struct {
+	manifestRead `yaml:",inline"`
+	manifestNode `yaml:",inline"`
+}
+
+// loadManifest reads and validates a manifest from path, or stdin if path is
+// "-". Dependencies are checked when running it.
+func loadManifest(path string) (*manifest, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var m manifest
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	if len(m.Reads) == 0 {
+		return nil, fmt.Errorf("manifest %s has no reads", path)
+	}
+	return &m, nil
+}
+
+// pipeline returns the pipeline of a manifest, with the providers that are
+// read from or depended on.
+func (m *manifest) pipeline(concurrency int) (tfclient.Pipeline, error) {
+	p := tfclient.Pipeline{Concurrency: concurrency}
+	used := make(map[string]bool)
+	var use func(name string) error
+	use = func(name string) error {
+		mp, ok := m.Providers[name]
+		if !ok || used[name] {
+			return nil // undeclared providers are reported by the pipeline
+		}
+		used[name] = true
+		pf := &providerFlags{provider: mp.Source, version: mp.Version}
+		cfg, err := pf.providerConfig()
+		if err != nil {
+			return fmt.Errorf("provider %s: %w", name, err)
+		}
+		cfg.Alias = name
+		p.Providers = append(p.Providers, tfclient.PipelineProvider{
+			PipelineNode: mp.node(name),
+			Provider:     cfg,
+			Config:       mp.Config,
+		})
+		for _, dep := range mp.DependsOn {
+			if err := use(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, read := range m.Reads {
+		p.Reads = append(p.Reads, tfclient.PipelineRead{
+			PipelineNode: read.node(read.Name),
+			Provider:     read.Provider,
+			DataSource:   read.DataSource,
+			Config:       read.Config,
+		})
+		for _, name := range append([]string{read.Provider}, read.DependsOn...) {
+			if err := use(name); err != nil {
+				return tfclient.Pipeline{}, err
+			}
+		}
+	}
+	return p, nil
+}
+
+func (n manifestNode) node(name string) tfclient.PipelineNode {
+	return tfclient.PipelineNode{Name: name, DependsOn: n.DependsOn, Retries: n.Retries, Timeout: n.Timeout}
+}
+
+// runRun implements "tf-data-client run", reading the data sources of a
+// manifest and printing their states keyed by read name.
+func runRun(args []string) error {
+	fs := newFlagSet("run", "<manifest.yaml>", "Read the data sources declared in a manifest, or - for stdin, printing their states in a single document keyed by read name.")
+	cf := addClientFlags(fs)
+	concurrency := fs.Int("concurrency", 4, "Maximum number of providers started or data sources read at once")
+	validateOnly := fs.Bool("validate", false, "Only validate the provider and read configurations against their schemas and with the providers, without configuring them or reading")
+	out := addOutputFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageErrorf("a manifest is required")
+	}
+	if *concurrency < 1 {
+		return usageErrorf("--concurrency must be at least 1")
+	}
+	if err := out.validate(); err != nil {
+		return err
+	}
+	if out.stream {
+		return usageErrorf("--stream is only supported by read, run prints the states of its reads in a single document")
+	}
+	m, err := loadManifest(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	p, err := m.pipeline(*concurrency)
+	if err != nil {
+		return usageErrorf("manifest %s: %v", fs.Arg(0), err)
+	}
+	client, err := cf.newClient(nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	// Report progress from the events of the pipeline nodes
+	providers := make(map[string]tfclient.ProviderConfig)
+	for _, pp := range p.Providers {
+		providers[pp.Name] = pp.Provider
+	}
+	dataSources := make(map[string]string)
+	for _, read := range p.Reads {
+		dataSources[read.Name] = read.DataSource
+	}
+	defer client.Subscribe(func(e tfclient.Event) {
+		started, ok := e.(*tfclient.NodeStarted)
+		if !ok {
+			return
+		}
+		var msg string
+		if cfg, ok := providers[started.Node]; ok {
+			msg = fmt.Sprintf("Starting provider %s (%s/%s)", started.Node, cfg.Namespace, cfg.Name)
+		} else {
+			msg = fmt.Sprintf("Reading %s (%s)", started.Node, dataSources[started.Node])
+		}
+		if *validateOnly {
+			msg = strings.Replace(msg, "Reading", "Validating", 1)
+		}
+		if started.Attempt > 1 {
+			msg += fmt.Sprintf(", attempt %d", started.Attempt)
+		}
+		fmt.Fprintln(os.Stderr, msg+"...")
+	})()
+
+	run := client.RunPipeline
+	if *validateOnly {
+		run = client.ValidatePipeline
+	}
+	result, err := run(ctx, p)
+	if result == nil {
+		return fmt.Errorf("manifest %s: %w", fs.Arg(0), err)
+	}
+
+	// Write the states that were read even if others failed
+	if !*validateOnly {
+		states := make(map[string]any)
+		var sources []tfclient.TerraformDataSource
+		for _, read := range p.Reads {
+			res := result.Reads[read.Name]
+			if res.Status != tfclient.NodeSucceeded {
+				continue
+			}
+			provider, _ := client.GetProvider(providers[read.Provider])
+			err := out.redact(res.State, func() (*tfclient.Schema, error) {
+				return provider.DataSourceSchema(read.DataSource)
+			})
+			if err != nil {
+				return fmt.Errorf("read %s: %w", read.Name, err)
+			}
+			states[read.Name] = res.State
+			sources = append(sources, tfclient.TerraformDataSource{
+				Name:       read.Name,
+				DataSource: read.DataSource,
+				Provider:   provider,
+				Result:     &tfclient.DataSourceResult{State: res.State, Sensitive: res.Sensitive},
+			})
+		}
+		if out.terraformFormat() {
+			err = out.writeTerraform(client, sources)
+		} else {
+			err = out.write(states)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, pp := range p.Providers {
+		if res := result.Providers[pp.Name]; res.Status != tfclient.NodeSucceeded {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", res.Err)
+		}
+	}
+	failed := 0
+	for _, read := range p.Reads {
+		if res := result.Reads[read.Name]; res.Status != tfclient.NodeSucceeded {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", res.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		if *validateOnly {
+			return withExitCode(exitRead, fmt.Errorf("%d of %d reads are invalid", failed, len(p.Reads)))
+		}
+		return withExitCode(exitRead, fmt.Errorf("%d of %d reads failed", failed, len(p.Reads)))
+	}
+	if *validateOnly {
+		fmt.Fprintln(os.Stderr, "Manifest is valid.")
+	}
+	return nil
+}
+
+// startManifestProviders starts and configures the providers of a manifest
+// named in names, each with its own client, for long-running commands. Close
+// the returned clients when done, even on error.
+func startManifestProviders(ctx context.Context, cf *clientFlags, declared map[string]manifestProvider, names []string) (map[string]tfclient.Provider, []*tfclient.Client, error) {
+	providers := make(map[string]tfclient.Provider)
+	var clients []*tfclient.Client
+	for _, name := range names {
+		if providers[name] != nil {
+			continue
+		}
+		p := declared[name]
+		pf := &providerFlags{clientFlags: cf, provider: p.Source, version: p.Version}
+		client, provider, err := pf.startProvider(ctx)
+		if err != nil {
+			return nil, clients, fmt.Errorf("provider %s: %w", name, err)
+		}
+		clients = append(clients, client)
+		if err := configureProvider(ctx, provider, p.Config); err != nil {
+			return nil, clients, fmt.Errorf("provider %s: %w", name, err)
+		}
+		providers[name] = provider
+	}
+	return providers, clients, nil
+}
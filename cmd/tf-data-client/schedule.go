@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/go-logr/logr"
+	tfclient "github.com/infracollect/tf-data-client"
+	"go.yaml.in/yaml/v3"
+)
+
+// schedulesConfig declares the scheduled reads of "tf-data-client serve
+// --schedules". Providers are declared as in run manifests.
+type schedulesConfig struct {
+	Providers map[string]manifestProvider `yaml:"providers"`
+	Schedules []scheduleConfig            `yaml:"schedules"`
+}
+
+type scheduleConfig struct {
+	manifestRead `yaml:",inline"`
+
+	Cron          string       `yaml:"cron"` // e.g. "*/5 * * * *" or "@every 1m"
+	OnlyChanges   bool         `yaml:"only_changes"`
+	ShowSensitive bool         `yaml:"show_sensitive"`
+	Sinks         []sinkConfig `yaml:"sinks"`
+}
+
+// sinkConfig declares where results are delivered, by type: stdout, webhook
+// (url and headers) or sns (topic_arn and region, with credentials from the
+// AWS environment).
+type sinkConfig struct {
+	Type     string            `yaml:"type"`
+	URL      string            `yaml:"url"`
+	Headers  map[string]string `yaml:"headers"`
+	TopicARN string            `yaml:"topic_arn"`
+	Region   string            `yaml:"region"`
+}
+
+// loadSchedules reads and validates a schedules file.
+func loadSchedules(path string) (*schedulesConfig, error) {
+	data, err := readInput(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var c schedulesConfig
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules %s: %w", path, err)
+	}
+
+	if len(c.Schedules) == 0 {
+		return nil, fmt.Errorf("schedules file %s has no schedules", path)
+	}
+	for i, s := range c.Schedules {
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("schedule %d of %s has no name", i+1, path)
+		case s.Cron == "":
+			return nil, fmt.Errorf("schedule %q of %s has no cron", s.Name, path)
+		case len(s.Sinks) == 0:
+			return nil, fmt.Errorf("schedule %q of %s has no sinks", s.Name, path)
+		}
+		if _, ok := c.Providers[s.Provider]; !ok {
+			return nil, fmt.Errorf("schedule %q of %s refers to undeclared provider %q", s.Name, path, s.Provider)
+		}
+	}
+	return &c, nil
+}
+
+// startScheduler starts the providers of a schedules file and returns a
+// scheduler running its schedules. Close the returned clients when done,
+// even on error.
+func startScheduler(ctx context.Context, cf *clientFlags, path string) (*tfclient.Scheduler, []*tfclient.Client, error) {
+	c, err := loadSchedules(path)
+	if err != nil {
+		return nil, nil, withExitCode(exitUsage, err)
+	}
+	sinks := make([][]tfclient.Sink, len(c.Schedules))
+	for i, s := range c.Schedules {
+		for _, sc := range s.Sinks {
+			sink, err := newSink(ctx, sc)
+			if err != nil {
+				return nil, nil, withExitCode(exitUsage, fmt.Errorf("schedule %s: %w", s.Name, err))
+			}
+			sinks[i] = append(sinks[i], sink)
+		}
+	}
+
+	var names []string
+	for _, s := range c.Schedules {
+		names = append(names, s.Provider)
+	}
+	providers, clients, err := startManifestProviders(ctx, cf, c.Providers, names)
+	if err != nil {
+		return nil, clients, err
+	}
+
+	scheduler := tfclient.NewScheduler(logr.FromSlogHandler(slog.Default().Handler()))
+	for i, s := range c.Schedules {
+		provider := providers[s.Provider]
+		schedule := tfclient.Schedule{
+			Name:        s.Name,
+			Spec:        s.Cron,
+			Provider:    provider,
+			DataSource:  s.DataSource,
+			Config:      s.Config,
+			OnlyChanges: s.OnlyChanges,
+			Sinks:       sinks[i],
+		}
+		if !s.ShowSensitive {
+			schema, err := provider.DataSourceSchema(s.DataSource)
+			if err != nil {
+				return nil, clients, withExitCode(exitNotFound, fmt.Errorf("schedule %s: %w", s.Name, err))
+			}
+			schedule.Filter = func(state map[string]any) map[string]any {
+				redactSchema(state, schema)
+				return state
+			}
+		}
+		if err := scheduler.Add(schedule); err != nil {
+			return nil, clients, withExitCode(exitUsage, err)
+		}
+	}
+	return scheduler, clients, nil
+}
+
+// newSink creates the sink declared by c.
+func newSink(ctx context.Context, c sinkConfig) (tfclient.Sink, error) {
+	switch c.Type {
+	case "stdout":
+		return tfclient.WriterSink(os.Stdout), nil
+
+	case "webhook":
+		if c.URL == "" {
+			return nil, fmt.Errorf("webhook sink has no url")
+		}
+		return tfclient.WebhookSink(c.URL, c.Headers, nil), nil
+
+	case "sns":
+		if c.TopicARN == "" {
+			return nil, fmt.Errorf("sns sink has no topic_arn")
+		}
+		var opts []func(*awsconfig.LoadOptions) error
+		if c.Region != "" {
+			opts = append(opts, awsconfig.WithRegion(c.Region))
+		}
+		cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+		}
+		client := sns.NewFromConfig(cfg)
+		return tfclient.SinkFunc(func(ctx context.Context, result *tfclient.ScheduleResult) error {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return fmt.Errorf("failed to encode result: %w", err)
+			}
+			_, err = client.Publish(ctx, &sns.PublishInput{
+				TopicArn: aws.String(c.TopicARN),
+				Subject:  aws.String(fmt.Sprintf("tf-data-client: %.80s", result.Schedule)),
+				Message:  aws.String(string(data)),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to publish result to SNS: %w", err)
+			}
+			return nil
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q, expected stdout, webhook or sns", c.Type)
+}
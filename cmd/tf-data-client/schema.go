@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// runSchema implements "tf-data-client schema <subcommand>".
+func runSchema(args []string) error {
+	if len(args) == 0 || isHelpFlag(args[0]) || args[0] == "help" {
+		schemaUsage()
+		return nil
+	}
+	switch args[0] {
+	case "describe":
+		return runSchemaDescribe(args[1:])
+	default:
+		schemaUsage()
+		return usageErrorf("unknown schema command %q", args[0])
+	}
+}
+
+func schemaUsage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s schema <command> [flags]
+
+Commands:
+  describe  Describe the attributes of a data source or of the provider configuration
+`, os.Args[0])
+}
+
+// runSchemaDescribe implements "tf-data-client schema describe", printing the
+// schema of a data source, or of the provider configuration if none is given.
+func runSchemaDescribe(args []string) error {
+	fs := newFlagSet("schema describe", "[data-source]", "Describe the attributes of a data source, or of the provider configuration without a data source.")
+	pf := addProviderFlags(fs)
+	jsonOutput := fs.Bool("json", false, "Print the schema as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return usageErrorf("at most one data source can be described")
+	}
+	if _, err := pf.providerConfig(); err != nil {
+		return err
+	}
+
+	client, provider, err := pf.startProvider(context.Background())
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	dataSource := fs.Arg(0)
+	var schema *tfclient.Schema
+	if dataSource == "" {
+		schema, err = provider.ProviderSchema()
+	} else {
+		schema, err = provider.DataSourceSchema(dataSource)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get schema: %w", err)
+	}
+
+	if *jsonOutput {
+		data, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema to JSON: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if dataSource == "" {
+		fmt.Printf("Provider %s\n", provider.Config().String())
+	} else {
+		fmt.Printf("Data source %s (%s)\n", dataSource, provider.Config().String())
+	}
+	if schema.Description != "" {
+		fmt.Printf("\n%s\n", schema.Description)
+	}
+	if schema.Deprecated {
+		fmt.Println("\nDeprecated.")
+	}
+
+	var arguments, readOnly []schemaRow
+	for _, row := range schemaRows(schema, "") {
+		if row.readOnly {
+			readOnly = append(readOnly, row)
+		} else {
+			arguments = append(arguments, row)
+		}
+	}
+	printSchemaRows("Arguments", arguments)
+	printSchemaRows("Read-only attributes", readOnly)
+	return nil
+}
+
+// schemaRow is a line of the schema describe table.
+type schemaRow struct {
+	name, typ, flags, description string
+	readOnly                      bool
+}
+
+// schemaRows flattens the attributes and blocks of a schema, naming nested
+// ones with a dotted path after their parent.
+func schemaRows(schema *tfclient.Schema, prefix string) []schemaRow {
+	var rows []schemaRow
+	for _, attr := range schema.Attributes {
+		rows = append(rows, attributeRows(attr, prefix)...)
+	}
+	for _, block := range schema.Blocks {
+		presence := "optional"
+		if block.MinItems > 0 {
+			presence = "required"
+		}
+		typ := "block"
+		if block.Nesting != "single" && block.Nesting != "group" {
+			typ = block.Nesting + " of blocks"
+		}
+		flags := []string{presence}
+		if block.Deprecated {
+			flags = append(flags, "deprecated")
+		}
+		rows = append(rows, schemaRow{
+			name:        prefix + block.Name,
+			typ:         typ,
+			flags:       strings.Join(flags, ", "),
+			description: block.Description,
+		})
+		rows = append(rows, schemaRows(&block.Schema, prefix+block.Name+".")...)
+	}
+	return rows
+}
+
+func attributeRows(attr tfclient.SchemaAttribute, prefix string) []schemaRow {
+	var flags []string
+	switch {
+	case attr.Required:
+		flags = append(flags, "required")
+	case attr.Optional && attr.Computed:
+		flags = append(flags, "optional", "computed")
+	case attr.Optional:
+		flags = append(flags, "optional")
+	case attr.Computed:
+		flags = append(flags, "computed")
+	}
+	if attr.Sensitive {
+		flags = append(flags, "sensitive")
+	}
+	if attr.Deprecated {
+		flags = append(flags, "deprecated")
+	}
+
+	typ := attr.Type
+	if attr.Nesting != "" {
+		typ = attr.Nesting + " of objects"
+		if attr.Nesting == "single" {
+			typ = "object"
+		}
+	}
+	rows := []schemaRow{{
+		name:        prefix + attr.Name,
+		typ:         typ,
+		flags:       strings.Join(flags, ", "),
+		description: attr.Description,
+		readOnly:    attr.ReadOnly(),
+	}}
+	for _, nested := range attr.Attributes {
+		nestedRows := attributeRows(nested, prefix+attr.Name+".")
+		// Attributes nested in a read-only one can't be configured either
+		for i := range nestedRows {
+			nestedRows[i].readOnly = nestedRows[i].readOnly || attr.ReadOnly()
+		}
+		rows = append(rows, nestedRows...)
+	}
+	return rows
+}
+
+func printSchemaRows(title string, rows []schemaRow) {
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tTYPE\tFLAGS\tDESCRIPTION")
+	for _, row := range rows {
+		// Keep descriptions on their row
+		description := strings.Join(strings.Fields(row.description), " ")
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", row.name, row.typ, row.flags, description)
+	}
+	w.Flush()
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/registry"
+)
+
+// runSearch implements "tf-data-client search [flags] [query]", listing the
+// providers matching query, or those of --namespace.
+func runSearch(args []string) error {
+	fs := newFlagSet("search", "[query]", "Search the registry for providers, or list those of a namespace.")
+	namespace := fs.String("namespace", "", "List the providers of a namespace (e.g., hashicorp) instead of searching")
+	registryHost := fs.String("registry", settings.Registry, "Provider registry host (optional, TFDC_REGISTRY, defaults to registry.terraform.io)")
+	asJSON := fs.Bool("json", false, "Output results as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if query == "" && *namespace == "" {
+		return usageErrorf("a search query or --namespace is required")
+	}
+
+	var opts []tfclient.Option
+	if *registryHost != "" {
+		opts = append(opts, tfclient.WithRegistryHost(*registryHost))
+	}
+	client, err := tfclient.New(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	var providers []registry.ProviderSummary
+	if *namespace != "" {
+		providers, err = client.ListRegistryProviders(ctx, *namespace)
+	} else {
+		providers, err = client.SearchProviders(ctx, query)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to search providers: %w", err)
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(providers, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal results to JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	if len(providers) == 0 {
+		fmt.Fprintln(os.Stderr, "No providers found.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tTIER\tDOWNLOADS\tDESCRIPTION")
+	for _, p := range providers {
+		fmt.Fprintf(tw, "%s/%s\t%s\t%d\t%s\n", p.Namespace, p.Name, p.Tier, p.Downloads, p.Description)
+	}
+	return tw.Flush()
+}
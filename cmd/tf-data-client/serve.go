@@ -0,0 +1,461 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tfclient "github.com/infracollect/tf-data-client"
+	"google.golang.org/grpc"
+)
+
+// maxRequestBody bounds the request bodies of the HTTP API.
+const maxRequestBody = 10 << 20
+
+// runServe implements "tf-data-client serve", an HTTP API keeping providers
+// running between requests.
+func runServe(args []string) error {
+	fs := newFlagSet("serve", "", `Serve an HTTP API that keeps configured providers running between requests:
+
+  POST   /providers                      create and configure a provider
+  GET    /providers                      list the providers
+  GET    /providers/{id}                 describe a provider
+  DELETE /providers/{id}                 stop a provider
+  GET    /providers/{id}/data-sources    list its data sources
+  POST   /providers/{id}/read            read a data source
+  DELETE /cache                          invalidate cached results, of the
+                                         ?provider=ns/name and ?data_source=
+                                         given
+
+With --result-ttl or --result-ttl-for, identical reads are served from a
+result cache until they expire, with X-Cache, Age and Cache-Control headers.
+
+With --grpc-listen, the DataClient gRPC service of daemonpb/daemon.proto is
+served as well.
+
+With --schedules, the reads of a schedules file are run on their cron
+schedules and their results, or changes, delivered to stdout, webhook or SNS
+sinks.`)
+	cf := addClientFlags(fs)
+	listen := fs.String("listen", "127.0.0.1:8080", "Address the HTTP API listens on, or empty to serve gRPC only")
+	grpcListen := fs.String("grpc-listen", "", "Address the gRPC service listens on (optional)")
+	token := fs.String("auth-token", os.Getenv("TFDC_AUTH_TOKEN"), "Bearer token required from clients (optional, TFDC_AUTH_TOKEN)")
+	resultTTL := fs.Duration("result-ttl", 0, "How long identical reads are served from the result cache, e.g. 30s (optional, disabled by default)")
+	var resultTTLs stringsFlag
+	fs.Var(&resultTTLs, "result-ttl-for", "TTL of the results of a data source, overriding --result-ttl, e.g. aws_ami=1h or aws_instance=0 to disable (repeatable)")
+	schedulesPath := fs.String("schedules", "", "File of data source reads to run on a schedule, delivering their results to sinks (optional)")
+	resultCacheFile := fs.String("result-cache-file", "", "File persisting the result cache across restarts (optional, kept in memory by default)")
+	showSensitive := fs.Bool("show-sensitive", false, "Return the attributes the schema marks as sensitive in read responses, which are masked by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return usageErrorf("serve takes no arguments")
+	}
+	if *listen == "" && *grpcListen == "" && *schedulesPath == "" {
+		return usageErrorf("--listen, --grpc-listen or --schedules is required")
+	}
+	// Configurations sent to the APIs come from their callers: resolving
+	// their references would disclose the files, environment and secrets of
+	// the server
+	api := *listen != "" || *grpcListen != ""
+	if api && cf.interpolate {
+		return usageErrorf("--interpolate only applies to --schedules served without the HTTP and gRPC APIs (--listen ''), never to configurations their callers send")
+	}
+	if api && cf.secrets {
+		return usageErrorf("--resolve-secrets only applies to --schedules served without the HTTP and gRPC APIs (--listen ''), never to configurations their callers send")
+	}
+	results, err := newResultCache(*resultTTL, resultTTLs, *resultCacheFile)
+	if err != nil {
+		return err
+	}
+	if results != nil {
+		defer results.Close()
+		cf.options = append(cf.options, tfclient.WithResultCache(results))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errs := make(chan error, 2)
+	var shutdown []func()
+
+	if *listen != "" {
+		lis, err := net.Listen("tcp", *listen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		s := &server{cf: cf, token: *token, results: results, showSensitive: *showSensitive, providers: make(map[string]*servedProvider)}
+		defer s.closeAll()
+		srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+		shutdown = append(shutdown, func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		})
+		slog.Info("serving HTTP", "address", lis.Addr().String(), "auth", *token != "")
+		go func() {
+			if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	if *grpcListen != "" {
+		lis, err := net.Listen("tcp", *grpcListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		// Unlike the HTTP API, the gRPC service shares one client, and so
+		// providers, between callers
+		client, err := cf.newClient(nil)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		var opts []grpc.ServerOption
+		if *token != "" {
+			auth := tfclient.BearerTokenAuth(*token)
+			opts = append(opts,
+				grpc.ChainUnaryInterceptor(tfclient.UnaryAuthInterceptor(auth)),
+				grpc.ChainStreamInterceptor(tfclient.StreamAuthInterceptor(auth)))
+		}
+		srv := grpc.NewServer(opts...)
+		daemon := tfclient.NewDaemonServer(client)
+		daemon.RevealSensitive = *showSensitive
+		daemon.Register(srv)
+		shutdown = append(shutdown, srv.GracefulStop)
+		slog.Info("serving gRPC", "address", lis.Addr().String(), "auth", *token != "")
+		go func() {
+			if err := srv.Serve(lis); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	if *schedulesPath != "" {
+		scheduler, clients, err := startScheduler(ctx, cf, *schedulesPath)
+		for _, client := range clients {
+			defer client.Close()
+		}
+		if err != nil {
+			return err
+		}
+		schedulerCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		shutdown = append(shutdown, func() {
+			cancel()
+			<-done
+		})
+		slog.Info("running schedules", "file", *schedulesPath)
+		go func() {
+			defer close(done)
+			scheduler.Run(schedulerCtx)
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+	slog.Info("shutting down")
+	for _, fn := range shutdown {
+		fn()
+	}
+	return err
+}
+
+// server serves the HTTP API of "tf-data-client serve".
+type server struct {
+	cf            *clientFlags
+	token         string
+	results       *tfclient.ResultCache // nil without --result-ttl
+	showSensitive bool
+
+	mu        sync.Mutex
+	providers map[string]*servedProvider
+}
+
+// servedProvider is a provider created through the API. Each has its own
+// client, so that providers with the same source can differ in configuration.
+type servedProvider struct {
+	ID       string `json:"id"`
+	Provider string `json:"provider"`
+	Version  string `json:"version"`
+
+	client   *tfclient.Client
+	provider tfclient.Provider
+}
+
+type createProviderRequest struct {
+	Provider string         `json:"provider"` // e.g. hashicorp/aws
+	Version  string         `json:"version"`
+	Config   map[string]any `json:"config"`
+}
+
+type readRequestBody struct {
+	DataSource string         `json:"data_source"`
+	Config     map[string]any `json:"config"`
+}
+
+// handler returns the routes of the HTTP API. With a token, every route but
+// the health check, left open to load balancer and Kubernetes probes,
+// requires it.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /providers", s.createProvider)
+	mux.HandleFunc("GET /providers", s.listProviders)
+	mux.HandleFunc("GET /providers/{id}", s.withProvider(func(w http.ResponseWriter, r *http.Request, p *servedProvider) {
+		writeJSON(w, http.StatusOK, p)
+	}))
+	mux.HandleFunc("DELETE /providers/{id}", s.deleteProvider)
+	mux.HandleFunc("GET /providers/{id}/data-sources", s.withProvider(func(w http.ResponseWriter, r *http.Request, p *servedProvider) {
+		dataSources := p.provider.ListDataSources()
+		sort.Strings(dataSources)
+		writeJSON(w, http.StatusOK, map[string][]string{"data_sources": dataSources})
+	}))
+	mux.HandleFunc("POST /providers/{id}/read", s.withProvider(s.read))
+	mux.HandleFunc("DELETE /cache", s.invalidate)
+
+	api := http.Handler(mux)
+	if s.token != "" {
+		want := []byte("Bearer " + s.token)
+		api = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+				return
+			}
+			mux.ServeHTTP(w, r)
+		})
+	}
+
+	root := http.NewServeMux()
+	root.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	root.Handle("/", api)
+	return root
+}
+
+func (s *server) createProvider(w http.ResponseWriter, r *http.Request) {
+	var req createProviderRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	pf := &providerFlags{clientFlags: s.cf, provider: req.Provider, version: req.Version}
+	if _, err := pf.providerConfig(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Providers outlive the request that creates them
+	ctx := context.WithoutCancel(r.Context())
+	client, provider, err := pf.startProvider(ctx)
+	if err == nil {
+		if err = configureProvider(ctx, provider, req.Config); err != nil {
+			client.Close()
+		}
+	}
+	if err != nil {
+		writeError(w, statusCode(err), err)
+		return
+	}
+
+	p := &servedProvider{
+		ID:       rand.Text(),
+		Provider: req.Provider,
+		Version:  provider.Config().Version,
+		client:   client,
+		provider: provider,
+	}
+	s.mu.Lock()
+	s.providers[p.ID] = p
+	s.mu.Unlock()
+	slog.Info("created provider", "id", p.ID, "provider", p.Provider, "version", p.Version)
+	writeJSON(w, http.StatusCreated, p)
+}
+
+func (s *server) listProviders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	list := make([]*servedProvider, 0, len(s.providers))
+	for _, p := range s.providers {
+		list = append(list, p)
+	}
+	s.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeJSON(w, http.StatusOK, map[string]any{"providers": list})
+}
+
+func (s *server) deleteProvider(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	p, ok := s.providers[r.PathValue("id")]
+	delete(s.providers, r.PathValue("id"))
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("provider %q not found", r.PathValue("id")))
+		return
+	}
+	p.client.Close()
+	slog.Info("stopped provider", "id", p.ID, "provider", p.Provider)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) read(w http.ResponseWriter, r *http.Request, p *servedProvider) {
+	var req readRequestBody
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.DataSource == "" {
+		writeError(w, http.StatusBadRequest, errors.New("data_source is required"))
+		return
+	}
+	result, err := p.provider.ReadDataSource(r.Context(), req.DataSource, req.Config)
+	if err != nil {
+		writeError(w, statusCode(withExitCode(exitRead, err)), err)
+		return
+	}
+	if !s.showSensitive {
+		// Mask what read would: callers shouldn't get more from the server
+		schema, err := p.provider.DataSourceSchema(req.DataSource)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		redactSchema(result.State, schema)
+	}
+	if s.results != nil {
+		if result.Cached {
+			w.Header().Set("X-Cache", "HIT")
+			w.Header().Set("Age", strconv.Itoa(int(time.Since(result.CachedAt).Seconds())))
+		} else {
+			w.Header().Set("X-Cache", "MISS")
+		}
+		if maxAge := int(time.Until(result.ExpiresAt).Seconds()); !result.ExpiresAt.IsZero() && maxAge > 0 {
+			w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"state": result.State})
+}
+
+// invalidate removes cached results, of the provider and data source query
+// parameters if given.
+func (s *server) invalidate(w http.ResponseWriter, r *http.Request) {
+	if s.results == nil {
+		writeError(w, http.StatusNotFound, errors.New("the result cache is disabled, see --result-ttl"))
+		return
+	}
+	var namespace, name string
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		var ok bool
+		if namespace, name, ok = strings.Cut(provider, "/"); !ok {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid provider %q, expected namespace/name", provider))
+			return
+		}
+	}
+	n, err := s.results.Invalidate(namespace, name, r.URL.Query().Get("data_source"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	slog.Info("invalidated cached results", "count", n)
+	writeJSON(w, http.StatusOK, map[string]int{"invalidated": n})
+}
+
+// newResultCache creates the result cache of the --result-ttl,
+// --result-ttl-for and --result-cache-file flags, or returns nil if no TTL
+// is set.
+func newResultCache(ttl time.Duration, ttlFor []string, path string) (*tfclient.ResultCache, error) {
+	opts := tfclient.ResultCacheOptions{TTL: ttl, TTLs: make(map[string]time.Duration), Path: path}
+	for _, s := range ttlFor {
+		dataSource, value, ok := strings.Cut(s, "=")
+		d, err := time.ParseDuration(value)
+		if !ok || dataSource == "" || err != nil {
+			return nil, usageErrorf("invalid --result-ttl-for %q, expected data_source=duration", s)
+		}
+		opts.TTLs[dataSource] = d
+	}
+	if ttl <= 0 && len(opts.TTLs) == 0 {
+		if path != "" {
+			return nil, usageErrorf("--result-cache-file requires --result-ttl or --result-ttl-for")
+		}
+		return nil, nil
+	}
+	return tfclient.NewResultCache(opts)
+}
+
+// withProvider adapts a handler of the provider of the {id} path wildcard.
+func (s *server) withProvider(fn func(w http.ResponseWriter, r *http.Request, p *servedProvider)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		p, ok := s.providers[r.PathValue("id")]
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, fmt.Errorf("provider %q not found", r.PathValue("id")))
+			return
+		}
+		fn(w, r, p)
+	}
+}
+
+// closeAll stops the providers.
+func (s *server) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, p := range s.providers {
+		p.client.Close()
+		delete(s.providers, id)
+	}
+}
+
+// decodeBody decodes a JSON request body into v, answering 400 and returning
+// false if it is invalid.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+// statusCode maps an error to an HTTP status by its exit code class.
+func statusCode(err error) int {
+	switch exitCode(err) {
+	case exitUsage:
+		return http.StatusBadRequest
+	case exitNotFound:
+		return http.StatusNotFound
+	case exitConfigure:
+		return http.StatusUnprocessableEntity
+	case exitDownload, exitRead:
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
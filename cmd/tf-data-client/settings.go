@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// settingsFileName is the name of the settings file, looked up in the working
+// directory, then in the user configuration directory, e.g.
+// ~/.config/tf-data-client/tf-data-client.yaml.
+const settingsFileName = "tf-data-client.yaml"
+
+// cliSettings are the defaults of the client flags, read from the settings
+// file and TFDC_* environment variables. Flags take precedence over the
+// environment, which takes precedence over the file.
+type cliSettings struct {
+	CacheDir string `yaml:"cache_dir"`
+	Registry string `yaml:"registry"`
+	Offline  bool   `yaml:"offline"`
+	LogLevel string `yaml:"log_level"`
+	// TerraformVersion is the Terraform version presented to providers.
+	TerraformVersion string `yaml:"terraform_version"`
+	// Providers maps providers to their default version or constraint, e.g.
+	// "hashicorp/aws": "~> 5.0", used when --version isn't set.
+	Providers map[string]string `yaml:"providers"`
+}
+
+// settings are loaded by run before any command parses its flags.
+var settings cliSettings
+
+// loadSettings reads the settings file, if any, then applies the environment.
+// TFDC_CONFIG names a settings file to use instead of looking one up.
+func loadSettings() (cliSettings, error) {
+	var s cliSettings
+	path, err := settingsPath()
+	if err != nil {
+		return s, err
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return s, fmt.Errorf("failed to read settings: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &s); err != nil {
+			return s, fmt.Errorf("failed to parse settings %s: %w", path, err)
+		}
+	}
+
+	if v, ok := os.LookupEnv("TFDC_CACHE_DIR"); ok {
+		s.CacheDir = v
+	}
+	if v, ok := os.LookupEnv("TFDC_REGISTRY"); ok {
+		s.Registry = v
+	}
+	if v, ok := os.LookupEnv("TFDC_OFFLINE"); ok {
+		offline, err := strconv.ParseBool(v)
+		if err != nil {
+			return s, fmt.Errorf("invalid TFDC_OFFLINE %q: %w", v, err)
+		}
+		s.Offline = offline
+	}
+	if v, ok := os.LookupEnv("TFDC_LOG_LEVEL"); ok {
+		s.LogLevel = v
+	}
+	if v, ok := os.LookupEnv("TFDC_TERRAFORM_VERSION"); ok {
+		s.TerraformVersion = v
+	}
+
+	if s.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(s.LogLevel)); err != nil {
+			return s, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", s.LogLevel)
+		}
+	}
+	return s, nil
+}
+
+// settingsPath returns the settings file to read, or "" if there is none.
+func settingsPath() (string, error) {
+	if path := os.Getenv("TFDC_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	candidates := []string{settingsFileName}
+	if dir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "tf-data-client", settingsFileName))
+	}
+	for _, path := range candidates {
+		_, err := os.Stat(path)
+		if err == nil {
+			return path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read settings: %w", err)
+		}
+	}
+	return "", nil
+}
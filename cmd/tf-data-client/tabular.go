@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/infracollect/tf-data-client/internal/parquet"
+)
+
+// tabularFormat reports whether the output format writes rows, with
+// writeRows, from all the values the result or --query yields.
+func (f *outputFlags) tabularFormat() bool {
+	return f.format == "csv" || f.format == "parquet"
+}
+
+// tableRows returns the rows of a table from values: the elements of their
+// listAttribute if set, else the elements of the lists among values, and
+// other values as rows of their own.
+func tableRows(values []any, listAttribute string) ([]any, error) {
+	var rows []any
+	for _, v := range values {
+		if listAttribute != "" {
+			m, _ := v.(map[string]any)
+			attr, ok := m[listAttribute]
+			if !ok {
+				return nil, fmt.Errorf("attribute %q not found in result", listAttribute)
+			}
+			if attr == nil {
+				continue
+			}
+			if v, ok = attr.([]any); !ok {
+				return nil, fmt.Errorf("attribute %q is not a list or set", listAttribute)
+			}
+		}
+		if elems, ok := v.([]any); ok {
+			rows = append(rows, elems...)
+		} else {
+			rows = append(rows, v)
+		}
+	}
+	return rows, nil
+}
+
+// flattenRow returns the columns of a row: the attributes of objects, with
+// those of nested objects named by their path, e.g. "tags.env". Rows that
+// aren't objects have a single column, "value".
+func flattenRow(row any) map[string]any {
+	m, ok := row.(map[string]any)
+	if !ok {
+		return map[string]any{"value": row}
+	}
+	cols := make(map[string]any)
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if nested, ok := v.(map[string]any); ok && len(nested) > 0 {
+				flatten(prefix+k+".", nested)
+				continue
+			}
+			cols[prefix+k] = v
+		}
+	}
+	flatten("", m)
+	return cols
+}
+
+// writeRows writes the rows of values, see tableRows, to w as CSV or Parquet,
+// with a column for each attribute of the rows, in sorted order. Values of
+// missing attributes are empty, or null in Parquet, and lists are JSON.
+func writeRows(w io.Writer, values []any, format, listAttribute string) error {
+	rows, err := tableRows(values, listAttribute)
+	if err != nil {
+		return err
+	}
+	flat := make([]map[string]any, len(rows))
+	names := make(map[string]bool)
+	for i, row := range rows {
+		flat[i] = flattenRow(row)
+		for name := range flat[i] {
+			names[name] = true
+		}
+	}
+	columns := slices.Sorted(maps.Keys(names))
+	if len(columns) == 0 {
+		columns = []string{"value"}
+	}
+
+	if format == "parquet" {
+		table := make([]parquet.Column, len(columns))
+		for i, name := range columns {
+			if table[i], err = parquetColumn(name, flat); err != nil {
+				return err
+			}
+		}
+		if err := parquet.Write(w, table); err != nil {
+			return fmt.Errorf("failed to write Parquet: %w", err)
+		}
+		return nil
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, row := range flat {
+		for i, name := range columns {
+			record[i] = cellString(row[name])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cellString renders a value in a CSV cell: null is empty, and lists and
+// objects are JSON.
+func cellString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// parquetColumn returns the column name of rows, typed after its values:
+// booleans, integers, other numbers, or else strings as in CSV.
+func parquetColumn(name string, rows []map[string]any) (parquet.Column, error) {
+	col := parquet.Column{Name: name, Type: parquet.ByteArray, Values: make([]any, len(rows))}
+	var bools, ints, floats, others int
+	for _, row := range rows {
+		switch v := row[name].(type) {
+		case nil:
+		case bool:
+			bools++
+		case float64:
+			if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+				ints++
+			} else {
+				floats++
+			}
+		case json.Number:
+			if _, err := v.Int64(); err == nil {
+				ints++
+			} else {
+				floats++
+			}
+		default:
+			others++
+		}
+	}
+	switch {
+	case others > 0 || bools > 0 && ints+floats > 0:
+	case bools > 0:
+		col.Type = parquet.Boolean
+	case floats > 0:
+		col.Type = parquet.Double
+	case ints > 0:
+		col.Type = parquet.Int64
+	}
+
+	for i, row := range rows {
+		v := row[name]
+		if v == nil {
+			continue
+		}
+		switch col.Type {
+		case parquet.Boolean:
+			col.Values[i] = v
+		case parquet.Int64:
+			if n, ok := v.(json.Number); ok {
+				col.Values[i], _ = n.Int64()
+			} else {
+				col.Values[i] = int64(v.(float64))
+			}
+		case parquet.Double:
+			if n, ok := v.(json.Number); ok {
+				f, err := n.Float64()
+				if err != nil {
+					return col, fmt.Errorf("column %s: %w", name, err)
+				}
+				col.Values[i] = f
+			} else {
+				col.Values[i] = v.(float64)
+			}
+		default:
+			col.Values[i] = cellString(v)
+		}
+	}
+	return col, nil
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// parseTemplate parses the template of --template or --template-file, with
+// templateFuncs.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// templateFuncs are the functions of output templates, named after their
+// sprig counterparts so that Helm users feel at home.
+var templateFuncs = template.FuncMap{
+	// Strings
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+	"split":      func(sep, s string) []any { return toList(strings.Split(s, sep)) },
+	"join":       join,
+	"quote":      func(v any) string { return strconv.Quote(toString(v)) },
+	"squote":     func(v any) string { return "'" + toString(v) + "'" },
+	"indent":     indent,
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"toString":   toString,
+
+	// Defaults
+	"default":  func(def, v any) any { return firstNonEmpty(v, def) },
+	"empty":    func(v any) bool { return isEmpty([]any{v}) },
+	"coalesce": func(vs ...any) any { return firstNonEmpty(vs...) },
+	"ternary": func(yes, no any, cond bool) any {
+		if cond {
+			return yes
+		}
+		return no
+	},
+
+	// Lists and objects
+	"list":      func(vs ...any) []any { return vs },
+	"first":     func(l []any) any { return at(l, 0) },
+	"last":      func(l []any) any { return at(l, len(l)-1) },
+	"uniq":      uniq,
+	"sortAlpha": sortAlpha,
+	"dict":      dict,
+	"keys":      keys,
+	"hasKey":    func(m map[string]any, key string) bool { _, ok := m[key]; return ok },
+	"get":       func(m map[string]any, key string) any { return m[key] },
+	"pluck":     pluck,
+
+	// Numbers
+	"add":     func(a, b any) (float64, error) { return arith(a, b, func(x, y float64) float64 { return x + y }) },
+	"sub":     func(a, b any) (float64, error) { return arith(a, b, func(x, y float64) float64 { return x - y }) },
+	"mul":     func(a, b any) (float64, error) { return arith(a, b, func(x, y float64) float64 { return x * y }) },
+	"div":     func(a, b any) (float64, error) { return arith(a, b, func(x, y float64) float64 { return x / y }) },
+	"float64": toFloat,
+
+	// Encoding
+	"toJson":       toJSON,
+	"toPrettyJson": toPrettyJSON,
+	"fromJson":     fromJSON,
+	"toYaml":       toYAML,
+
+	// Environment
+	"env": os.Getenv,
+	"now": time.Now,
+	"date": func(layout string, t any) (string, error) {
+		switch t := t.(type) {
+		case time.Time:
+			return t.Format(layout), nil
+		case string:
+			parsed, err := time.Parse(time.RFC3339, t)
+			if err != nil {
+				return "", err
+			}
+			return parsed.Format(layout), nil
+		}
+		return "", fmt.Errorf("date: expected a time or an RFC 3339 string, got %T", t)
+	},
+}
+
+// toString formats a value of a state as text, JSON for lists and objects.
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	if s, err := toJSON(v); err == nil {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// toFloat converts a number, or a string holding one, to float64.
+func toFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("not a number: %v", v)
+}
+
+func arith(a, b any, op func(x, y float64) float64) (float64, error) {
+	x, err := toFloat(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return 0, err
+	}
+	return op(x, y), nil
+}
+
+// firstNonEmpty returns the first of vs that isn't empty, or nil.
+func firstNonEmpty(vs ...any) any {
+	for _, v := range vs {
+		if !isEmpty([]any{v}) {
+			return v
+		}
+	}
+	return nil
+}
+
+// toList converts a slice of any element type to []any.
+func toList(v any) []any {
+	if l, ok := v.([]any); ok {
+		return l
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	l := make([]any, rv.Len())
+	for i := range l {
+		l[i] = rv.Index(i).Interface()
+	}
+	return l
+}
+
+func at(l []any, i int) any {
+	if i < 0 || i >= len(l) {
+		return nil
+	}
+	return l[i]
+}
+
+func join(sep string, v any) string {
+	var parts []string
+	for _, e := range toList(v) {
+		parts = append(parts, toString(e))
+	}
+	return strings.Join(parts, sep)
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func uniq(l []any) []any {
+	var out []any
+	seen := make(map[string]bool)
+	for _, v := range l {
+		key := toString(v)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func sortAlpha(v any) []string {
+	var out []string
+	for _, e := range toList(v) {
+		out = append(out, toString(e))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func dict(kvs ...any) (map[string]any, error) {
+	if len(kvs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected key and value pairs")
+	}
+	m := make(map[string]any, len(kvs)/2)
+	for i := 0; i < len(kvs); i += 2 {
+		m[toString(kvs[i])] = kvs[i+1]
+	}
+	return m, nil
+}
+
+// keys returns the keys of an object, sorted.
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// pluck returns the values of key in the objects of a list that have it.
+func pluck(key string, l []any) []any {
+	var out []any
+	for _, e := range l {
+		if m, ok := e.(map[string]any); ok {
+			if v, ok := m[key]; ok {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func toPrettyJSON(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	return string(data), err
+}
+
+func fromJSON(s string) (any, error) {
+	var v any
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+func toYAML(v any) (string, error) {
+	data, err := yaml.Marshal(yamlNumbers(v))
+	return strings.TrimSuffix(string(data), "\n"), err
+}
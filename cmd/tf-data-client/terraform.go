@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// validateTerraform checks the flags of the Terraform formats: hcl, tfstate
+// and import.
+func (f *outputFlags) validateTerraform() error {
+	switch f.format {
+	case "hcl", "tfstate":
+		if f.queryExpr != "" || f.templateText != "" || f.templateFile != "" {
+			return usageErrorf("--format %s renders whole results, it can't be used with --query or a template", f.format)
+		}
+		if f.tfName != "" && tfclient.TerraformName(f.tfName) != f.tfName {
+			return usageErrorf("invalid --tf-name %q: must start with a letter or underscore and contain only letters, digits, underscores and dashes", f.tfName)
+		}
+	case "import":
+		if f.importTo == "" || f.queryExpr == "" {
+			return usageErrorf("--format import requires --import-to and a --query selecting the IDs, e.g. --import-to aws_instance --query .ids")
+		}
+	}
+	return nil
+}
+
+// terraformFormat reports whether the output format renders whole reads into
+// Terraform artifacts, with writeTerraform instead of write.
+func (f *outputFlags) terraformFormat() bool {
+	return f.format == "hcl" || f.format == "tfstate"
+}
+
+// writeTerraform writes reads as data blocks or a Terraform state, as the
+// format requests, to the output file, or stdout.
+func (f *outputFlags) writeTerraform(client *tfclient.Client, sources []tfclient.TerraformDataSource) error {
+	var buf bytes.Buffer
+	var err error
+	if f.format == "hcl" {
+		err = tfclient.WriteTerraformDataBlocks(&buf, sources)
+	} else {
+		err = client.WriteTerraformState(&buf, sources)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", f.format, err)
+	}
+	return f.writeOutput(buf.Bytes())
+}
+
+// writeImports writes an import block for each of values: an ID, or an object
+// with an "id" and optionally a "name" for its resource, which defaults to the
+// ID. to is the resource type, or address prefix, the resources are imported
+// into.
+func writeImports(w io.Writer, values []any, to string) error {
+	var imports []tfclient.TerraformImport
+	names := make(map[string]int)
+	add := func(id, name string) {
+		name = tfclient.TerraformName(name)
+		if names[name]++; names[name] > 1 {
+			name += "_" + strconv.Itoa(names[name])
+		}
+		imports = append(imports, tfclient.TerraformImport{To: to + "." + name, ID: id})
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			add(v, v)
+		case []any:
+			// A list of IDs, e.g. the ids attribute of aws_instances
+			for _, e := range v {
+				id, ok := e.(string)
+				if !ok {
+					return fmt.Errorf("--format import expects IDs or objects with an id, got a list of %s", typeName(e))
+				}
+				add(id, id)
+			}
+		case map[string]any:
+			id, ok := v["id"].(string)
+			if !ok {
+				return fmt.Errorf("--format import expects IDs or objects with an id, got an object without an id string")
+			}
+			name, _ := v["name"].(string)
+			if name == "" {
+				name = id
+			}
+			add(id, name)
+		default:
+			return fmt.Errorf("--format import expects IDs or objects with an id, got %s", typeName(v))
+		}
+	}
+	return tfclient.WriteTerraformImports(w, imports)
+}
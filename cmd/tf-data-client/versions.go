@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// runVersions implements "tf-data-client versions namespace/name", listing
+// the versions of a provider in the registry.
+func runVersions(args []string) error {
+	fs := newFlagSet("versions", "<namespace/name>", "List the versions of a provider in the registry, newest first, with the plugin protocols they support. Only protocol 6 providers can be run. With --offline, the cached versions are listed.")
+	cf := addClientFlags(fs)
+	asJSON := fs.Bool("json", false, "Output versions as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return usageErrorf("a provider is required")
+	}
+	namespace, name, ok := strings.Cut(fs.Arg(0), "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return usageErrorf("provider must be in format namespace/name (e.g., hashicorp/kubernetes)")
+	}
+
+	client, err := cf.newClient(nil)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	versions, err := client.ProviderVersions(context.Background(), namespace, name)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		out, err := json.MarshalIndent(versions, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal versions to JSON: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tPROTOCOLS")
+	for _, v := range versions {
+		fmt.Fprintf(tw, "%s\t%s\n", v.Version, strings.Join(v.Protocols, ", "))
+	}
+	return tw.Flush()
+}
+
+// runOutdated implements "tf-data-client outdated", reporting the cached or
+// locked providers with newer releases.
+func runOutdated(args []string) error {
+	fs := newFlagSet("outdated", "", "Compare the providers pinned by a lock file, and the newest cached version of the other cached providers, with the latest versions in the registry.")
+	cf := addClientFlags(fs)
+	lockFile := fs.String("lock-file", "", "Terraform dependency lock file (.terraform.lock.hcl) whose versions are compared (optional)")
+	asJSON := fs.Bool("json", false, "Output providers as JSON")
+	all := fs.Bool("all", false, "Also list up-to-date providers")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var opts []tfclient.Option
+	if *lockFile != "" {
+		opts = append(opts, tfclient.WithLockFile(*lockFile))
+	}
+	client, err := cf.newClient(nil, opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	updates, checkErr := client.CheckUpdates(context.Background())
+	if !*all {
+		outdated := updates[:0]
+		for _, u := range updates {
+			if u.Outdated() {
+				outdated = append(outdated, u)
+			}
+		}
+		updates = outdated
+	}
+
+	if *asJSON {
+		type update struct {
+			Provider string `json:"provider"`
+			Current  string `json:"current"`
+			Locked   bool   `json:"locked"`
+			Latest   string `json:"latest"`
+			Outdated bool   `json:"outdated"`
+		}
+		list := make([]update, 0, len(updates))
+		for _, u := range updates {
+			list = append(list, update{u.Namespace + "/" + u.Name, u.Current, u.Locked, u.Latest, u.Outdated()})
+		}
+		out, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal providers to JSON: %w", err)
+		}
+		fmt.Println(string(out))
+	} else if len(updates) == 0 {
+		if checkErr == nil {
+			fmt.Fprintln(os.Stderr, "All providers are up to date.")
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROVIDER\tCURRENT\tLATEST\tSTATUS")
+		for _, u := range updates {
+			status := "up to date"
+			if u.Outdated() {
+				status = "outdated"
+			}
+			if u.Locked {
+				status += " (locked)"
+			}
+			fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\n", u.Namespace, u.Name, u.Current, u.Latest, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return checkErr
+}
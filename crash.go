@@ -0,0 +1,156 @@
+package tfclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stderrTailLines is how many of the last stderr lines of a provider are kept
+// for crash diagnostics.
+const stderrTailLines = 100
+
+// tailBuffer keeps the last lines written to it.
+type tailBuffer struct {
+	mu      sync.Mutex
+	lines   []string
+	partial []byte
+	max     int
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.partial = append(b.partial, p...)
+	for {
+		i := strings.IndexByte(string(b.partial), '\n')
+		if i < 0 {
+			break
+		}
+		b.add(string(b.partial[:i]))
+		b.partial = b.partial[i+1:]
+	}
+	if len(b.partial) >= maxOutputLine {
+		b.add(string(b.partial))
+		b.partial = nil
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) add(line string) {
+	b.lines = append(b.lines, strings.TrimRight(line, "\r"))
+	if len(b.lines) > b.max {
+		b.lines = b.lines[len(b.lines)-b.max:]
+	}
+}
+
+// Lines returns the kept lines, including an unterminated last line.
+func (b *tailBuffer) Lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := append([]string(nil), b.lines...)
+	if len(b.partial) > 0 {
+		lines = append(lines, string(b.partial))
+	}
+	return lines
+}
+
+// recordFailedRPCs is a gRPC interceptor remembering the calls to a provider
+// process that failed because the connection broke, for crash diagnostics.
+func (c *subprocessConn) recordFailedRPCs(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if status.Code(err) == codes.Unavailable {
+		c.mu.Lock()
+		if !slices.Contains(c.failedRPCs, method) {
+			c.failedRPCs = append(c.failedRPCs, method)
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// crashed turns the error of a call that failed because inst's process died
+// into an ErrProviderCrashed, written to a bundle with WithCrashBundleDir.
+// Errors of processes other than local ones, stopped by the client or killed
+// for exceeding their limits are returned as is.
+func (p *provider) crashed(inst *pluginInstance, err error) error {
+	conn, ok := inst.conn.(*subprocessConn)
+	if !ok || p.closed() {
+		return err
+	}
+
+	// The call may fail before the process is reaped
+	deadline := time.Now().Add(time.Second)
+	for !conn.Exited() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if conn.KilledBy() != "" {
+		return err
+	}
+
+	crash := &ErrProviderCrashed{
+		Namespace: p.namespace,
+		Name:      p.name,
+		Version:   p.version,
+		PID:       conn.pid,
+		ExitCode:  -1,
+		Time:      time.Now(),
+		Err:       err,
+	}
+	if conn.Exited() && conn.cmd.ProcessState != nil {
+		crash.ExitStatus = conn.cmd.ProcessState.String()
+		crash.ExitCode = conn.cmd.ProcessState.ExitCode()
+	}
+	conn.mu.Lock()
+	crash.RPCs = append([]string(nil), conn.failedRPCs...)
+	conn.mu.Unlock()
+	if conn.stderr != nil {
+		crash.Stderr = conn.stderr.Lines()
+	}
+
+	if p.crashDir != "" {
+		path, werr := writeCrashBundle(p.crashDir, crash)
+		if werr != nil {
+			p.logger.Error(werr, "failed to write crash bundle", "provider", p.Config().String())
+		}
+		crash.BundlePath = path
+	}
+	p.logger.Error(crash, "provider crashed", "provider", p.Config().String(), "pid", crash.PID)
+	return crash
+}
+
+// writeCrashBundle writes a crash as JSON in dir, returning the file path.
+func writeCrashBundle(dir string, crash *ErrProviderCrashed) (string, error) {
+	bundle := struct {
+		*ErrProviderCrashed
+		Error string `json:"error"`
+	}{crash, crash.Err.Error()}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("crash-%s-%s-%s-%d-%s.json", crash.Namespace, crash.Name, crash.Version, crash.PID, crash.Time.UTC().Format("20060102T150405Z"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
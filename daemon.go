@@ -0,0 +1,276 @@
+package tfclient
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/infracollect/tf-data-client/daemonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DaemonServer serves the DataClient gRPC service of daemonpb, published in
+// daemonpb/daemon.proto, with the wrapped Client, so that services in any
+// language can read data sources from a long-running tf-data-client, e.g. a
+// sidecar. Register UnaryAuthInterceptor and StreamAuthInterceptor on the
+// gRPC server to authenticate callers.
+//
+// Providers are identified by namespace/name@version and, as with
+// AgentServer, shared by every caller creating the same one, including their
+// configuration. ReadDataSource replaces the values of the attributes the
+// schema marks as sensitive with "(sensitive)", unless RevealSensitive is set.
+type DaemonServer struct {
+	daemonpb.UnimplementedDataClientServer
+	client *Client
+
+	// RevealSensitive returns sensitive attributes in ReadDataSource
+	// responses instead of masking them.
+	RevealSensitive bool
+}
+
+// NewDaemonServer returns a DaemonServer running providers with client.
+func NewDaemonServer(client *Client) *DaemonServer {
+	return &DaemonServer{client: client}
+}
+
+// Register registers the DataClient and health services on s.
+func (d *DaemonServer) Register(s *grpc.Server) {
+	daemonpb.RegisterDataClientServer(s, d)
+	grpc_health_v1.RegisterHealthServer(s, &agentHealthServer{agent: &AgentServer{client: d.client}})
+}
+
+func (d *DaemonServer) CreateProvider(ctx context.Context, req *daemonpb.CreateProviderRequest) (*daemonpb.ProviderInfo, error) {
+	if req.Namespace == "" || req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "namespace and name are required")
+	}
+	p, err := d.client.CreateProvider(ctx, ProviderConfig{Namespace: req.Namespace, Name: req.Name, Version: req.Version})
+	if err != nil {
+		return nil, daemonStatus(err, codes.FailedPrecondition)
+	}
+	return providerInfo(p), nil
+}
+
+func (d *DaemonServer) Configure(ctx context.Context, req *daemonpb.ConfigureRequest) (*daemonpb.ConfigureResponse, error) {
+	p, err := d.provider(req.ProviderId)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Configure(ctx, req.Config.AsMap()); err != nil {
+		return nil, daemonStatus(err, codes.FailedPrecondition)
+	}
+	return &daemonpb.ConfigureResponse{}, nil
+}
+
+func (d *DaemonServer) ListDataSources(ctx context.Context, req *daemonpb.ListDataSourcesRequest) (*daemonpb.ListDataSourcesResponse, error) {
+	p, err := d.provider(req.ProviderId)
+	if err != nil {
+		return nil, err
+	}
+	dataSources := p.ListDataSources()
+	sort.Strings(dataSources)
+	return &daemonpb.ListDataSourcesResponse{DataSources: dataSources}, nil
+}
+
+func (d *DaemonServer) ReadDataSource(req *daemonpb.ReadDataSourceRequest, stream grpc.ServerStreamingServer[daemonpb.ReadDataSourceResponse]) error {
+	p, err := d.provider(req.ProviderId)
+	if err != nil {
+		return err
+	}
+	if req.DataSource == "" {
+		return status.Error(codes.InvalidArgument, "data_source is required")
+	}
+	if !p.IsConfigured() {
+		return status.Errorf(codes.FailedPrecondition, "provider %s is not configured", req.ProviderId)
+	}
+	result, err := p.ReadDataSource(stream.Context(), req.DataSource, req.Config.AsMap())
+	if err != nil {
+		return daemonStatus(err, codes.Unknown)
+	}
+	if d.client.resultCache != nil {
+		cacheStatus := "MISS"
+		if result.Cached {
+			cacheStatus = "HIT"
+		}
+		stream.SetHeader(metadata.Pairs("x-cache", cacheStatus))
+	}
+	if !d.RevealSensitive {
+		schema, err := p.(*provider).currentSchema(stream.Context())
+		if err != nil {
+			return daemonStatus(err, codes.Unknown)
+		}
+		result.State = redactBlock(schema.GetDataSourceSchemas()[req.DataSource].GetBlock(), result.State)
+	}
+
+	if req.StreamAttribute == "" {
+		state, err := structpb.NewStruct(result.State)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to encode state: %v", err)
+		}
+		return stream.Send(&daemonpb.ReadDataSourceResponse{Result: &daemonpb.ReadDataSourceResponse_State{State: state}})
+	}
+
+	value, ok := result.State[req.StreamAttribute]
+	if !ok {
+		return status.Errorf(codes.InvalidArgument, "attribute %q not found in the state of %s", req.StreamAttribute, req.DataSource)
+	}
+	elems, ok := value.([]any)
+	if value != nil && !ok {
+		return status.Errorf(codes.InvalidArgument, "attribute %q is not a list or set", req.StreamAttribute)
+	}
+	for _, elem := range elems {
+		v, err := structpb.NewValue(elem)
+		if err != nil {
+			return status.Errorf(codes.Internal, "failed to encode element: %v", err)
+		}
+		if err := stream.Send(&daemonpb.ReadDataSourceResponse{Result: &daemonpb.ReadDataSourceResponse_Element{Element: v}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DaemonServer) ListProviders(ctx context.Context, req *daemonpb.ListProvidersRequest) (*daemonpb.ListProvidersResponse, error) {
+	d.client.mu.Lock()
+	providers := make([]*daemonpb.ProviderInfo, 0, len(d.client.providers))
+	for _, p := range d.client.providers {
+		providers = append(providers, providerInfo(p))
+	}
+	d.client.mu.Unlock()
+	slices.SortFunc(providers, func(a, b *daemonpb.ProviderInfo) int { return strings.Compare(a.Id, b.Id) })
+	return &daemonpb.ListProvidersResponse{Providers: providers}, nil
+}
+
+func (d *DaemonServer) StopProvider(ctx context.Context, req *daemonpb.StopProviderRequest) (*daemonpb.StopProviderResponse, error) {
+	p, err := d.provider(req.ProviderId)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.client.StopProvider(ctx, p.Config()); err != nil {
+		return nil, daemonStatus(err, codes.Internal)
+	}
+	return &daemonpb.StopProviderResponse{}, nil
+}
+
+// provider returns the running provider with an ID of providerInfo.
+func (d *DaemonServer) provider(id string) (Provider, error) {
+	d.client.mu.Lock()
+	defer d.client.mu.Unlock()
+	p, ok := d.client.providers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "provider %q is not running", id)
+	}
+	return p, nil
+}
+
+func providerInfo(p Provider) *daemonpb.ProviderInfo {
+	cfg := p.Config()
+	return &daemonpb.ProviderInfo{
+		Id:         cfg.String(),
+		Namespace:  cfg.Namespace,
+		Name:       cfg.Name,
+		Version:    cfg.Version,
+		Configured: p.IsConfigured(),
+	}
+}
+
+// daemonStatus converts an error of the Client to a gRPC status, with code
+// for errors of no more specific class.
+func daemonStatus(err error, code codes.Code) error {
+	var (
+		providerNotFound *ErrProviderNotFound
+		versionNotFound  *ErrVersionNotFound
+		dataNotFound     *ErrDataSourceNotFound
+		cacheMiss        *ErrOfflineCacheMiss
+		denied           *ErrProviderDenied
+		invalid          *ErrInvalidConfig
+		downloadFailed   *ErrDownloadFailed
+	)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	case errors.As(err, &providerNotFound), errors.As(err, &versionNotFound),
+		errors.As(err, &dataNotFound), errors.As(err, &cacheMiss):
+		code = codes.NotFound
+	case errors.As(err, &denied):
+		code = codes.PermissionDenied
+	case errors.As(err, &invalid):
+		code = codes.InvalidArgument
+	case errors.As(err, &downloadFailed):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
+
+// AuthFunc authenticates a call to a gRPC method, such as
+// "/tfdataclient.daemon.v1.DataClient/ReadDataSource", returning the context
+// to handle it with, or an error to reject it. Errors that aren't a gRPC
+// status are returned as codes.Unauthenticated.
+type AuthFunc func(ctx context.Context, fullMethod string) (context.Context, error)
+
+// BearerTokenAuth returns an AuthFunc accepting the calls with the metadata
+// "authorization: Bearer <token>".
+func BearerTokenAuth(token string) AuthFunc {
+	want := "Bearer " + token
+	return func(ctx context.Context, fullMethod string) (context.Context, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("authorization"); len(values) == 1 && subtle.ConstantTimeCompare([]byte(values[0]), []byte(want)) == 1 {
+			return ctx, nil
+		}
+		return nil, errors.New("missing or invalid bearer token")
+	}
+}
+
+// UnaryAuthInterceptor returns a server interceptor authenticating unary
+// calls with auth, e.g. for grpc.ChainUnaryInterceptor.
+func UnaryAuthInterceptor(auth AuthFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, auth, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor returns a server interceptor authenticating
+// streaming calls with auth, e.g. for grpc.ChainStreamInterceptor.
+func StreamAuthInterceptor(auth AuthFunc) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), auth, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticate(ctx context.Context, auth AuthFunc, fullMethod string) (context.Context, error) {
+	newCtx, err := auth(ctx, fullMethod)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if newCtx == nil {
+		newCtx = ctx
+	}
+	return newCtx, nil
+}
+
+// authenticatedStream is a server stream with the context an AuthFunc
+// returned.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,789 @@
+// Daemon API of tf-data-client: create Terraform providers, configure them
+// and read their data sources, as served by tfclient.DaemonServer and
+// "tf-data-client serve --grpc-listen".
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: daemonpb/daemon.proto
+
+package daemonpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CreateProviderRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"` // e.g. hashicorp
+	Name      string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`           // e.g. aws
+	// Exact version or constraint such as "~> 5.0"; the latest if empty.
+	Version       string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateProviderRequest) Reset() {
+	*x = CreateProviderRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateProviderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateProviderRequest) ProtoMessage() {}
+
+func (x *CreateProviderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateProviderRequest.ProtoReflect.Descriptor instead.
+func (*CreateProviderRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CreateProviderRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *CreateProviderRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateProviderRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type ProviderInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the provider in other calls: namespace/name@version.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Namespace     string `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name          string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Version       string `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"` // resolved version
+	Configured    bool   `protobuf:"varint,5,opt,name=configured,proto3" json:"configured,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProviderInfo) Reset() {
+	*x = ProviderInfo{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderInfo) ProtoMessage() {}
+
+func (x *ProviderInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderInfo.ProtoReflect.Descriptor instead.
+func (*ProviderInfo) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ProviderInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetConfigured() bool {
+	if x != nil {
+		return x.Configured
+	}
+	return false
+}
+
+type ConfigureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProviderId    string                 `protobuf:"bytes,1,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	Config        *structpb.Struct       `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureRequest) Reset() {
+	*x = ConfigureRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureRequest) ProtoMessage() {}
+
+func (x *ConfigureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureRequest.ProtoReflect.Descriptor instead.
+func (*ConfigureRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ConfigureRequest) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+func (x *ConfigureRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type ConfigureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureResponse) Reset() {
+	*x = ConfigureResponse{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureResponse) ProtoMessage() {}
+
+func (x *ConfigureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureResponse.ProtoReflect.Descriptor instead.
+func (*ConfigureResponse) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{3}
+}
+
+type ListDataSourcesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProviderId    string                 `protobuf:"bytes,1,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDataSourcesRequest) Reset() {
+	*x = ListDataSourcesRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDataSourcesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDataSourcesRequest) ProtoMessage() {}
+
+func (x *ListDataSourcesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDataSourcesRequest.ProtoReflect.Descriptor instead.
+func (*ListDataSourcesRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListDataSourcesRequest) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+type ListDataSourcesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DataSources   []string               `protobuf:"bytes,1,rep,name=data_sources,json=dataSources,proto3" json:"data_sources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDataSourcesResponse) Reset() {
+	*x = ListDataSourcesResponse{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDataSourcesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDataSourcesResponse) ProtoMessage() {}
+
+func (x *ListDataSourcesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDataSourcesResponse.ProtoReflect.Descriptor instead.
+func (*ListDataSourcesResponse) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListDataSourcesResponse) GetDataSources() []string {
+	if x != nil {
+		return x.DataSources
+	}
+	return nil
+}
+
+type ReadDataSourceRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	ProviderId string                 `protobuf:"bytes,1,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	DataSource string                 `protobuf:"bytes,2,opt,name=data_source,json=dataSource,proto3" json:"data_source,omitempty"`
+	Config     *structpb.Struct       `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	// List attribute of the state whose elements are streamed one per message
+	// instead of the whole state.
+	StreamAttribute string `protobuf:"bytes,4,opt,name=stream_attribute,json=streamAttribute,proto3" json:"stream_attribute,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReadDataSourceRequest) Reset() {
+	*x = ReadDataSourceRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadDataSourceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadDataSourceRequest) ProtoMessage() {}
+
+func (x *ReadDataSourceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadDataSourceRequest.ProtoReflect.Descriptor instead.
+func (*ReadDataSourceRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ReadDataSourceRequest) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+func (x *ReadDataSourceRequest) GetDataSource() string {
+	if x != nil {
+		return x.DataSource
+	}
+	return ""
+}
+
+func (x *ReadDataSourceRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *ReadDataSourceRequest) GetStreamAttribute() string {
+	if x != nil {
+		return x.StreamAttribute
+	}
+	return ""
+}
+
+type ReadDataSourceResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*ReadDataSourceResponse_State
+	//	*ReadDataSourceResponse_Element
+	Result        isReadDataSourceResponse_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadDataSourceResponse) Reset() {
+	*x = ReadDataSourceResponse{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadDataSourceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadDataSourceResponse) ProtoMessage() {}
+
+func (x *ReadDataSourceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadDataSourceResponse.ProtoReflect.Descriptor instead.
+func (*ReadDataSourceResponse) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReadDataSourceResponse) GetResult() isReadDataSourceResponse_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *ReadDataSourceResponse) GetState() *structpb.Struct {
+	if x != nil {
+		if x, ok := x.Result.(*ReadDataSourceResponse_State); ok {
+			return x.State
+		}
+	}
+	return nil
+}
+
+func (x *ReadDataSourceResponse) GetElement() *structpb.Value {
+	if x != nil {
+		if x, ok := x.Result.(*ReadDataSourceResponse_Element); ok {
+			return x.Element
+		}
+	}
+	return nil
+}
+
+type isReadDataSourceResponse_Result interface {
+	isReadDataSourceResponse_Result()
+}
+
+type ReadDataSourceResponse_State struct {
+	State *structpb.Struct `protobuf:"bytes,1,opt,name=state,proto3,oneof"`
+}
+
+type ReadDataSourceResponse_Element struct {
+	Element *structpb.Value `protobuf:"bytes,2,opt,name=element,proto3,oneof"` // with stream_attribute
+}
+
+func (*ReadDataSourceResponse_State) isReadDataSourceResponse_Result() {}
+
+func (*ReadDataSourceResponse_Element) isReadDataSourceResponse_Result() {}
+
+type ListProvidersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersRequest) Reset() {
+	*x = ListProvidersRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersRequest) ProtoMessage() {}
+
+func (x *ListProvidersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersRequest.ProtoReflect.Descriptor instead.
+func (*ListProvidersRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{8}
+}
+
+type ListProvidersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Providers     []*ProviderInfo        `protobuf:"bytes,1,rep,name=providers,proto3" json:"providers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersResponse) Reset() {
+	*x = ListProvidersResponse{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersResponse) ProtoMessage() {}
+
+func (x *ListProvidersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersResponse.ProtoReflect.Descriptor instead.
+func (*ListProvidersResponse) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListProvidersResponse) GetProviders() []*ProviderInfo {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+type StopProviderRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProviderId    string                 `protobuf:"bytes,1,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopProviderRequest) Reset() {
+	*x = StopProviderRequest{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopProviderRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopProviderRequest) ProtoMessage() {}
+
+func (x *StopProviderRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopProviderRequest.ProtoReflect.Descriptor instead.
+func (*StopProviderRequest) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StopProviderRequest) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+type StopProviderResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopProviderResponse) Reset() {
+	*x = StopProviderResponse{}
+	mi := &file_daemonpb_daemon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopProviderResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopProviderResponse) ProtoMessage() {}
+
+func (x *StopProviderResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_daemonpb_daemon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopProviderResponse.ProtoReflect.Descriptor instead.
+func (*StopProviderResponse) Descriptor() ([]byte, []int) {
+	return file_daemonpb_daemon_proto_rawDescGZIP(), []int{11}
+}
+
+var File_daemonpb_daemon_proto protoreflect.FileDescriptor
+
+const file_daemonpb_daemon_proto_rawDesc = "" +
+	"\n" +
+	"\x15daemonpb/daemon.proto\x12\x16tfdataclient.daemon.v1\x1a\x1cgoogle/protobuf/struct.proto\"c\n" +
+	"\x15CreateProviderRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\"\x8a\x01\n" +
+	"\fProviderInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\x12\x1e\n" +
+	"\n" +
+	"configured\x18\x05 \x01(\bR\n" +
+	"configured\"d\n" +
+	"\x10ConfigureRequest\x12\x1f\n" +
+	"\vprovider_id\x18\x01 \x01(\tR\n" +
+	"providerId\x12/\n" +
+	"\x06config\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x06config\"\x13\n" +
+	"\x11ConfigureResponse\"9\n" +
+	"\x16ListDataSourcesRequest\x12\x1f\n" +
+	"\vprovider_id\x18\x01 \x01(\tR\n" +
+	"providerId\"<\n" +
+	"\x17ListDataSourcesResponse\x12!\n" +
+	"\fdata_sources\x18\x01 \x03(\tR\vdataSources\"\xb5\x01\n" +
+	"\x15ReadDataSourceRequest\x12\x1f\n" +
+	"\vprovider_id\x18\x01 \x01(\tR\n" +
+	"providerId\x12\x1f\n" +
+	"\vdata_source\x18\x02 \x01(\tR\n" +
+	"dataSource\x12/\n" +
+	"\x06config\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x06config\x12)\n" +
+	"\x10stream_attribute\x18\x04 \x01(\tR\x0fstreamAttribute\"\x87\x01\n" +
+	"\x16ReadDataSourceResponse\x12/\n" +
+	"\x05state\x18\x01 \x01(\v2\x17.google.protobuf.StructH\x00R\x05state\x122\n" +
+	"\aelement\x18\x02 \x01(\v2\x16.google.protobuf.ValueH\x00R\aelementB\b\n" +
+	"\x06result\"\x16\n" +
+	"\x14ListProvidersRequest\"[\n" +
+	"\x15ListProvidersResponse\x12B\n" +
+	"\tproviders\x18\x01 \x03(\v2$.tfdataclient.daemon.v1.ProviderInfoR\tproviders\"6\n" +
+	"\x13StopProviderRequest\x12\x1f\n" +
+	"\vprovider_id\x18\x01 \x01(\tR\n" +
+	"providerId\"\x16\n" +
+	"\x14StopProviderResponse2\x95\x05\n" +
+	"\n" +
+	"DataClient\x12e\n" +
+	"\x0eCreateProvider\x12-.tfdataclient.daemon.v1.CreateProviderRequest\x1a$.tfdataclient.daemon.v1.ProviderInfo\x12`\n" +
+	"\tConfigure\x12(.tfdataclient.daemon.v1.ConfigureRequest\x1a).tfdataclient.daemon.v1.ConfigureResponse\x12r\n" +
+	"\x0fListDataSources\x12..tfdataclient.daemon.v1.ListDataSourcesRequest\x1a/.tfdataclient.daemon.v1.ListDataSourcesResponse\x12q\n" +
+	"\x0eReadDataSource\x12-.tfdataclient.daemon.v1.ReadDataSourceRequest\x1a..tfdataclient.daemon.v1.ReadDataSourceResponse0\x01\x12l\n" +
+	"\rListProviders\x12,.tfdataclient.daemon.v1.ListProvidersRequest\x1a-.tfdataclient.daemon.v1.ListProvidersResponse\x12i\n" +
+	"\fStopProvider\x12+.tfdataclient.daemon.v1.StopProviderRequest\x1a,.tfdataclient.daemon.v1.StopProviderResponseB1Z/github.com/infracollect/tf-data-client/daemonpbb\x06proto3"
+
+var (
+	file_daemonpb_daemon_proto_rawDescOnce sync.Once
+	file_daemonpb_daemon_proto_rawDescData []byte
+)
+
+func file_daemonpb_daemon_proto_rawDescGZIP() []byte {
+	file_daemonpb_daemon_proto_rawDescOnce.Do(func() {
+		file_daemonpb_daemon_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_daemonpb_daemon_proto_rawDesc), len(file_daemonpb_daemon_proto_rawDesc)))
+	})
+	return file_daemonpb_daemon_proto_rawDescData
+}
+
+var file_daemonpb_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_daemonpb_daemon_proto_goTypes = []any{
+	(*CreateProviderRequest)(nil),   // 0: tfdataclient.daemon.v1.CreateProviderRequest
+	(*ProviderInfo)(nil),            // 1: tfdataclient.daemon.v1.ProviderInfo
+	(*ConfigureRequest)(nil),        // 2: tfdataclient.daemon.v1.ConfigureRequest
+	(*ConfigureResponse)(nil),       // 3: tfdataclient.daemon.v1.ConfigureResponse
+	(*ListDataSourcesRequest)(nil),  // 4: tfdataclient.daemon.v1.ListDataSourcesRequest
+	(*ListDataSourcesResponse)(nil), // 5: tfdataclient.daemon.v1.ListDataSourcesResponse
+	(*ReadDataSourceRequest)(nil),   // 6: tfdataclient.daemon.v1.ReadDataSourceRequest
+	(*ReadDataSourceResponse)(nil),  // 7: tfdataclient.daemon.v1.ReadDataSourceResponse
+	(*ListProvidersRequest)(nil),    // 8: tfdataclient.daemon.v1.ListProvidersRequest
+	(*ListProvidersResponse)(nil),   // 9: tfdataclient.daemon.v1.ListProvidersResponse
+	(*StopProviderRequest)(nil),     // 10: tfdataclient.daemon.v1.StopProviderRequest
+	(*StopProviderResponse)(nil),    // 11: tfdataclient.daemon.v1.StopProviderResponse
+	(*structpb.Struct)(nil),         // 12: google.protobuf.Struct
+	(*structpb.Value)(nil),          // 13: google.protobuf.Value
+}
+var file_daemonpb_daemon_proto_depIdxs = []int32{
+	12, // 0: tfdataclient.daemon.v1.ConfigureRequest.config:type_name -> google.protobuf.Struct
+	12, // 1: tfdataclient.daemon.v1.ReadDataSourceRequest.config:type_name -> google.protobuf.Struct
+	12, // 2: tfdataclient.daemon.v1.ReadDataSourceResponse.state:type_name -> google.protobuf.Struct
+	13, // 3: tfdataclient.daemon.v1.ReadDataSourceResponse.element:type_name -> google.protobuf.Value
+	1,  // 4: tfdataclient.daemon.v1.ListProvidersResponse.providers:type_name -> tfdataclient.daemon.v1.ProviderInfo
+	0,  // 5: tfdataclient.daemon.v1.DataClient.CreateProvider:input_type -> tfdataclient.daemon.v1.CreateProviderRequest
+	2,  // 6: tfdataclient.daemon.v1.DataClient.Configure:input_type -> tfdataclient.daemon.v1.ConfigureRequest
+	4,  // 7: tfdataclient.daemon.v1.DataClient.ListDataSources:input_type -> tfdataclient.daemon.v1.ListDataSourcesRequest
+	6,  // 8: tfdataclient.daemon.v1.DataClient.ReadDataSource:input_type -> tfdataclient.daemon.v1.ReadDataSourceRequest
+	8,  // 9: tfdataclient.daemon.v1.DataClient.ListProviders:input_type -> tfdataclient.daemon.v1.ListProvidersRequest
+	10, // 10: tfdataclient.daemon.v1.DataClient.StopProvider:input_type -> tfdataclient.daemon.v1.StopProviderRequest
+	1,  // 11: tfdataclient.daemon.v1.DataClient.CreateProvider:output_type -> tfdataclient.daemon.v1.ProviderInfo
+	3,  // 12: tfdataclient.daemon.v1.DataClient.Configure:output_type -> tfdataclient.daemon.v1.ConfigureResponse
+	5,  // 13: tfdataclient.daemon.v1.DataClient.ListDataSources:output_type -> tfdataclient.daemon.v1.ListDataSourcesResponse
+	7,  // 14: tfdataclient.daemon.v1.DataClient.ReadDataSource:output_type -> tfdataclient.daemon.v1.ReadDataSourceResponse
+	9,  // 15: tfdataclient.daemon.v1.DataClient.ListProviders:output_type -> tfdataclient.daemon.v1.ListProvidersResponse
+	11, // 16: tfdataclient.daemon.v1.DataClient.StopProvider:output_type -> tfdataclient.daemon.v1.StopProviderResponse
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_daemonpb_daemon_proto_init() }
+func file_daemonpb_daemon_proto_init() {
+	if File_daemonpb_daemon_proto != nil {
+		return
+	}
+	file_daemonpb_daemon_proto_msgTypes[7].OneofWrappers = []any{
+		(*ReadDataSourceResponse_State)(nil),
+		(*ReadDataSourceResponse_Element)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_daemonpb_daemon_proto_rawDesc), len(file_daemonpb_daemon_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   12,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_daemonpb_daemon_proto_goTypes,
+		DependencyIndexes: file_daemonpb_daemon_proto_depIdxs,
+		MessageInfos:      file_daemonpb_daemon_proto_msgTypes,
+	}.Build()
+	File_daemonpb_daemon_proto = out.File
+	file_daemonpb_daemon_proto_goTypes = nil
+	file_daemonpb_daemon_proto_depIdxs = nil
+}
@@ -0,0 +1,339 @@
+// Daemon API of tf-data-client: create Terraform providers, configure them
+// and read their data sources, as served by tfclient.DaemonServer and
+// "tf-data-client serve --grpc-listen".
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: daemonpb/daemon.proto
+
+package daemonpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	DataClient_CreateProvider_FullMethodName  = "/tfdataclient.daemon.v1.DataClient/CreateProvider"
+	DataClient_Configure_FullMethodName       = "/tfdataclient.daemon.v1.DataClient/Configure"
+	DataClient_ListDataSources_FullMethodName = "/tfdataclient.daemon.v1.DataClient/ListDataSources"
+	DataClient_ReadDataSource_FullMethodName  = "/tfdataclient.daemon.v1.DataClient/ReadDataSource"
+	DataClient_ListProviders_FullMethodName   = "/tfdataclient.daemon.v1.DataClient/ListProviders"
+	DataClient_StopProvider_FullMethodName    = "/tfdataclient.daemon.v1.DataClient/StopProvider"
+)
+
+// DataClientClient is the client API for DataClient service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DataClientClient interface {
+	// CreateProvider downloads, if needed, and starts a provider, returning it
+	// with its resolved version. Providers are shared by every caller creating
+	// the same namespace/name@version.
+	CreateProvider(ctx context.Context, in *CreateProviderRequest, opts ...grpc.CallOption) (*ProviderInfo, error)
+	// Configure configures a provider created with CreateProvider.
+	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*ConfigureResponse, error)
+	// ListDataSources returns the data sources of a provider, sorted.
+	ListDataSources(ctx context.Context, in *ListDataSourcesRequest, opts ...grpc.CallOption) (*ListDataSourcesResponse, error)
+	// ReadDataSource reads a data source of a configured provider. The state
+	// is sent in a single message, or, with stream_attribute, the elements of
+	// one of its list attributes in a message each.
+	ReadDataSource(ctx context.Context, in *ReadDataSourceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadDataSourceResponse], error)
+	// ListProviders returns the running providers.
+	ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error)
+	// StopProvider stops a provider.
+	StopProvider(ctx context.Context, in *StopProviderRequest, opts ...grpc.CallOption) (*StopProviderResponse, error)
+}
+
+type dataClientClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDataClientClient(cc grpc.ClientConnInterface) DataClientClient {
+	return &dataClientClient{cc}
+}
+
+func (c *dataClientClient) CreateProvider(ctx context.Context, in *CreateProviderRequest, opts ...grpc.CallOption) (*ProviderInfo, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProviderInfo)
+	err := c.cc.Invoke(ctx, DataClient_CreateProvider_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClientClient) Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*ConfigureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConfigureResponse)
+	err := c.cc.Invoke(ctx, DataClient_Configure_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClientClient) ListDataSources(ctx context.Context, in *ListDataSourcesRequest, opts ...grpc.CallOption) (*ListDataSourcesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDataSourcesResponse)
+	err := c.cc.Invoke(ctx, DataClient_ListDataSources_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClientClient) ReadDataSource(ctx context.Context, in *ReadDataSourceRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ReadDataSourceResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DataClient_ServiceDesc.Streams[0], DataClient_ReadDataSource_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReadDataSourceRequest, ReadDataSourceResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataClient_ReadDataSourceClient = grpc.ServerStreamingClient[ReadDataSourceResponse]
+
+func (c *dataClientClient) ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProvidersResponse)
+	err := c.cc.Invoke(ctx, DataClient_ListProviders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *dataClientClient) StopProvider(ctx context.Context, in *StopProviderRequest, opts ...grpc.CallOption) (*StopProviderResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopProviderResponse)
+	err := c.cc.Invoke(ctx, DataClient_StopProvider_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DataClientServer is the server API for DataClient service.
+// All implementations must embed UnimplementedDataClientServer
+// for forward compatibility.
+type DataClientServer interface {
+	// CreateProvider downloads, if needed, and starts a provider, returning it
+	// with its resolved version. Providers are shared by every caller creating
+	// the same namespace/name@version.
+	CreateProvider(context.Context, *CreateProviderRequest) (*ProviderInfo, error)
+	// Configure configures a provider created with CreateProvider.
+	Configure(context.Context, *ConfigureRequest) (*ConfigureResponse, error)
+	// ListDataSources returns the data sources of a provider, sorted.
+	ListDataSources(context.Context, *ListDataSourcesRequest) (*ListDataSourcesResponse, error)
+	// ReadDataSource reads a data source of a configured provider. The state
+	// is sent in a single message, or, with stream_attribute, the elements of
+	// one of its list attributes in a message each.
+	ReadDataSource(*ReadDataSourceRequest, grpc.ServerStreamingServer[ReadDataSourceResponse]) error
+	// ListProviders returns the running providers.
+	ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error)
+	// StopProvider stops a provider.
+	StopProvider(context.Context, *StopProviderRequest) (*StopProviderResponse, error)
+	mustEmbedUnimplementedDataClientServer()
+}
+
+// UnimplementedDataClientServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDataClientServer struct{}
+
+func (UnimplementedDataClientServer) CreateProvider(context.Context, *CreateProviderRequest) (*ProviderInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateProvider not implemented")
+}
+func (UnimplementedDataClientServer) Configure(context.Context, *ConfigureRequest) (*ConfigureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Configure not implemented")
+}
+func (UnimplementedDataClientServer) ListDataSources(context.Context, *ListDataSourcesRequest) (*ListDataSourcesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDataSources not implemented")
+}
+func (UnimplementedDataClientServer) ReadDataSource(*ReadDataSourceRequest, grpc.ServerStreamingServer[ReadDataSourceResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ReadDataSource not implemented")
+}
+func (UnimplementedDataClientServer) ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProviders not implemented")
+}
+func (UnimplementedDataClientServer) StopProvider(context.Context, *StopProviderRequest) (*StopProviderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopProvider not implemented")
+}
+func (UnimplementedDataClientServer) mustEmbedUnimplementedDataClientServer() {}
+func (UnimplementedDataClientServer) testEmbeddedByValue()                    {}
+
+// UnsafeDataClientServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DataClientServer will
+// result in compilation errors.
+type UnsafeDataClientServer interface {
+	mustEmbedUnimplementedDataClientServer()
+}
+
+func RegisterDataClientServer(s grpc.ServiceRegistrar, srv DataClientServer) {
+	// If the following call pancis, it indicates UnimplementedDataClientServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&DataClient_ServiceDesc, srv)
+}
+
+func _DataClient_CreateProvider_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateProviderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataClientServer).CreateProvider(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataClient_CreateProvider_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataClientServer).CreateProvider(ctx, req.(*CreateProviderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataClient_Configure_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataClientServer).Configure(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataClient_Configure_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataClientServer).Configure(ctx, req.(*ConfigureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataClient_ListDataSources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDataSourcesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataClientServer).ListDataSources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataClient_ListDataSources_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataClientServer).ListDataSources(ctx, req.(*ListDataSourcesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataClient_ReadDataSource_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReadDataSourceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DataClientServer).ReadDataSource(m, &grpc.GenericServerStream[ReadDataSourceRequest, ReadDataSourceResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DataClient_ReadDataSourceServer = grpc.ServerStreamingServer[ReadDataSourceResponse]
+
+func _DataClient_ListProviders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProvidersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataClientServer).ListProviders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataClient_ListProviders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataClientServer).ListProviders(ctx, req.(*ListProvidersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DataClient_StopProvider_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopProviderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DataClientServer).StopProvider(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DataClient_StopProvider_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DataClientServer).StopProvider(ctx, req.(*StopProviderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// DataClient_ServiceDesc is the grpc.ServiceDesc for DataClient service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DataClient_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "tfdataclient.daemon.v1.DataClient",
+	HandlerType: (*DataClientServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateProvider",
+			Handler:    _DataClient_CreateProvider_Handler,
+		},
+		{
+			MethodName: "Configure",
+			Handler:    _DataClient_Configure_Handler,
+		},
+		{
+			MethodName: "ListDataSources",
+			Handler:    _DataClient_ListDataSources_Handler,
+		},
+		{
+			MethodName: "ListProviders",
+			Handler:    _DataClient_ListProviders_Handler,
+		},
+		{
+			MethodName: "StopProvider",
+			Handler:    _DataClient_StopProvider_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ReadDataSource",
+			Handler:       _DataClient_ReadDataSource_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "daemonpb/daemon.proto",
+}
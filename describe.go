@@ -0,0 +1,133 @@
+package tfclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Schema describes the attributes and nested blocks of a provider's or a data
+// source's configuration, as reported by the provider.
+type Schema struct {
+	Description string            `json:"description,omitempty"`
+	Deprecated  bool              `json:"deprecated,omitempty"`
+	Attributes  []SchemaAttribute `json:"attributes,omitempty"` // sorted by name
+	Blocks      []SchemaBlock     `json:"blocks,omitempty"`     // sorted by name
+}
+
+// SchemaAttribute describes an attribute of a Schema.
+type SchemaAttribute struct {
+	Name string `json:"name"`
+	// Type is the type constraint of the attribute in Terraform syntax, e.g.
+	// "string" or "list(object({name=string}))".
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Computed    bool   `json:"computed,omitempty"`
+	Sensitive   bool   `json:"sensitive,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+
+	// Nesting and Attributes describe an attribute of nested attributes:
+	// Nesting is "single", "list", "set" or "map", and Attributes are the
+	// attributes of each element.
+	Nesting    string            `json:"nesting,omitempty"`
+	Attributes []SchemaAttribute `json:"attributes,omitempty"`
+}
+
+// SchemaBlock describes a nested block of a Schema.
+type SchemaBlock struct {
+	Name string `json:"name"`
+	// Nesting is "single", "group", "list", "set" or "map".
+	Nesting  string `json:"nesting"`
+	MinItems int    `json:"min_items,omitempty"`
+	MaxItems int    `json:"max_items,omitempty"`
+	Schema
+}
+
+// ReadOnly reports whether the attribute is set by the provider only, and
+// can't be configured.
+func (a SchemaAttribute) ReadOnly() bool {
+	return a.Computed && !a.Required && !a.Optional
+}
+
+// schemaFromBlock converts a proto schema block to a Schema.
+func schemaFromBlock(block *tfplugin6.Schema_Block) (*Schema, error) {
+	schema := &Schema{}
+	if block == nil {
+		return schema, nil
+	}
+	schema.Description = block.Description
+	schema.Deprecated = block.Deprecated
+
+	attrs, err := schemaAttributes(block.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	schema.Attributes = attrs
+
+	for _, blockType := range block.BlockTypes {
+		nested, err := schemaFromBlock(blockType.Block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe nested block %s: %w", blockType.TypeName, err)
+		}
+		schema.Blocks = append(schema.Blocks, SchemaBlock{
+			Name:     blockType.TypeName,
+			Nesting:  strings.ToLower(blockType.Nesting.String()),
+			MinItems: int(blockType.MinItems),
+			MaxItems: int(blockType.MaxItems),
+			Schema:   *nested,
+		})
+	}
+	sort.Slice(schema.Blocks, func(i, j int) bool { return schema.Blocks[i].Name < schema.Blocks[j].Name })
+	return schema, nil
+}
+
+// schemaAttributes converts proto schema attributes to SchemaAttributes,
+// sorted by name.
+func schemaAttributes(protoAttrs []*tfplugin6.Schema_Attribute) ([]SchemaAttribute, error) {
+	var attrs []SchemaAttribute
+	for _, attr := range protoAttrs {
+		a := SchemaAttribute{
+			Name:        attr.Name,
+			Description: attr.Description,
+			Required:    attr.Required,
+			Optional:    attr.Optional,
+			Computed:    attr.Computed,
+			Sensitive:   attr.Sensitive,
+			Deprecated:  attr.Deprecated,
+		}
+
+		var attrType cty.Type
+		switch {
+		case attr.NestedType != nil:
+			nestedType, err := nestedObjectToType(attr.NestedType)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert nested type for %s: %w", attr.Name, err)
+			}
+			nested, err := schemaAttributes(attr.NestedType.Attributes)
+			if err != nil {
+				return nil, err
+			}
+			attrType = nestedType
+			a.Nesting = strings.ToLower(attr.NestedType.Nesting.String())
+			a.Attributes = nested
+		case len(attr.Type) > 0:
+			if err := json.Unmarshal(attr.Type, &attrType); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal type for %s: %w", attr.Name, err)
+			}
+		default:
+			attrType = cty.DynamicPseudoType
+		}
+		a.Type = typeexpr.TypeString(attrType)
+
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Name < attrs[j].Name })
+	return attrs, nil
+}
@@ -0,0 +1,121 @@
+// Package diff compares data source states, using their schema to compare
+// sets as sets, whatever the order of their elements, and to elide sensitive
+// values from the changes.
+package diff
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	tfclient "github.com/infracollect/tf-data-client"
+	"github.com/infracollect/tf-data-client/internal/statediff"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Op is the kind of a Change.
+type Op string
+
+const (
+	Added   Op = "added"
+	Removed Op = "removed"
+	Changed Op = "changed"
+)
+
+// Change is a difference between two states.
+type Change struct {
+	// Path locates the value, e.g. "items[0].name". Elements of sets have no
+	// index: their changes have the path of the set, and are additions and
+	// removals.
+	Path string `json:"path"`
+	Op   Op     `json:"op"`
+	// Old and New are the values, with those of the sensitive attributes
+	// they hold replaced by "(sensitive)".
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+	// Sensitive is set when the value is of a sensitive attribute, whose Old
+	// and New are left out.
+	Sensitive bool `json:"sensitive,omitempty"`
+}
+
+// States returns the differences between two states of a data source, ordered
+// by path. schema is that of the data source, as returned by
+// Provider.DataSourceSchema; without it, lists are compared element by
+// element and no value is elided, as by tfclient.DiffStates.
+func States(old, new map[string]any, schema *tfclient.Schema) []Change {
+	var s *statediff.Shape
+	if schema != nil {
+		s = schemaShape(schema)
+	}
+	var changes []Change
+	for _, c := range statediff.Diff(old, new, s) {
+		changes = append(changes, Change{Path: c.Path, Op: Op(c.Op), Old: c.Old, New: c.New, Sensitive: c.Sensitive})
+	}
+	return changes
+}
+
+// schemaShape returns the shape of the objects of a schema.
+func schemaShape(schema *tfclient.Schema) *statediff.Shape {
+	s := &statediff.Shape{Kind: statediff.Object, Attrs: make(map[string]*statediff.Shape)}
+	for _, attr := range schema.Attributes {
+		var a *statediff.Shape
+		if attr.Nesting != "" {
+			a = nestedShape(attr.Nesting, schemaShape(&tfclient.Schema{Attributes: attr.Attributes}))
+		} else {
+			a = typeShape(attr.Type)
+		}
+		if attr.Sensitive {
+			a = &statediff.Shape{Kind: a.Kind, Elem: a.Elem, Attrs: a.Attrs, Sensitive: true}
+		}
+		s.Attrs[attr.Name] = a
+	}
+	for _, block := range schema.Blocks {
+		s.Attrs[block.Name] = nestedShape(block.Nesting, schemaShape(&block.Schema))
+	}
+	return s
+}
+
+// nestedShape returns the shape of nested attributes or blocks with nesting
+// "single", "group", "list", "set" or "map".
+func nestedShape(nesting string, obj *statediff.Shape) *statediff.Shape {
+	switch nesting {
+	case "list":
+		return &statediff.Shape{Kind: statediff.List, Elem: obj}
+	case "set":
+		return &statediff.Shape{Kind: statediff.Set, Elem: obj}
+	case "map":
+		return &statediff.Shape{Kind: statediff.Map, Elem: obj}
+	}
+	return obj
+}
+
+// typeShape returns the shape of a type constraint in Terraform syntax, e.g.
+// "set(object({name=string}))", or a primitive shape if it can't be parsed.
+func typeShape(constraint string) *statediff.Shape {
+	expr, diags := hclsyntax.ParseExpression([]byte(constraint), "type", hcl.InitialPos)
+	if diags.HasErrors() {
+		return &statediff.Shape{}
+	}
+	ty, diags := typeexpr.TypeConstraint(expr)
+	if diags.HasErrors() {
+		return &statediff.Shape{}
+	}
+	return ctyShape(ty)
+}
+
+func ctyShape(ty cty.Type) *statediff.Shape {
+	switch {
+	case ty.IsListType():
+		return &statediff.Shape{Kind: statediff.List, Elem: ctyShape(ty.ElementType())}
+	case ty.IsSetType():
+		return &statediff.Shape{Kind: statediff.Set, Elem: ctyShape(ty.ElementType())}
+	case ty.IsMapType():
+		return &statediff.Shape{Kind: statediff.Map, Elem: ctyShape(ty.ElementType())}
+	case ty.IsObjectType():
+		s := &statediff.Shape{Kind: statediff.Object, Attrs: make(map[string]*statediff.Shape)}
+		for name, attr := range ty.AttributeTypes() {
+			s.Attrs[name] = ctyShape(attr)
+		}
+		return s
+	}
+	return &statediff.Shape{}
+}
@@ -0,0 +1,74 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+func credsSchema(nesting string) *tfclient.Schema {
+	return &tfclient.Schema{Blocks: []tfclient.SchemaBlock{{
+		Name:    "creds",
+		Nesting: nesting,
+		Schema: tfclient.Schema{Attributes: []tfclient.SchemaAttribute{
+			{Name: "user", Type: "string"},
+			{Name: "password", Type: "string", Sensitive: true},
+			{Name: "tokens", Type: "map(string)", Sensitive: true},
+		}},
+	}}}
+}
+
+func TestStatesRedactsNestedSetBlocks(t *testing.T) {
+	old := map[string]any{"creds": []any{map[string]any{"user": "a", "password": "old-secret"}}}
+	new := map[string]any{"creds": []any{map[string]any{"user": "a", "password": "new-secret"}}}
+
+	got := States(old, new, credsSchema("set"))
+	want := []Change{
+		{Path: "creds", Op: Added, New: map[string]any{"user": "a", "password": "(sensitive)"}},
+		{Path: "creds", Op: Removed, Old: map[string]any{"user": "a", "password": "(sensitive)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("States() = %#v, want %#v", got, want)
+	}
+}
+
+func TestStatesRedactsNestedListBlocks(t *testing.T) {
+	old := map[string]any{"creds": []any{
+		map[string]any{"user": "a", "password": "secret-a"},
+	}}
+	new := map[string]any{"creds": []any{
+		map[string]any{"user": "a", "password": "secret-a"},
+		map[string]any{"user": "b", "password": "secret-b", "tokens": map[string]any{"x": "secret-x"}},
+	}}
+
+	got := States(old, new, credsSchema("list"))
+	want := []Change{
+		{Path: "creds[1]", Op: Added, New: map[string]any{"user": "b", "password": "(sensitive)", "tokens": "(sensitive)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("States() = %#v, want %#v", got, want)
+	}
+
+	// Removing the block elides it the same way
+	got = States(new, old, credsSchema("list"))
+	want = []Change{
+		{Path: "creds[1]", Op: Removed, Old: map[string]any{"user": "b", "password": "(sensitive)", "tokens": "(sensitive)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("States() = %#v, want %#v", got, want)
+	}
+}
+
+func TestStatesRedactsAddedBlocks(t *testing.T) {
+	old := map[string]any{}
+	new := map[string]any{"creds": map[string]any{"user": "a", "password": "secret"}}
+
+	got := States(old, new, credsSchema("single"))
+	want := []Change{
+		{Path: "creds", Op: Added, New: map[string]any{"user": "a", "password": "(sensitive)"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("States() = %#v, want %#v", got, want)
+	}
+}
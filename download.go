@@ -0,0 +1,137 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/infracollect/tf-data-client/registry"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// ProviderPackage is a provider release archive downloaded by
+// DownloadProviderFor.
+type ProviderPackage struct {
+	Provider ProviderConfig // with the resolved version
+	OS       string
+	Arch     string
+	Filename string // name of the archive in the registry, e.g. terraform-provider-aws_5.0.0_darwin_arm64.zip
+	Path     string // the downloaded archive, which the caller removes
+	SHA256   string // of the archive
+}
+
+// DownloadProviderFor downloads the release archive of a provider built for
+// another platform, e.g. "darwin" and "arm64", so that a build pipeline can
+// bundle providers for the machines it ships to. The version is resolved and
+// the provider policy and lock file apply as in CreateProvider, and the
+// archive is verified against the registry's checksum. It doesn't touch the
+// cache, which only holds providers for the current platform; install the
+// archive on the target machine with InstallProviderFromArchive.
+func (c *Client) DownloadProviderFor(ctx context.Context, cfg ProviderConfig, goos, goarch string) (_ *ProviderPackage, err error) {
+	attrs := append(providerAttrs(cfg.Namespace, cfg.Name, cfg.Version), attribute.String("provider.platform", goos+"_"+goarch))
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.DownloadProviderFor", attrs...)
+	defer func() { endSpan(span, err) }()
+
+	if goos == "" || goarch == "" {
+		return nil, fmt.Errorf("an operating system and architecture are required to download %s", cfg)
+	}
+	key := cfg.Namespace + "/" + cfg.Name
+	if _, ok := c.devOverrides[key]; ok {
+		return nil, fmt.Errorf("cannot download %s: it is a development override", key)
+	}
+	if _, ok := c.inProcess[key]; ok {
+		return nil, fmt.Errorf("cannot download %s: it is an in-process provider", key)
+	}
+	if c.replayDir != "" {
+		return nil, fmt.Errorf("cannot download %s: providers are replayed", key)
+	}
+
+	rule, err := c.policy.rule(cfg.Namespace, cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+	version, locked, err := c.resolveRequest(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	resolved := ProviderConfig{Namespace: cfg.Namespace, Name: cfg.Name, Version: version}
+	if err := c.checkPolicyRule(ctx, rule, resolved); err != nil {
+		return nil, err
+	}
+
+	pkg, err := c.downloadPackage(ctx, resolved, goos, goarch)
+	if err != nil {
+		return nil, &ErrDownloadFailed{Namespace: cfg.Namespace, Name: cfg.Name, Version: version, Err: err}
+	}
+	if locked != nil {
+		if err := verifyLockedArchive(resolved, locked, pkg.Path, pkg.SHA256); err != nil {
+			os.Remove(pkg.Path)
+			return nil, err
+		}
+	}
+
+	c.logger.V(1).Info("downloaded provider", "provider", resolved.String(), "platform", goos+"_"+goarch, "path", pkg.Path)
+	return pkg, nil
+}
+
+// downloadPackage downloads the archive of a provider version for a platform
+// to a temporary file.
+func (c *Client) downloadPackage(ctx context.Context, cfg ProviderConfig, goos, goarch string) (*ProviderPackage, error) {
+	info, err := c.downloadInfoFor(ctx, cfg.Namespace, cfg.Name, cfg.Version, goos, goarch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get download info: %w", err)
+	}
+	if info.SHA256Sum == "" {
+		return nil, fmt.Errorf("registry published no SHA-256 checksum for %s/%s %s, refusing to download it", cfg.Namespace, cfg.Name, cfg.Version)
+	}
+
+	tmpFile, err := os.CreateTemp("", fmt.Sprintf("terraform-provider-%s_%s_%s_%s-*.zip", cfg.Name, cfg.Version, goos, goarch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpFile.Close()
+
+	if c.downloadProgress != nil {
+		ctx = registry.ContextWithProgress(ctx, func(downloaded, total int64) {
+			c.downloadProgress(DownloadProgress{Provider: cfg, Downloaded: downloaded, Total: total})
+		})
+	}
+	if err := c.downloadEmitting(ctx, cfg, info, tmpFile.Name()); err != nil {
+		os.Remove(tmpFile.Name())
+		return nil, fmt.Errorf("failed to download provider: %w", err)
+	}
+
+	filename := info.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("terraform-provider-%s_%s_%s_%s.zip", cfg.Name, cfg.Version, goos, goarch)
+	}
+	return &ProviderPackage{
+		Provider: cfg,
+		OS:       goos,
+		Arch:     goarch,
+		Filename: filename,
+		Path:     tmpFile.Name(),
+		SHA256:   strings.ToLower(info.SHA256Sum),
+	}, nil
+}
+
+// verifyLockedArchive checks a provider archive against the checksums of its
+// lock file entry, as verifyLocked does for an unpacked package.
+func verifyLockedArchive(cfg ProviderConfig, locked *lockedProvider, archivePath, archiveSum string) error {
+	if slices.Contains(locked.hashes, "zh:"+strings.ToLower(archiveSum)) {
+		return nil
+	}
+	if h1, err := dirhash.HashZip(archivePath, dirhash.Hash1); err == nil && slices.Contains(locked.hashes, h1) {
+		return nil
+	}
+	return &ErrLockMismatch{
+		Namespace: cfg.Namespace,
+		Name:      cfg.Name,
+		Version:   locked.version,
+		Locked:    locked.version,
+		Reason:    "package does not match any of the checksums in the lock file",
+	}
+}
@@ -0,0 +1,29 @@
+package tfclient
+
+import (
+	"maps"
+	"os"
+	"slices"
+)
+
+// providerEnv builds the environment of a provider process: the host
+// environment (unless WithCleanEnv was used), then the client-wide variables
+// from WithProviderEnv, then the provider's own ProviderConfig.Env. Later
+// entries win.
+func (c *Client) providerEnv(cfg ProviderConfig) []string {
+	var env []string
+	if !c.cleanEnv {
+		env = os.Environ()
+	}
+	env = appendEnv(env, c.env)
+	env = appendEnv(env, cfg.Env)
+	return env
+}
+
+// appendEnv appends vars to env in KEY=VALUE form, in key order.
+func appendEnv(env []string, vars map[string]string) []string {
+	for _, k := range slices.Sorted(maps.Keys(vars)) {
+		env = append(env, k+"="+vars[k])
+	}
+	return env
+}
@@ -1,6 +1,13 @@
 package tfclient
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/infracollect/tf-data-client/cache"
+	"github.com/infracollect/tf-data-client/registry"
+)
 
 // ErrProviderNotFound is returned when a provider cannot be found in the registry
 // (e.g. resolving latest version or when the provider does not exist).
@@ -21,14 +28,19 @@ func (e *ErrProviderNotFound) Unwrap() error {
 	return e.Err
 }
 
-// ErrVersionNotFound is returned when a specific version of a provider cannot be found.
+// ErrVersionNotFound is returned when a specific version of a provider cannot be found,
+// or when no version matches a version constraint.
 type ErrVersionNotFound struct {
 	Namespace string
 	Name      string
-	Version   string
+	Version   string   // exact version or constraint
+	Available []string // candidate versions, when a constraint matched none of them
 }
 
 func (e *ErrVersionNotFound) Error() string {
+	if len(e.Available) > 0 {
+		return fmt.Sprintf("version %s not found for provider %s/%s (available: %s)", e.Version, e.Namespace, e.Name, strings.Join(e.Available, ", "))
+	}
 	return fmt.Sprintf("version %s not found for provider %s/%s", e.Version, e.Namespace, e.Name)
 }
 
@@ -42,6 +54,20 @@ func (e *ErrProviderNotConfigured) Error() string {
 	return fmt.Sprintf("provider not configured: %s/%s", e.Namespace, e.Name)
 }
 
+// ErrProviderAlreadyConfigured is returned by Configure when the provider,
+// shared by the callers of CreateProvider, is already configured differently.
+type ErrProviderAlreadyConfigured struct {
+	Namespace string
+	Name      string
+	Version   string
+	Alias     string
+}
+
+func (e *ErrProviderAlreadyConfigured) Error() string {
+	cfg := ProviderConfig{Namespace: e.Namespace, Name: e.Name, Version: e.Version, Alias: e.Alias}
+	return fmt.Sprintf("provider %s is already configured differently; use Reconfigure, or an alias per configuration", cfg)
+}
+
 // ErrDataSourceNotFound is returned when a data source type doesn't exist in the provider schema.
 type ErrDataSourceNotFound struct {
 	TypeName  string
@@ -53,6 +79,113 @@ func (e *ErrDataSourceNotFound) Error() string {
 	return fmt.Sprintf("data source %q not found in provider %s/%s", e.TypeName, e.Namespace, e.Name)
 }
 
+// ErrUnknownValues is returned when a data source state holds unknown values,
+// which providers must not return when reading data sources.
+type ErrUnknownValues struct {
+	Namespace string
+	Name      string
+	TypeName  string
+	Paths     []string // of the unknown values, e.g. items[0].id
+}
+
+func (e *ErrUnknownValues) Error() string {
+	return fmt.Sprintf("provider %s/%s returned unknown values for data source %q at %s", e.Namespace, e.Name, e.TypeName, strings.Join(e.Paths, ", "))
+}
+
+// ErrInvalidConfig is returned by ValidateProviderConfig and
+// ValidateDataSourceConfig when a configuration doesn't conform to its schema
+// or the provider rejects it.
+type ErrInvalidConfig struct {
+	Namespace string
+	Name      string
+	TypeName  string   // data source, empty for the provider configuration
+	Problems  []string // one per invalid attribute or provider diagnostic
+}
+
+func (e *ErrInvalidConfig) Error() string {
+	what := fmt.Sprintf("provider %s/%s configuration", e.Namespace, e.Name)
+	if e.TypeName != "" {
+		what = fmt.Sprintf("data source %q configuration", e.TypeName)
+	}
+	return fmt.Sprintf("invalid %s: %s", what, strings.Join(e.Problems, "; "))
+}
+
+// ErrChecksumMismatch is returned, wrapped in ErrDownloadFailed, when a downloaded
+// provider archive doesn't match the SHA-256 checksum published by the registry.
+type ErrChecksumMismatch = registry.ErrChecksumMismatch
+
+// ErrSignatureVerification is returned, wrapped in ErrDownloadFailed, when a
+// provider release isn't signed by a key the trust policy accepts.
+type ErrSignatureVerification = registry.ErrSignatureVerification
+
+// ErrUnsafeArchive is returned, wrapped in ErrDownloadFailed, when a provider
+// archive exceeds the extraction limits (see WithExtractLimits) or holds a
+// symlink or a path outside of the provider directory.
+type ErrUnsafeArchive = cache.ErrUnsafeArchive
+
+// ErrLockMismatch is returned when a provider doesn't match the version or
+// hashes recorded for it in the lock file (WithLockFile).
+type ErrLockMismatch struct {
+	Namespace string
+	Name      string
+	Version   string // requested version or constraint
+	Locked    string // version in the lock file
+	Reason    string
+}
+
+func (e *ErrLockMismatch) Error() string {
+	return fmt.Sprintf("provider %s/%s does not match the lock file (locked version %s): %s", e.Namespace, e.Name, e.Locked, e.Reason)
+}
+
+// ErrProviderDenied is returned when the provider policy (WithProviderPolicy)
+// doesn't allow a provider to run.
+type ErrProviderDenied struct {
+	Namespace string
+	Name      string
+	Version   string // resolved version, empty if denied by source
+	Reason    string
+	Err       error // signature verification error, if any
+}
+
+func (e *ErrProviderDenied) Error() string {
+	source := e.Namespace + "/" + e.Name
+	if e.Version != "" {
+		source += "@" + e.Version
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("provider %s denied by policy: %s: %v", source, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("provider %s denied by policy: %s", source, e.Reason)
+}
+
+func (e *ErrProviderDenied) Unwrap() error {
+	return e.Err
+}
+
+// ErrOfflineCacheMiss is returned in offline mode (WithOfflineMode) when a
+// provider, or any version of it, is neither cached nor mirrored. It names
+// what needs to be pre-seeded, e.g. with InstallProviderFromArchive.
+type ErrOfflineCacheMiss struct {
+	Namespace string
+	Name      string
+	Version   string // empty when no version is available at all
+	OS        string
+	Arch      string
+	Available []string // cached or mirrored versions
+}
+
+func (e *ErrOfflineCacheMiss) Error() string {
+	source := e.Namespace + "/" + e.Name
+	if e.Version != "" {
+		source += " " + e.Version
+	}
+	msg := fmt.Sprintf("offline mode: provider %s for %s_%s is not cached or mirrored; pre-seed it with InstallProviderFromArchive or a filesystem mirror", source, e.OS, e.Arch)
+	if len(e.Available) > 0 {
+		msg += fmt.Sprintf(" (available: %s)", strings.Join(e.Available, ", "))
+	}
+	return msg
+}
+
 // ErrDownloadFailed is returned when provider download fails.
 type ErrDownloadFailed struct {
 	Namespace string
@@ -131,3 +264,132 @@ func (e *ErrProtocolUnsupported) Error() string {
 			"Try using a provider that supports protocol v6, or check if a newer version of this provider exists",
 		e.Namespace, e.Name, e.Version, e.ProviderVersion, e.ClientVersion)
 }
+
+// ErrProviderUnhealthy is returned when a provider process has exited or stops
+// responding to health checks.
+type ErrProviderUnhealthy struct {
+	Namespace string
+	Name      string
+	Version   string
+	Err       error
+}
+
+func (e *ErrProviderUnhealthy) Error() string {
+	return fmt.Sprintf("provider %s/%s@%s is unhealthy: %v", e.Namespace, e.Name, e.Version, e.Err)
+}
+
+func (e *ErrProviderUnhealthy) Unwrap() error {
+	return e.Err
+}
+
+// ErrCircuitOpen is the Err of the ErrProviderUnhealthy returned by calls to
+// a provider whose circuit breaker is open (WithCircuitBreaker), after
+// Failures consecutive failed RPCs, the last of which was Err.
+type ErrCircuitOpen struct {
+	Failures int
+	RetryAt  time.Time // when a call is let through again
+	Err      error
+}
+
+func (e *ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("circuit breaker open after %d consecutive failures, until %s: %v", e.Failures, e.RetryAt.Format(time.RFC3339), e.Err)
+}
+
+func (e *ErrCircuitOpen) Unwrap() error {
+	return e.Err
+}
+
+// ErrCallTimeout is returned by calls to a provider that ran out of time,
+// either their context's deadline or the call timeout
+// (WithDefaultCallTimeout), as opposed to failures of the provider: the call
+// may succeed if retried with more time. Timeout is the call timeout that
+// expired, 0 if it was the context's deadline.
+type ErrCallTimeout struct {
+	Namespace string
+	Name      string
+	Version   string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *ErrCallTimeout) Error() string {
+	if e.Timeout > 0 {
+		return fmt.Sprintf("call to provider %s/%s@%s timed out after %s: %v", e.Namespace, e.Name, e.Version, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("call to provider %s/%s@%s exceeded its deadline: %v", e.Namespace, e.Name, e.Version, e.Err)
+}
+
+func (e *ErrCallTimeout) Unwrap() error {
+	return e.Err
+}
+
+// ErrProviderCrashed is returned when a local provider process exits
+// unexpectedly during a call, e.g. because it panicked. It holds what is
+// known about the crash; with WithCrashBundleDir, it is also written to a
+// file.
+type ErrProviderCrashed struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	PID       int    `json:"pid"`
+	// ExitStatus describes how the process exited, e.g. "exit status 2" or
+	// "signal: segmentation fault", "" if it was still exiting.
+	ExitStatus string `json:"exit_status,omitempty"`
+	ExitCode   int    `json:"exit_code"` // -1 if killed by a signal or unknown
+	// RPCs are the plugin protocol calls cut short, e.g.
+	// "/tfplugin6.Provider/ReadDataSource".
+	RPCs []string `json:"rpcs,omitempty"`
+	// Stderr holds the last lines the provider wrote to stderr, including
+	// its logs and any panic trace.
+	Stderr     []string  `json:"stderr,omitempty"`
+	Time       time.Time `json:"time"`
+	BundlePath string    `json:"-"` // the crash bundle written, if any
+	Err        error     `json:"-"` // the error of the call
+}
+
+func (e *ErrProviderCrashed) Error() string {
+	msg := fmt.Sprintf("provider %s/%s@%s crashed", e.Namespace, e.Name, e.Version)
+	if len(e.RPCs) > 0 {
+		msg += " during " + strings.Join(e.RPCs, ", ")
+	}
+	if e.ExitStatus != "" {
+		msg += " (" + e.ExitStatus + ")"
+	}
+	for _, line := range e.Stderr {
+		if strings.HasPrefix(line, "panic: ") {
+			msg += ": " + line
+			break
+		}
+	}
+	if e.BundlePath != "" {
+		msg += "; details in " + e.BundlePath
+	}
+	return msg
+}
+
+func (e *ErrProviderCrashed) Unwrap() error {
+	return e.Err
+}
+
+// ErrProviderKilled is returned when a provider process was killed for
+// exceeding its resource limits (see WithProcessLimits).
+type ErrProviderKilled struct {
+	Namespace string
+	Name      string
+	Version   string
+	Reason    string
+}
+
+func (e *ErrProviderKilled) Error() string {
+	return fmt.Sprintf("provider %s/%s@%s was killed: %s", e.Namespace, e.Name, e.Version, e.Reason)
+}
+
+// ErrDependencyCycle is returned by RunPipeline and ValidatePipeline when the
+// nodes of a pipeline depend on each other in a cycle.
+type ErrDependencyCycle struct {
+	Nodes []string // the nodes of the cycle, the first one repeated at the end
+}
+
+func (e *ErrDependencyCycle) Error() string {
+	return fmt.Sprintf("pipeline nodes depend on each other in a cycle: %s", strings.Join(e.Nodes, " -> "))
+}
@@ -0,0 +1,165 @@
+package tfclient
+
+import (
+	"sync"
+	"time"
+)
+
+// Event is an occurrence in the life of a client's providers, sent to the
+// hooks set with WithHooks or Subscribe. It is one of
+// *ProviderDownloadStarted, *ProviderDownloadFinished, *ProviderLaunched,
+// *ProviderExited, *ReadStarted, *ReadFinished, *NodeStarted,
+// *NodeFinished, *CircuitOpened or *CircuitClosed; switch on its type.
+type Event interface {
+	event()
+}
+
+// ProviderDownloadStarted is sent when a provider archive starts downloading
+// from the registry.
+type ProviderDownloadStarted struct {
+	Provider ProviderConfig // with the resolved version
+	Time     time.Time
+}
+
+// ProviderDownloadFinished is sent when a provider download ends.
+type ProviderDownloadFinished struct {
+	Provider ProviderConfig
+	Duration time.Duration
+	Err      error // nil if the download succeeded
+}
+
+// ProviderLaunched is sent when a provider process starts serving, including
+// each process of a pool and processes relaunched after a crash.
+type ProviderLaunched struct {
+	Provider ProviderConfig
+	Slot     int  // index of the process in the provider's pool
+	Restart  bool // whether it replaces a process that exited
+}
+
+// ProviderExited is sent when a provider process is found to have exited,
+// or is stopped by Close.
+type ProviderExited struct {
+	Provider ProviderConfig
+	Slot     int
+	// Expected is set when the process was stopped by the client, e.g. by
+	// Close, rather than crashing.
+	Expected bool
+	Err      error // what revealed the exit, nil if expected
+}
+
+// ReadStarted is sent when a data source read starts.
+type ReadStarted struct {
+	Provider   ProviderConfig
+	DataSource string
+	Time       time.Time
+}
+
+// ReadFinished is sent when a data source read ends.
+type ReadFinished struct {
+	Provider   ProviderConfig
+	DataSource string
+	Duration   time.Duration
+	Cached     bool  // served by the result cache (WithResultCache)
+	Err        error // nil if the read succeeded
+}
+
+// NodeStarted is sent when an attempt at running a pipeline node starts.
+type NodeStarted struct {
+	Node    string
+	Attempt int // from 1, incremented by retries
+}
+
+// NodeFinished is sent when a pipeline node succeeded, failed after its
+// retries, or was skipped.
+type NodeFinished struct {
+	Node     string
+	Status   NodeStatus
+	Duration time.Duration
+	Err      error // nil if the node succeeded
+}
+
+// CircuitOpened is sent when the circuit breaker of a provider opens
+// (WithCircuitBreaker), making its calls fail fast.
+type CircuitOpened struct {
+	Provider ProviderConfig
+	Err      error // of the failed call opening it
+}
+
+// CircuitClosed is sent when a call succeeds through the circuit breaker of a
+// provider after it opened.
+type CircuitClosed struct {
+	Provider ProviderConfig
+}
+
+func (*ProviderDownloadStarted) event()  {}
+func (*ProviderDownloadFinished) event() {}
+func (*ProviderLaunched) event()         {}
+func (*ProviderExited) event()           {}
+func (*ReadStarted) event()              {}
+func (*ReadFinished) event()             {}
+func (*NodeStarted) event()              {}
+func (*NodeFinished) event()             {}
+func (*CircuitOpened) event()            {}
+func (*CircuitClosed) event()            {}
+
+// EventHook receives events. Hooks are called synchronously from the
+// goroutine causing the event, so they should be fast, and must be safe for
+// concurrent use.
+type EventHook func(Event)
+
+// eventHooks are the hooks of a client, shared with its providers.
+type eventHooks struct {
+	mu    sync.RWMutex
+	hooks map[int]EventHook
+	next  int
+}
+
+// add adds a hook, returning a function removing it.
+func (h *eventHooks) add(hook EventHook) func() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hooks == nil {
+		h.hooks = make(map[int]EventHook)
+	}
+	id := h.next
+	h.next++
+	h.hooks[id] = hook
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.hooks, id)
+		})
+	}
+}
+
+// emit sends e to the hooks. h may be nil.
+func (h *eventHooks) emit(e Event) {
+	if h == nil {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, hook := range h.hooks {
+		hook(e)
+	}
+}
+
+// Subscribe adds a hook receiving the events of the client's providers from
+// now on, returning a function removing it, e.g. when a progress UI closes.
+func (c *Client) Subscribe(hook EventHook) (unsubscribe func()) {
+	return c.events.add(hook)
+}
+
+// exited reports that a process of the provider exited, once per process.
+func (p *provider) exited(inst *pluginInstance, expected bool, err error) {
+	if inst.exitReported.Swap(true) {
+		return
+	}
+	if expected {
+		err = nil
+	}
+	p.events.emit(&ProviderExited{Provider: p.Config(), Slot: inst.slot, Expected: expected, Err: err})
+}
@@ -0,0 +1,74 @@
+package tfclient
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrTooManyProviders is returned by CreateProvider when WithMaxProviders
+// providers are running and none is idle to make room for another.
+type ErrTooManyProviders struct {
+	Max int
+}
+
+func (e *ErrTooManyProviders) Error() string {
+	return fmt.Sprintf("%d providers are running and all are busy", e.Max)
+}
+
+// touch records that the provider is used.
+func (p *provider) touch() {
+	p.lastUsed.Store(time.Now().UnixNano())
+}
+
+// lastUsedAt returns when the provider was last used.
+func (p *provider) lastUsedAt() time.Time {
+	return time.Unix(0, p.lastUsed.Load())
+}
+
+// idle reports whether none of the provider's processes is serving a call.
+func (p *provider) idle() bool {
+	for _, inst := range p.instances() {
+		if inst.inflight.Load() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// makeRoom stops the least recently used idle provider if launching another
+// would exceed WithMaxProviders. The caller must hold c.mu.
+func (c *Client) makeRoom() error {
+	if c.maxProviders <= 0 || len(c.providers)+len(c.launching) < c.maxProviders {
+		return nil
+	}
+
+	var (
+		lruKey string
+		lru    *provider
+	)
+	for key, p := range c.providers {
+		if p.idle() && (lru == nil || p.lastUsedAt().Before(lru.lastUsedAt())) {
+			lruKey, lru = key, p
+		}
+	}
+	if lru == nil {
+		return &ErrTooManyProviders{Max: c.maxProviders}
+	}
+
+	c.logger.Info("stopping least recently used provider", "provider", lru.Config().String(), "last_used", lru.lastUsedAt())
+	c.forget(lruKey)
+	// Stopping may take the grace period, while c.mu is held
+	go lru.Close()
+	return nil
+}
+
+// forget removes a provider from the running providers. The caller must hold
+// c.mu.
+func (c *Client) forget(key string) {
+	delete(c.providers, key)
+	for requested, resolved := range c.resolvedKeys {
+		if resolved == key {
+			delete(c.resolvedKeys, requested)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package tfclient
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	hcljson "github.com/hashicorp/hcl/v2/json"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// DecodeHCLConfig decodes a provider or data source configuration written in
+// HCL, or in HCL's JSON syntax if filename ends with ".json", against its
+// schema, e.g. from ProviderSchema or DataSourceSchema. The result can be
+// passed to Configure or ReadDataSource.
+//
+// The file holds the content of the configuration block, as in Terraform:
+// attributes and nested blocks. For pasting from Terraform code, a native
+// syntax file may instead hold a single provider or data block, whose content
+// is decoded. Expressions can't refer to variables, see
+// DecodeHCLConfigWithVars, or call functions.
+func DecodeHCLConfig(filename string, src []byte, schema *Schema) (map[string]any, error) {
+	return decodeHCLConfig(filename, src, schema, nil)
+}
+
+// DecodeHCLConfigWithVars is DecodeHCLConfig where expressions can refer to
+// variables as var.<name>, e.g. "${var.region}", so that one configuration
+// serves several environments. Variables take their values from the tfvars
+// files of vars, then its raw values, which are strings. In HCL's JSON
+// syntax, references are only evaluated in string templates.
+func DecodeHCLConfigWithVars(filename string, src []byte, schema *Schema, vars TerraformVars) (map[string]any, error) {
+	values, err := vars.values()
+	if err != nil {
+		return nil, err
+	}
+	return decodeHCLConfig(filename, src, schema, &hcl.EvalContext{
+		Variables: map[string]cty.Value{"var": cty.ObjectVal(values)},
+	})
+}
+
+// decodeHCLConfig decodes a configuration, evaluating its expressions in
+// ctx.
+func decodeHCLConfig(filename string, src []byte, schema *Schema, ctx *hcl.EvalContext) (map[string]any, error) {
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		file, diags = hcljson.Parse(src, filename)
+	} else {
+		file, diags = hclsyntax.ParseConfig(src, filename, hcl.InitialPos)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, diags)
+	}
+
+	spec, err := schemaSpec(schema)
+	if err != nil {
+		return nil, err
+	}
+	val, diags := hcldec.Decode(configBody(file.Body, schema), spec, ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode %s: %w", filename, diags)
+	}
+	return ctyValueToMap(val, valueOptions{})
+}
+
+// configBody returns the body of the single provider or data block of a
+// configuration pasted from Terraform code, or body itself.
+func configBody(body hcl.Body, schema *Schema) hcl.Body {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok || len(syntaxBody.Attributes) != 0 || len(syntaxBody.Blocks) != 1 {
+		return body
+	}
+	block := syntaxBody.Blocks[0]
+	if block.Type != "provider" && block.Type != "data" {
+		return body
+	}
+	// A nested block of the schema with the same name isn't a wrapper
+	for _, b := range schema.Blocks {
+		if b.Name == block.Type {
+			return body
+		}
+	}
+	return block.Body
+}
+
+// schemaSpec returns the hcldec specification of the configurable
+// attributes and blocks of a schema.
+func schemaSpec(schema *Schema) (hcldec.ObjectSpec, error) {
+	spec := hcldec.ObjectSpec{}
+	for _, attr := range schema.Attributes {
+		if attr.ReadOnly() {
+			continue
+		}
+		ty, err := attributeType(attr)
+		if err != nil {
+			return nil, err
+		}
+		spec[attr.Name] = &hcldec.AttrSpec{Name: attr.Name, Type: ty, Required: attr.Required}
+	}
+
+	for _, block := range schema.Blocks {
+		nested, err := schemaSpec(&block.Schema)
+		if err != nil {
+			return nil, err
+		}
+		switch block.Nesting {
+		case "single", "group":
+			spec[block.Name] = &hcldec.BlockSpec{TypeName: block.Name, Nested: nested, Required: block.MinItems > 0}
+		case "list":
+			spec[block.Name] = &hcldec.BlockListSpec{TypeName: block.Name, Nested: nested, MinItems: block.MinItems, MaxItems: block.MaxItems}
+		case "set":
+			spec[block.Name] = &hcldec.BlockSetSpec{TypeName: block.Name, Nested: nested, MinItems: block.MinItems, MaxItems: block.MaxItems}
+		case "map":
+			spec[block.Name] = &hcldec.BlockMapSpec{TypeName: block.Name, LabelNames: []string{"key"}, Nested: nested}
+		default:
+			return nil, fmt.Errorf("block %s has unsupported nesting %q", block.Name, block.Nesting)
+		}
+	}
+	return spec, nil
+}
+
+// attributeType returns the type constraint of an attribute. Nested
+// attributes that aren't required may be omitted from its objects.
+func attributeType(attr SchemaAttribute) (cty.Type, error) {
+	if attr.Nesting == "" {
+		expr, diags := hclsyntax.ParseExpression([]byte(attr.Type), attr.Name, hcl.InitialPos)
+		if diags.HasErrors() {
+			return cty.NilType, fmt.Errorf("invalid type %q of %s: %w", attr.Type, attr.Name, diags)
+		}
+		ty, diags := typeexpr.TypeConstraint(expr)
+		if diags.HasErrors() {
+			return cty.NilType, fmt.Errorf("invalid type %q of %s: %w", attr.Type, attr.Name, diags)
+		}
+		return ty, nil
+	}
+
+	attrTypes := make(map[string]cty.Type)
+	var optional []string
+	for _, nested := range attr.Attributes {
+		ty, err := attributeType(nested)
+		if err != nil {
+			return cty.NilType, err
+		}
+		attrTypes[nested.Name] = ty
+		if !nested.Required {
+			optional = append(optional, nested.Name)
+		}
+	}
+	objType := cty.ObjectWithOptionalAttrs(attrTypes, optional)
+
+	switch attr.Nesting {
+	case "list":
+		return cty.List(objType), nil
+	case "set":
+		return cty.Set(objType), nil
+	case "map":
+		return cty.Map(objType), nil
+	default:
+		return objType, nil
+	}
+}
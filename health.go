@@ -0,0 +1,107 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errProcessExited is reported when the provider subprocess is no longer running.
+var errProcessExited = errors.New("provider process exited")
+
+// Ping checks that every provider process is still running and answers the
+// go-plugin health service. The provider is marked unhealthy when any fails.
+func (p *provider) Ping(ctx context.Context) error {
+	err := p.pingAll(ctx)
+	p.setHealthy(err)
+	if err != nil {
+		return &ErrProviderUnhealthy{
+			Namespace: p.namespace,
+			Name:      p.name,
+			Version:   p.version,
+			Err:       err,
+		}
+	}
+	return nil
+}
+
+// Healthy reports the result of the most recent health check.
+func (p *provider) Healthy() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.healthy
+}
+
+func (p *provider) pingAll(ctx context.Context) error {
+	var errs []error
+	for _, inst := range p.instances() {
+		if err := inst.ping(ctx); err != nil {
+			if errors.Is(err, errProcessExited) {
+				p.exited(inst, false, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (inst *pluginInstance) ping(ctx context.Context) error {
+	if inst.conn.Exited() {
+		return errProcessExited
+	}
+	return inst.conn.Ping(ctx)
+}
+
+// setHealthy records the health check outcome and logs state transitions.
+func (p *provider) setHealthy(err error) {
+	p.mu.Lock()
+	was := p.healthy
+	p.healthy = err == nil
+	p.mu.Unlock()
+
+	switch {
+	case was && err != nil:
+		p.logger.Error(err, "provider unhealthy", "provider", p.Config().String())
+	case !was && err == nil:
+		p.logger.Info("provider healthy again", "provider", p.Config().String())
+	}
+}
+
+// startHealthCheck pings the provider every interval until the provider is closed.
+// With auto-restart enabled, a failed check relaunches the provider right away
+// instead of waiting for the next request.
+func (p *provider) startHealthCheck(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+				p.checkHealth(ctx)
+				cancel()
+			}
+		}
+	}()
+}
+
+// checkHealth runs one health check round. With auto-restart enabled, each
+// failed instance is relaunched.
+func (p *provider) checkHealth(ctx context.Context) {
+	if err := p.Ping(ctx); err == nil || !p.autoRestart {
+		return
+	}
+
+	for _, inst := range p.instances() {
+		if inst.ping(ctx) == nil {
+			continue
+		}
+		if _, err := p.restart(ctx, inst); err != nil {
+			p.logger.Error(err, "failed to restart provider", "provider", p.Config().String(), "slot", inst.slot)
+		}
+	}
+	p.setHealthy(p.pingAll(ctx))
+}
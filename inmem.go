@@ -0,0 +1,84 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const inMemoryBufferSize = 1 << 20
+
+// inMemoryConn is a connection to a provider server running in this process.
+type inMemoryConn struct {
+	cc      *grpc.ClientConn
+	server  *grpc.Server
+	stopped atomic.Bool
+}
+
+func (c *inMemoryConn) Exited() bool {
+	return c.stopped.Load()
+}
+
+func (c *inMemoryConn) Ping(ctx context.Context) error {
+	if c.Exited() {
+		return errProcessExited
+	}
+	return nil
+}
+
+func (c *inMemoryConn) Kill() {
+	if c.stopped.Swap(true) {
+		return
+	}
+	c.cc.Close()
+	c.server.Stop()
+}
+
+// Terminate stops the server at once: it runs no operations of its own to
+// wait for.
+func (c *inMemoryConn) Terminate(time.Duration) {
+	c.Kill()
+}
+
+// Pid always returns 0: in-process servers run in this process.
+func (c *inMemoryConn) Pid() int {
+	return 0
+}
+
+// KilledBy always returns "": in-process servers have no resource limits.
+func (c *inMemoryConn) KilledBy() string {
+	return ""
+}
+
+// serveInMemory serves srv on an in-memory listener and returns an instance
+// connected to it.
+func serveInMemory(srv tfplugin6.ProviderServer, dialOpts []grpc.DialOption) (*pluginInstance, error) {
+	lis := bufconn.Listen(inMemoryBufferSize)
+	server := grpc.NewServer()
+	tfplugin6.RegisterProviderServer(server, srv)
+	go server.Serve(lis)
+
+	opts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	}, dialOpts...)
+	cc, err := grpc.NewClient("passthrough:///in-memory", opts...)
+	if err != nil {
+		server.Stop()
+		return nil, fmt.Errorf("failed to connect to in-memory provider: %w", err)
+	}
+
+	return &pluginInstance{
+		conn:       &inMemoryConn{cc: cc, server: server},
+		grpcClient: tfplugin6.NewProviderClient(cc),
+	}, nil
+}
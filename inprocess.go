@@ -0,0 +1,238 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+)
+
+// inProcessProvider is a provider server registered with WithInProcessProvider.
+type inProcessProvider struct {
+	version string
+	server  tfprotov6.ProviderServer
+}
+
+// inProcessServer serves a terraform-plugin-go provider over the tfplugin6
+// protocol. tf6server can't be used for this: it registers its own copy of the
+// tfplugin6 protobuf types, which conflicts with ours at init time.
+type inProcessServer struct {
+	tfplugin6.UnimplementedProviderServer
+	server tfprotov6.ProviderServer
+}
+
+func (s *inProcessServer) GetMetadata(ctx context.Context, _ *tfplugin6.GetMetadata_Request) (*tfplugin6.GetMetadata_Response, error) {
+	resp, err := s.server.GetMetadata(ctx, &tfprotov6.GetMetadataRequest{})
+	if err != nil {
+		return nil, err
+	}
+	out := &tfplugin6.GetMetadata_Response{
+		ServerCapabilities: toProtoCapabilities(resp.ServerCapabilities),
+		Diagnostics:        toProtoDiagnostics(resp.Diagnostics),
+	}
+	for _, ds := range resp.DataSources {
+		out.DataSources = append(out.DataSources, &tfplugin6.GetMetadata_DataSourceMetadata{TypeName: ds.TypeName})
+	}
+	return out, nil
+}
+
+func (s *inProcessServer) GetProviderSchema(ctx context.Context, _ *tfplugin6.GetProviderSchema_Request) (*tfplugin6.GetProviderSchema_Response, error) {
+	resp, err := s.server.GetProviderSchema(ctx, &tfprotov6.GetProviderSchemaRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &tfplugin6.GetProviderSchema_Response{
+		ServerCapabilities: toProtoCapabilities(resp.ServerCapabilities),
+		Diagnostics:        toProtoDiagnostics(resp.Diagnostics),
+		DataSourceSchemas:  make(map[string]*tfplugin6.Schema, len(resp.DataSourceSchemas)),
+	}
+	if out.Provider, err = toProtoSchema(resp.Provider); err != nil {
+		return nil, fmt.Errorf("provider schema: %w", err)
+	}
+	for name, schema := range resp.DataSourceSchemas {
+		if out.DataSourceSchemas[name], err = toProtoSchema(schema); err != nil {
+			return nil, fmt.Errorf("data source %s schema: %w", name, err)
+		}
+	}
+	return out, nil
+}
+
+func (s *inProcessServer) ValidateProviderConfig(ctx context.Context, req *tfplugin6.ValidateProviderConfig_Request) (*tfplugin6.ValidateProviderConfig_Response, error) {
+	resp, err := s.server.ValidateProviderConfig(ctx, &tfprotov6.ValidateProviderConfigRequest{
+		Config: fromProtoDynamicValue(req.Config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.ValidateProviderConfig_Response{Diagnostics: toProtoDiagnostics(resp.Diagnostics)}, nil
+}
+
+func (s *inProcessServer) ConfigureProvider(ctx context.Context, req *tfplugin6.ConfigureProvider_Request) (*tfplugin6.ConfigureProvider_Response, error) {
+	resp, err := s.server.ConfigureProvider(ctx, &tfprotov6.ConfigureProviderRequest{
+		TerraformVersion: req.TerraformVersion,
+		Config:           fromProtoDynamicValue(req.Config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.ConfigureProvider_Response{Diagnostics: toProtoDiagnostics(resp.Diagnostics)}, nil
+}
+
+func (s *inProcessServer) ValidateDataResourceConfig(ctx context.Context, req *tfplugin6.ValidateDataResourceConfig_Request) (*tfplugin6.ValidateDataResourceConfig_Response, error) {
+	resp, err := s.server.ValidateDataResourceConfig(ctx, &tfprotov6.ValidateDataResourceConfigRequest{
+		TypeName: req.TypeName,
+		Config:   fromProtoDynamicValue(req.Config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.ValidateDataResourceConfig_Response{Diagnostics: toProtoDiagnostics(resp.Diagnostics)}, nil
+}
+
+func (s *inProcessServer) ReadDataSource(ctx context.Context, req *tfplugin6.ReadDataSource_Request) (*tfplugin6.ReadDataSource_Response, error) {
+	resp, err := s.server.ReadDataSource(ctx, &tfprotov6.ReadDataSourceRequest{
+		TypeName:     req.TypeName,
+		Config:       fromProtoDynamicValue(req.Config),
+		ProviderMeta: fromProtoDynamicValue(req.ProviderMeta),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := &tfplugin6.ReadDataSource_Response{
+		State:       toProtoDynamicValue(resp.State),
+		Diagnostics: toProtoDiagnostics(resp.Diagnostics),
+	}
+	if resp.Deferred != nil {
+		out.Deferred = &tfplugin6.Deferred{Reason: tfplugin6.Deferred_Reason(resp.Deferred.Reason)}
+	}
+	return out, nil
+}
+
+func (s *inProcessServer) StopProvider(ctx context.Context, _ *tfplugin6.StopProvider_Request) (*tfplugin6.StopProvider_Response, error) {
+	resp, err := s.server.StopProvider(ctx, &tfprotov6.StopProviderRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.StopProvider_Response{Error: resp.Error}, nil
+}
+
+func fromProtoDynamicValue(v *tfplugin6.DynamicValue) *tfprotov6.DynamicValue {
+	if v == nil {
+		return nil
+	}
+	return &tfprotov6.DynamicValue{MsgPack: v.Msgpack, JSON: v.Json}
+}
+
+func toProtoDynamicValue(v *tfprotov6.DynamicValue) *tfplugin6.DynamicValue {
+	if v == nil {
+		return nil
+	}
+	return &tfplugin6.DynamicValue{Msgpack: v.MsgPack, Json: v.JSON}
+}
+
+func toProtoCapabilities(c *tfprotov6.ServerCapabilities) *tfplugin6.ServerCapabilities {
+	if c == nil {
+		return nil
+	}
+	return &tfplugin6.ServerCapabilities{
+		PlanDestroy:               c.PlanDestroy,
+		GetProviderSchemaOptional: c.GetProviderSchemaOptional,
+		MoveResourceState:         c.MoveResourceState,
+	}
+}
+
+func toProtoDiagnostics(diags []*tfprotov6.Diagnostic) []*tfplugin6.Diagnostic {
+	var out []*tfplugin6.Diagnostic
+	for _, d := range diags {
+		if d == nil {
+			continue
+		}
+		out = append(out, &tfplugin6.Diagnostic{
+			Severity: tfplugin6.Diagnostic_Severity(d.Severity),
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		})
+	}
+	return out
+}
+
+func toProtoSchema(s *tfprotov6.Schema) (*tfplugin6.Schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	block, err := toProtoBlock(s.Block)
+	if err != nil {
+		return nil, err
+	}
+	return &tfplugin6.Schema{Version: s.Version, Block: block}, nil
+}
+
+func toProtoBlock(b *tfprotov6.SchemaBlock) (*tfplugin6.Schema_Block, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	attrs, err := toProtoAttributes(b.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	out := &tfplugin6.Schema_Block{
+		Version:         b.Version,
+		Attributes:      attrs,
+		Description:     b.Description,
+		DescriptionKind: tfplugin6.StringKind(b.DescriptionKind),
+		Deprecated:      b.Deprecated,
+	}
+	for _, nested := range b.BlockTypes {
+		block, err := toProtoBlock(nested.Block)
+		if err != nil {
+			return nil, fmt.Errorf("block %s: %w", nested.TypeName, err)
+		}
+		out.BlockTypes = append(out.BlockTypes, &tfplugin6.Schema_NestedBlock{
+			TypeName: nested.TypeName,
+			Block:    block,
+			Nesting:  tfplugin6.Schema_NestedBlock_NestingMode(nested.Nesting),
+			MinItems: nested.MinItems,
+			MaxItems: nested.MaxItems,
+		})
+	}
+	return out, nil
+}
+
+func toProtoAttributes(attrs []*tfprotov6.SchemaAttribute) ([]*tfplugin6.Schema_Attribute, error) {
+	var out []*tfplugin6.Schema_Attribute
+	for _, attr := range attrs {
+		a := &tfplugin6.Schema_Attribute{
+			Name:            attr.Name,
+			Description:     attr.Description,
+			Required:        attr.Required,
+			Optional:        attr.Optional,
+			Computed:        attr.Computed,
+			Sensitive:       attr.Sensitive,
+			DescriptionKind: tfplugin6.StringKind(attr.DescriptionKind),
+			Deprecated:      attr.Deprecated,
+			WriteOnly:       attr.WriteOnly,
+		}
+		if attr.Type != nil {
+			typ, err := attr.Type.MarshalJSON()
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %w", attr.Name, err)
+			}
+			a.Type = typ
+		}
+		if attr.NestedType != nil {
+			nested, err := toProtoAttributes(attr.NestedType.Attributes)
+			if err != nil {
+				return nil, fmt.Errorf("attribute %s: %w", attr.Name, err)
+			}
+			a.NestedType = &tfplugin6.Schema_Object{
+				Attributes: nested,
+				Nesting:    tfplugin6.Schema_Object_NestingMode(attr.NestedType.Nesting),
+			}
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
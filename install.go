@@ -0,0 +1,142 @@
+package tfclient
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/infracollect/tf-data-client/cache"
+)
+
+// InstallProviderFromArchive populates the cache with a provider release
+// archive (the zip published to the registry) for cfg, so that it can be
+// created without registry access. cfg.Version is required; set it in
+// CreateProvider too, as resolving the latest version still queries the
+// registry. A version already in the cache is kept. It returns the path to the
+// cached executable.
+func (c *Client) InstallProviderFromArchive(ctx context.Context, cfg ProviderConfig, archivePath string) (string, error) {
+	id, err := installIdentifier(cfg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(archivePath); err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", cfg, err)
+	}
+
+	execPath, err := c.cache.GetOrPut(ctx, id, func(context.Context) (string, func(), error) {
+		return archivePath, nil, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", cfg, err)
+	}
+	return execPath, nil
+}
+
+// InstallProviderFromBinary populates the cache with a provider executable for
+// cfg, as InstallProviderFromArchive does. path is either the executable or a
+// directory holding an unpacked release, in which case the executable is the
+// terraform-provider-<name>* file in it.
+func (c *Client) InstallProviderFromBinary(ctx context.Context, cfg ProviderConfig, path string) (string, error) {
+	id, err := installIdentifier(cfg)
+	if err != nil {
+		return "", err
+	}
+	binPath, err := findInstallBinary(path, cfg.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", cfg, err)
+	}
+
+	execPath, err := c.cache.GetOrPut(ctx, id, func(context.Context) (string, func(), error) {
+		return zipBinary(binPath, cfg)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to install %s: %w", cfg, err)
+	}
+	return execPath, nil
+}
+
+func installIdentifier(cfg ProviderConfig) (cache.ProviderIdentifier, error) {
+	if cfg.Namespace == "" || cfg.Name == "" || cfg.Version == "" {
+		return cache.ProviderIdentifier{}, fmt.Errorf("namespace, name and version are required to install a provider, got %q", cfg.String())
+	}
+	if isVersionConstraint(cfg.Version) {
+		return cache.ProviderIdentifier{}, fmt.Errorf("an exact version is required to install a provider, got %q", cfg.Version)
+	}
+	return cache.ProviderIdentifier{
+		Namespace: cfg.Namespace,
+		Name:      cfg.Name,
+		Version:   cfg.Version,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}, nil
+}
+
+// findInstallBinary returns path, or the provider executable in it if path is
+// a directory.
+func findInstallBinary(path, name string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+
+	matches, err := filepath.Glob(filepath.Join(path, "terraform-provider-"+name+"*"))
+	if err != nil {
+		return "", err
+	}
+	for _, m := range matches {
+		if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("no terraform-provider-%s executable in %s", name, path)
+}
+
+// zipBinary packs binPath into a temporary release-style archive, renaming it
+// after the provider if needed so the cache can find it.
+func zipBinary(binPath string, cfg ProviderConfig) (_ string, _ func(), err error) {
+	src, err := os.Open(binPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+
+	name := filepath.Base(binPath)
+	if !strings.HasPrefix(name, "terraform-provider-"+cfg.Name) {
+		name = fmt.Sprintf("terraform-provider-%s_v%s", cfg.Name, cfg.Version)
+		if runtime.GOOS == "windows" {
+			name += ".exe"
+		}
+	}
+
+	tmpFile, err := os.CreateTemp("", "provider-*.zip")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	cleanup := func() { os.Remove(tmpPath) }
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
+
+	zw := zip.NewWriter(tmpFile)
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err == nil {
+		_, err = io.Copy(w, src)
+	}
+	err = errors.Join(err, zw.Close(), tmpFile.Close())
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to archive provider binary: %w", err)
+	}
+	return tmpPath, cleanup, nil
+}
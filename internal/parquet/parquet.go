@@ -0,0 +1,236 @@
+// Package parquet writes flat tables in the Apache Parquet format: a single
+// row group of optional columns, PLAIN encoded and uncompressed, which every
+// Parquet reader supports.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Type is the physical type of a column.
+type Type int32
+
+const (
+	Boolean   Type = 0
+	Int64     Type = 2
+	Double    Type = 5
+	ByteArray Type = 6 // written as UTF-8 strings
+)
+
+// Column is a column of a table. Its values are nil for nulls, or of the Go
+// type of the column's Type: bool, int64, float64 or string.
+type Column struct {
+	Name   string
+	Type   Type
+	Values []any
+}
+
+// magic starts and ends Parquet files.
+const magic = "PAR1"
+
+// Thrift enum values of the Parquet format.
+const (
+	encodingPlain      = 0
+	encodingRLE        = 3
+	pageTypeData       = 0
+	repetitionOptional = 1
+	convertedTypeUTF8  = 0
+	codecUncompressed  = 0
+)
+
+// Write writes a table of columns, which must have the same number of values,
+// as a Parquet file to w.
+func Write(w io.Writer, columns []Column) error {
+	if len(columns) == 0 {
+		return errors.New("parquet: a table needs at least one column")
+	}
+	rows := len(columns[0].Values)
+	for _, c := range columns {
+		if len(c.Values) != rows {
+			return fmt.Errorf("parquet: column %s has %d values, expected %d", c.Name, len(c.Values), rows)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(magic)
+	var chunks []columnChunk
+	for _, c := range columns {
+		offset := int64(buf.Len())
+		page, err := dataPage(c)
+		if err != nil {
+			return err
+		}
+		buf.Write(page)
+		chunks = append(chunks, columnChunk{column: c, offset: offset, size: int64(len(page))})
+	}
+
+	var meta compactWriter
+	writeFileMetaData(&meta, columns, chunks, int64(rows))
+	buf.Write(meta.buf.Bytes())
+	binary.Write(&buf, binary.LittleEndian, uint32(meta.buf.Len()))
+	buf.WriteString(magic)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// columnChunk locates the page of a column in the file.
+type columnChunk struct {
+	column Column
+	offset int64
+	size   int64 // of the page with its header
+}
+
+// dataPage returns the data page of a column with its header: the
+// definition levels, 0 for nulls and 1 for values, then the values.
+func dataPage(c Column) ([]byte, error) {
+	var body bytes.Buffer
+	levels := make([]int, len(c.Values))
+	for i, v := range c.Values {
+		if v != nil {
+			levels[i] = 1
+		}
+	}
+	encoded := rleLevels(levels)
+	binary.Write(&body, binary.LittleEndian, uint32(len(encoded)))
+	body.Write(encoded)
+
+	var bits, nbits int
+	for _, v := range c.Values {
+		if v == nil {
+			continue
+		}
+		switch c.Type {
+		case Boolean:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, typeError(c, v)
+			}
+			if b {
+				bits |= 1 << nbits
+			}
+			if nbits++; nbits == 8 {
+				body.WriteByte(byte(bits))
+				bits, nbits = 0, 0
+			}
+		case Int64:
+			n, ok := v.(int64)
+			if !ok {
+				return nil, typeError(c, v)
+			}
+			binary.Write(&body, binary.LittleEndian, n)
+		case Double:
+			f, ok := v.(float64)
+			if !ok {
+				return nil, typeError(c, v)
+			}
+			binary.Write(&body, binary.LittleEndian, math.Float64bits(f))
+		case ByteArray:
+			s, ok := v.(string)
+			if !ok {
+				return nil, typeError(c, v)
+			}
+			binary.Write(&body, binary.LittleEndian, uint32(len(s)))
+			body.WriteString(s)
+		default:
+			return nil, fmt.Errorf("parquet: column %s has unsupported type %d", c.Name, c.Type)
+		}
+	}
+	if nbits > 0 {
+		body.WriteByte(byte(bits))
+	}
+
+	// PageHeader
+	var header compactWriter
+	header.i32(1, pageTypeData)
+	header.i32(2, int32(body.Len()))
+	header.i32(3, int32(body.Len()))
+	header.structBegin(5) // DataPageHeader
+	header.i32(1, int32(len(c.Values)))
+	header.i32(2, encodingPlain)
+	header.i32(3, encodingRLE)
+	header.i32(4, encodingRLE)
+	header.structEnd()
+	header.stop()
+	return append(header.buf.Bytes(), body.Bytes()...), nil
+}
+
+func typeError(c Column, v any) error {
+	return fmt.Errorf("parquet: column %s holds a %T, not matching its type %d", c.Name, v, c.Type)
+}
+
+// rleLevels encodes definition levels of bit width 1 in runs of the RLE
+// hybrid encoding: a varint of the run length shifted left by one, then the
+// level in one byte.
+func rleLevels(levels []int) []byte {
+	var buf []byte
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		buf = append(buf, byte(levels[i]))
+		i = j
+	}
+	return buf
+}
+
+// writeFileMetaData writes the FileMetaData of the file.
+func writeFileMetaData(w *compactWriter, columns []Column, chunks []columnChunk, rows int64) {
+	w.i32(1, 1) // version
+
+	w.listBegin(2, compactStruct, len(columns)+1) // schema
+	w.elemStructBegin()
+	w.str(4, "schema")
+	w.i32(5, int32(len(columns)))
+	w.elemStructEnd()
+	for _, c := range columns {
+		w.elemStructBegin()
+		w.i32(1, int32(c.Type))
+		w.i32(3, repetitionOptional)
+		w.str(4, c.Name)
+		if c.Type == ByteArray {
+			w.i32(6, convertedTypeUTF8)
+		}
+		w.elemStructEnd()
+	}
+
+	w.i64(3, rows)
+
+	var total int64
+	for _, chunk := range chunks {
+		total += chunk.size
+	}
+	w.listBegin(4, compactStruct, 1) // row_groups
+	w.elemStructBegin()
+	w.listBegin(1, compactStruct, len(chunks)) // columns
+	for _, chunk := range chunks {
+		w.elemStructBegin()
+		w.i64(2, chunk.offset) // file_offset
+		w.structBegin(3)       // ColumnMetaData
+		w.i32(1, int32(chunk.column.Type))
+		w.listBegin(2, compactI32, 2)
+		w.elemI32(encodingPlain)
+		w.elemI32(encodingRLE)
+		w.listBegin(3, compactBinary, 1)
+		w.elemStr(chunk.column.Name)
+		w.i32(4, codecUncompressed)
+		w.i64(5, int64(len(chunk.column.Values)))
+		w.i64(6, chunk.size)
+		w.i64(7, chunk.size)
+		w.i64(9, chunk.offset) // data_page_offset
+		w.structEnd()
+		w.elemStructEnd()
+	}
+	w.i64(2, total)
+	w.i64(3, rows)
+	w.elemStructEnd()
+
+	w.str(6, "tf-data-client")
+	w.stop()
+}
@@ -0,0 +1,103 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Types of the Thrift compact protocol, which encodes Parquet metadata.
+const (
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter writes Thrift structs in the compact protocol. Field headers
+// hold the delta from the previous field ID of the struct, kept on a stack
+// for nested structs.
+type compactWriter struct {
+	buf    bytes.Buffer
+	last   int16
+	nested []int16
+}
+
+func (w *compactWriter) field(id int16, typ byte) {
+	if delta := id - w.last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.last = id
+}
+
+// varint writes a zigzag varint.
+func (w *compactWriter) varint(n int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(n<<1^n>>63)))
+}
+
+func (w *compactWriter) i32(id int16, n int32) {
+	w.field(id, compactI32)
+	w.varint(int64(n))
+}
+
+func (w *compactWriter) i64(id int16, n int64) {
+	w.field(id, compactI64)
+	w.varint(n)
+}
+
+func (w *compactWriter) str(id int16, s string) {
+	w.field(id, compactBinary)
+	w.elemStr(s)
+}
+
+// listBegin writes the header of a list field of n elements of type typ,
+// which are written next with the elem methods.
+func (w *compactWriter) listBegin(id int16, typ byte, n int) {
+	w.field(id, compactList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		w.buf.WriteByte(0xf0 | typ)
+		w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+	}
+}
+
+func (w *compactWriter) elemI32(n int32) {
+	w.varint(int64(n))
+}
+
+func (w *compactWriter) elemStr(s string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.buf.WriteString(s)
+}
+
+// elemStructBegin starts a struct element of a list, ended by
+// elemStructEnd.
+func (w *compactWriter) elemStructBegin() {
+	w.nested = append(w.nested, w.last)
+	w.last = 0
+}
+
+func (w *compactWriter) elemStructEnd() {
+	w.stop()
+	w.last = w.nested[len(w.nested)-1]
+	w.nested = w.nested[:len(w.nested)-1]
+}
+
+// structBegin starts a struct field, ended by structEnd.
+func (w *compactWriter) structBegin(id int16) {
+	w.field(id, compactStruct)
+	w.elemStructBegin()
+}
+
+func (w *compactWriter) structEnd() {
+	w.elemStructEnd()
+}
+
+// stop ends the fields of the top-level struct.
+func (w *compactWriter) stop() {
+	w.buf.WriteByte(0)
+}
@@ -0,0 +1,252 @@
+// Package statediff compares data source states, given the shape of their
+// values: sets are compared as sets, whatever the order of their elements,
+// and sensitive values are elided from the changes. It backs
+// tfclient.DiffStates and the diff package.
+package statediff
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Op is the kind of a Change.
+type Op string
+
+const (
+	Added   Op = "added"
+	Removed Op = "removed"
+	Changed Op = "changed"
+)
+
+// Redacted replaces the values of sensitive attributes within the values of
+// changes.
+const Redacted = "(sensitive)"
+
+// Change is a difference between two states.
+type Change struct {
+	Path string
+	Op   Op
+	Old  any
+	New  any
+	// Sensitive is set when the value is of a sensitive attribute, whose Old
+	// and New are left out.
+	Sensitive bool
+}
+
+// Kind is how a value is compared.
+type Kind int
+
+const (
+	Primitive Kind = iota // or unknown, compared structurally
+	Object
+	List
+	Set
+	Map
+)
+
+// Shape is the type of a value, from a schema.
+type Shape struct {
+	Kind      Kind
+	Elem      *Shape            // of lists, sets and maps
+	Attrs     map[string]*Shape // of objects
+	Sensitive bool
+}
+
+// hasSensitive reports whether values of s hold sensitive values.
+func (s *Shape) hasSensitive() bool {
+	if s == nil {
+		return false
+	}
+	if s.Sensitive || s.Elem.hasSensitive() {
+		return true
+	}
+	for _, a := range s.Attrs {
+		if a.hasSensitive() {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the differences between two states of shape s, ordered by
+// path. Without a shape, lists are compared element by element and no value
+// is elided.
+func Diff(old, new map[string]any, s *Shape) []Change {
+	d := &differ{}
+	d.diff("", old, new, s)
+	return d.changes
+}
+
+// Redact returns v, of shape s, with the values of its sensitive attributes
+// replaced by Redacted. v is copied where it changes.
+func Redact(v any, s *Shape) any {
+	if !s.hasSensitive() || v == nil {
+		return v
+	}
+	if s.Sensitive {
+		return Redacted
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			es := s.Elem
+			if s.Kind != Map {
+				es = s.Attrs[k]
+			}
+			out[k] = Redact(e, es)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = Redact(e, s.Elem)
+		}
+		return out
+	}
+	return v
+}
+
+// differ accumulates the changes between two states.
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(path string, op Op, old, new any, s *Shape) {
+	c := Change{Path: path, Op: op, Old: Redact(old, s), New: Redact(new, s)}
+	if s != nil && s.Sensitive {
+		c.Old, c.New, c.Sensitive = nil, nil, true
+	}
+	d.changes = append(d.changes, c)
+}
+
+// diff compares two values of shape s, nil if unknown.
+func (d *differ) diff(path string, old, new any, s *Shape) {
+	if s != nil && s.Sensitive {
+		if !reflect.DeepEqual(old, new) {
+			d.add(path, Changed, old, new, s)
+		}
+		return
+	}
+
+	switch o := old.(type) {
+	case map[string]any:
+		n, ok := new.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for k := range o {
+			keys[k] = true
+		}
+		for k := range n {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			var es *Shape
+			switch {
+			case s == nil:
+			case s.Kind == Map:
+				es = s.Elem
+			default:
+				es = s.Attrs[k]
+			}
+			ov, inOld := o[k]
+			nv, inNew := n[k]
+			switch {
+			case !inOld:
+				d.add(p, Added, nil, nv, es)
+			case !inNew:
+				d.add(p, Removed, ov, nil, es)
+			default:
+				d.diff(p, ov, nv, es)
+			}
+		}
+		return
+
+	case []any:
+		n, ok := new.([]any)
+		if !ok {
+			break
+		}
+		var es *Shape
+		if s != nil {
+			es = s.Elem
+		}
+		if s != nil && s.Kind == Set {
+			d.diffSet(path, o, n, es)
+			return
+		}
+		for i := range max(len(o), len(n)) {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(o):
+				d.add(p, Added, nil, n[i], es)
+			case i >= len(n):
+				d.add(p, Removed, o[i], nil, es)
+			default:
+				d.diff(p, o[i], n[i], es)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(old, new) {
+		d.add(path, Changed, old, new, s)
+	}
+}
+
+// diffSet compares the elements of two sets by value: elements of old that
+// aren't in new are removed, and elements of new that aren't in old are
+// added, in the order of their JSON encoding.
+func (d *differ) diffSet(path string, old, new []any, es *Shape) {
+	count := make(map[string]int)
+	values := make(map[string]any)
+	for _, v := range old {
+		key := encode(v)
+		count[key]--
+		values[key] = v
+	}
+	for _, v := range new {
+		key := encode(v)
+		count[key]++
+		values[key] = v
+	}
+	keys := make([]string, 0, len(count))
+	for key, n := range count {
+		if n != 0 {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		for n := count[key]; n < 0; n++ {
+			d.add(path, Removed, values[key], nil, es)
+		}
+		for n := count[key]; n > 0; n-- {
+			d.add(path, Added, nil, values[key], es)
+		}
+	}
+}
+
+// encode returns the JSON encoding of a value, whose objects have sorted
+// keys, as the key of set elements.
+func encode(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,168 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ConfigResolver rewrites provider and data source configurations before they
+// are checked against the schema and sent to the provider, e.g. to substitute
+// references to environment variables or secrets.
+//
+// ResolveConfig must not modify config, and must be safe for concurrent use.
+type ConfigResolver interface {
+	ResolveConfig(ctx context.Context, config map[string]any) (map[string]any, error)
+}
+
+// ConfigResolverFunc adapts a function to the ConfigResolver interface.
+type ConfigResolverFunc func(ctx context.Context, config map[string]any) (map[string]any, error)
+
+// ResolveConfig calls f(ctx, config).
+func (f ConfigResolverFunc) ResolveConfig(ctx context.Context, config map[string]any) (map[string]any, error) {
+	return f(ctx, config)
+}
+
+// Interpolator is a ConfigResolver calling InterpolateConfig.
+var Interpolator ConfigResolver = ConfigResolverFunc(func(_ context.Context, config map[string]any) (map[string]any, error) {
+	return InterpolateConfig(config)
+})
+
+// interpolation matches ${env:VAR} and ${file:/path} references, and their
+// escaped forms starting with "$$".
+var interpolation = regexp.MustCompile(`\$?\$\{(env|file):([^}]*)\}`)
+
+// InterpolateConfig returns a copy of config where ${env:VAR} in strings is
+// replaced by the value of environment variable VAR, and ${file:/path} by the
+// content of the file, without its trailing newline. References to unset
+// variables or unreadable files are errors. "$${" escapes a reference, and
+// other "${...}" sequences are left as is.
+func InterpolateConfig(config map[string]any) (map[string]any, error) {
+	return rewriteConfig(config, interpolateString)
+}
+
+// rewriteConfig returns a copy of config where strings are replaced by
+// rewrite(s, path), path being their attribute path.
+func rewriteConfig(config map[string]any, rewrite func(s, path string) (string, error)) (map[string]any, error) {
+	rewritten, err := rewriteStrings(config, "", rewrite)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := rewritten.(map[string]any)
+	return m, nil
+}
+
+// rewriteStrings rewrites the strings of v, whose attribute path is path,
+// copying the maps and slices holding them.
+func rewriteStrings(v any, path string, rewrite func(s, path string) (string, error)) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return rewrite(v, path)
+	case map[string]any:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			r, err := rewriteStrings(e, attributePath(path, k), rewrite)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		if v == nil {
+			return v, nil
+		}
+		out := make([]any, len(v))
+		for i, e := range v {
+			r, err := rewriteStrings(e, fmt.Sprintf("%s[%d]", path, i), rewrite)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]string:
+		if v == nil {
+			return v, nil
+		}
+		out := make(map[string]string, len(v))
+		for k, e := range v {
+			r, err := rewrite(e, attributePath(path, k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []string:
+		if v == nil {
+			return v, nil
+		}
+		out := make([]string, len(v))
+		for i, e := range v {
+			r, err := rewrite(e, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// interpolateString replaces the references in s, the value of the attribute
+// at path.
+func interpolateString(s, path string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var firstErr error
+	out := interpolation.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, "$$") {
+			return ref[1:]
+		}
+		m := interpolation.FindStringSubmatch(ref)
+		value, err := lookupReference(m[1], m[2])
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("attribute %s: %w", path, err)
+		}
+		return value
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return out, nil
+}
+
+// lookupReference returns the value of an env or file reference.
+func lookupReference(kind, ref string) (string, error) {
+	switch kind {
+	case "env":
+		value, ok := os.LookupEnv(ref)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", ref)
+		}
+		return value, nil
+	default:
+		data, err := os.ReadFile(ref)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file: %w", err)
+		}
+		s := strings.TrimSuffix(string(data), "\n")
+		return strings.TrimSuffix(s, "\r"), nil
+	}
+}
+
+// attributePath returns the path of attribute name of the object at path.
+func attributePath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
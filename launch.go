@@ -0,0 +1,160 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin/runner"
+)
+
+// LaunchOptions customizes how a provider process is started.
+type LaunchOptions struct {
+	// Dir is the working directory of the provider. Providers resolving
+	// relative paths (e.g. a kubeconfig or credentials file) resolve them
+	// from here. Defaults to the working directory of this process.
+	Dir string
+
+	// Args are extra command line arguments passed to the provider.
+	Args []string
+
+	// Stdin is the provider's standard input. Defaults to os.Stdin. Every
+	// process of a pool, and every restart, reads from the same reader.
+	Stdin io.Reader
+
+	// SysProcAttr holds OS-specific process attributes. It is copied for
+	// each launch; fields needed by WithProcessLimits are set on the copy.
+	SysProcAttr *syscall.SysProcAttr
+
+	// Umask is the file mode creation mask of the provider (Linux only).
+	// Nil inherits the mask of this process.
+	Umask *os.FileMode
+}
+
+// command builds the provider command for the options.
+func (o *LaunchOptions) command(execPath string) *exec.Cmd {
+	if o == nil {
+		return exec.Command(execPath)
+	}
+
+	cmd := exec.Command(execPath, o.Args...)
+	cmd.Dir = o.Dir
+	cmd.Stdin = o.Stdin
+	if o.SysProcAttr != nil {
+		attr := *o.SysProcAttr
+		cmd.SysProcAttr = &attr
+	}
+	return cmd
+}
+
+// threadSetup prepares the calling OS thread before the provider process is
+// started from it. The process inherits the thread's state.
+type threadSetup func() error
+
+// startOnThread runs start on a dedicated OS thread prepared by setups. With no
+// setups start runs on the calling goroutine.
+func startOnThread(setups []threadSetup, start func() error) error {
+	if len(setups) == 0 {
+		return start()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		// The thread is never unlocked: once altered it must not run anything
+		// else, so the runtime discards it when this goroutine returns.
+		// Threads the runtime creates meanwhile are spawned from a clean
+		// template thread, not this one.
+		runtime.LockOSThread()
+
+		for _, setup := range setups {
+			if err := setup(); err != nil {
+				errCh <- err
+				return
+			}
+		}
+		errCh <- start()
+	}()
+	return <-errCh
+}
+
+// processRunner starts a prepared provider command for go-plugin. go-plugin's
+// own runner overwrites the command's stdin, so we supply one that keeps it.
+type processRunner struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr io.ReadCloser
+	path   string
+	pid    int
+}
+
+var _ runner.Runner = (*processRunner)(nil)
+
+// runnerFunc returns a go-plugin RunnerFunc starting cmd. go-plugin passes a
+// spec command carrying the environment and stdin it wants the plugin to have.
+func runnerFunc(cmd *exec.Cmd) func(hclog.Logger, *exec.Cmd, string) (runner.Runner, error) {
+	return func(_ hclog.Logger, spec *exec.Cmd, _ string) (runner.Runner, error) {
+		cmd.Env = append(cmd.Env, spec.Env...)
+		if cmd.Stdin == nil {
+			cmd.Stdin = spec.Stdin
+		}
+
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return nil, err
+		}
+		return &processRunner{cmd: cmd, stdout: stdout, stderr: stderr, path: cmd.Path}, nil
+	}
+}
+
+func (r *processRunner) Start(context.Context) error {
+	if err := r.cmd.Start(); err != nil {
+		return err
+	}
+	r.pid = r.cmd.Process.Pid
+	return nil
+}
+
+func (r *processRunner) Wait(context.Context) error {
+	return r.cmd.Wait()
+}
+
+func (r *processRunner) Kill(context.Context) error {
+	if r.cmd.Process == nil {
+		return nil
+	}
+	// Kill may be called more than once.
+	if err := r.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
+
+func (r *processRunner) Stdout() io.ReadCloser { return r.stdout }
+func (r *processRunner) Stderr() io.ReadCloser { return r.stderr }
+func (r *processRunner) Name() string          { return r.path }
+
+func (r *processRunner) ID() string {
+	return fmt.Sprintf("%d", r.pid)
+}
+
+func (r *processRunner) Diagnose(context.Context) string {
+	return fmt.Sprintf("the provider at %s failed to start or to complete the plugin handshake; check that it was built for %s/%s and is executable", r.path, runtime.GOOS, runtime.GOARCH)
+}
+
+func (r *processRunner) PluginToHost(network, addr string) (string, string, error) {
+	return network, addr, nil
+}
+
+func (r *processRunner) HostToPlugin(network, addr string) (string, string, error) {
+	return network, addr, nil
+}
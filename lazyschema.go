@@ -0,0 +1,74 @@
+package tfclient
+
+import (
+	"context"
+	"slices"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+)
+
+// discoverSchema is used instead of getSchema at launch with WithLazySchemas.
+// It lists the provider's data sources with the GetMetadata RPC, deferring
+// GetProviderSchema to the first call needing the schema. Providers without
+// GetMetadata, or not announcing that GetProviderSchema is optional, get
+// their schema fetched at once.
+func (p *provider) discoverSchema(ctx context.Context) error {
+	if f := p.schemaFile; f != nil {
+		if resp := f.load(); resp != nil && resp.GetServerCapabilities().GetGetProviderSchemaOptional() {
+			p.mu.Lock()
+			p.schema = resp
+			p.mu.Unlock()
+			return nil
+		}
+	}
+
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.GetMetadata", providerAttrs(p.namespace, p.name, p.version)...)
+	resp, err := p.instances()[0].grpcClient.GetMetadata(ctx, &tfplugin6.GetMetadata_Request{})
+	if err == nil {
+		err = checkDiagnostics(resp.Diagnostics)
+	}
+	endSpan(span, err)
+	if err != nil || !resp.GetServerCapabilities().GetGetProviderSchemaOptional() {
+		p.logger.V(1).Info("provider metadata unavailable, fetching its schema", "provider", p.Config().String())
+		return p.getSchema(ctx)
+	}
+
+	names := make([]string, 0, len(resp.DataSources))
+	for _, ds := range resp.DataSources {
+		names = append(names, ds.TypeName)
+	}
+	p.mu.Lock()
+	p.dataSources = names
+	p.mu.Unlock()
+	return nil
+}
+
+// currentSchema returns the provider schema, fetching it first if
+// discoverSchema deferred it.
+func (p *provider) currentSchema(ctx context.Context) (*tfplugin6.GetProviderSchema_Response, error) {
+	if schema := p.providerSchema(); schema != nil {
+		return schema, nil
+	}
+
+	p.schemaMu.Lock()
+	defer p.schemaMu.Unlock()
+	if schema := p.providerSchema(); schema != nil {
+		return schema, nil
+	}
+	if err := p.getSchema(ctx); err != nil {
+		return nil, &ErrSchemaFailed{Namespace: p.namespace, Name: p.name, Err: err}
+	}
+	return p.providerSchema(), nil
+}
+
+// hasDataSource reports whether the provider has a data source, without
+// fetching a deferred schema.
+func (p *provider) hasDataSource(typeName string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.schema != nil {
+		_, ok := p.schema.DataSourceSchemas[typeName]
+		return ok
+	}
+	return slices.Contains(p.dataSources, typeName)
+}
@@ -0,0 +1,41 @@
+package tfclient
+
+import "os/exec"
+
+// ProcessLimits caps the resources a provider process may use. Zero values
+// leave the corresponding resource unlimited.
+//
+// Limits are enforced with cgroups v2 on Linux and job objects on Windows.
+// Other platforms reject non-empty limits.
+type ProcessLimits struct {
+	// MemoryBytes is the maximum resident memory of the provider process.
+	// Exceeding it kills the process and surfaces ErrProviderKilled.
+	MemoryBytes uint64
+
+	// CPU is the maximum number of CPUs the process may use, e.g. 0.5 or 2.
+	CPU float64
+
+	// OpenFiles is the maximum number of open file descriptors (Linux only).
+	OpenFiles uint64
+
+	// CgroupParent is the cgroup v2 directory under which per-process cgroups
+	// are created (Linux only). Defaults to /sys/fs/cgroup/tf-data-client.
+	CgroupParent string
+}
+
+func (l ProcessLimits) empty() bool {
+	return l.MemoryBytes == 0 && l.CPU == 0 && l.OpenFiles == 0
+}
+
+// limiter enforces ProcessLimits on a single provider process.
+type limiter interface {
+	// prepare is called before the process is started.
+	prepare(cmd *exec.Cmd) error
+	// attach is called once the process is running.
+	attach(pid int) error
+	// killedBy returns why the process was killed for exceeding its limits,
+	// or "" if it wasn't.
+	killedBy() string
+	// release frees the resources held for the process once it exited.
+	release()
+}
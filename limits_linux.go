@@ -0,0 +1,175 @@
+package tfclient
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	defaultCgroupParent = "/sys/fs/cgroup/tf-data-client"
+	cpuPeriodMicros     = 100000
+)
+
+// cgroupLimiter places the provider process in a dedicated cgroup v2.
+type cgroupLimiter struct {
+	limits ProcessLimits
+	dir    string   // per-process cgroup, empty when only rlimits are used
+	dirFD  *os.File // kept open for SysProcAttr.CgroupFD
+}
+
+func newLimiter(limits ProcessLimits) (limiter, error) {
+	l := &cgroupLimiter{limits: limits}
+	if limits.MemoryBytes == 0 && limits.CPU == 0 {
+		return l, nil
+	}
+
+	parent := limits.CgroupParent
+	if parent == "" {
+		parent = defaultCgroupParent
+	}
+	if err := setupCgroupParent(parent); err != nil {
+		return nil, err
+	}
+
+	var suffix [6]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		return nil, err
+	}
+	l.dir = filepath.Join(parent, "provider-"+hex.EncodeToString(suffix[:]))
+	if err := os.Mkdir(l.dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	if limits.MemoryBytes > 0 {
+		if err := writeCgroupFile(l.dir, "memory.max", strconv.FormatUint(limits.MemoryBytes, 10)); err != nil {
+			l.release()
+			return nil, err
+		}
+		// Without this, the kernel would swap instead of enforcing the limit.
+		_ = writeCgroupFile(l.dir, "memory.swap.max", "0")
+	}
+	if limits.CPU > 0 {
+		quota := int64(limits.CPU * cpuPeriodMicros)
+		if err := writeCgroupFile(l.dir, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriodMicros)); err != nil {
+			l.release()
+			return nil, err
+		}
+	}
+
+	fd, err := os.Open(l.dir)
+	if err != nil {
+		l.release()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	l.dirFD = fd
+	return l, nil
+}
+
+// setupCgroupParent creates parent and enables the memory and cpu controllers
+// for its children.
+func setupCgroupParent(parent string) error {
+	if err := checkCgroup2(parent); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("failed to create cgroup %s: %w", parent, err)
+	}
+	if err := writeCgroupFile(parent, "cgroup.subtree_control", "+memory +cpu"); err != nil {
+		return fmt.Errorf("cgroup v2 with memory and cpu controllers is required: %w", err)
+	}
+	return nil
+}
+
+// checkCgroup2 verifies that dir, or its closest existing ancestor, lives on a
+// cgroup v2 filesystem. Without this check a cgroup v1 host would get plain
+// directories created under its tmpfs.
+func checkCgroup2(dir string) error {
+	for {
+		var st unix.Statfs_t
+		err := unix.Statfs(dir, &st)
+		if err == nil {
+			if st.Type != unix.CGROUP2_SUPER_MAGIC {
+				return fmt.Errorf("%s is not on a cgroup v2 filesystem", dir)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) || filepath.Dir(dir) == dir {
+			return fmt.Errorf("failed to inspect %s: %w", dir, err)
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (l *cgroupLimiter) prepare(cmd *exec.Cmd) error {
+	if l.dirFD == nil {
+		return nil
+	}
+	// Start the process directly inside the cgroup so limits apply from the
+	// first instruction.
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(l.dirFD.Fd())
+	return nil
+}
+
+func (l *cgroupLimiter) attach(pid int) error {
+	if l.limits.OpenFiles == 0 {
+		return nil
+	}
+	rlim := unix.Rlimit{Cur: l.limits.OpenFiles, Max: l.limits.OpenFiles}
+	if err := unix.Prlimit(pid, unix.RLIMIT_NOFILE, &rlim, nil); err != nil {
+		return fmt.Errorf("failed to set open files limit: %w", err)
+	}
+	return nil
+}
+
+func (l *cgroupLimiter) killedBy() string {
+	if l.dir == "" || l.limits.MemoryBytes == 0 {
+		return ""
+	}
+
+	f, err := os.Open(filepath.Join(l.dir, "memory.events"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), " ")
+		if key == "oom_kill" && value != "0" {
+			return fmt.Sprintf("memory limit of %d bytes exceeded", l.limits.MemoryBytes)
+		}
+	}
+	return ""
+}
+
+func (l *cgroupLimiter) release() {
+	if l.dirFD != nil {
+		l.dirFD.Close()
+		l.dirFD = nil
+	}
+	if l.dir != "" {
+		// Fails harmlessly if a process is somehow still in the cgroup.
+		_ = os.Remove(l.dir)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows
+
+package tfclient
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func newLimiter(limits ProcessLimits) (limiter, error) {
+	return nil, fmt.Errorf("process limits are not supported on %s", runtime.GOOS)
+}
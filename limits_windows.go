@@ -0,0 +1,137 @@
+package tfclient
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Job object definitions missing from x/sys/windows.
+const (
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+	jobObjectMsgProcessMemoryLimit = 9
+)
+
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+type jobObjectAssociateCompletionPort struct {
+	CompletionKey  uintptr
+	CompletionPort windows.Handle
+}
+
+// jobLimiter assigns the provider process to a job object with limits.
+type jobLimiter struct {
+	limits ProcessLimits
+	job    windows.Handle
+	port   windows.Handle // receives job notifications such as memory limit violations
+	reason string
+}
+
+func newLimiter(limits ProcessLimits) (limiter, error) {
+	if limits.OpenFiles > 0 {
+		return nil, fmt.Errorf("open files limit is not supported on windows")
+	}
+
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job object: %w", err)
+	}
+	l := &jobLimiter{limits: limits, job: job}
+
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{}
+	info.BasicLimitInformation.LimitFlags = windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE
+	if limits.MemoryBytes > 0 {
+		info.BasicLimitInformation.LimitFlags |= windows.JOB_OBJECT_LIMIT_PROCESS_MEMORY
+		info.ProcessMemoryLimit = uintptr(limits.MemoryBytes)
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		l.release()
+		return nil, fmt.Errorf("failed to set job memory limit: %w", err)
+	}
+
+	if limits.CPU > 0 {
+		// CpuRate is expressed in 1/100th of a percent of all processors.
+		rate := uint32(limits.CPU / float64(runtime.NumCPU()) * 10000)
+		rate = max(1, min(rate, 10000))
+		cpu := jobObjectCPURateControlInformation{
+			ControlFlags: jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap,
+			CPURate:      rate,
+		}
+		if _, err := windows.SetInformationJobObject(job, windows.JobObjectCpuRateControlInformation,
+			uintptr(unsafe.Pointer(&cpu)), uint32(unsafe.Sizeof(cpu))); err != nil {
+			l.release()
+			return nil, fmt.Errorf("failed to set job CPU limit: %w", err)
+		}
+	}
+
+	port, err := windows.CreateIoCompletionPort(windows.InvalidHandle, 0, 0, 1)
+	if err != nil {
+		l.release()
+		return nil, fmt.Errorf("failed to create completion port: %w", err)
+	}
+	l.port = port
+
+	assoc := jobObjectAssociateCompletionPort{CompletionKey: uintptr(job), CompletionPort: port}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectAssociateCompletionPortInformation,
+		uintptr(unsafe.Pointer(&assoc)), uint32(unsafe.Sizeof(assoc))); err != nil {
+		l.release()
+		return nil, fmt.Errorf("failed to associate completion port: %w", err)
+	}
+
+	return l, nil
+}
+
+func (l *jobLimiter) prepare(cmd *exec.Cmd) error {
+	return nil
+}
+
+func (l *jobLimiter) attach(pid int) error {
+	proc, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(pid))
+	if err != nil {
+		return fmt.Errorf("failed to open provider process: %w", err)
+	}
+	defer windows.CloseHandle(proc)
+
+	if err := windows.AssignProcessToJobObject(l.job, proc); err != nil {
+		return fmt.Errorf("failed to assign provider process to job: %w", err)
+	}
+	return nil
+}
+
+func (l *jobLimiter) killedBy() string {
+	if l.reason != "" || l.port == 0 {
+		return l.reason
+	}
+
+	// Drain pending job notifications without blocking.
+	for {
+		var msg uint32
+		var key uintptr
+		var overlapped *windows.Overlapped
+		if err := windows.GetQueuedCompletionStatus(l.port, &msg, &key, &overlapped, 0); err != nil {
+			return l.reason
+		}
+		if msg == jobObjectMsgProcessMemoryLimit {
+			l.reason = fmt.Sprintf("memory limit of %d bytes exceeded", l.limits.MemoryBytes)
+		}
+	}
+}
+
+func (l *jobLimiter) release() {
+	if l.port != 0 {
+		windows.CloseHandle(l.port)
+		l.port = 0
+	}
+	if l.job != 0 {
+		windows.CloseHandle(l.job)
+		l.job = 0
+	}
+}
@@ -0,0 +1,256 @@
+package tfclient
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/infracollect/tf-data-client/registry"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// lockFileHeader is the header Terraform writes, so that lock files can be
+// shared with it.
+const lockFileHeader = `# This file is maintained automatically by "terraform init".
+# Manual edits may be lost in future updates.
+`
+
+// lockedProvider is a provider entry of a dependency lock file.
+type lockedProvider struct {
+	version     string
+	constraints string   // the constraint the version was selected with, if any
+	hashes      []string // "h1:" package hashes and "zh:" archive hashes
+}
+
+// providerSelection is a provider installed by CreateProvider, to be
+// recorded by WriteLockFile.
+type providerSelection struct {
+	version     string
+	constraints string
+	packageDir  string // unpacked package, hashed into an "h1:" hash
+	archiveSum  string // SHA-256 of the downloaded archive, if it was downloaded
+}
+
+// providerAddress returns the lock file source address of a provider, e.g.
+// "registry.terraform.io/hashicorp/aws".
+func (c *Client) providerAddress(namespace, name string) string {
+	host := c.registryHost
+	if host == "" {
+		host = registry.TerraformRegistryHost
+	}
+	return strings.ToLower(host + "/" + namespace + "/" + name)
+}
+
+// lockedVersion returns the version to create for cfg given its lock file
+// entry, failing if the requested version or constraint excludes it.
+func lockedVersion(cfg ProviderConfig, locked *lockedProvider) (string, error) {
+	mismatch := func(reason string) error {
+		return &ErrLockMismatch{Namespace: cfg.Namespace, Name: cfg.Name, Version: cfg.Version, Locked: locked.version, Reason: reason}
+	}
+
+	switch {
+	case cfg.Version == "":
+	case isVersionConstraint(cfg.Version):
+		constraints, err := version.NewConstraint(cfg.Version)
+		if err != nil {
+			return "", fmt.Errorf("invalid version constraint %q: %w", cfg.Version, err)
+		}
+		v, err := version.NewVersion(locked.version)
+		if err != nil {
+			return "", mismatch("invalid locked version")
+		}
+		if !constraints.Check(v) {
+			return "", mismatch("locked version does not match the constraint")
+		}
+	case cfg.Version != locked.version:
+		return "", mismatch("a different version is locked")
+	}
+	return locked.version, nil
+}
+
+// verifyLocked checks an installed package against the hashes in its lock
+// file entry. A package matches through its "h1:" hash, or, when it was just
+// downloaded, through the "zh:" hash of its archive.
+func verifyLocked(cfg ProviderConfig, locked *lockedProvider, packageDir, archiveSum string) error {
+	if archiveSum != "" && slices.Contains(locked.hashes, "zh:"+strings.ToLower(archiveSum)) {
+		return nil
+	}
+	h1, err := packageHash(packageDir)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(locked.hashes, h1) {
+		return nil
+	}
+	return &ErrLockMismatch{
+		Namespace: cfg.Namespace,
+		Name:      cfg.Name,
+		Version:   locked.version,
+		Locked:    locked.version,
+		Reason:    "package does not match any of the checksums in the lock file",
+	}
+}
+
+// packageHash returns the "h1:" hash Terraform records for an unpacked
+// provider package.
+func packageHash(dir string) (string, error) {
+	h1, err := dirhash.HashDir(dir, "", dirhash.Hash1)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash provider package: %w", err)
+	}
+	return h1, nil
+}
+
+// readLockFile parses a .terraform.lock.hcl file into its entries, keyed by
+// provider source address.
+func readLockFile(path string) (map[string]*lockedProvider, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lock file: %w", err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse lock file: %w", diags)
+	}
+	content, diags := file.Body.Content(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "provider", LabelNames: []string{"source"}}},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse lock file: %w", diags)
+	}
+
+	locks := make(map[string]*lockedProvider, len(content.Blocks))
+	for _, block := range content.Blocks {
+		source := block.Labels[0]
+		attrs, diags := block.Body.Content(&hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{
+				{Name: "version", Required: true},
+				{Name: "constraints"},
+				{Name: "hashes"},
+			},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse lock file entry for %s: %w", source, diags)
+		}
+
+		locked := &lockedProvider{}
+		for name, attr := range attrs.Attributes {
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("failed to parse lock file entry for %s: %w", source, diags)
+			}
+			switch name {
+			case "version", "constraints":
+				if !value.Type().Equals(cty.String) || value.IsNull() {
+					return nil, fmt.Errorf("lock file entry for %s: %s must be a string", source, name)
+				}
+				if name == "version" {
+					locked.version = value.AsString()
+				} else {
+					locked.constraints = value.AsString()
+				}
+			case "hashes":
+				if !value.Type().IsTupleType() && !value.Type().IsListType() {
+					return nil, fmt.Errorf("lock file entry for %s: hashes must be a list of strings", source)
+				}
+				for _, hash := range value.AsValueSlice() {
+					if !hash.Type().Equals(cty.String) || hash.IsNull() {
+						return nil, fmt.Errorf("lock file entry for %s: hashes must be a list of strings", source)
+					}
+					locked.hashes = append(locked.hashes, hash.AsString())
+				}
+			}
+		}
+		locks[strings.ToLower(source)] = locked
+	}
+	return locks, nil
+}
+
+// WriteLockFile writes a Terraform-compatible dependency lock file
+// (.terraform.lock.hcl) recording the version and hashes of every provider
+// this client installed from the registry or cache. Entries of the lock file
+// loaded with WithLockFile are kept, and merged with those of the providers
+// installed since, so a lock file can accumulate the hashes of each platform
+// it is used on. Development overrides, in-process, remote and replayed
+// providers are not recorded.
+func (c *Client) WriteLockFile(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	locks := make(map[string]*lockedProvider, len(c.lockFile)+len(c.selections))
+	for source, locked := range c.lockFile {
+		locks[source] = locked
+	}
+	for source, sel := range c.selections {
+		h1, err := packageHash(sel.packageDir)
+		if err != nil {
+			return err
+		}
+		locked := &lockedProvider{version: sel.version, constraints: sel.constraints, hashes: []string{h1}}
+		if sel.archiveSum != "" {
+			locked.hashes = append(locked.hashes, "zh:"+strings.ToLower(sel.archiveSum))
+		}
+		if previous, ok := locks[source]; ok && previous.version == sel.version {
+			locked.hashes = append(locked.hashes, previous.hashes...)
+			if locked.constraints == "" {
+				locked.constraints = previous.constraints
+			}
+		}
+		locks[source] = locked
+	}
+
+	var b strings.Builder
+	b.WriteString(lockFileHeader)
+	sources := make([]string, 0, len(locks))
+	for source := range locks {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		locked := locks[source]
+		fmt.Fprintf(&b, "\nprovider %s {\n", strconv.Quote(source))
+		if locked.constraints != "" {
+			fmt.Fprintf(&b, "  version     = %s\n", strconv.Quote(locked.version))
+			fmt.Fprintf(&b, "  constraints = %s\n", strconv.Quote(locked.constraints))
+		} else {
+			fmt.Fprintf(&b, "  version = %s\n", strconv.Quote(locked.version))
+		}
+		hashes := slices.Clone(locked.hashes)
+		sort.Strings(hashes)
+		hashes = slices.Compact(hashes)
+		b.WriteString("  hashes = [\n")
+		for _, hash := range hashes {
+			fmt.Fprintf(&b, "    %s,\n", strconv.Quote(hash))
+		}
+		b.WriteString("  ]\n}\n")
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".terraform.lock.hcl-*")
+	if err != nil {
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	writeErr := tmp.Chmod(0644)
+	if writeErr == nil {
+		_, writeErr = tmp.WriteString(b.String())
+	}
+	if err := tmp.Close(); writeErr == nil {
+		writeErr = err
+	}
+	if writeErr == nil {
+		writeErr = os.Rename(tmp.Name(), path)
+	}
+	if writeErr != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write lock file: %w", writeErr)
+	}
+	return nil
+}
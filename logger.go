@@ -1,24 +1,43 @@
 package tfclient
 
 import (
+	"bytes"
+	"encoding/json"
 	"io"
 	"log"
+	"sort"
+	"strings"
+	"sync/atomic"
 
 	"github.com/go-logr/logr"
 	"github.com/hashicorp/go-hclog"
 )
 
-func newHclogAdapter(logger logr.Logger) hclog.Logger {
-	return &hclogAdapter{logger: logger}
+func newHclogAdapter(logger logr.Logger, terminated *atomic.Bool) hclog.Logger {
+	return &hclogAdapter{logger: logger, terminated: terminated}
 }
 
 type hclogAdapter struct {
 	logger      logr.Logger
 	impliedArgs []interface{}
 	name        string
+	terminated  *atomic.Bool // set once the process is sent SIGTERM, may be nil
+}
+
+// pluginExitedMsg is logged by go-plugin when a plugin process exits, as an
+// error if it exited with a signal or a non-zero status.
+const pluginExitedMsg = "plugin process exited"
+
+// expectedExit reports whether msg is go-plugin's exit error of a process
+// that was asked to exit with SIGTERM.
+func (a *hclogAdapter) expectedExit(msg string) bool {
+	return msg == pluginExitedMsg && a.terminated != nil && a.terminated.Load()
 }
 
 func (a *hclogAdapter) Log(level hclog.Level, msg string, args ...interface{}) {
+	if level == hclog.Error && a.expectedExit(msg) {
+		level = hclog.Info
+	}
 	switch level {
 	case hclog.Trace, hclog.Debug:
 		a.logger.V(1).Info(msg, args...)
@@ -48,6 +67,10 @@ func (a *hclogAdapter) Warn(msg string, args ...interface{}) {
 }
 
 func (a *hclogAdapter) Error(msg string, args ...interface{}) {
+	if a.expectedExit(msg) {
+		a.logger.Info(msg, args...)
+		return
+	}
 	a.logger.Error(nil, msg, args...)
 }
 
@@ -80,6 +103,7 @@ func (a *hclogAdapter) With(args ...interface{}) hclog.Logger {
 		logger:      a.logger.WithValues(args...),
 		impliedArgs: append(a.impliedArgs, args...),
 		name:        a.name,
+		terminated:  a.terminated,
 	}
 }
 
@@ -96,6 +120,7 @@ func (a *hclogAdapter) Named(name string) hclog.Logger {
 		logger:      a.logger.WithName(name),
 		impliedArgs: a.impliedArgs,
 		name:        newName,
+		terminated:  a.terminated,
 	}
 }
 
@@ -104,6 +129,7 @@ func (a *hclogAdapter) ResetNamed(name string) hclog.Logger {
 		logger:      a.logger.WithName(name),
 		impliedArgs: a.impliedArgs,
 		name:        name,
+		terminated:  a.terminated,
 	}
 }
 
@@ -131,6 +157,56 @@ type hclogWriter struct {
 }
 
 func (w *hclogWriter) Write(p []byte) (n int, err error) {
-	w.adapter.Info(string(p))
+	w.adapter.logLine(bytes.TrimRight(p, "\r\n"))
 	return len(p), nil
 }
+
+// logLine logs a line of provider output. JSON-formatted hclog lines are logged
+// at their level with their fields; anything else at info level.
+func (a *hclogAdapter) logLine(line []byte) {
+	if level, msg, args, ok := parseHclogJSON(line); ok {
+		a.Log(level, msg, args...)
+		return
+	}
+	a.Info(string(line))
+}
+
+// parseHclogJSON parses a line written by an hclog logger in JSON format. The
+// @message and @level keys give the message and level; other keys become
+// key/value pairs in sorted order, with the "@" prefix of hclog's own keys
+// (@module, @caller, @timestamp) removed.
+func parseHclogJSON(line []byte) (level hclog.Level, msg string, args []interface{}, ok bool) {
+	if len(line) == 0 || line[0] != '{' {
+		return hclog.NoLevel, "", nil, false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var entry map[string]interface{}
+	if err := dec.Decode(&entry); err != nil {
+		return hclog.NoLevel, "", nil, false
+	}
+	msg, ok = entry["@message"].(string)
+	if !ok {
+		return hclog.NoLevel, "", nil, false
+	}
+
+	level = hclog.Info
+	if s, isString := entry["@level"].(string); isString {
+		if l := hclog.LevelFromString(s); l != hclog.NoLevel {
+			level = l
+		}
+	}
+
+	keys := make([]string, 0, len(entry))
+	for k := range entry {
+		if k != "@message" && k != "@level" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, strings.TrimPrefix(k, "@"), entry[k])
+	}
+	return level, msg, args, true
+}
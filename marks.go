@@ -0,0 +1,131 @@
+package tfclient
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// sensitiveMarkType is the type of sensitiveMark.
+type sensitiveMarkType struct{}
+
+// sensitiveMark marks the values of the attributes a schema declares
+// sensitive.
+var sensitiveMark = sensitiveMarkType{}
+
+// markSensitive returns val with the values of the attributes block
+// declares sensitive marked with sensitiveMark, including in nested
+// attributes and blocks. Like in cty, a mark within a set marks the whole set.
+func markSensitive(val cty.Value, block *tfplugin6.Schema_Block) cty.Value {
+	marks := sensitiveMarks(val, block, nil, nil)
+	if len(marks) == 0 {
+		return val
+	}
+	return val.MarkWithPaths(marks)
+}
+
+func sensitiveMarks(val cty.Value, block *tfplugin6.Schema_Block, path cty.Path, marks []cty.PathValueMarks) []cty.PathValueMarks {
+	if block == nil || val.IsNull() || !val.IsKnown() || !val.Type().IsObjectType() {
+		return marks
+	}
+	for _, attr := range block.Attributes {
+		if !val.Type().HasAttribute(attr.Name) {
+			continue
+		}
+		attrPath := path.Copy().GetAttr(attr.Name)
+		if attr.Sensitive {
+			marks = append(marks, cty.PathValueMarks{Path: attrPath, Marks: cty.NewValueMarks(sensitiveMark)})
+			continue
+		}
+		if obj := attr.NestedType; obj != nil {
+			nested := &tfplugin6.Schema_Block{Attributes: obj.Attributes}
+			single := obj.Nesting == tfplugin6.Schema_Object_SINGLE
+			marks = nestedMarks(val.GetAttr(attr.Name), nested, single, attrPath, marks)
+		}
+	}
+	for _, bt := range block.BlockTypes {
+		if !val.Type().HasAttribute(bt.TypeName) {
+			continue
+		}
+		single := bt.Nesting == tfplugin6.Schema_NestedBlock_SINGLE || bt.Nesting == tfplugin6.Schema_NestedBlock_GROUP
+		marks = nestedMarks(val.GetAttr(bt.TypeName), bt.Block, single, path.Copy().GetAttr(bt.TypeName), marks)
+	}
+	return marks
+}
+
+// nestedMarks adds the marks of a nested attribute or block value: an object,
+// or a collection of objects unless single.
+func nestedMarks(val cty.Value, block *tfplugin6.Schema_Block, single bool, path cty.Path, marks []cty.PathValueMarks) []cty.PathValueMarks {
+	if single {
+		return sensitiveMarks(val, block, path, marks)
+	}
+	if val.IsNull() || !val.IsKnown() || !val.CanIterateElements() {
+		return marks
+	}
+	for it := val.ElementIterator(); it.Next(); {
+		key, elem := it.Element()
+		marks = sensitiveMarks(elem, block, path.Copy().Index(key), marks)
+	}
+	return marks
+}
+
+// sensitivePaths returns the paths of the values of val marked with
+// sensitiveMark, sorted, in the notation of formatPath.
+func sensitivePaths(val cty.Value) []string {
+	_, marks := val.UnmarkDeepWithPaths()
+	var paths []string
+	for _, pvm := range marks {
+		if _, ok := pvm.Marks[sensitiveMark]; ok {
+			paths = append(paths, formatPath(pvm.Path))
+		}
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// unknownPaths returns the paths of the unknown values of val, in the
+// notation of formatPath.
+func unknownPaths(val cty.Value) []string {
+	if val.IsWhollyKnown() {
+		return nil
+	}
+	var paths []string
+	cty.Walk(val, func(path cty.Path, v cty.Value) (bool, error) {
+		if !v.IsKnown() {
+			paths = append(paths, formatPath(path))
+			return false, nil
+		}
+		return true, nil
+	})
+	return paths
+}
+
+// formatPath formats a path into a value like an expression in Terraform,
+// e.g. items[0].tags["env"]. Set elements, which have no key, are [*].
+func formatPath(path cty.Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch step := step.(type) {
+		case cty.GetAttrStep:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(step.Name)
+		case cty.IndexStep:
+			switch step.Key.Type() {
+			case cty.Number:
+				i, _ := step.Key.AsBigFloat().Int64()
+				fmt.Fprintf(&b, "[%d]", i)
+			case cty.String:
+				b.WriteString("[" + strconv.Quote(step.Key.AsString()) + "]")
+			default:
+				b.WriteString("[*]")
+			}
+		}
+	}
+	return b.String()
+}
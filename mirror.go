@@ -0,0 +1,176 @@
+package tfclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// MirrorProvider downloads the release archives of a provider built for
+// platforms, such as "linux_amd64", into dir in the layout written by
+// "terraform providers mirror", which WithFilesystemMirror and Terraform's
+// filesystem_mirror read:
+//
+//	<dir>/<host>/<namespace>/<name>/terraform-provider-<name>_<version>_<os>_<arch>.zip
+//
+// The index.json and <version>.json files of the provider network mirror
+// protocol are kept up to date next to the archives, so that dir can also be
+// served as a network_mirror. The version is resolved once for all platforms,
+// then each archive is downloaded and verified as in DownloadProviderFor. The
+// returned packages' Path is the archive in the mirror.
+func (c *Client) MirrorProvider(ctx context.Context, cfg ProviderConfig, dir string, platforms []string) (_ []ProviderPackage, err error) {
+	attrs := append(providerAttrs(cfg.Namespace, cfg.Name, cfg.Version), attribute.StringSlice("provider.platforms", platforms))
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.MirrorProvider", attrs...)
+	defer func() { endSpan(span, err) }()
+
+	if len(platforms) == 0 {
+		return nil, fmt.Errorf("at least one platform is required to mirror %s", cfg)
+	}
+	providerDir := filepath.Join(dir, filepath.FromSlash(c.providerAddress(cfg.Namespace, cfg.Name)))
+	if err := os.MkdirAll(providerDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mirror directory: %w", err)
+	}
+
+	var pkgs []ProviderPackage
+	archives := make(map[string]mirrorArchive)
+	for _, platform := range platforms {
+		goos, goarch, ok := strings.Cut(platform, "_")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid platform %q, expected os_arch such as linux_amd64", platform)
+		}
+		pkg, err := c.DownloadProviderFor(ctx, cfg, goos, goarch)
+		if err != nil {
+			return nil, err
+		}
+		// Later platforms get the version resolved for the first one
+		cfg.Version = pkg.Provider.Version
+
+		path := filepath.Join(providerDir, pkg.Filename)
+		err = moveFile(pkg.Path, path)
+		if err != nil {
+			os.Remove(pkg.Path)
+			return nil, fmt.Errorf("failed to add %s to the mirror: %w", pkg.Filename, err)
+		}
+		pkg.Path = path
+
+		h1, err := dirhash.HashZip(path, dirhash.Hash1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", pkg.Filename, err)
+		}
+		archives[platform] = mirrorArchive{URL: pkg.Filename, Hashes: []string{h1}}
+		pkgs = append(pkgs, *pkg)
+		c.logger.Info("mirrored provider", "provider", pkg.Provider.String(), "platform", platform, "path", path)
+	}
+
+	if err := updateMirrorIndex(providerDir, cfg.Version, archives); err != nil {
+		return nil, err
+	}
+	return pkgs, nil
+}
+
+// mirrorArchive is an archive of a <version>.json file of the network mirror
+// protocol.
+type mirrorArchive struct {
+	URL    string   `json:"url"`
+	Hashes []string `json:"hashes,omitempty"`
+}
+
+// updateMirrorIndex adds a version to the index.json of a provider directory
+// and archives to its <version>.json, keeping the entries already there.
+func updateMirrorIndex(providerDir, version string, archives map[string]mirrorArchive) error {
+	var index struct {
+		Versions map[string]struct{} `json:"versions"`
+	}
+	if err := readMirrorJSON(filepath.Join(providerDir, "index.json"), &index); err != nil {
+		return err
+	}
+	if index.Versions == nil {
+		index.Versions = make(map[string]struct{})
+	}
+	index.Versions[version] = struct{}{}
+	if err := writeMirrorJSON(filepath.Join(providerDir, "index.json"), index); err != nil {
+		return err
+	}
+
+	var versionFile struct {
+		Archives map[string]mirrorArchive `json:"archives"`
+	}
+	path := filepath.Join(providerDir, version+".json")
+	if err := readMirrorJSON(path, &versionFile); err != nil {
+		return err
+	}
+	if versionFile.Archives == nil {
+		versionFile.Archives = make(map[string]mirrorArchive)
+	}
+	for platform, archive := range archives {
+		versionFile.Archives[platform] = archive
+	}
+	return writeMirrorJSON(path, versionFile)
+}
+
+// readMirrorJSON decodes the file at path into v, if it exists.
+func readMirrorJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeMirrorJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// moveFile moves the file at src to dst, copying it if they are on
+// different filesystems. dst is replaced atomically.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return os.Chmod(dst, 0644)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-"+filepath.Base(dst)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,118 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/hashicorp/go-version"
+	"github.com/infracollect/tf-data-client/cache"
+	"github.com/infracollect/tf-data-client/registry"
+)
+
+// offlineRegistry implements registry.Registry for offline mode
+// (WithOfflineMode): versions are resolved from the cache and the filesystem
+// mirror, if any, and nothing is fetched over the network.
+type offlineRegistry struct {
+	cache  cache.Cache
+	mirror registry.Registry // nil without a filesystem mirror
+}
+
+// GetVersions returns the cached and mirrored versions of a provider.
+func (r *offlineRegistry) GetVersions(ctx context.Context, namespace, name string) ([]registry.VersionInfo, error) {
+	versions, err := r.versions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, &ErrOfflineCacheMiss{Namespace: namespace, Name: name, OS: runtime.GOOS, Arch: runtime.GOARCH}
+	}
+
+	result := make([]registry.VersionInfo, len(versions))
+	for i, v := range versions {
+		result[i] = registry.VersionInfo{Version: v}
+	}
+	return result, nil
+}
+
+// GetLatestVersion returns the latest stable version that is cached or mirrored.
+func (r *offlineRegistry) GetLatestVersion(ctx context.Context, namespace, name string) (string, error) {
+	versions, err := r.versions(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	latest := newestVersion(versions, nil, false)
+	if latest == "" {
+		return "", &ErrOfflineCacheMiss{Namespace: namespace, Name: name, OS: runtime.GOOS, Arch: runtime.GOARCH, Available: versions}
+	}
+	return latest, nil
+}
+
+// GetDownloadInfo returns the location of a provider build in the mirror. It
+// is only called for providers missing from the cache, so without a mirror
+// build it fails with *ErrOfflineCacheMiss.
+func (r *offlineRegistry) GetDownloadInfo(ctx context.Context, namespace, name, version, goos, goarch string) (*registry.DownloadInfo, error) {
+	if r.mirror != nil {
+		if info, err := r.mirror.GetDownloadInfo(ctx, namespace, name, version, goos, goarch); err == nil {
+			return info, nil
+		}
+	}
+
+	available, err := r.versions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &ErrOfflineCacheMiss{
+		Namespace: namespace,
+		Name:      name,
+		Version:   version,
+		OS:        goos,
+		Arch:      goarch,
+		Available: available,
+	}
+}
+
+// DownloadToPath copies a provider archive from the mirror.
+func (r *offlineRegistry) DownloadToPath(ctx context.Context, info *registry.DownloadInfo, destPath string) error {
+	if r.mirror == nil {
+		return fmt.Errorf("offline mode: cannot download %s", info.DownloadURL)
+	}
+	return r.mirror.DownloadToPath(ctx, info, destPath)
+}
+
+// versions lists the cached and mirrored versions of a provider, oldest
+// first. A provider missing from the mirror isn't an error.
+func (r *offlineRegistry) versions(ctx context.Context, namespace, name string) ([]string, error) {
+	seen := make(map[string]bool)
+	var versions []string
+	add := func(v string) {
+		if !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+
+	if lister, ok := r.cache.(cache.VersionLister); ok {
+		cached, err := lister.Versions(ctx, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range cached {
+			add(v)
+		}
+	}
+	if r.mirror != nil {
+		if mirrored, err := r.mirror.GetVersions(ctx, namespace, name); err == nil {
+			for _, v := range mirrored {
+				add(v.Version)
+			}
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, erri := version.NewVersion(versions[i])
+		vj, errj := version.NewVersion(versions[j])
+		return erri == nil && errj == nil && vi.LessThan(vj)
+	})
+	return versions, nil
+}
@@ -1,11 +1,24 @@
 package tfclient
 
 import (
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/infracollect/tf-data-client/cache"
 	"github.com/infracollect/tf-data-client/registry"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // Option configures a Client.
@@ -36,6 +49,94 @@ func WithCacheDir(dir string) Option {
 	}
 }
 
+// WithCacheLimits bounds the filesystem cache to maxBytes of unpacked
+// providers and maxEntries provider versions, evicting the least recently
+// used ones after each install; 0 disables a limit. It applies to the default
+// cache and the one set with WithCacheDir.
+func WithCacheLimits(maxBytes int64, maxEntries int) Option {
+	return func(cl *Client) error {
+		if maxBytes < 0 || maxEntries < 0 {
+			return fmt.Errorf("cache limits must not be negative")
+		}
+		cl.cacheMaxBytes = maxBytes
+		cl.cacheMaxEntries = maxEntries
+		return nil
+	}
+}
+
+// WithCacheDedup stores files identical across cached provider versions once,
+// hard linking them into each version directory, to save disk space when many
+// versions are cached. It applies to the default cache and the one set with
+// WithCacheDir, on platforms with hard links other than Windows.
+func WithCacheDedup() Option {
+	return func(cl *Client) error {
+		cl.cacheDedup = true
+		return nil
+	}
+}
+
+// WithExtractLimits bounds the total uncompressed size and the number of files
+// of the provider archives extracted into the cache, rejecting larger ones
+// with ErrUnsafeArchive; 0 keeps the default of cache.DefaultExtractLimits and
+// a negative value disables a limit. Symlinks and absolute paths are always
+// rejected.
+func WithExtractLimits(maxBytes int64, maxFiles int) Option {
+	return func(cl *Client) error {
+		cl.extractLimits = &cache.ExtractLimits{MaxBytes: maxBytes, MaxFiles: maxFiles}
+		return nil
+	}
+}
+
+// WithCacheLockTimeout bounds how long installing or evicting a provider
+// waits for another process holding its cache lock, failing with
+// cache.ErrLockTimeout, e.g. when that process hangs. By default it waits
+// until the context is done.
+func WithCacheLockTimeout(timeout time.Duration) Option {
+	return func(cl *Client) error {
+		if timeout <= 0 {
+			return fmt.Errorf("cache lock timeout must be positive")
+		}
+		cl.cacheLockTimeout = timeout
+		return nil
+	}
+}
+
+// WithCacheTTL revalidates cached providers last validated more than ttl ago
+// before using them: the unpacked files must still match the hash recorded
+// when they were installed, and the archive they came from must still match
+// the registry's checksum. Entries failing either check, or cached before
+// metadata was recorded, are purged and downloaded again. If the registry
+// can't be reached, entries whose files are intact are used as they are.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(cl *Client) error {
+		if ttl < 0 {
+			return fmt.Errorf("cache TTL must not be negative, got %s", ttl)
+		}
+		cl.cacheTTL = ttl
+		return nil
+	}
+}
+
+// WithTerraformPluginCache uses providers already installed by Terraform in a
+// plugin cache directory, or a .terraform/providers directory, rather than
+// downloading them again. dir defaults to TF_PLUGIN_CACHE_DIR. If writable is
+// set, providers missing from dir are installed there for Terraform to reuse
+// too; otherwise they go to the cache set with WithCache or WithCacheDir, or
+// the default one.
+func WithTerraformPluginCache(dir string, writable bool) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			dir = os.Getenv("TF_PLUGIN_CACHE_DIR")
+		}
+		if dir == "" {
+			return fmt.Errorf("plugin cache directory is required when TF_PLUGIN_CACHE_DIR is not set")
+		}
+		cl.pluginCacheDir = dir
+		cl.pluginCacheWritable = writable
+		return nil
+	}
+}
+
 // WithRegistry sets a custom registry implementation.
 func WithRegistry(r registry.Registry) Option {
 	return func(cl *Client) error {
@@ -47,7 +148,637 @@ func WithRegistry(r registry.Registry) Option {
 // WithHTTPClient sets a custom HTTP client for the default registry.
 func WithHTTPClient(client *http.Client) Option {
 	return func(cl *Client) error {
-		cl.registry = registry.NewTerraformRegistry(client)
+		cl.httpClient = client
+		return nil
+	}
+}
+
+// WithRegistryCredentials sets the registry API tokens by hostname, instead of
+// loading them from the Terraform CLI configuration and TF_TOKEN_<host>
+// variables. Pass an empty map to send no credentials. Ignored with
+// WithRegistry.
+func WithRegistryCredentials(creds registry.Credentials) Option {
+	return func(cl *Client) error {
+		cl.registryCredentials = make(registry.Credentials, len(creds))
+		for host, token := range creds {
+			cl.registryCredentials[strings.ToLower(host)] = token
+		}
+		return nil
+	}
+}
+
+// WithFilesystemMirror serves providers from a local directory populated by
+// "terraform providers mirror" instead of the registry, for fully offline
+// operation. WithRegistryHost selects the host directory within it. Ignored
+// with WithRegistry.
+func WithFilesystemMirror(dir string) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			return fmt.Errorf("filesystem mirror directory is required")
+		}
+		cl.mirrorDir = dir
+		return nil
+	}
+}
+
+// WithRegistryRetry sets how the default registry retries requests failing
+// with a transient network error, 429 or 5xx, instead of
+// registry.DefaultRetryPolicy. Ignored with WithRegistry.
+func WithRegistryRetry(policy registry.RetryPolicy) Option {
+	return func(cl *Client) error {
+		if policy.MaxAttempts < 1 {
+			return fmt.Errorf("registry retry attempts must be at least 1, got %d", policy.MaxAttempts)
+		}
+		if policy.MinBackoff < 0 || policy.MaxBackoff < policy.MinBackoff {
+			return fmt.Errorf("invalid registry retry backoff %s-%s", policy.MinBackoff, policy.MaxBackoff)
+		}
+		cl.registryOpts = append(cl.registryOpts, registry.WithRetryPolicy(policy))
+		return nil
+	}
+}
+
+// WithRegistryCache caches registry version lists and download info in dir,
+// serving them without a request for ttl and revalidating them with their
+// ETag afterwards. Repeated CreateProvider calls, and CI runs sharing dir,
+// then don't hit registry rate limits. Ignored with WithRegistry.
+func WithRegistryCache(dir string, ttl time.Duration) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			return fmt.Errorf("registry cache directory is required")
+		}
+		if ttl < 0 {
+			return fmt.Errorf("registry cache TTL must not be negative, got %s", ttl)
+		}
+		cl.registryOpts = append(cl.registryOpts, registry.WithResponseCache(dir, ttl))
+		return nil
+	}
+}
+
+// WithTrustPolicy sets which signatures of provider releases downloaded from
+// the default registry are accepted. By default any signing key the registry
+// publishes is, as with Terraform; registry.TrustOfficial only accepts
+// providers signed by HashiCorp. Ignored with WithRegistry.
+func WithTrustPolicy(policy registry.TrustPolicy) Option {
+	return func(cl *Client) error {
+		if policy.MinTrust < registry.TrustCommunity || policy.MinTrust > registry.TrustOfficial {
+			return fmt.Errorf("invalid trust level %s", policy.MinTrust)
+		}
+		cl.registryOpts = append(cl.registryOpts, registry.WithTrustPolicy(policy))
+		return nil
+	}
+}
+
+// WithRegistryHost selects the provider registry by hostname instead of
+// registry.terraform.io, e.g. registry.OpenTofuRegistryHost or a private
+// registry found through service discovery. Ignored with WithRegistry.
+func WithRegistryHost(host string) Option {
+	return func(cl *Client) error {
+		if host == "" || strings.ContainsAny(host, "/ ") {
+			return fmt.Errorf("invalid registry host %q", host)
+		}
+		cl.registryHost = host
+		return nil
+	}
+}
+
+// WithHealthCheck enables a background health check that pings every running
+// provider at the given interval. Providers whose process has exited are marked
+// unhealthy and an error is logged through the client logger.
+func WithHealthCheck(interval time.Duration) Option {
+	return func(cl *Client) error {
+		if interval <= 0 {
+			return fmt.Errorf("health check interval must be positive, got %s", interval)
+		}
+		cl.healthCheckInterval = interval
+		return nil
+	}
+}
+
+// WithMaxProviders bounds the number of running providers. Launching another
+// provider stops the least recently used one with no call in progress, which
+// later calls through it fail; CreateProvider fails with ErrTooManyProviders
+// if all are busy.
+func WithMaxProviders(n int) Option {
+	return func(cl *Client) error {
+		if n < 1 {
+			return fmt.Errorf("max providers must be at least 1, got %d", n)
+		}
+		cl.maxProviders = n
+		return nil
+	}
+}
+
+// defaultStopGracePeriod is how long stopping providers waits by default, see
+// WithStopGracePeriod.
+const defaultStopGracePeriod = 5 * time.Second
+
+// WithStopGracePeriod sets how long stopping a provider, e.g. by Close,
+// waits at each step: after the StopProvider RPC asking it to cancel its
+// operations, for its calls in progress to complete, then after asking its
+// process to exit (SIGTERM), for it to exit before killing it. Zero kills
+// providers at once. It is 5s by default.
+func WithStopGracePeriod(d time.Duration) Option {
+	return func(cl *Client) error {
+		if d < 0 {
+			return fmt.Errorf("stop grace period must not be negative, got %s", d)
+		}
+		cl.stopGrace = d
+		return nil
+	}
+}
+
+// WithSchemaRefresh makes providers' schemas be fetched from them on each
+// launch, ignoring the schemas cached by the filesystem cache, which are
+// updated instead. Use it if a cached schema is wrong, e.g. after replacing a
+// provider executable while keeping its modification time.
+func WithSchemaRefresh() Option {
+	return func(cl *Client) error {
+		cl.refreshSchemas = true
+		return nil
+	}
+}
+
+// WithLazySchemas defers fetching providers' schemas, which takes seconds for
+// big providers, to the first call needing them. Providers list their data
+// sources with the GetMetadata RPC instead, so ListDataSources doesn't need
+// the schema. The plugin protocol has no call for the schema of a single data
+// source, so reading one still fetches the whole schema, unless cached on
+// disk. Providers without GetMetadata get their schema fetched at launch.
+func WithLazySchemas() Option {
+	return func(cl *Client) error {
+		cl.lazySchemas = true
+		return nil
+	}
+}
+
+// WithExactNumbers makes numbers in data source results json.Number instead
+// of float64, keeping the precision of big integers and decimals, e.g. IDs
+// above 2^53. json.Number is encoded as is by encoding/json.
+func WithExactNumbers() Option {
+	return func(cl *Client) error {
+		cl.values.exactNumbers = true
+		return nil
+	}
+}
+
+// WithSortedSets sorts the elements of sets of objects, lists or maps in data
+// source results by their JSON encoding, for results to diff cleanly across
+// runs and versions. Such sets are otherwise in a consistent order left
+// undefined by go-cty, which may change. Sets of strings, numbers or booleans
+// are always sorted by value, and JSON encoding sorts map keys.
+func WithSortedSets() Option {
+	return func(cl *Client) error {
+		cl.values.sortSets = true
+		return nil
+	}
+}
+
+// WithCrashBundleDir writes a JSON debug bundle in dir when a provider
+// process crashes during a call, holding the ErrProviderCrashed details: the
+// RPCs in flight, the exit status and the tail of the provider's stderr.
+// The bundle's path is reported in ErrProviderCrashed.BundlePath.
+func WithCrashBundleDir(dir string) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			return fmt.Errorf("crash bundle directory is required")
+		}
+		cl.crashDir = dir
+		return nil
+	}
+}
+
+// WithAutoRestart enables supervision of provider processes. When a provider
+// process dies, it is relaunched, its schema is re-fetched, the last Configure
+// call is replayed and the in-flight request is retried once.
+func WithAutoRestart() Option {
+	return func(cl *Client) error {
+		cl.autoRestart = true
+		return nil
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker per provider: after
+// b.Failures consecutive failed RPCs, calls to the provider fail fast with
+// ErrProviderUnhealthy, wrapping ErrCircuitOpen, for b.Cooldown, so that a
+// wedged provider doesn't stall its callers. RPC errors, crashes and calls
+// running out of the provider's call timeout count as failures; calls whose
+// context was canceled or reached its deadline don't, and errors the provider
+// reported in diagnostics count as successes, as it answered.
+func WithCircuitBreaker(b CircuitBreaker) Option {
+	return func(cl *Client) error {
+		if b.Failures < 1 {
+			return fmt.Errorf("circuit breaker failures must be at least 1, got %d", b.Failures)
+		}
+		if b.Cooldown <= 0 {
+			return fmt.Errorf("circuit breaker cooldown must be positive, got %s", b.Cooldown)
+		}
+		cl.circuitBreaker = &b
+		return nil
+	}
+}
+
+// WithDefaultCallTimeout bounds each call to a provider, e.g. Configure or
+// ReadDataSource, to d, on top of the deadline of its context, so that a
+// provider that hangs doesn't hold its caller forever.
+// ProviderConfig.CallTimeout overrides it per provider. Calls that run out of
+// time, whether from this timeout or their context's deadline, fail with
+// ErrCallTimeout.
+func WithDefaultCallTimeout(d time.Duration) Option {
+	return func(cl *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("call timeout must be positive, got %s", d)
+		}
+		cl.callTimeout = d
+		return nil
+	}
+}
+
+// WithProviderPool makes CreateProvider launch up to n identical processes per
+// provider. Reads are sent to the least busy process and Configure is applied
+// to every process in the pool.
+func WithProviderPool(n int) Option {
+	return func(cl *Client) error {
+		if n < 1 {
+			return fmt.Errorf("provider pool size must be at least 1, got %d", n)
+		}
+		cl.poolSize = n
+		return nil
+	}
+}
+
+// WithRemoteAgent runs providers on a remote host through a provider execution
+// agent (see NewAgentServer and cmd/tf-data-agent) instead of launching them
+// locally. Schema, Configure and ReadDataSource calls are proxied over a gRPC
+// channel secured with creds.
+func WithRemoteAgent(target string, creds credentials.TransportCredentials) Option {
+	return func(cl *Client) error {
+		if target == "" {
+			return fmt.Errorf("remote agent target is required")
+		}
+		if creds == nil {
+			return fmt.Errorf("remote agent transport credentials are required")
+		}
+		cl.agent = &remoteAgent{target: target, creds: creds}
+		return nil
+	}
+}
+
+// WithProcessLimits caps the memory, CPU and open files of every launched
+// provider process. A provider killed for exceeding its limits makes the
+// in-flight call fail with ErrProviderKilled.
+func WithProcessLimits(limits ProcessLimits) Option {
+	return func(cl *Client) error {
+		if limits.CPU < 0 {
+			return fmt.Errorf("CPU limit must not be negative, got %v", limits.CPU)
+		}
+		cl.processLimits = &limits
+		return nil
+	}
+}
+
+// WithSandbox runs every provider process under the given sandbox policy,
+// restricting filesystem writes and network access. ProviderConfig.Sandbox
+// overrides it for a single provider.
+func WithSandbox(policy SandboxPolicy) Option {
+	return func(cl *Client) error {
+		cl.sandbox = &policy
+		return nil
+	}
+}
+
+// WithProviderEnv sets environment variables for every launched provider, on
+// top of the inherited environment. ProviderConfig.Env takes precedence.
+func WithProviderEnv(env map[string]string) Option {
+	return func(cl *Client) error {
+		if cl.env == nil {
+			cl.env = make(map[string]string, len(env))
+		}
+		maps.Copy(cl.env, env)
+		return nil
+	}
+}
+
+// WithCleanEnv launches providers with an empty environment instead of
+// inheriting this process's, so only variables set through WithProviderEnv and
+// ProviderConfig.Env reach them.
+func WithCleanEnv() Option {
+	return func(cl *Client) error {
+		cl.cleanEnv = true
+		return nil
+	}
+}
+
+// WithProviderOutput copies the raw stdout and stderr of every provider
+// process to w, e.g. to keep panics and plain-text warnings in a file. Output
+// is logged line by line at info level regardless.
+func WithProviderOutput(w io.Writer) Option {
+	return func(cl *Client) error {
+		if w == nil {
+			return fmt.Errorf("provider output writer must not be nil")
+		}
+		cl.providerOutput = &syncWriter{w: w}
+		return nil
+	}
+}
+
+// WithUnaryInterceptor adds interceptors around every unary gRPC call made to
+// a provider, e.g. to add auth, metrics or request logging. Interceptors run
+// in the order they are added, across calls to this option.
+func WithUnaryInterceptor(interceptors ...grpc.UnaryClientInterceptor) Option {
+	return func(cl *Client) error {
+		for _, i := range interceptors {
+			if i == nil {
+				return fmt.Errorf("unary interceptor must not be nil")
+			}
+		}
+		cl.unaryInterceptors = append(cl.unaryInterceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithStreamInterceptor adds interceptors around every streaming gRPC call
+// made to a provider. Interceptors run in the order they are added.
+func WithStreamInterceptor(interceptors ...grpc.StreamClientInterceptor) Option {
+	return func(cl *Client) error {
+		for _, i := range interceptors {
+			if i == nil {
+				return fmt.Errorf("stream interceptor must not be nil")
+			}
+		}
+		cl.streamInterceptors = append(cl.streamInterceptors, interceptors...)
+		return nil
+	}
+}
+
+// WithTracerProvider records OpenTelemetry spans for registry lookups,
+// downloads, cache operations, provider launches, Configure and ReadDataSource.
+// Spans are children of the span in the context passed to each call.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(cl *Client) error {
+		if tp == nil {
+			return fmt.Errorf("tracer provider must not be nil")
+		}
+		cl.tracer = tp.Tracer(tracerName)
+		return nil
+	}
+}
+
+// WithHooks sends the events of the client's providers, such as downloads,
+// launches, exits and reads, to hooks, e.g. for progress UIs, metrics or
+// alerting. Hooks can also be added later with Client.Subscribe.
+func WithHooks(hooks ...EventHook) Option {
+	return func(cl *Client) error {
+		for _, hook := range hooks {
+			if hook == nil {
+				return fmt.Errorf("event hook must not be nil")
+			}
+			cl.events.add(hook)
+		}
+		return nil
+	}
+}
+
+// WithResultCache serves data source reads of providers created by the
+// client from rc while their results haven't expired, reading and caching
+// them otherwise. The caller closes rc, which may be shared by clients.
+func WithResultCache(rc *ResultCache) Option {
+	return func(cl *Client) error {
+		if rc == nil {
+			return fmt.Errorf("result cache must not be nil")
+		}
+		cl.resultCache = rc
+		return nil
+	}
+}
+
+// WithAuditHook sends an AuditEvent to hook for every Configure and
+// ReadDataSource call on providers created by the client.
+func WithAuditHook(hook AuditHook) Option {
+	return func(cl *Client) error {
+		if hook == nil {
+			return fmt.Errorf("audit hook must not be nil")
+		}
+		cl.auditHook = hook
+		return nil
+	}
+}
+
+// WithConfigResolver rewrites the configurations passed to Configure,
+// Reconfigure, ReadDataSource and the validation methods with resolvers, in
+// order, before checking them against the schema, e.g. with Interpolator to
+// substitute ${env:VAR} and ${file:/path} references. Audit events hash the
+// configurations as passed, before resolution.
+func WithConfigResolver(resolvers ...ConfigResolver) Option {
+	return func(cl *Client) error {
+		for _, r := range resolvers {
+			if r == nil {
+				return fmt.Errorf("config resolver must not be nil")
+			}
+		}
+		cl.resolvers = append(cl.resolvers, resolvers...)
+		return nil
+	}
+}
+
+// WithRecording records the schema and every ReadDataSource response of the
+// providers created by the client under dir, one JSON file per provider
+// version, for later use with WithReplay.
+func WithRecording(dir string) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			return fmt.Errorf("recording directory is required")
+		}
+		cl.recordDir = dir
+		return nil
+	}
+}
+
+// WithReplay serves providers from interactions recorded with WithRecording
+// instead of downloading and launching them. Reads with a configuration that
+// wasn't recorded fail. If ProviderConfig.Version is empty, the only recorded
+// version is used.
+func WithReplay(dir string) Option {
+	return func(cl *Client) error {
+		if dir == "" {
+			return fmt.Errorf("replay directory is required")
+		}
+		cl.replayDir = dir
+		return nil
+	}
+}
+
+// WithInProcessProvider serves namespace/name at version from server, running
+// in this process, instead of downloading and launching a binary. Provider
+// authors can test their data sources through this client, and small custom
+// providers can be bundled without separate binaries. CreateProvider uses it
+// when ProviderConfig.Version is empty or equal to version.
+func WithInProcessProvider(namespace, name, version string, server tfprotov6.ProviderServer) Option {
+	return func(cl *Client) error {
+		if namespace == "" || name == "" || version == "" {
+			return fmt.Errorf("in-process provider namespace, name and version are required")
+		}
+		if server == nil {
+			return fmt.Errorf("in-process provider server must not be nil")
+		}
+		if cl.inProcess == nil {
+			cl.inProcess = make(map[string]inProcessProvider)
+		}
+		cl.inProcess[namespace+"/"+name] = inProcessProvider{version: version, server: server}
+		return nil
+	}
+}
+
+// WithReadRateLimit throttles the ReadDataSource calls of providers of
+// source ("namespace/name") to rps per second, with bursts of up to burst
+// calls, e.g. to stay under the rate limits of a cloud API during bulk reads.
+// The limit is shared by every provider of source the client creates,
+// whatever their version or alias. Calls wait for their turn, failing if
+// their context is done first; reads served by the result cache don't count.
+func WithReadRateLimit(source string, rps float64, burst int) Option {
+	return func(cl *Client) error {
+		namespace, name, ok := strings.Cut(source, "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("read rate limit source must be namespace/name, got %q", source)
+		}
+		if rps <= 0 {
+			return fmt.Errorf("read rate limit of %s must be positive, got %v", source, rps)
+		}
+		if burst < 1 {
+			return fmt.Errorf("read rate limit burst of %s must be at least 1, got %d", source, burst)
+		}
+		if cl.readLimiters == nil {
+			cl.readLimiters = make(map[string]*rate.Limiter)
+		}
+		cl.readLimiters[source] = rate.NewLimiter(rate.Limit(rps), burst)
+		return nil
+	}
+}
+
+// WithDevOverride launches the local binary at path for source
+// ("namespace/name"), skipping registry resolution and the cache, like the
+// Terraform CLI dev_overrides setting. It also takes precedence over
+// WithReplay and WithRemoteAgent. Without a requested version the provider
+// reports version "0.0.0-dev".
+func WithDevOverride(source, path string) Option {
+	return func(cl *Client) error {
+		namespace, name, ok := strings.Cut(source, "/")
+		if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+			return fmt.Errorf("dev override source must be namespace/name, got %q", source)
+		}
+		if path == "" {
+			return fmt.Errorf("dev override path for %s is required", source)
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid dev override path for %s: %w", source, err)
+		}
+		if cl.devOverrides == nil {
+			cl.devOverrides = make(map[string]string)
+		}
+		cl.devOverrides[source] = abs
+		return nil
+	}
+}
+
+// WithLockFile pins providers to the versions and hashes recorded in a
+// Terraform dependency lock file (.terraform.lock.hcl), as written by
+// WriteLockFile or "terraform init". CreateProvider then uses the locked
+// version when none is requested, fails with *ErrLockMismatch if the
+// requested version or constraint excludes it, and refuses packages whose
+// hashes aren't in the lock file. Providers missing from the lock file aren't
+// restricted.
+func WithLockFile(path string) Option {
+	return func(cl *Client) error {
+		locks, err := readLockFile(path)
+		if err != nil {
+			return err
+		}
+		cl.lockFile = locks
+		return nil
+	}
+}
+
+// WithProviderPolicy restricts which providers CreateProvider may run, by
+// source, minimum version and signature, failing with *ErrProviderDenied
+// before anything is downloaded or launched.
+func WithProviderPolicy(policy ProviderPolicy) Option {
+	return func(cl *Client) error {
+		if err := policy.validate(); err != nil {
+			return err
+		}
+		cl.policy = &policy
+		return nil
+	}
+}
+
+// WithOfflineMode forbids network access: versions are resolved from the
+// cache and the filesystem mirror (WithFilesystemMirror) only, registries
+// other than a filesystem mirror are ignored, and providers that aren't
+// available locally fail with *ErrOfflineCacheMiss. Incompatible with
+// WithRemoteAgent.
+func WithOfflineMode() Option {
+	return func(cl *Client) error {
+		cl.offline = true
+		return nil
+	}
+}
+
+// WithPrefetchConcurrency sets how many providers Prefetch resolves and
+// downloads at once (default 4).
+func WithPrefetchConcurrency(n int) Option {
+	return func(cl *Client) error {
+		if n < 1 {
+			return fmt.Errorf("prefetch concurrency must be at least 1, got %d", n)
+		}
+		cl.prefetchConcurrency = n
+		return nil
+	}
+}
+
+// WithDownloadProgress calls fn as provider archives download, e.g. to render
+// a progress bar or log long downloads. fn is called often, possibly from
+// several goroutines at once during Prefetch, and must be quick.
+func WithDownloadProgress(fn func(DownloadProgress)) Option {
+	return func(cl *Client) error {
+		cl.downloadProgress = fn
+		return nil
+	}
+}
+
+// defaultTerraformVersion is the Terraform version providers are told they
+// run under by default.
+const defaultTerraformVersion = "1.0.0"
+
+// WithTerraformVersion sets the Terraform version sent to providers when
+// configuring them, "1.0.0" by default. Some providers enable features or
+// warn based on it, so present the version of the Terraform CLI the
+// configurations target, e.g. "1.9.8". ProviderConfig.TerraformVersion
+// overrides it per provider.
+func WithTerraformVersion(v string) Option {
+	return func(cl *Client) error {
+		if err := checkTerraformVersion(v); err != nil {
+			return err
+		}
+		cl.terraformVersion = v
+		return nil
+	}
+}
+
+// checkTerraformVersion checks that v is a version number.
+func checkTerraformVersion(v string) error {
+	if _, err := version.NewVersion(v); err != nil {
+		return fmt.Errorf("invalid Terraform version %q: %w", v, err)
+	}
+	return nil
+}
+
+// WithAllowPrereleases lets CreateProvider resolve the latest version, or a
+// version constraint, to a prerelease such as "3.0.0-rc1". By default only
+// stable versions are considered, unless a constraint names a prerelease.
+// ProviderConfig.AllowPrereleases overrides this per provider.
+func WithAllowPrereleases() Option {
+	return func(cl *Client) error {
+		cl.allowPrereleases = true
 		return nil
 	}
 }
@@ -0,0 +1,187 @@
+package tfclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// OrphanProcess is a provider process left running by a client process that
+// exited without stopping it, e.g. because it crashed.
+type OrphanProcess struct {
+	PID        int       `json:"pid"`
+	Provider   string    `json:"provider"` // namespace/name@version
+	Executable string    `json:"executable"`
+	OwnerPID   int       `json:"owner_pid"` // of the client process that launched it
+	Started    time.Time `json:"started"`
+}
+
+// processRecord is kept in the processes directory of the filesystem cache
+// while a provider process runs, so that it can be found if its client
+// process exits without stopping it.
+type processRecord struct {
+	OrphanProcess
+	Host string `json:"host"`
+}
+
+// processesDir returns the directory of the process records of a cache
+// directory.
+func processesDir(cacheDir string) string {
+	return filepath.Join(cacheDir, ".processes")
+}
+
+// trackProcess records a launched provider process in dir, returning the
+// path of the record to remove once it is stopped, or "" if it isn't
+// tracked.
+func trackProcess(dir string, pid int, provider string, logger logr.Logger) string {
+	exe := processExecutable(pid)
+	if dir == "" || exe == "" {
+		return ""
+	}
+	host, _ := os.Hostname()
+	rec := processRecord{
+		OrphanProcess: OrphanProcess{
+			PID:        pid,
+			Provider:   provider,
+			Executable: exe,
+			OwnerPID:   os.Getpid(),
+			Started:    time.Now(),
+		},
+		Host: host,
+	}
+	data, err := json.Marshal(rec)
+	if err == nil {
+		err = os.MkdirAll(dir, 0o755)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d-%d.json", rec.OwnerPID, pid))
+	if err == nil {
+		err = os.WriteFile(path, data, 0o644)
+	}
+	if err != nil {
+		logger.Error(err, "failed to record provider process", "provider", provider, "pid", pid)
+		return ""
+	}
+	return path
+}
+
+// FindOrphans returns the provider processes launched by client processes,
+// on this host and with the same filesystem cache, that exited without
+// stopping them. Records of processes that are gone are removed. Only
+// supported on Linux.
+func (c *Client) FindOrphans(ctx context.Context) ([]OrphanProcess, error) {
+	orphans, _, err := c.findOrphans(ctx)
+	return orphans, err
+}
+
+// ReapOrphans terminates the processes returned by FindOrphans, killing those
+// that haven't exited after the stop grace period (WithStopGracePeriod), and
+// returns them.
+func (c *Client) ReapOrphans(ctx context.Context) ([]OrphanProcess, error) {
+	orphans, records, err := c.findOrphans(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	var reaped []OrphanProcess
+	for i, orphan := range orphans {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		if err := terminateProcess(orphan.PID, c.stopGrace); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop provider %s (pid %d): %w", orphan.Provider, orphan.PID, err))
+			continue
+		}
+		c.logger.Info("reaped orphaned provider process", "provider", orphan.Provider, "pid", orphan.PID, "owner_pid", orphan.OwnerPID)
+		os.Remove(records[i])
+		reaped = append(reaped, orphan)
+	}
+	return reaped, errors.Join(errs...)
+}
+
+// findOrphans returns the orphaned processes and the paths of their records.
+func (c *Client) findOrphans(ctx context.Context) ([]OrphanProcess, []string, error) {
+	if !processTracking {
+		return nil, nil, fmt.Errorf("finding orphaned provider processes is not supported on %s", runtime.GOOS)
+	}
+	if c.processesDir == "" {
+		return nil, nil, errors.New("finding orphaned provider processes requires the filesystem cache")
+	}
+	entries, err := os.ReadDir(c.processesDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list provider processes: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	var (
+		orphans []OrphanProcess
+		records []string
+	)
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(c.processesDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // removed meanwhile
+		}
+		var rec processRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			c.logger.Error(err, "invalid provider process record", "path", path)
+			continue
+		}
+
+		switch {
+		case rec.Host != host:
+			// Launched on another host sharing the cache
+		case rec.OwnerPID == os.Getpid() || processExecutable(rec.OwnerPID) != "":
+			// Its client process still runs
+		case processExecutable(rec.PID) != rec.Executable:
+			// Exited, and its PID may have been reused since
+			os.Remove(path)
+		default:
+			orphans = append(orphans, rec.OrphanProcess)
+			records = append(records, path)
+		}
+	}
+	return orphans, records, nil
+}
+
+// terminateProcess sends SIGTERM to a process, and kills it if it hasn't
+// exited after grace.
+func terminateProcess(pid int, grace time.Duration) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if grace > 0 && process.Signal(syscall.SIGTERM) == nil {
+		deadline := time.Now().Add(grace)
+		for processExecutable(pid) != "" && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if processExecutable(pid) == "" {
+			return nil
+		}
+	}
+	if err := process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	return nil
+}
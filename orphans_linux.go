@@ -0,0 +1,25 @@
+package tfclient
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// processTracking reports whether provider processes are recorded so that
+// orphans can be found.
+const processTracking = true
+
+// processExecutable returns the path of the executable of a running process,
+// or "" if there is no such process.
+func processExecutable(pid int) string {
+	if pid <= 0 {
+		return ""
+	}
+	exe, err := os.Readlink("/proc/" + strconv.Itoa(pid) + "/exe")
+	if err != nil {
+		return ""
+	}
+	// The cache may have been pruned since the process started
+	return strings.TrimSuffix(exe, " (deleted)")
+}
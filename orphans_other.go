@@ -0,0 +1,12 @@
+//go:build !linux
+
+package tfclient
+
+// processTracking reports whether provider processes are recorded so that
+// orphans can be found.
+const processTracking = false
+
+// processExecutable returns "": processes aren't tracked on this platform.
+func processExecutable(pid int) string {
+	return ""
+}
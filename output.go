@@ -0,0 +1,71 @@
+package tfclient
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// maxOutputLine caps how much of an unterminated line is buffered before it is
+// logged anyway.
+const maxOutputLine = 64 * 1024
+
+// outputWriter logs each line a provider writes to its stdout or stderr, and
+// copies the raw output to the WithProviderOutput writer, if any.
+type outputWriter struct {
+	logger *hclogAdapter
+	raw    io.Writer
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func newOutputWriter(logger logr.Logger, stream string, raw io.Writer) *outputWriter {
+	return &outputWriter{logger: &hclogAdapter{logger: logger.WithValues("stream", stream)}, raw: raw}
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	if w.raw != nil {
+		// Output is best effort: a failing writer must not break the provider.
+		_, _ = w.raw.Write(p)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) >= maxOutputLine {
+		w.log(w.buf)
+		w.buf = nil
+	}
+	return len(p), nil
+}
+
+func (w *outputWriter) log(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if len(line) > 0 {
+		w.logger.logLine(line)
+	}
+}
+
+// syncWriter serializes writes from concurrently running providers.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,598 @@
+package tfclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultPipelineConcurrency is the number of nodes RunPipeline runs at
+// once, unless set in Pipeline.Concurrency.
+const defaultPipelineConcurrency = 4
+
+// Pipeline is a graph of named nodes, providers to configure and data sources
+// to read, run by Client.RunPipeline in dependency order and otherwise in
+// parallel: "terraform plan" for data sources only.
+//
+// Configurations can refer to the states of reads with
+// ${reads.<name>.state.<path>}, e.g. ${reads.vpc.state.subnets[0].id}, which
+// makes the node depend on the read. A string that is a single reference
+// takes the referenced value as is, which can be a number, list or object;
+// references within strings are formatted, lists and objects as JSON. "$${"
+// escapes a reference.
+type Pipeline struct {
+	Providers []PipelineProvider
+	Reads     []PipelineRead
+	// Concurrency is the number of nodes run at once, 4 if 0.
+	Concurrency int
+}
+
+// PipelineNode holds the settings common to the nodes of a Pipeline.
+type PipelineNode struct {
+	// Name identifies the node in dependencies, references and results.
+	// Names are unique across the providers and reads of a pipeline.
+	Name string
+	// DependsOn names the nodes that must succeed before this one runs, in
+	// addition to those its configuration refers to.
+	DependsOn []string
+	// Retries is the number of times a failed node is retried, with an
+	// exponential backoff from one second. Invalid configurations and missing
+	// data sources aren't retried.
+	Retries int
+	// Timeout bounds each attempt, none if 0.
+	Timeout time.Duration
+}
+
+// PipelineProvider is a provider node of a Pipeline, created and configured
+// by the client. Its name is the provider alias unless Provider.Alias is set,
+// so providers with the same source are configured independently; it must
+// then start with a letter or underscore and contain only letters, digits,
+// underscores and dashes.
+type PipelineProvider struct {
+	PipelineNode
+	Provider ProviderConfig
+	Config   map[string]any
+}
+
+// PipelineRead is a data source read node of a Pipeline. It depends on its
+// provider.
+type PipelineRead struct {
+	PipelineNode
+	Provider   string // name of a PipelineProvider
+	DataSource string
+	Config     map[string]any
+}
+
+// NodeStatus is the outcome of a pipeline node.
+type NodeStatus string
+
+const (
+	NodeSucceeded NodeStatus = "succeeded"
+	NodeFailed    NodeStatus = "failed"
+	// NodeSkipped is the status of nodes depending on a failed node.
+	NodeSkipped NodeStatus = "skipped"
+)
+
+// PipelineResult is the consolidated result of RunPipeline, keyed by node
+// name.
+type PipelineResult struct {
+	Providers map[string]*NodeResult `json:"providers"`
+	Reads     map[string]*NodeResult `json:"reads"`
+}
+
+// NodeResult is the result of a pipeline node.
+type NodeResult struct {
+	Status NodeStatus `json:"status"`
+	// Provider is the provider of the node, namespace/name@version, once
+	// created.
+	Provider string `json:"provider,omitempty"`
+	// State and Sensitive are those of the DataSourceResult of reads.
+	State     map[string]any `json:"state,omitempty"`
+	Sensitive []string       `json:"sensitive,omitempty"`
+	Attempts  int            `json:"attempts"` // 0 if skipped
+	Start     time.Time      `json:"start"`
+	Duration  time.Duration  `json:"duration"`
+	Error     string         `json:"error,omitempty"`
+	Err       error          `json:"-"`
+}
+
+// pipelineReference matches references to the states of reads, and their
+// escaped forms starting with "$$".
+var pipelineReference = regexp.MustCompile(`\$?\$\{reads\.([A-Za-z0-9_-]+)\.state((?:\.[A-Za-z0-9_-]+|\[[0-9]+\])*)\}`)
+
+// pipelineGraph is a validated Pipeline, whose nodes are its providers
+// followed by its reads.
+type pipelineGraph struct {
+	p     Pipeline
+	names []string
+	deps  [][]int // indexes of the nodes each node depends on
+}
+
+// newPipelineGraph checks a pipeline and returns its dependency graph.
+func newPipelineGraph(p Pipeline) (*pipelineGraph, error) {
+	g := &pipelineGraph{p: p}
+	index := make(map[string]int)
+	add := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s %d of the pipeline has no name", kind, len(g.names)+1)
+		}
+		if _, ok := index[name]; ok {
+			return fmt.Errorf("pipeline has several nodes named %q", name)
+		}
+		index[name] = len(g.names)
+		g.names = append(g.names, name)
+		return nil
+	}
+	for _, provider := range p.Providers {
+		if err := add("provider", provider.Name); err != nil {
+			return nil, err
+		}
+		if provider.Provider.Alias == "" && !aliasRegex.MatchString(provider.Name) {
+			return nil, fmt.Errorf("invalid provider node name %q: must start with a letter or underscore and contain only letters, digits, underscores and dashes, or set an alias", provider.Name)
+		}
+	}
+	for _, read := range p.Reads {
+		if err := add("read", read.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	g.deps = make([][]int, len(g.names))
+	depend := func(i int, name, what string) error {
+		j, ok := index[name]
+		if !ok {
+			return fmt.Errorf("node %q %s undeclared node %q", g.names[i], what, name)
+		}
+		if !slices.Contains(g.deps[i], j) {
+			g.deps[i] = append(g.deps[i], j)
+		}
+		return nil
+	}
+	nodeDeps := func(i int, node PipelineNode, config map[string]any) error {
+		for _, name := range node.DependsOn {
+			if err := depend(i, name, "depends on"); err != nil {
+				return err
+			}
+		}
+		for _, name := range configReferences(config) {
+			if j, ok := index[name]; ok && j < len(p.Providers) {
+				return fmt.Errorf("node %q refers to the state of provider %q, not a read", node.Name, name)
+			}
+			if err := depend(i, name, "refers to"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for i, provider := range p.Providers {
+		if err := nodeDeps(i, provider.PipelineNode, provider.Config); err != nil {
+			return nil, err
+		}
+	}
+	for k, read := range p.Reads {
+		i := len(p.Providers) + k
+		if read.DataSource == "" {
+			return nil, fmt.Errorf("read %q has no data source", read.Name)
+		}
+		j, ok := index[read.Provider]
+		if !ok || j >= len(p.Providers) {
+			return nil, fmt.Errorf("read %q refers to undeclared provider %q", read.Name, read.Provider)
+		}
+		g.deps[i] = append(g.deps[i], j)
+		if err := nodeDeps(i, read.PipelineNode, read.Config); err != nil {
+			return nil, err
+		}
+	}
+	if cycle := g.cycle(); cycle != nil {
+		return nil, &ErrDependencyCycle{Nodes: cycle}
+	}
+	return g, nil
+}
+
+// cycle returns the names of the nodes of a dependency cycle, the first one
+// repeated at the end, or nil if there is none.
+func (g *pipelineGraph) cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(g.names))
+	var path []int
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			var cycle []string
+			for _, j := range append(path[slices.Index(path, i):], i) {
+				cycle = append(cycle, g.names[j])
+			}
+			return cycle
+		}
+		state[i] = visiting
+		path = append(path, i)
+		for _, j := range g.deps[i] {
+			if cycle := visit(j); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range g.names {
+		if cycle := visit(i); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// RunPipeline runs the nodes of a pipeline: each once the nodes it depends
+// on succeeded, with up to p.Concurrency nodes at once. Nodes depending on a
+// failed node are skipped. It returns the result of every node, and the
+// errors of the failed nodes, joined; or only an error if the pipeline is
+// invalid, e.g. *ErrDependencyCycle.
+//
+// Providers are created with CreateProvider and stay running in the client
+// after RunPipeline returns, so running the pipeline again reuses them.
+func (c *Client) RunPipeline(ctx context.Context, p Pipeline) (*PipelineResult, error) {
+	return c.runPipeline(ctx, p, false)
+}
+
+// ValidatePipeline checks a pipeline as RunPipeline would run it, creating its
+// providers and validating the configurations of providers and reads with
+// ValidateProviderConfig and ValidateDataSourceConfig, without configuring
+// providers or reading. Configurations referring to reads are only known
+// once reading, so they're not validated.
+func (c *Client) ValidatePipeline(ctx context.Context, p Pipeline) (*PipelineResult, error) {
+	return c.runPipeline(ctx, p, true)
+}
+
+// runPipeline runs or validates a pipeline.
+func (c *Client) runPipeline(ctx context.Context, p Pipeline, validate bool) (*PipelineResult, error) {
+	g, err := newPipelineGraph(p)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultPipelineConcurrency
+	}
+
+	var mu sync.Mutex
+	providers := make(map[string]Provider)
+	states := make(map[string]map[string]any)
+	results := make([]*NodeResult, len(g.names))
+	forEachAfter(g.deps, concurrency, func(i int, failed []int) bool {
+		name := g.names[i]
+		if len(failed) > 0 {
+			err := fmt.Errorf("node %s: skipped, it depends on node %s, which failed", name, g.names[failed[0]])
+			results[i] = &NodeResult{Status: NodeSkipped, Start: time.Now(), Error: err.Error(), Err: err}
+			c.events.emit(&NodeFinished{Node: name, Status: NodeSkipped, Err: err})
+			return false
+		}
+
+		var node PipelineNode
+		var run func(ctx context.Context, res *NodeResult) error
+		if i < len(p.Providers) {
+			pp := p.Providers[i]
+			node = pp.PipelineNode
+			run = func(ctx context.Context, res *NodeResult) error {
+				cfg := pp.Provider
+				if cfg.Alias == "" {
+					cfg.Alias = pp.Name
+				}
+				provider, err := c.CreateProvider(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				res.Provider = provider.Config().String()
+				mu.Lock()
+				providers[pp.Name] = provider
+				mu.Unlock()
+				if validate {
+					if len(configReferences(pp.Config)) > 0 {
+						return nil
+					}
+					return provider.ValidateProviderConfig(ctx, pp.Config)
+				}
+				mu.Lock()
+				config, err := resolveReferences(pp.Config, states)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+				return provider.Configure(ctx, config)
+			}
+		} else {
+			pr := p.Reads[i-len(p.Providers)]
+			node = pr.PipelineNode
+			run = func(ctx context.Context, res *NodeResult) error {
+				mu.Lock()
+				provider := providers[pr.Provider]
+				mu.Unlock()
+				res.Provider = provider.Config().String()
+				if validate {
+					if len(configReferences(pr.Config)) > 0 {
+						return nil
+					}
+					return provider.ValidateDataSourceConfig(ctx, pr.DataSource, pr.Config)
+				}
+				mu.Lock()
+				config, err := resolveReferences(pr.Config, states)
+				mu.Unlock()
+				if err != nil {
+					return err
+				}
+				result, err := provider.ReadDataSource(ctx, pr.DataSource, config)
+				if err != nil {
+					return err
+				}
+				res.State, res.Sensitive = result.State, result.Sensitive
+				mu.Lock()
+				states[pr.Name] = result.State
+				mu.Unlock()
+				return nil
+			}
+		}
+
+		res := c.runNode(ctx, node, run)
+		results[i] = res
+		return res.Status == NodeSucceeded
+	})
+
+	result := &PipelineResult{Providers: make(map[string]*NodeResult), Reads: make(map[string]*NodeResult)}
+	var errs []error
+	for i, res := range results {
+		if i < len(p.Providers) {
+			result.Providers[g.names[i]] = res
+		} else {
+			result.Reads[g.names[i]] = res
+		}
+		if res.Status == NodeFailed {
+			errs = append(errs, res.Err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// runNode runs a node with its retries and timeout.
+func (c *Client) runNode(ctx context.Context, node PipelineNode, run func(ctx context.Context, res *NodeResult) error) *NodeResult {
+	res := &NodeResult{Start: time.Now()}
+	backoff := time.Second
+	var err error
+	for {
+		res.Attempts++
+		c.events.emit(&NodeStarted{Node: node.Name, Attempt: res.Attempts})
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if node.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, node.Timeout)
+		}
+		err = run(attemptCtx, res)
+		cancel()
+		if err == nil || res.Attempts > node.Retries || !retryableNodeError(err) || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+	res.Duration = time.Since(res.Start)
+	res.Status = NodeSucceeded
+	if err != nil {
+		res.Status = NodeFailed
+		res.Err = fmt.Errorf("node %s: %w", node.Name, err)
+		res.Error = res.Err.Error()
+	}
+	c.events.emit(&NodeFinished{Node: node.Name, Status: res.Status, Duration: res.Duration, Err: res.Err})
+	return res
+}
+
+// retryableNodeError reports whether a node failing with err may succeed
+// when retried: not when its configuration is wrong or the provider can't be
+// installed as requested.
+func retryableNodeError(err error) bool {
+	var (
+		invalid    *ErrInvalidConfig
+		notFound   *ErrDataSourceNotFound
+		unknown    *ErrUnknownValues
+		configured *ErrProviderAlreadyConfigured
+		version    *ErrVersionNotFound
+		denied     *ErrProviderDenied
+		lock       *ErrLockMismatch
+		offline    *ErrOfflineCacheMiss
+		reference  *errReference
+	)
+	return !errors.As(err, &invalid) && !errors.As(err, &notFound) && !errors.As(err, &unknown) &&
+		!errors.As(err, &configured) && !errors.As(err, &version) && !errors.As(err, &denied) &&
+		!errors.As(err, &lock) && !errors.As(err, &offline) && !errors.As(err, &reference)
+}
+
+// configReferences returns the names of the reads that a configuration
+// refers to, in order of appearance, without duplicates.
+func configReferences(config map[string]any) []string {
+	var names []string
+	rewriteConfig(config, func(s, _ string) (string, error) {
+		for _, m := range pipelineReference.FindAllStringSubmatch(s, -1) {
+			if !strings.HasPrefix(m[0], "$$") && !slices.Contains(names, m[1]) {
+				names = append(names, m[1])
+			}
+		}
+		return s, nil
+	})
+	return names
+}
+
+// errReference is returned for references that can't be resolved.
+type errReference struct {
+	ref string
+	msg string
+}
+
+func (e *errReference) Error() string {
+	return e.ref + ": " + e.msg
+}
+
+// resolveReferences returns a copy of config where references to the states
+// of reads are replaced by the values they point to.
+func resolveReferences(config map[string]any, states map[string]map[string]any) (map[string]any, error) {
+	if config == nil {
+		return nil, nil
+	}
+	resolved, err := resolveValue(config, states)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]any), nil
+}
+
+// resolveValue resolves the references in the strings of a value.
+func resolveValue(v any, states map[string]map[string]any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if m := pipelineReference.FindStringSubmatch(v); m != nil && m[0] == v && !strings.HasPrefix(v, "$$") {
+			return referencedValue(states, m[0], m[1], m[2])
+		}
+		var firstErr error
+		s := pipelineReference.ReplaceAllStringFunc(v, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			m := pipelineReference.FindStringSubmatch(ref)
+			value, err := referencedValue(states, ref, m[1], m[2])
+			if err == nil {
+				var str string
+				if str, err = referenceString(ref, value); err == nil {
+					return str
+				}
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ""
+		})
+		return s, firstErr
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			r, err := resolveValue(e, states)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			r, err := resolveValue(e, states)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// referencePath matches the steps of the path of a reference.
+var referencePath = regexp.MustCompile(`\.([A-Za-z0-9_-]+)|\[([0-9]+)\]`)
+
+// referencedValue returns the value at path, e.g. ".subnets[0].id", in the
+// state of read name.
+func referencedValue(states map[string]map[string]any, ref, name, path string) (any, error) {
+	var v any = states[name]
+	for _, m := range referencePath.FindAllStringSubmatch(path, -1) {
+		switch c := v.(type) {
+		case map[string]any:
+			if m[1] == "" {
+				return nil, &errReference{ref, "can't index an object"}
+			}
+			var ok bool
+			if v, ok = c[m[1]]; !ok {
+				return nil, &errReference{ref, fmt.Sprintf("no attribute %s", m[1])}
+			}
+		case []any:
+			if m[2] == "" {
+				return nil, &errReference{ref, fmt.Sprintf("a list has no attribute %s", m[1])}
+			}
+			i, _ := strconv.Atoi(m[2])
+			if i >= len(c) {
+				return nil, &errReference{ref, fmt.Sprintf("index %d out of range, the list has %d elements", i, len(c))}
+			}
+			v = c[i]
+		default:
+			return nil, &errReference{ref, "not an object or list"}
+		}
+	}
+	return v, nil
+}
+
+// referenceString formats a referenced value within a string.
+func referenceString(ref string, v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", &errReference{ref, "value is null"}
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// forEachAfter calls fn for 0 to len(deps)-1 on up to concurrency goroutines
+// at once, each after fn returned for its dependencies in deps, which must
+// not form a cycle, and waits for them. fn reports whether it succeeded; it
+// is passed the dependencies that didn't, if any, to skip its work.
+func forEachAfter(deps [][]int, concurrency int, fn func(i int, failed []int) bool) {
+	n := len(deps)
+	done := make([]chan struct{}, n)
+	ok := make([]bool, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+			var failed []int
+			for _, j := range deps[i] {
+				<-done[j]
+				if !ok[j] {
+					failed = append(failed, j)
+				}
+			}
+			if len(failed) == 0 {
+				sem <- struct{}{}
+				defer func() { <-sem }()
+			}
+			ok[i] = fn(i, failed)
+		}()
+	}
+	wg.Wait()
+}
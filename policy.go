@@ -0,0 +1,124 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/infracollect/tf-data-client/registry"
+)
+
+// ProviderPolicy restricts which providers CreateProvider may run. It is
+// evaluated before anything is downloaded or launched.
+type ProviderPolicy struct {
+	// Allow lists the providers that may run. The first rule whose Source
+	// matches a provider applies to it. If Allow is empty, every provider not
+	// denied may run.
+	Allow []ProviderRule
+
+	// Deny lists providers that may never run, whatever Allow says. Only the
+	// Source of deny rules is used.
+	Deny []ProviderRule
+}
+
+// ProviderRule matches providers by source and sets requirements for them.
+type ProviderRule struct {
+	// Source is a "namespace/name" pattern in path.Match syntax, such as
+	// "hashicorp/*" or "*/aws".
+	Source string
+
+	// MinVersion, if set, is the lowest version allowed.
+	MinVersion string
+
+	// RequireSignature requires a release signed by a key of at least
+	// MinTrust, as verified against the registry. Providers that can't be
+	// verified, such as development overrides and filesystem mirrors, are
+	// denied.
+	RequireSignature bool
+	MinTrust         registry.TrustLevel
+}
+
+func (r ProviderRule) matches(namespace, name string) bool {
+	ok, _ := path.Match(strings.ToLower(r.Source), strings.ToLower(namespace+"/"+name))
+	return ok
+}
+
+// validate checks the patterns and versions of the policy's rules.
+func (p *ProviderPolicy) validate() error {
+	for _, rule := range append(append([]ProviderRule{}, p.Allow...), p.Deny...) {
+		if _, err := path.Match(rule.Source, ""); err != nil || rule.Source == "" {
+			return fmt.Errorf("invalid provider policy source pattern %q", rule.Source)
+		}
+		if rule.MinVersion != "" {
+			if _, err := version.NewVersion(rule.MinVersion); err != nil {
+				return fmt.Errorf("invalid provider policy minimum version %q for %s: %w", rule.MinVersion, rule.Source, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rule returns the allow rule applying to a provider, or *ErrProviderDenied
+// if the provider may not run. It returns nil without a policy or allow list.
+func (p *ProviderPolicy) rule(namespace, name string) (*ProviderRule, error) {
+	if p == nil {
+		return nil, nil
+	}
+	for _, rule := range p.Deny {
+		if rule.matches(namespace, name) {
+			return nil, &ErrProviderDenied{Namespace: namespace, Name: name, Reason: fmt.Sprintf("denied by rule %q", rule.Source)}
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil, nil
+	}
+	for i := range p.Allow {
+		if p.Allow[i].matches(namespace, name) {
+			return &p.Allow[i], nil
+		}
+	}
+	return nil, &ErrProviderDenied{Namespace: namespace, Name: name, Reason: "not in the allow list"}
+}
+
+// checkPolicyRule applies an allow rule's version and signature requirements
+// to a resolved provider.
+func (c *Client) checkPolicyRule(ctx context.Context, rule *ProviderRule, cfg ProviderConfig) error {
+	if rule == nil {
+		return nil
+	}
+	denied := func(reason string, err error) error {
+		return &ErrProviderDenied{Namespace: cfg.Namespace, Name: cfg.Name, Version: cfg.Version, Reason: reason, Err: err}
+	}
+
+	if rule.MinVersion != "" {
+		v, err := version.NewVersion(cfg.Version)
+		if err != nil || v.LessThan(version.Must(version.NewVersion(rule.MinVersion))) {
+			return denied(fmt.Sprintf("version is below the minimum %s allowed by rule %q", rule.MinVersion, rule.Source), nil)
+		}
+	}
+
+	if rule.RequireSignature {
+		key := cfg.Namespace + "/" + cfg.Name
+		_, devOverride := c.devOverrides[key]
+		_, inProcess := c.inProcess[key]
+		verifier, ok := c.registry.(registry.SignatureVerifier)
+		if devOverride || inProcess || c.replayDir != "" || !ok {
+			return denied(fmt.Sprintf("rule %q requires a signature, which can't be verified for this provider", rule.Source), nil)
+		}
+
+		info, err := c.downloadInfo(ctx, cfg.Namespace, cfg.Name, cfg.Version)
+		if err != nil {
+			return fmt.Errorf("failed to get download info: %w", err)
+		}
+		level, err := verifier.VerifySignature(ctx, info)
+		if err != nil {
+			return denied(fmt.Sprintf("rule %q requires a signature", rule.Source), err)
+		}
+		if level < rule.MinTrust {
+			return denied(fmt.Sprintf("signed by a %s key, but rule %q requires %s", level, rule.Source, rule.MinTrust), nil)
+		}
+	}
+	return nil
+}
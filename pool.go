@@ -0,0 +1,108 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+)
+
+// launchPool starts size processes of the same provider binary. The first
+// launch error is returned as is so callers can classify it; later failures only
+// shrink the pool, since a smaller pool is still usable.
+func (c *Client) launchPool(size int, launch func() (*pluginInstance, error), cfg ProviderConfig) ([]*pluginInstance, error) {
+	first, err := launch()
+	if err != nil {
+		return nil, err
+	}
+	if size <= 1 {
+		return []*pluginInstance{first}, nil
+	}
+
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		insts = []*pluginInstance{first}
+	)
+	for i := 1; i < size; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inst, err := launch()
+			if err != nil {
+				c.logger.Error(err, "failed to launch pooled provider process", "provider", cfg.String())
+				return
+			}
+			mu.Lock()
+			insts = append(insts, inst)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	c.logger.V(1).Info("launched provider pool", "provider", cfg.String(), "size", len(insts))
+	return insts, nil
+}
+
+// acquire returns the least busy instance and marks a request in flight on it.
+// Callers must call release when done.
+func (p *provider) acquire() *pluginInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	best := p.insts[0]
+	for _, inst := range p.insts[1:] {
+		if inst.inflight.Load() < best.inflight.Load() {
+			best = inst
+		}
+	}
+	best.inflight.Add(1)
+	return best
+}
+
+func (inst *pluginInstance) release() {
+	inst.inflight.Add(-1)
+}
+
+// broadcast invokes fn on every instance in the pool in parallel and returns
+// the combined errors. If fn fails on any instance, the instances it
+// succeeded on are relaunched, so that a Configure that failed part-way
+// doesn't leave some processes configured.
+func (p *provider) broadcast(ctx context.Context, fn func(ctx context.Context, client tfplugin6.ProviderClient) error) error {
+	insts := p.instances()
+	errs := make([]error, len(insts))
+
+	var wg sync.WaitGroup
+	for i, inst := range insts {
+		// Mark the call in flight, as acquire does
+		inst.inflight.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer inst.release()
+			errs[i] = p.callInstance(ctx, inst, fn)
+		}()
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if err == nil {
+		return nil
+	}
+	// Roll back even if ctx was canceled, which may be why fn failed
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+	defer cancel()
+	for i, inst := range insts {
+		if errs[i] != nil {
+			continue
+		}
+		p.exited(inst, true, nil)
+		if _, rerr := p.restart(ctx, inst); rerr != nil {
+			p.logger.Error(rerr, "failed to relaunch provider after a partial broadcast", "provider", p.Config().String(), "slot", inst.slot)
+			inst.kill()
+		}
+	}
+	return err
+}
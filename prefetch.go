@@ -0,0 +1,100 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// defaultPrefetchConcurrency is the number of providers Prefetch fetches at
+// once, unless set with WithPrefetchConcurrency.
+const defaultPrefetchConcurrency = 4
+
+// Prefetch resolves the versions of providers and downloads them into the
+// cache concurrently, without launching them, so that later CreateProvider
+// calls don't wait on downloads; e.g. to warm the cache at service startup.
+// The provider policy and lock file apply as in CreateProvider. Providers
+// that aren't run from the cache (development overrides, in-process, replayed
+// and remote providers) are skipped. It returns the errors of all failed
+// providers, joined.
+func (c *Client) Prefetch(ctx context.Context, providers []ProviderConfig) error {
+	concurrency := c.prefetchConcurrency
+	if concurrency < 1 {
+		concurrency = defaultPrefetchConcurrency
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, concurrency)
+		errs = make([]error, len(providers))
+		seen = make(map[string]bool)
+	)
+	for i, cfg := range providers {
+		if seen[requestKey(cfg)] {
+			continue
+		}
+		seen[requestKey(cfg)] = true
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("failed to prefetch %s: %w", cfg, ctx.Err())
+				return
+			}
+			errs[i] = c.prefetch(ctx, cfg)
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// prefetch resolves and downloads a single provider.
+func (c *Client) prefetch(ctx context.Context, cfg ProviderConfig) (err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Prefetch", providerAttrs(cfg.Namespace, cfg.Name, cfg.Version)...)
+	defer func() { endSpan(span, err) }()
+
+	key := cfg.Namespace + "/" + cfg.Name
+	_, devOverride := c.devOverrides[key]
+	_, inProcess := c.inProcess[key]
+	if devOverride || inProcess || c.replayDir != "" || c.agent != nil {
+		return nil
+	}
+
+	rule, err := c.policy.rule(cfg.Namespace, cfg.Name)
+	if err != nil {
+		return err
+	}
+	version, locked, err := c.resolveRequest(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	resolved := ProviderConfig{Namespace: cfg.Namespace, Name: cfg.Name, Version: version}
+	if err := c.checkPolicyRule(ctx, rule, resolved); err != nil {
+		return err
+	}
+
+	execPath, archiveSum, err := c.getOrDownloadProvider(ctx, cfg.Namespace, cfg.Name, version)
+	if err != nil {
+		return &ErrDownloadFailed{
+			Namespace: cfg.Namespace,
+			Name:      cfg.Name,
+			Version:   version,
+			Err:       err,
+		}
+	}
+	if locked != nil {
+		if err := verifyLocked(cfg, locked, filepath.Dir(execPath), archiveSum); err != nil {
+			return err
+		}
+	}
+
+	c.logger.V(1).Info("prefetched provider", "provider", resolved.String(), "path", execPath)
+	return nil
+}
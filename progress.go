@@ -0,0 +1,14 @@
+package tfclient
+
+// DownloadProgress reports the progress of a provider download to the
+// function set with WithDownloadProgress.
+type DownloadProgress struct {
+	Provider   ProviderConfig // with the resolved version
+	Downloaded int64          // bytes received so far; restarts from 0 on retries
+	Total      int64          // archive size in bytes, or -1 if unknown
+}
+
+// Done reports whether the whole archive was received.
+func (p DownloadProgress) Done() bool {
+	return p.Total >= 0 && p.Downloaded >= p.Total
+}
@@ -1,16 +1,27 @@
 package tfclient
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/infracollect/tf-data-client/internal/tfplugin6"
 	"github.com/go-logr/logr"
 	"github.com/hashicorp/go-plugin"
-	"github.com/zclconf/go-cty/cty/msgpack"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 )
 
@@ -51,17 +62,66 @@ func (p *grpcProviderPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Serve
 
 // DataSourceResult contains the result of reading a data source.
 type DataSourceResult struct {
+	// State holds every attribute of the data source's schema: null values
+	// are nil, so that a null attribute is present with a nil value, while
+	// empty lists, sets and maps are empty. A state with unknown values is
+	// an ErrUnknownValues error.
 	State map[string]interface{}
+
+	// Sensitive holds the paths of the values of State that the schema marks
+	// as sensitive, e.g. "password" or "users[0].token", sorted. Elements of
+	// sets have no path, so a sensitive value in a set makes the whole set
+	// sensitive.
+	Sensitive []string
+
+	// Raw is the state as the provider encoded it, set instead of State for
+	// calls made with a context from WithRawResult.
+	Raw *RawState
+
+	// Cached is set when the state was served by the result cache
+	// (WithResultCache). CachedAt and ExpiresAt are set when the state is in
+	// the cache, whether it was just read or served from it.
+	Cached    bool
+	CachedAt  time.Time
+	ExpiresAt time.Time
 }
 
 // Provider is the interface for interacting with a Terraform provider.
 type Provider interface {
+	// Configure configures the provider. Providers are shared by the callers
+	// of CreateProvider with the same version and alias, so once configured,
+	// Configure only accepts the same configuration again; use Reconfigure,
+	// or a distinct ProviderConfig.Alias per configuration.
 	Configure(ctx context.Context, config map[string]interface{}) error
+	// Reconfigure replaces the provider's processes with new ones configured
+	// with config, re-fetching the schema. Reads in progress complete against
+	// the previous processes and configuration; reads started after
+	// Reconfigure returns use the new ones. On error, the provider keeps its
+	// previous configuration.
+	Reconfigure(ctx context.Context, config map[string]interface{}) error
 	ReadDataSource(ctx context.Context, typeName string, config map[string]interface{}) (*DataSourceResult, error)
 	IsConfigured() bool
 	ListDataSources() []string
 	Close() error
 
+	// ProviderSchema describes the provider's own configuration, as passed to
+	// Configure.
+	ProviderSchema() (*Schema, error)
+	// DataSourceSchema describes the configuration and state of a data source.
+	DataSourceSchema(typeName string) (*Schema, error)
+
+	// ValidateProviderConfig checks a provider configuration without
+	// configuring the provider.
+	ValidateProviderConfig(ctx context.Context, config map[string]interface{}) error
+	// ValidateDataSourceConfig checks a data source configuration without
+	// reading the data source.
+	ValidateDataSourceConfig(ctx context.Context, typeName string, config map[string]interface{}) error
+
+	// Ping checks that the provider process is alive and responding.
+	Ping(ctx context.Context) error
+	// Healthy reports whether the last health check succeeded.
+	Healthy() bool
+
 	// Config returns the provider identity. Version is always the resolved version (e.g. from latest when not specified).
 	Config() ProviderConfig
 }
@@ -72,33 +132,276 @@ type provider struct {
 	namespace string
 	name      string
 	version   string
+	alias     string
 
 	// Private fields
-	pluginClient *plugin.Client
-	grpcClient   tfplugin6.ProviderClient
-	schema       *tfplugin6.GetProviderSchema_Response
+	terraformVersion string                          // sent in ConfigureProvider
+	launch           func() (*pluginInstance, error) // starts a new process for the same binary
+	autoRestart      bool
+	breaker          *circuitBreaker // nil without WithCircuitBreaker
+	readLimiter      *rate.Limiter   // nil without WithReadRateLimit
+	callTimeout      time.Duration   // bounds each call, 0 for none
+	logger           logr.Logger
+	tracer           trace.Tracer
+	auditHook        AuditHook
+	results          *ResultCache
+	events           *eventHooks
+	launchedAt       time.Time
+	stopGrace        time.Duration // WithStopGracePeriod
+	crashDir         string        // WithCrashBundleDir
+	schemaFile       *schemaFile   // nil when the schema isn't cached
+	values           valueOptions  // WithExactNumbers, WithSortedSets
+	resolvers        []ConfigResolver
+	lastUsed         atomic.Int64 // Unix nanoseconds, for WithMaxProviders
+
+	// Counters reported by Client.ListProviders
+	calls      atomic.Int64
+	reads      atomic.Int64
+	readErrors atomic.Int64
+
+	mu           sync.Mutex
+	insts        []*pluginInstance                     // one per pooled process, never empty
+	schema       *tfplugin6.GetProviderSchema_Response // nil until needed with WithLazySchemas
+	dataSources  []string                              // from GetMetadata while schema is nil
 	configured   bool
+	configureReq *tfplugin6.ConfigureProvider_Request // last successful Configure, replayed on restart
+	healthy      bool
+
+	configureMu sync.Mutex // serializes Configure and Reconfigure
+	restartMu   sync.Mutex
+	schemaMu    sync.Mutex    // serializes fetching a deferred schema
+	done        chan struct{} // closed when the provider is closed
+	closeOnce   sync.Once
+}
+
+// pluginInstance is a single running provider process.
+type pluginInstance struct {
+	conn       pluginConn
+	grpcClient tfplugin6.ProviderClient
+
+	slot     int          // index in provider.insts
+	inflight atomic.Int64 // requests currently using this instance
+
+	exitReported atomic.Bool // whether ProviderExited was sent
+}
+
+func (i *pluginInstance) kill() {
+	i.conn.Kill()
+}
+
+// pluginConn manages the connection to a provider process, whether it runs as
+// a local subprocess or behind a remote agent.
+type pluginConn interface {
+	// Exited reports whether the provider process is known to be gone.
+	Exited() bool
+	// Ping checks that the provider answers health checks.
+	Ping(ctx context.Context) error
+	// Kill terminates the process or connection. Safe to call multiple times.
+	Kill()
+	// Terminate asks the process to exit, and kills it if it hasn't after
+	// grace.
+	Terminate(grace time.Duration)
+	// KilledBy returns why the process was killed for exceeding its resource
+	// limits, or "" if it wasn't.
+	KilledBy() string
+	// Pid returns the process ID of a local provider process, or 0.
+	Pid() int
+}
+
+// subprocessConn is a local provider subprocess managed by go-plugin.
+type subprocessConn struct {
+	client  *plugin.Client
+	rpc     plugin.ClientProtocol
+	limiter limiter // nil without process limits
+	pid     int
+	record  string // process record to remove once stopped, see trackProcess
+	cmd     *exec.Cmd
+	stderr  *tailBuffer // last stderr lines, for crash diagnostics
+
+	terminated atomic.Bool // set by Terminate before sending SIGTERM
+
+	mu         sync.Mutex
+	failedRPCs []string // methods that failed with the connection, see recordFailedRPCs
+}
+
+func (c *subprocessConn) Exited() bool {
+	return c.client.Exited()
+}
+
+func (c *subprocessConn) Ping(ctx context.Context) error {
+	// go-plugin's Ping does not take a context, so run it aside and give up
+	// when ctx is done.
+	errCh := make(chan error, 1)
+	go func() { errCh <- c.rpc.Ping() }()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *subprocessConn) Kill() {
+	c.client.Kill()
+	if c.limiter != nil {
+		c.limiter.release()
+	}
+	if c.record != "" {
+		os.Remove(c.record)
+	}
 }
 
-// launchProvider starts a provider binary and connects to it.
-func launchProvider(execPath string, logger logr.Logger) (*provider, error) {
+func (c *subprocessConn) Terminate(grace time.Duration) {
+	if c.client.Exited() {
+		c.Kill()
+		return
+	}
+	c.terminated.Store(true)
+	if process, err := os.FindProcess(c.pid); err == nil && process.Signal(syscall.SIGTERM) == nil {
+		deadline := time.Now().Add(grace)
+		for !c.client.Exited() && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	c.Kill()
+}
+
+func (c *subprocessConn) Pid() int {
+	return c.pid
+}
+
+func (c *subprocessConn) KilledBy() string {
+	if c.limiter == nil || !c.client.Exited() {
+		return ""
+	}
+	return c.limiter.killedBy()
+}
+
+// launchConfig describes how to start a provider process.
+type launchConfig struct {
+	execPath string
+	env      []string // complete environment, the host's is not inherited
+	logger   logr.Logger
+	limits   *ProcessLimits // nil for no limits
+	sandbox  *SandboxPolicy // nil for no sandbox
+	opts     *LaunchOptions // nil for defaults
+	output   io.Writer      // raw stdout/stderr copy, may be nil
+	dialOpts []grpc.DialOption
+
+	provider     string // namespace/name@version, for the process record
+	processesDir string // where to record the process, "" not to
+}
+
+// newProvider wraps one or more launched plugin instances of the same binary.
+func newProvider(namespace, name, version string, insts []*pluginInstance, logger logr.Logger) *provider {
+	for i, inst := range insts {
+		inst.slot = i
+	}
+	return &provider{
+		namespace: namespace,
+		name:      name,
+		version:   version,
+		logger:    logger,
+		insts:     insts,
+		healthy:   true,
+		done:      make(chan struct{}),
+		tracer:    noopTracer,
+	}
+}
+
+// instances returns a snapshot of the running plugin instances.
+func (p *provider) instances() []*pluginInstance {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*pluginInstance(nil), p.insts...)
+}
+
+// providerSchema returns the most recently fetched schema.
+func (p *provider) providerSchema() *tfplugin6.GetProviderSchema_Response {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.schema
+}
+
+// launchPlugin starts a provider binary and connects to it.
+func launchPlugin(lc launchConfig) (*pluginInstance, error) {
+	cmd := lc.opts.command(lc.execPath)
+	cmd.Env = lc.env
+
+	var umask threadSetup
+	if lc.opts != nil && lc.opts.Umask != nil {
+		var err error
+		if umask, err = umaskSetup(*lc.opts.Umask); err != nil {
+			return nil, err
+		}
+	}
+
+	var lim limiter
+	if lc.limits != nil && !lc.limits.empty() {
+		var err error
+		if lim, err = newLimiter(*lc.limits); err != nil {
+			return nil, fmt.Errorf("failed to set up process limits: %w", err)
+		}
+		if err := lim.prepare(cmd); err != nil {
+			lim.release()
+			return nil, fmt.Errorf("failed to set up process limits: %w", err)
+		}
+	}
+
+	sandbox, err := prepareSandbox(cmd, lc.sandbox)
+	if err != nil {
+		if lim != nil {
+			lim.release()
+		}
+		return nil, fmt.Errorf("failed to set up sandbox: %w", err)
+	}
+
+	// The sandbox setup goes first: it releases the ruleset it holds.
+	var setups []threadSetup
+	for _, setup := range []threadSetup{sandbox, umask} {
+		if setup != nil {
+			setups = append(setups, setup)
+		}
+	}
+
+	conn := &subprocessConn{limiter: lim, cmd: cmd, stderr: newTailBuffer(stderrTailLines)}
+	stderr := io.Writer(conn.stderr)
+	if lc.output != nil {
+		stderr = io.MultiWriter(lc.output, conn.stderr)
+	}
+	dialOpts := append(slices.Clip(lc.dialOpts), grpc.WithChainUnaryInterceptor(conn.recordFailedRPCs))
+
 	config := &plugin.ClientConfig{
 		HandshakeConfig:  handshake,
 		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
 		Managed:          true,
-		Cmd:              exec.Command(execPath),
+		RunnerFunc:       runnerFunc(cmd),
 		AutoMTLS:         true,
-		Logger:           newHclogAdapter(logger),
+		SkipHostEnv:      true, // cmd.Env already holds the host environment when wanted
+		Logger:           newHclogAdapter(lc.logger, &conn.terminated),
+		SyncStdout:       newOutputWriter(lc.logger, "stdout", lc.output),
+		SyncStderr:       newOutputWriter(lc.logger, "stderr", lc.output),
+		Stderr:           stderr,
+		GRPCDialOptions:  dialOpts,
 		VersionedPlugins: map[int]plugin.PluginSet{
 			6: {"provider": &grpcProviderPlugin{}},
 		},
 	}
 
 	client := plugin.NewClient(config)
+	conn.client = client
 
-	rpcClient, err := client.Client()
+	var rpcClient plugin.ClientProtocol
+	err = startOnThread(setups, func() error {
+		_, err := client.Start()
+		return err
+	})
+	if err == nil {
+		rpcClient, err = client.Client()
+	}
 	if err != nil {
-		client.Kill()
+		conn.Kill()
 		// Check for protocol version mismatch
 		if matches := protocolVersionRegex.FindStringSubmatch(err.Error()); matches != nil {
 			pluginVer, _ := strconv.Atoi(matches[1])
@@ -111,110 +414,332 @@ func launchProvider(execPath string, logger logr.Logger) (*provider, error) {
 		return nil, fmt.Errorf("failed to get RPC client: %w", err)
 	}
 
+	conn.rpc = rpcClient
+	conn.pid = cmd.Process.Pid
+	conn.record = trackProcess(lc.processesDir, conn.pid, lc.provider, lc.logger)
+
+	if lim != nil {
+		if err := lim.attach(cmd.Process.Pid); err != nil {
+			conn.Kill()
+			return nil, fmt.Errorf("failed to apply process limits: %w", err)
+		}
+	}
+
 	raw, err := rpcClient.Dispense("provider")
 	if err != nil {
-		client.Kill()
+		conn.Kill()
 		return nil, fmt.Errorf("failed to dispense provider: %w", err)
 	}
 
 	grpcClient, ok := raw.(tfplugin6.ProviderClient)
 	if !ok {
-		client.Kill()
+		conn.Kill()
 		return nil, fmt.Errorf("unexpected provider type: %T", raw)
 	}
 
-	return &provider{
-		pluginClient: client,
-		grpcClient:   grpcClient,
+	return &pluginInstance{
+		conn:       conn,
+		grpcClient: grpcClient,
 	}, nil
 }
 
 // getSchema retrieves the provider schema.
-func (p *provider) getSchema(ctx context.Context) error {
-	resp, err := p.grpcClient.GetProviderSchema(ctx, &tfplugin6.GetProviderSchema_Request{})
+func (p *provider) getSchema(ctx context.Context) (err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.GetProviderSchema", providerAttrs(p.namespace, p.name, p.version)...)
+	defer func() { endSpan(span, err) }()
+
+	resp, err := p.loadSchema(ctx, p.instances()[0].grpcClient)
 	if err != nil {
-		return fmt.Errorf("failed to get provider schema: %w", err)
+		return err
+	}
+
+	p.mu.Lock()
+	p.schema = resp
+	p.mu.Unlock()
+	return nil
+}
+
+// fetchSchema calls GetProviderSchema and checks its diagnostics.
+func fetchSchema(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.GetProviderSchema_Response, error) {
+	resp, err := client.GetProviderSchema(ctx, &tfplugin6.GetProviderSchema_Request{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider schema: %w", err)
 	}
 
 	if err := checkDiagnostics(resp.Diagnostics); err != nil {
-		return fmt.Errorf("provider schema error: %w", err)
+		return nil, fmt.Errorf("provider schema error: %w", err)
 	}
 
-	p.schema = resp
-	return nil
+	return resp, nil
 }
 
 // IsConfigured returns whether the provider has been configured.
 func (p *provider) IsConfigured() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	return p.configured
 }
 
 // Config returns the provider identity with resolved version.
 func (p *provider) Config() ProviderConfig {
-	return ProviderConfig{Namespace: p.namespace, Name: p.name, Version: p.version}
+	return ProviderConfig{Namespace: p.namespace, Name: p.name, Version: p.version, Alias: p.alias}
 }
 
 // Configure configures the provider with the given configuration.
-func (p *provider) Configure(ctx context.Context, config map[string]interface{}) error {
-	if p.schema == nil {
-		return fmt.Errorf("schema not loaded")
+func (p *provider) Configure(ctx context.Context, config map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.Configure", providerAttrs(p.namespace, p.name, p.version)...)
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	schema, err := p.currentSchema(ctx)
+	defer func() { p.audit(ctx, "Configure", "", schema.GetProvider().GetBlock(), config, start, err) }()
+	if err != nil {
+		return err
 	}
 
-	providerSchema := p.schema.Provider
-	if providerSchema == nil {
-		return fmt.Errorf("provider schema not found")
+	p.configureMu.Lock()
+	defer p.configureMu.Unlock()
+
+	req, err := p.configureRequest(ctx, schema, config)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	current := p.configureReq
+	p.mu.Unlock()
+	if current != nil {
+		if bytes.Equal(current.Config.Msgpack, req.Config.Msgpack) {
+			return nil
+		}
+		return &ErrProviderAlreadyConfigured{Namespace: p.namespace, Name: p.name, Version: p.version, Alias: p.alias}
+	}
+
+	err = p.broadcast(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		return configureInstance(ctx, client, req)
+	})
+	if err != nil {
+		return err
 	}
 
-	schemaType, err := schemaBlockToType(providerSchema.Block)
+	p.mu.Lock()
+	p.configured = true
+	p.configureReq = req
+	p.mu.Unlock()
+	return nil
+}
+
+// Reconfigure launches a new process per pool slot, configures them with
+// config and swaps them in, retiring the previous processes once idle.
+func (p *provider) Reconfigure(ctx context.Context, config map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.Reconfigure", providerAttrs(p.namespace, p.name, p.version)...)
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	schema, err := p.currentSchema(ctx)
+	defer func() { p.audit(ctx, "Reconfigure", "", schema.GetProvider().GetBlock(), config, start, err) }()
 	if err != nil {
-		return fmt.Errorf("failed to convert provider schema to type: %w", err)
+		return err
 	}
 
-	configValue, err := mapToCtyValue(config, schemaType)
+	p.configureMu.Lock()
+	defer p.configureMu.Unlock()
+	// Keep crashed processes from being restarted with the old configuration
+	// meanwhile
+	p.restartMu.Lock()
+	defer p.restartMu.Unlock()
+
+	if p.closed() {
+		return errProviderClosed
+	}
+	req, err := p.configureRequest(ctx, schema, config)
 	if err != nil {
-		return fmt.Errorf("failed to convert config to cty value: %w", err)
+		return err
 	}
 
-	configBytes, err := msgpack.Marshal(configValue, schemaType)
+	old := p.instances()
+	insts := make([]*pluginInstance, 0, len(old))
+	fail := func(err error) error {
+		for _, inst := range insts {
+			inst.kill()
+		}
+		return err
+	}
+	var newSchema *tfplugin6.GetProviderSchema_Response
+	for _, prev := range old {
+		inst, err := p.launch()
+		if err != nil {
+			return fail(fmt.Errorf("failed to launch provider: %w", err))
+		}
+		inst.slot = prev.slot
+		insts = append(insts, inst)
+
+		if newSchema, err = p.loadSchema(ctx, inst.grpcClient); err != nil {
+			return fail(err)
+		}
+		if err := configureInstance(ctx, inst.grpcClient, req); err != nil {
+			return fail(err)
+		}
+	}
+
+	p.mu.Lock()
+	p.insts = insts
+	p.schema = newSchema
+	p.configured = true
+	p.configureReq = req
+	p.mu.Unlock()
+
+	for _, inst := range old {
+		go p.retire(inst)
+	}
+	// Close may have run while we were launching; don't leak the new processes
+	if p.closed() {
+		return fail(errProviderClosed)
+	}
+	for _, inst := range insts {
+		p.events.emit(&ProviderLaunched{Provider: p.Config(), Slot: inst.slot, Restart: true})
+	}
+	p.logger.Info("provider reconfigured", "provider", p.Config().String())
+	return nil
+}
+
+// retire stops a process replaced by Reconfigure once its calls in progress
+// complete.
+func (p *provider) retire(inst *pluginInstance) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for inst.inflight.Load() > 0 && !p.closed() {
+		select {
+		case <-ticker.C:
+		case <-p.done:
+		}
+	}
+	p.stop(inst)
+}
+
+// configureRequest encodes a provider configuration against the schema.
+func (p *provider) configureRequest(ctx context.Context, schema *tfplugin6.GetProviderSchema_Response, config map[string]interface{}) (*tfplugin6.ConfigureProvider_Request, error) {
+	if schema == nil {
+		return nil, fmt.Errorf("schema not loaded")
+	}
+
+	providerSchema := schema.Provider
+	if providerSchema == nil {
+		return nil, fmt.Errorf("provider schema not found")
+	}
+
+	configBytes, err := p.checkConfig(ctx, "", providerSchema.Block, config)
 	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
+		return nil, err
 	}
 
-	resp, err := p.grpcClient.ConfigureProvider(ctx, &tfplugin6.ConfigureProvider_Request{
-		TerraformVersion: "1.0.0",
+	return &tfplugin6.ConfigureProvider_Request{
+		TerraformVersion: p.terraformVersion,
 		Config:           &tfplugin6.DynamicValue{Msgpack: configBytes},
-	})
+	}, nil
+}
+
+// configureInstance sends a configure request to one process.
+func configureInstance(ctx context.Context, client tfplugin6.ProviderClient, req *tfplugin6.ConfigureProvider_Request) error {
+	resp, err := client.ConfigureProvider(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to configure provider: %w", err)
 	}
-
 	if err := checkDiagnostics(resp.Diagnostics); err != nil {
 		return fmt.Errorf("configure provider error: %w", err)
 	}
-
-	p.configured = true
 	return nil
 }
 
 // ListDataSources returns the list of available data source types.
 func (p *provider) ListDataSources() []string {
-	if p.schema == nil {
-		return nil
+	schema := p.providerSchema()
+	if schema == nil {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return slices.Clone(p.dataSources)
 	}
 	var names []string
-	for name := range p.schema.DataSourceSchemas {
+	for name := range schema.DataSourceSchemas {
 		names = append(names, name)
 	}
 	return names
 }
 
+func (p *provider) ProviderSchema() (*Schema, error) {
+	schema, err := p.currentSchema(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return schemaFromBlock(schema.Provider.GetBlock())
+}
+
+func (p *provider) DataSourceSchema(typeName string) (*Schema, error) {
+	if !p.hasDataSource(typeName) {
+		return nil, &ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+	schema, err := p.currentSchema(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	dataSourceSchema, ok := schema.DataSourceSchemas[typeName]
+	if !ok {
+		return nil, &ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+	return schemaFromBlock(dataSourceSchema.Block)
+}
+
 // ReadDataSource reads a data source and returns the result.
-func (p *provider) ReadDataSource(ctx context.Context, typeName string, config map[string]interface{}) (*DataSourceResult, error) {
-	if p.schema == nil {
-		return nil, fmt.Errorf("schema not loaded")
+func (p *provider) ReadDataSource(ctx context.Context, typeName string, config map[string]interface{}) (res *DataSourceResult, err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.ReadDataSource",
+		append(providerAttrs(p.namespace, p.name, p.version), attribute.String("data_source.type", typeName))...)
+	defer func() { endSpan(span, err) }()
+
+	start := time.Now()
+	p.events.emit(&ReadStarted{Provider: p.Config(), DataSource: typeName, Time: start})
+	p.reads.Add(1)
+	p.touch()
+	defer func() {
+		if err != nil {
+			p.readErrors.Add(1)
+		}
+		p.events.emit(&ReadFinished{
+			Provider:   p.Config(),
+			DataSource: typeName,
+			Duration:   time.Since(start),
+			Cached:     res != nil && res.Cached,
+			Err:        err,
+		})
+	}()
+	var schema *tfplugin6.GetProviderSchema_Response
+	defer func() {
+		var block *tfplugin6.Schema_Block
+		if ds := schema.GetDataSourceSchemas()[typeName]; ds != nil {
+			block = ds.Block
+		}
+		p.audit(ctx, "ReadDataSource", typeName, block, config, start, err)
+	}()
+
+	if !p.hasDataSource(typeName) {
+		return nil, &ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+	if schema, err = p.currentSchema(ctx); err != nil {
+		return nil, err
 	}
 
-	dataSourceSchema, ok := p.schema.DataSourceSchemas[typeName]
+	dataSourceSchema, ok := schema.DataSourceSchemas[typeName]
 	if !ok {
 		return nil, &ErrDataSourceNotFound{
 			TypeName:  typeName,
@@ -228,19 +753,48 @@ func (p *provider) ReadDataSource(ctx context.Context, typeName string, config m
 		return nil, fmt.Errorf("failed to convert data source schema to type: %w", err)
 	}
 
-	configValue, err := mapToCtyValue(config, schemaType)
+	configBytes, err := p.checkConfig(ctx, typeName, dataSourceSchema.Block, config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert config to cty value: %w", err)
+		return nil, err
 	}
 
-	configBytes, err := msgpack.Marshal(configValue, schemaType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	raw := rawResult(ctx)
+	var cacheKey string
+	if p.results != nil && !raw {
+		p.mu.Lock()
+		var providerConfig []byte
+		if p.configureReq != nil {
+			providerConfig = p.configureReq.Config.GetMsgpack()
+		}
+		p.mu.Unlock()
+		cacheKey = resultKey(providerKey(p.namespace, p.name, p.alias, p.version), providerConfig, typeName, configBytes)
+		if e := p.results.get(cacheKey); e != nil {
+			p.logger.V(1).Info("serving cached data source result", "data_source", typeName, "expires_at", e.ExpiresAt)
+			span.SetAttributes(attribute.Bool("data_source.cached", true))
+			return &DataSourceResult{State: copyState(e.State, p.values.exactNumbers), Sensitive: e.Sensitive, Cached: true, CachedAt: e.CachedAt, ExpiresAt: e.ExpiresAt}, nil
+		}
 	}
 
-	resp, err := p.grpcClient.ReadDataSource(ctx, &tfplugin6.ReadDataSource_Request{
-		TypeName: typeName,
-		Config:   &tfplugin6.DynamicValue{Msgpack: configBytes},
+	if p.readLimiter != nil {
+		if err := p.readLimiter.Wait(ctx); err != nil {
+			err = fmt.Errorf("failed to wait for the read rate limit: %w", err)
+			// Wait fails early when the deadline would pass before the turn of
+			// the call.
+			if _, ok := ctx.Deadline(); ok && !errors.Is(err, context.Canceled) {
+				err = &ErrCallTimeout{Namespace: p.namespace, Name: p.name, Version: p.version, Err: err}
+			}
+			return nil, err
+		}
+	}
+
+	var resp *tfplugin6.ReadDataSource_Response
+	err = p.call(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		var err error
+		resp, err = client.ReadDataSource(ctx, &tfplugin6.ReadDataSource_Request{
+			TypeName: typeName,
+			Config:   &tfplugin6.DynamicValue{Msgpack: configBytes},
+		})
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to read data source: %w", err)
@@ -250,23 +804,113 @@ func (p *provider) ReadDataSource(ctx context.Context, typeName string, config m
 		return nil, fmt.Errorf("read data source error: %w", err)
 	}
 
+	if raw {
+		rawState, err := newRawState(resp.State, schemaType)
+		if err != nil {
+			return nil, err
+		}
+		return &DataSourceResult{Raw: rawState}, nil
+	}
+
 	state, err := decodeDynamicValue(resp.State, schemaType)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode state: %w", err)
 	}
 
-	stateMap, err := ctyValueToMap(state)
+	if paths := unknownPaths(state); len(paths) > 0 {
+		return nil, &ErrUnknownValues{Namespace: p.namespace, Name: p.name, TypeName: typeName, Paths: paths}
+	}
+	state = markSensitive(state, dataSourceSchema.Block)
+
+	stateMap, err := ctyValueToMap(state, p.values)
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert state to map: %w", err)
 	}
 
-	return &DataSourceResult{State: stateMap}, nil
+	result := &DataSourceResult{State: stateMap, Sensitive: sensitivePaths(state)}
+	if p.results != nil {
+		e, err := p.results.put(cacheKey, p.namespace+"/"+p.name, typeName, copyState(stateMap, p.values.exactNumbers), result.Sensitive)
+		if err != nil {
+			p.logger.Error(err, "failed to cache data source result", "data_source", typeName)
+		}
+		if e != nil {
+			result.CachedAt, result.ExpiresAt = e.CachedAt, e.ExpiresAt
+		}
+	}
+	return result, nil
 }
 
 // Close shuts down the provider process.
 func (p *provider) Close() error {
-	if p.pluginClient != nil {
-		p.pluginClient.Kill()
+	p.closeOnce.Do(func() { close(p.done) })
+	var wg sync.WaitGroup
+	for _, inst := range p.instances() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.stop(inst)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// stop stops a process gracefully: the StopProvider RPC asks it to cancel its
+// operations, which are given up to the grace period to complete, then the
+// process is asked to exit and killed if it hasn't after the grace period.
+func (p *provider) stop(inst *pluginInstance) {
+	defer p.exited(inst, true, nil)
+	if p.stopGrace <= 0 || inst.conn.Exited() {
+		inst.kill()
+		return
+	}
+
+	// Processes behind an agent are shared with its other clients, whose
+	// operations must go on
+	if _, remote := inst.conn.(*remoteConn); !remote {
+		ctx, cancel := context.WithTimeout(context.Background(), p.stopGrace)
+		defer cancel()
+		resp, err := inst.grpcClient.StopProvider(ctx, &tfplugin6.StopProvider_Request{})
+		if err == nil && resp.Error != "" {
+			err = errors.New(resp.Error)
+		}
+		if err != nil {
+			p.logger.V(1).Info("provider failed to stop its operations", "provider", p.Config().String(), "slot", inst.slot, "error", err.Error())
+		}
+
+		ticker := time.NewTicker(50 * time.Millisecond)
+		defer ticker.Stop()
+		for inst.inflight.Load() > 0 && ctx.Err() == nil {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+			}
+		}
+	}
+	inst.conn.Terminate(p.stopGrace)
+}
+
+// shutdown stops the provider like Close, once its calls in progress complete
+// or ctx is done.
+func (p *provider) shutdown(ctx context.Context) error {
+	// Stop restarts and health checks meanwhile
+	p.closeOnce.Do(func() { close(p.done) })
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !p.idle() && ctx.Err() == nil {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+		}
+	}
+
+	busy := !p.idle()
+	if err := p.Close(); err != nil {
+		return err
+	}
+	if busy {
+		return fmt.Errorf("provider %s killed with calls in progress: %w", p.Config(), context.Cause(ctx))
 	}
 	return nil
 }
@@ -0,0 +1,54 @@
+package tfclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// RawState is a data source state as returned by the provider, see
+// WithRawResult.
+type RawState struct {
+	// Msgpack or JSON holds the encoded state, depending on which encoding
+	// the provider used; providers use msgpack.
+	Msgpack []byte
+	JSON    []byte
+	// Type is the type of the state in go-cty's JSON type notation, e.g.
+	// ["object",{"id":"string"}], needed to decode Msgpack, e.g. with go-cty's
+	// msgpack.Unmarshal.
+	Type json.RawMessage
+}
+
+type rawResultKey struct{}
+
+// WithRawResult returns a context making ReadDataSource calls made with it
+// return the state as the provider encoded it, in DataSourceResult.Raw,
+// instead of decoding it into DataSourceResult.State. Use it to forward
+// results to another system without the cost or fidelity loss of decoding
+// them. The result cache is bypassed, since it holds decoded states.
+func WithRawResult(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rawResultKey{}, true)
+}
+
+// rawResult reports whether ctx asks for raw results, see WithRawResult.
+func rawResult(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawResultKey{}).(bool)
+	return raw
+}
+
+// newRawState returns the raw state of a ReadDataSource response.
+func newRawState(state *tfplugin6.DynamicValue, ty cty.Type) (*RawState, error) {
+	typeJSON, err := ctyjson.MarshalType(ty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal state type: %w", err)
+	}
+	return &RawState{
+		Msgpack: state.GetMsgpack(),
+		JSON:    state.GetJson(),
+		Type:    typeJSON,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when a downloaded archive doesn't match the
+// SHA-256 checksum published for it.
+type ErrChecksumMismatch struct {
+	URL      string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch for %s: expected sha256 %s, got %s", e.URL, e.Expected, e.Actual)
+}
+
+// copyVerified copies src to dst while hashing it, and fails with
+// *ErrChecksumMismatch if the SHA-256 digest isn't expected. An empty expected
+// digest skips verification.
+func copyVerified(dst io.Writer, src io.Reader, expected, url string) error {
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, h), src); err != nil {
+		return err
+	}
+	if expected == "" {
+		return nil
+	}
+	if actual := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return &ErrChecksumMismatch{URL: url, Expected: expected, Actual: actual}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Credentials maps registry hostnames to API tokens.
+type Credentials map[string]string
+
+// Token returns the token for host, or "" if there is none.
+func (c Credentials) Token(host string) string {
+	return c[strings.ToLower(host)]
+}
+
+// LoadCredentials reads registry tokens the way the Terraform CLI does, from
+// lowest to highest precedence:
+//   - credentials blocks of the CLI config file (TF_CLI_CONFIG_FILE, or
+//     ~/.terraformrc, %APPDATA%\terraform.rc on Windows)
+//   - credentials.tfrc.json, as written by "terraform login"
+//   - TF_TOKEN_<host> environment variables, with dots in the hostname
+//     written as underscores and hyphens as double underscores
+//
+// Missing files are ignored.
+func LoadCredentials() (Credentials, error) {
+	creds := make(Credentials)
+
+	configDir := cliConfigDir()
+
+	configFile := os.Getenv("TF_CLI_CONFIG_FILE")
+	if configFile == "" && configDir != "" {
+		configFile = filepath.Join(filepath.Dir(configDir), ".terraformrc")
+		if runtime.GOOS == "windows" {
+			configFile = filepath.Join(filepath.Dir(configDir), "terraform.rc")
+		}
+	}
+	if configFile != "" {
+		if err := loadCLIConfigCredentials(configFile, creds); err != nil {
+			return nil, err
+		}
+	}
+
+	if configDir != "" {
+		if err := loadCredentialsJSON(filepath.Join(configDir, "credentials.tfrc.json"), creds); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		key, token, _ := strings.Cut(kv, "=")
+		host, ok := strings.CutPrefix(key, "TF_TOKEN_")
+		if !ok || host == "" || token == "" {
+			continue
+		}
+		host = strings.ReplaceAll(host, "__", "-")
+		host = strings.ReplaceAll(host, "_", ".")
+		creds[strings.ToLower(host)] = token
+	}
+
+	return creds, nil
+}
+
+// cliConfigDir returns the Terraform CLI configuration directory:
+// ~/.terraform.d, or %APPDATA%\terraform.d on Windows. It returns "" if the
+// home directory is unknown.
+func cliConfigDir() string {
+	if runtime.GOOS == "windows" {
+		if appData := os.Getenv("APPDATA"); appData != "" {
+			return filepath.Join(appData, "terraform.d")
+		}
+		return ""
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".terraform.d")
+}
+
+// loadCLIConfigCredentials adds the tokens of credentials blocks in a CLI
+// config file to creds. Other settings are ignored.
+func loadCLIConfigCredentials(path string, creds Credentials) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read CLI config: %w", err)
+	}
+
+	file, diags := hclsyntax.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse CLI config: %w", diags)
+	}
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "credentials", LabelNames: []string{"host"}}},
+	})
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse CLI config: %w", diags)
+	}
+
+	for _, block := range content.Blocks {
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return fmt.Errorf("failed to parse credentials for %s: %w", block.Labels[0], diags)
+		}
+		attr, ok := attrs["token"]
+		if !ok {
+			continue
+		}
+		token, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() || !token.Type().Equals(cty.String) || token.IsNull() {
+			return fmt.Errorf("credentials token for %s must be a string", block.Labels[0])
+		}
+		creds[strings.ToLower(block.Labels[0])] = token.AsString()
+	}
+	return nil
+}
+
+type credentialsFile struct {
+	Credentials map[string]struct {
+		Token string `json:"token"`
+	} `json:"credentials"`
+}
+
+// loadCredentialsJSON adds the tokens of a credentials.tfrc.json file to creds.
+func loadCredentialsJSON(path string, creds Credentials) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for host, c := range file.Credentials {
+		if c.Token != "" {
+			creds[strings.ToLower(host)] = c.Token
+		}
+	}
+	return nil
+}
+
+// AuthenticatedClient returns a copy of client sending creds' bearer token
+// with every request to a host it has a token for, including downloads served
+// by that host. If client is nil, http.DefaultClient is used.
+func AuthenticatedClient(client *http.Client, creds Credentials) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if len(creds) == 0 {
+		return client
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	authenticated := *client
+	authenticated.Transport = &credentialsTransport{base: base, creds: creds}
+	return &authenticated
+}
+
+type credentialsTransport struct {
+	base  http.RoundTripper
+	creds Credentials
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.creds.Token(req.URL.Host)
+	if token == "" || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
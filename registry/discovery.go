@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Public registry hosts.
+const (
+	TerraformRegistryHost = "registry.terraform.io"
+	OpenTofuRegistryHost  = "registry.opentofu.org"
+)
+
+// NewRegistry returns a Registry for the provider registry at host, e.g.
+// "registry.opentofu.org" or "registry.example.com:8443". The public registries
+// are used directly; other hosts are resolved through Terraform service
+// discovery (/.well-known/terraform.json) on first use.
+// If client is nil, http.DefaultClient is used.
+func NewRegistry(client *http.Client, host string, opts ...Option) Registry {
+	switch host {
+	case "", TerraformRegistryHost:
+		return NewTerraformRegistry(client, opts...)
+	case OpenTofuRegistryHost:
+		return NewOpenTofuRegistry(client, opts...)
+	}
+
+	r := NewTerraformRegistry(client, opts...)
+	r.baseURL = ""
+	r.host = host
+	return r
+}
+
+type discoveryResponse struct {
+	ProvidersV1 string `json:"providers.v1"`
+}
+
+// providersURL returns the providers.v1 endpoint, discovering it on first use.
+func (r *TerraformRegistry) providersURL(ctx context.Context) (string, error) {
+	r.discoverMu.Lock()
+	defer r.discoverMu.Unlock()
+
+	if r.baseURL != "" {
+		return r.baseURL, nil
+	}
+
+	base := &url.URL{Scheme: "https", Host: r.host, Path: "/.well-known/terraform.json"}
+	resp, err := r.do(ctx, base.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to discover registry %s: %w", r.host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry %s discovery returned status %d", r.host, resp.StatusCode)
+	}
+
+	var services discoveryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&services); err != nil {
+		return "", fmt.Errorf("failed to decode discovery response of %s: %w", r.host, err)
+	}
+	if services.ProvidersV1 == "" {
+		return "", fmt.Errorf("registry %s does not serve providers", r.host)
+	}
+
+	// The endpoint may be relative to the discovery document
+	endpoint, err := base.Parse(services.ProvidersV1)
+	if err != nil {
+		return "", fmt.Errorf("invalid providers.v1 endpoint of %s: %w", r.host, err)
+	}
+	r.baseURL = strings.TrimSuffix(endpoint.String(), "/")
+	return r.baseURL, nil
+}
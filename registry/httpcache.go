@@ -0,0 +1,127 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// responseCache stores registry API responses on disk, keyed by URL.
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cachedResponse is the on-disk form of a cached response.
+type cachedResponse struct {
+	URL       string    `json:"url"`
+	ETag      string    `json:"etag,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Body      []byte    `json:"body"`
+}
+
+func (c *responseCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *responseCache) load(rawURL string) *cachedResponse {
+	data, err := os.ReadFile(c.path(rawURL))
+	if err != nil {
+		return nil
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	return &entry
+}
+
+// store writes entry atomically. Failures only cost a later refetch.
+func (c *responseCache) store(entry *cachedResponse) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, ".response-*")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	if err := tmp.Close(); writeErr != nil || err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(entry.URL)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// doCached is do for registry API calls: responses are served from the
+// response cache while fresh, then revalidated with If-None-Match.
+func (r *TerraformRegistry) doCached(ctx context.Context, rawURL string) (*http.Response, error) {
+	if r.cache == nil {
+		return r.do(ctx, rawURL)
+	}
+
+	entry := r.cache.load(rawURL)
+	if entry != nil && time.Since(entry.FetchedAt) < r.cache.ttl {
+		return cachedHTTPResponse(entry), nil
+	}
+
+	var header http.Header
+	if entry != nil && entry.ETag != "" {
+		header = http.Header{"If-None-Match": {entry.ETag}}
+	}
+	resp, err := r.doWithHeader(ctx, rawURL, header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		resp.Body.Close()
+		if entry == nil {
+			return nil, fmt.Errorf("registry returned 304 for uncached %s", rawURL)
+		}
+		entry.FetchedAt = time.Now()
+		r.cache.store(entry)
+		return cachedHTTPResponse(entry), nil
+
+	case http.StatusOK:
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		r.cache.store(&cachedResponse{
+			URL:       rawURL,
+			ETag:      resp.Header.Get("ETag"),
+			FetchedAt: time.Now(),
+			Body:      body,
+		})
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+	return resp, nil
+}
+
+func cachedHTTPResponse(entry *cachedResponse) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(entry.Body)),
+	}
+}
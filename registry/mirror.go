@@ -0,0 +1,324 @@
+package registry
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+// FilesystemMirror implements Registry for a local directory laid out like
+// the output of "terraform providers mirror":
+//
+//	<dir>/<host>/<namespace>/<name>/terraform-provider-<name>_<version>_<os>_<arch>.zip
+//
+// The unpacked layout, <dir>/<host>/<namespace>/<name>/<version>/<os>_<arch>/,
+// is read as well. Nothing is fetched over the network.
+type FilesystemMirror struct {
+	dir  string
+	host string
+}
+
+// NewFilesystemMirror creates a FilesystemMirror serving the providers of host
+// (registry.terraform.io if empty) from dir.
+func NewFilesystemMirror(dir, host string) *FilesystemMirror {
+	if host == "" {
+		host = TerraformRegistryHost
+	}
+	return &FilesystemMirror{dir: dir, host: host}
+}
+
+// mirrorPackage is a provider build found in the mirror.
+type mirrorPackage struct {
+	version  string
+	os       string
+	arch     string
+	path     string // zip archive, or directory in the unpacked layout
+	unpacked bool
+}
+
+func (m *FilesystemMirror) providerDir(namespace, name string) string {
+	return filepath.Join(m.dir, m.host, namespace, name)
+}
+
+// packages lists the builds of a provider in the mirror.
+func (m *FilesystemMirror) packages(namespace, name string) ([]mirrorPackage, error) {
+	dir := m.providerDir(namespace, name)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("provider %s/%s not found in mirror %s", namespace, name, m.dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror: %w", err)
+	}
+
+	prefix := "terraform-provider-" + name + "_"
+	var pkgs []mirrorPackage
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			// Packed layout: terraform-provider-<name>_<version>_<os>_<arch>.zip
+			rest, ok := strings.CutPrefix(entry.Name(), prefix)
+			if !ok || !strings.HasSuffix(rest, ".zip") {
+				continue
+			}
+			parts := strings.Split(strings.TrimSuffix(rest, ".zip"), "_")
+			if len(parts) != 3 {
+				continue
+			}
+			pkgs = append(pkgs, mirrorPackage{
+				version: parts[0],
+				os:      parts[1],
+				arch:    parts[2],
+				path:    filepath.Join(dir, entry.Name()),
+			})
+			continue
+		}
+
+		// Unpacked layout: <version>/<os>_<arch>/
+		platforms, err := os.ReadDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mirror: %w", err)
+		}
+		for _, platform := range platforms {
+			goos, goarch, ok := strings.Cut(platform.Name(), "_")
+			if !ok || !platform.IsDir() {
+				continue
+			}
+			pkgs = append(pkgs, mirrorPackage{
+				version:  entry.Name(),
+				os:       goos,
+				arch:     goarch,
+				path:     filepath.Join(dir, entry.Name(), platform.Name()),
+				unpacked: true,
+			})
+		}
+	}
+	return pkgs, nil
+}
+
+// GetVersions returns the versions of a provider in the mirror, for any platform.
+func (m *FilesystemMirror) GetVersions(ctx context.Context, namespace, name string) ([]VersionInfo, error) {
+	pkgs, err := m.packages(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var result []VersionInfo
+	for _, pkg := range pkgs {
+		if _, err := version.NewVersion(pkg.version); err != nil || seen[pkg.version] {
+			continue
+		}
+		seen[pkg.version] = true
+		result = append(result, VersionInfo{Version: pkg.version})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return version.Must(version.NewVersion(result[i].Version)).LessThan(version.Must(version.NewVersion(result[j].Version)))
+	})
+	return result, nil
+}
+
+// GetLatestVersion returns the latest stable version of a provider in the mirror.
+func (m *FilesystemMirror) GetLatestVersion(ctx context.Context, namespace, name string) (string, error) {
+	versions, err := m.GetVersions(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return latestStable(versions, namespace, name)
+}
+
+// GetDownloadInfo returns the location of a provider build in the mirror, as
+// a file:// URL. SHA256Sum is the zh: hash listed in the mirror's
+// <version>.json index, or else the checksum of the archive as found.
+func (m *FilesystemMirror) GetDownloadInfo(ctx context.Context, namespace, name, version, goos, goarch string) (*DownloadInfo, error) {
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	pkgs, err := m.packages(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, pkg := range pkgs {
+		if pkg.version != version || pkg.os != goos || pkg.arch != goarch {
+			continue
+		}
+		info := &DownloadInfo{
+			OS:          goos,
+			Arch:        goarch,
+			Filename:    filepath.Base(pkg.path),
+			DownloadURL: fileURL(pkg.path),
+		}
+		if !pkg.unpacked {
+			info.SHA256Sum = m.indexedHash(namespace, name, version, goos+"_"+goarch)
+		}
+		if info.SHA256Sum == "" {
+			// The mirror is trusted: checksum the build as found, to catch
+			// changes between now and DownloadToPath
+			if info.SHA256Sum, err = packageSHA256(pkg); err != nil {
+				return nil, fmt.Errorf("failed to read mirror: %w", err)
+			}
+		}
+		return info, nil
+	}
+	return nil, fmt.Errorf("version %s not found for provider %s/%s on %s_%s in mirror %s", version, namespace, name, goos, goarch, m.dir)
+}
+
+type mirrorVersionIndex struct {
+	Archives map[string]struct {
+		Hashes []string `json:"hashes"`
+	} `json:"archives"`
+}
+
+// indexedHash returns the zip archive SHA-256 recorded in <version>.json, or
+// "" if there is none.
+func (m *FilesystemMirror) indexedHash(namespace, name, version, platform string) string {
+	data, err := os.ReadFile(filepath.Join(m.providerDir(namespace, name), version+".json"))
+	if err != nil {
+		return ""
+	}
+	var index mirrorVersionIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return ""
+	}
+	for _, hash := range index.Archives[platform].Hashes {
+		if sum, ok := strings.CutPrefix(hash, "zh:"); ok {
+			return sum
+		}
+	}
+	return ""
+}
+
+// packageSHA256 returns the hex SHA-256 of a build's archive, as
+// DownloadToPath would write it.
+func packageSHA256(pkg mirrorPackage) (string, error) {
+	h := sha256.New()
+	if pkg.unpacked {
+		if err := zipDir(pkg.path, h); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	f, err := os.Open(pkg.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DownloadToPath copies a provider archive from the mirror to destPath,
+// verifying it against info.SHA256Sum and reporting progress as
+// TerraformRegistry.DownloadToPath does. Builds in the unpacked layout are
+// zipped.
+func (m *FilesystemMirror) DownloadToPath(ctx context.Context, info *DownloadInfo, destPath string) error {
+	src, err := fromFileURL(info.DownloadURL)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to read mirror: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	var in io.ReadCloser
+	size := stat.Size()
+	if stat.IsDir() {
+		pr, pw := io.Pipe()
+		go func() { pw.CloseWithError(zipDir(src, pw)) }()
+		in = pr
+		size = -1
+	} else if in, err = os.Open(src); err != nil {
+		return fmt.Errorf("failed to read mirror: %w", err)
+	}
+	defer in.Close()
+
+	return writeVerified(destPath, progressReader(ctx, in, 0, size), info)
+}
+
+// zipDir writes the regular files under dir to w as a zip archive.
+func zipDir(dir string, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+func fromFileURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "file" {
+		return "", fmt.Errorf("not a mirror file URL: %q", rawURL)
+	}
+	path := u.Path
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, "/")
+	}
+	return filepath.FromSlash(path), nil
+}
@@ -0,0 +1,429 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+)
+
+// OCI media types of provider artifacts, as laid out by OpenTofu's OCI
+// provider mirrors: a version tag points to an index of one manifest per
+// platform, each with the provider's zip archive as its single layer.
+const (
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociArchiveMediaType  = "archive/zip"
+)
+
+// OCIRegistry implements Registry for providers published as OCI artifacts
+// in a container registry such as ghcr.io or ECR. The provider namespace/name
+// is pulled from the repository <repository>/<namespace>/<name>, one tag per
+// version, e.g. ghcr.io/example/providers/hashicorp/aws:5.0.0.
+//
+// Archives are verified against their OCI digest. Pin a version's digest
+// with Pin to fail on any other content published under its tag.
+type OCIRegistry struct {
+	client     *http.Client
+	host       string // e.g. "ghcr.io"
+	repository string // repository prefix within host
+
+	username, password string // for token requests, anonymous if empty
+
+	mu     sync.Mutex
+	tokens map[string]string // repository -> bearer token
+	pins   map[string]string // "namespace/name@version" -> index digest
+}
+
+// NewOCIRegistry creates an OCIRegistry pulling providers from repository,
+// e.g. "ghcr.io/example/providers". If client is nil, http.DefaultClient is
+// used.
+func NewOCIRegistry(client *http.Client, repository string) (*OCIRegistry, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	host, prefix, ok := strings.Cut(strings.TrimSuffix(repository, "/"), "/")
+	if !ok || host == "" || prefix == "" {
+		return nil, fmt.Errorf("invalid OCI repository %q: expected <host>/<repository>", repository)
+	}
+	return &OCIRegistry{
+		client:     client,
+		host:       host,
+		repository: prefix,
+		tokens:     make(map[string]string),
+		pins:       make(map[string]string),
+	}, nil
+}
+
+// SetCredentials sets the username and password, or access token, used to
+// obtain registry tokens, e.g. a GitHub username and personal access token
+// for ghcr.io.
+func (r *OCIRegistry) SetCredentials(username, password string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.username, r.password = username, password
+	clear(r.tokens)
+}
+
+// Pin requires the tag of a provider version to resolve to digest, e.g.
+// "sha256:3b0c...", the digest of its OCI index.
+func (r *OCIRegistry) Pin(namespace, name, version, digest string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pins[namespace+"/"+name+"@"+version] = digest
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"` // index
+	Layers    []ociDescriptor `json:"layers"`    // manifest
+}
+
+// GetVersions returns the versions tagged in a provider's repository.
+func (r *OCIRegistry) GetVersions(ctx context.Context, namespace, name string) ([]VersionInfo, error) {
+	repo := r.repositoryOf(namespace, name)
+	next := r.url(repo, "tags/list")
+
+	var result []VersionInfo
+	for next != "" {
+		resp, err := r.get(ctx, repo, next, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch versions: %w", err)
+		}
+		var tags struct {
+			Tags []string `json:"tags"`
+		}
+		err = decodeOCIResponse(resp, &tags)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("provider %s/%s not found in %s/%s", namespace, name, r.host, r.repository)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s: %w", repo, err)
+		}
+		for _, tag := range tags.Tags {
+			if _, err := version.NewVersion(tag); err == nil {
+				result = append(result, VersionInfo{Version: tag})
+			}
+		}
+		next = nextLink(resp, next)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return version.Must(version.NewVersion(result[i].Version)).LessThan(version.Must(version.NewVersion(result[j].Version)))
+	})
+	return result, nil
+}
+
+// GetLatestVersion returns the latest stable version tagged in a provider's
+// repository.
+func (r *OCIRegistry) GetLatestVersion(ctx context.Context, namespace, name string) (string, error) {
+	versions, err := r.GetVersions(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	return latestStable(versions, namespace, name)
+}
+
+// GetDownloadInfo resolves a provider version's tag to the archive of a
+// platform. SHA256Sum is the archive's OCI digest.
+func (r *OCIRegistry) GetDownloadInfo(ctx context.Context, namespace, name, version, goos, goarch string) (*DownloadInfo, error) {
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	repo := r.repositoryOf(namespace, name)
+
+	index, digest, err := r.manifest(ctx, repo, version)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	pinned := r.pins[namespace+"/"+name+"@"+version]
+	r.mu.Unlock()
+	if pinned != "" && pinned != digest {
+		return nil, fmt.Errorf("%s:%s resolved to %s, but %s is pinned", repo, version, digest, pinned)
+	}
+	if index.MediaType != ociIndexMediaType {
+		return nil, fmt.Errorf("%s:%s is not an OCI index", repo, version)
+	}
+
+	var platform *ociDescriptor
+	for i, m := range index.Manifests {
+		if m.Platform != nil && m.Platform.OS == goos && m.Platform.Architecture == goarch {
+			platform = &index.Manifests[i]
+			break
+		}
+	}
+	if platform == nil {
+		return nil, fmt.Errorf("version %s not found for provider %s/%s on %s_%s", version, namespace, name, goos, goarch)
+	}
+
+	manifest, _, err := r.manifest(ctx, repo, platform.Digest)
+	if err != nil {
+		return nil, err
+	}
+	var layer *ociDescriptor
+	for i, l := range manifest.Layers {
+		if l.MediaType == ociArchiveMediaType {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	sum, ok := strings.CutPrefix(layerDigest(layer), "sha256:")
+	if !ok {
+		return nil, fmt.Errorf("%s@%s has no sha256 zip archive layer", repo, platform.Digest)
+	}
+
+	filename := layer.Annotations["org.opencontainers.image.title"]
+	if filename == "" {
+		filename = fmt.Sprintf("terraform-provider-%s_%s_%s_%s.zip", name, version, goos, goarch)
+	}
+	return &DownloadInfo{
+		OS:          goos,
+		Arch:        goarch,
+		Filename:    filename,
+		DownloadURL: r.url(repo, "blobs/"+layer.Digest),
+		SHA256Sum:   sum,
+	}, nil
+}
+
+// DownloadToPath downloads a provider archive blob to destPath, verifying it
+// against its digest and reporting progress to the ProgressFunc set with
+// ContextWithProgress.
+func (r *OCIRegistry) DownloadToPath(ctx context.Context, info *DownloadInfo, destPath string) error {
+	repo, ok := r.blobRepository(info.DownloadURL)
+	if !ok {
+		return fmt.Errorf("not a blob URL of %s/%s: %q", r.host, r.repository, info.DownloadURL)
+	}
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	resp, err := r.get(ctx, repo, info.DownloadURL, "")
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+	return writeVerified(destPath, progressReader(ctx, resp.Body, 0, resp.ContentLength), info)
+}
+
+// manifest fetches the manifest or index at reference, a tag or digest, and
+// returns it with its digest.
+func (r *OCIRegistry) manifest(ctx context.Context, repo, reference string) (*ociManifest, string, error) {
+	resp, err := r.get(ctx, repo, r.url(repo, "manifests/"+reference), ociIndexMediaType+", "+ociManifestMediaType)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", fmt.Errorf("%s:%s not found", repo, reference)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("registry returned status %d for %s:%s", resp.StatusCode, repo, reference)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read manifest: %w", err)
+	}
+	sum := sha256.Sum256(body)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	if strings.HasPrefix(reference, "sha256:") && reference != digest {
+		return nil, "", fmt.Errorf("manifest %s:%s does not match its digest", repo, reference)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, "", fmt.Errorf("invalid manifest %s:%s: %w", repo, reference, err)
+	}
+	if manifest.MediaType == "" {
+		manifest.MediaType = resp.Header.Get("Content-Type")
+	}
+	return &manifest, digest, nil
+}
+
+// get sends an authenticated GET request for a repository, obtaining a
+// bearer token when challenged.
+func (r *OCIRegistry) get(ctx context.Context, repo, rawURL, accept string) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		r.mu.Lock()
+		token := r.tokens[repo]
+		r.mu.Unlock()
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authenticate(ctx, repo, challenge); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authenticate obtains a pull token for repo from the token service named in
+// a Bearer challenge.
+func (r *OCIRegistry) authenticate(ctx context.Context, repo, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("unsupported authentication challenge from %s: %q", r.host, challenge)
+	}
+	attrs := parseChallenge(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || realm.Host == "" {
+		return fmt.Errorf("invalid token realm from %s: %q", r.host, attrs["realm"])
+	}
+	query := realm.Query()
+	if service := attrs["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", "repository:"+repo+":pull")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	username, password := r.username, r.password
+	r.mu.Unlock()
+	if username != "" || password != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get token from %s: %w", realm.Host, err)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := decodeOCIResponse(resp, &token); err != nil {
+		return fmt.Errorf("failed to get token from %s: %w", realm.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+
+	r.mu.Lock()
+	r.tokens[repo] = token.Token
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *OCIRegistry) repositoryOf(namespace, name string) string {
+	return strings.ToLower(r.repository + "/" + namespace + "/" + name)
+}
+
+func (r *OCIRegistry) url(repo, path string) string {
+	return "https://" + r.host + "/v2/" + repo + "/" + path
+}
+
+// blobRepository returns the repository of a blob URL of this registry.
+func (r *OCIRegistry) blobRepository(rawURL string) (string, bool) {
+	rest, ok := strings.CutPrefix(rawURL, "https://"+r.host+"/v2/")
+	if !ok {
+		return "", false
+	}
+	repo, _, ok := strings.Cut(rest, "/blobs/")
+	return repo, ok
+}
+
+// decodeOCIResponse decodes a JSON response body and closes it, failing on
+// statuses other than 200.
+func decodeOCIResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(v)
+}
+
+// nextLink returns the URL of the next page of a paginated response, from
+// its Link header, or "".
+func nextLink(resp *http.Response, current string) string {
+	link := resp.Header.Get("Link")
+	target, rel, ok := strings.Cut(link, ";")
+	if !ok || !strings.Contains(rel, `rel="next"`) {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+	if err != nil {
+		return ""
+	}
+	return next.String()
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(params string) map[string]string {
+	attrs := make(map[string]string)
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(params, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+			params = strings.TrimPrefix(params, ",")
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		attrs[key] = value
+	}
+	return attrs
+}
+
+func layerDigest(layer *ociDescriptor) string {
+	if layer == nil {
+		return ""
+	}
+	return layer.Digest
+}
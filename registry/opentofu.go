@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const openTofuRegistryBaseURL = "https://registry.opentofu.org/v1/providers"
+
+// OpenTofuRegistry implements Registry for the OpenTofu registry. It speaks
+// the same protocol as the Terraform registry, but a provider's signing keys
+// are often published as a single armored block holding several keys, without
+// key IDs, and may be missing altogether for unsigned providers, which are
+// then accepted on their checksum alone.
+type OpenTofuRegistry struct {
+	*TerraformRegistry
+}
+
+// NewOpenTofuRegistry creates a new OpenTofuRegistry with the given HTTP client.
+// If client is nil, http.DefaultClient is used.
+func NewOpenTofuRegistry(client *http.Client, opts ...Option) *OpenTofuRegistry {
+	r := NewTerraformRegistry(client, opts...)
+	r.baseURL = openTofuRegistryBaseURL
+	r.allowUnsigned = true
+	return &OpenTofuRegistry{TerraformRegistry: r}
+}
+
+// GetDownloadInfo returns download information for a specific provider
+// version, with one SigningKey per armored key. SigningKeys is empty when the
+// provider has no published key.
+func (r *OpenTofuRegistry) GetDownloadInfo(ctx context.Context, namespace, name, version, goos, goarch string) (*DownloadInfo, error) {
+	info, err := r.TerraformRegistry.GetDownloadInfo(ctx, namespace, name, version, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []SigningKey
+	for _, key := range info.SigningKeys {
+		blocks := splitArmoredKeys(key.ASCIIArmor)
+		if len(blocks) == 1 {
+			keys = append(keys, key)
+			continue
+		}
+		for _, block := range blocks {
+			keys = append(keys, SigningKey{ASCIIArmor: block})
+		}
+	}
+	info.SigningKeys = keys
+	return info, nil
+}
+
+const armorBegin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+
+// splitArmoredKeys splits concatenated armored public key blocks.
+func splitArmoredKeys(armor string) []string {
+	var blocks []string
+	for _, part := range strings.SplitAfter(armor, "-----END PGP PUBLIC KEY BLOCK-----") {
+		if i := strings.Index(part, armorBegin); i >= 0 {
+			blocks = append(blocks, strings.TrimSpace(part[i:])+"\n")
+		}
+	}
+	if len(blocks) == 0 && strings.TrimSpace(armor) != "" {
+		blocks = append(blocks, armor)
+	}
+	return blocks
+}
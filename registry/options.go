@@ -0,0 +1,31 @@
+package registry
+
+import "time"
+
+// Option configures a TerraformRegistry.
+type Option func(*TerraformRegistry)
+
+// WithRetryPolicy sets how failed registry requests are retried, instead of
+// DefaultRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(r *TerraformRegistry) {
+		r.retry = policy
+	}
+}
+
+// WithResponseCache caches version lists and download info in dir. Cached
+// responses are used without a request for ttl, then revalidated with their
+// ETag, so repeated lookups don't count against registry rate limits.
+func WithResponseCache(dir string, ttl time.Duration) Option {
+	return func(r *TerraformRegistry) {
+		r.cache = &responseCache{dir: dir, ttl: ttl}
+	}
+}
+
+// WithTrustPolicy sets which provider release signatures are accepted. By
+// default any key published by the registry is, as with Terraform.
+func WithTrustPolicy(policy TrustPolicy) Option {
+	return func(r *TerraformRegistry) {
+		r.trust = policy
+	}
+}
@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"context"
+	"io"
+)
+
+// ProgressFunc is called as a provider archive downloads, with the number of
+// bytes received so far and the archive size, or -1 if it isn't known. It is
+// called often, from the downloading goroutine. downloaded includes bytes
+// received before a resumed download, and restarts from 0 when a download has
+// to start over.
+type ProgressFunc func(downloaded, total int64)
+
+type progressKey struct{}
+
+// ContextWithProgress returns a context making DownloadToPath report its
+// progress to fn.
+func ContextWithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ProgressFromContext returns the ProgressFunc set with ContextWithProgress,
+// or nil, for Registry implementations to report download progress to.
+func ProgressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// progressReader wraps r to report the progress set on ctx, if any.
+func progressReader(ctx context.Context, r io.Reader, start, total int64) io.Reader {
+	fn := ProgressFromContext(ctx)
+	if fn == nil {
+		return r
+	}
+	if total < 0 {
+		total = -1
+	}
+	fn(start, total)
+	return &countingReader{r: r, fn: fn, n: start, total: total}
+}
+
+type countingReader struct {
+	r     io.Reader
+	fn    ProgressFunc
+	n     int64
+	total int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.n += int64(n)
+		r.fn(r.n, r.total)
+	}
+	return n, err
+}
@@ -3,15 +3,17 @@ package registry
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
-	"sort"
-	"strconv"
-	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-version"
 )
 
 // Registry defines the interface for provider registries.
@@ -19,7 +21,7 @@ type Registry interface {
 	// GetVersions returns all available versions for a provider.
 	GetVersions(ctx context.Context, namespace, name string) ([]VersionInfo, error)
 
-	// GetLatestVersion returns the latest version for a provider.
+	// GetLatestVersion returns the latest stable (non-prerelease) version for a provider.
 	GetLatestVersion(ctx context.Context, namespace, name string) (string, error)
 
 	// GetDownloadInfo returns download information for a specific provider version.
@@ -31,22 +33,37 @@ type Registry interface {
 
 const terraformRegistryBaseURL = "https://registry.terraform.io/v1/providers"
 
-// TerraformRegistry implements Registry for the Terraform/OpenTofu registry.
+// TerraformRegistry implements Registry for registries speaking the Terraform
+// provider registry protocol.
 type TerraformRegistry struct {
 	client  *http.Client
-	baseURL string
+	baseURL string // providers.v1 endpoint, without trailing slash
+
+	host       string // when set, baseURL is found through service discovery
+	discoverMu sync.Mutex
+
+	retry RetryPolicy
+	cache *responseCache // nil disables response caching
+
+	trust         TrustPolicy
+	allowUnsigned bool // accept releases without signing keys, as OpenTofu does
 }
 
 // NewTerraformRegistry creates a new TerraformRegistry with the given HTTP client.
 // If client is nil, http.DefaultClient is used.
-func NewTerraformRegistry(client *http.Client) *TerraformRegistry {
+func NewTerraformRegistry(client *http.Client, opts ...Option) *TerraformRegistry {
 	if client == nil {
 		client = http.DefaultClient
 	}
-	return &TerraformRegistry{
+	r := &TerraformRegistry{
 		client:  client,
 		baseURL: terraformRegistryBaseURL,
+		retry:   DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
 type versionsResponse struct {
@@ -57,23 +74,32 @@ type versionsResponse struct {
 }
 
 type downloadResponse struct {
-	OS          string `json:"os"`
-	Arch        string `json:"arch"`
-	Filename    string `json:"filename"`
-	DownloadURL string `json:"download_url"`
-	SHA256Sum   string `json:"shasum"`
+	Protocols           []string `json:"protocols"`
+	OS                  string   `json:"os"`
+	Arch                string   `json:"arch"`
+	Filename            string   `json:"filename"`
+	DownloadURL         string   `json:"download_url"`
+	SHA256Sum           string   `json:"shasum"`
+	SHASumsURL          string   `json:"shasums_url"`
+	SHASumsSignatureURL string   `json:"shasums_signature_url"`
+	SigningKeys         struct {
+		GPGPublicKeys []struct {
+			KeyID          string `json:"key_id"`
+			ASCIIArmor     string `json:"ascii_armor"`
+			TrustSignature string `json:"trust_signature"`
+		} `json:"gpg_public_keys"`
+	} `json:"signing_keys"`
 }
 
 // GetVersions returns all available versions for a provider.
 func (r *TerraformRegistry) GetVersions(ctx context.Context, namespace, name string) ([]VersionInfo, error) {
-	url := fmt.Sprintf("%s/%s/%s/versions", r.baseURL, namespace, name)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	baseURL, err := r.providersURL(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	url := fmt.Sprintf("%s/%s/%s/versions", baseURL, namespace, name)
 
-	resp, err := r.client.Do(req)
+	resp, err := r.doCached(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch versions: %w", err)
 	}
@@ -103,32 +129,38 @@ func (r *TerraformRegistry) GetVersions(ctx context.Context, namespace, name str
 	return result, nil
 }
 
-// GetLatestVersion returns the latest version for a provider.
+// GetLatestVersion returns the latest stable version for a provider, comparing
+// versions by semver precedence.
 func (r *TerraformRegistry) GetLatestVersion(ctx context.Context, namespace, name string) (string, error) {
 	versions, err := r.GetVersions(ctx, namespace, name)
 	if err != nil {
 		return "", err
 	}
+	return latestStable(versions, namespace, name)
+}
 
+// latestStable returns the highest stable version of a provider.
+func latestStable(versions []VersionInfo, namespace, name string) (string, error) {
 	if len(versions) == 0 {
 		return "", fmt.Errorf("no versions found for provider %s/%s", namespace, name)
 	}
 
-	// Sort versions semantically to find the latest
-	sort.Slice(versions, func(i, j int) bool {
-		mi, ni, pi := semverParts(versions[i].Version)
-		mj, nj, pj := semverParts(versions[j].Version)
-		if mi != mj {
-			return mi < mj
+	// Pick the highest stable version: prereleases are never "latest"
+	var latest *version.Version
+	for _, v := range versions {
+		parsed, err := version.NewVersion(v.Version)
+		if err != nil || parsed.Prerelease() != "" {
+			continue
 		}
-		if ni != nj {
-			return ni < nj
+		if latest == nil || parsed.GreaterThan(latest) {
+			latest = parsed
 		}
-		return pi < pj
-	})
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no stable versions found for provider %s/%s, only prereleases", namespace, name)
+	}
 
-	// Return the last version (highest semver)
-	return versions[len(versions)-1].Version, nil
+	return latest.Original(), nil
 }
 
 // GetDownloadInfo returns download information for a specific provider version.
@@ -140,14 +172,13 @@ func (r *TerraformRegistry) GetDownloadInfo(ctx context.Context, namespace, name
 		goarch = runtime.GOARCH
 	}
 
-	url := fmt.Sprintf("%s/%s/%s/%s/download/%s/%s", r.baseURL, namespace, name, version, goos, goarch)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	baseURL, err := r.providersURL(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	url := fmt.Sprintf("%s/%s/%s/%s/download/%s/%s", baseURL, namespace, name, version, goos, goarch)
 
-	resp, err := r.client.Do(req)
+	resp, err := r.doCached(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch download info: %w", err)
 	}
@@ -166,68 +197,92 @@ func (r *TerraformRegistry) GetDownloadInfo(ctx context.Context, namespace, name
 		return nil, fmt.Errorf("failed to decode download response: %w", err)
 	}
 
-	return &DownloadInfo{
-		OS:          dl.OS,
-		Arch:        dl.Arch,
-		Filename:    dl.Filename,
-		DownloadURL: dl.DownloadURL,
-		SHA256Sum:   dl.SHA256Sum,
-	}, nil
+	info := &DownloadInfo{
+		Protocols:           dl.Protocols,
+		OS:                  dl.OS,
+		Arch:                dl.Arch,
+		Filename:            dl.Filename,
+		DownloadURL:         dl.DownloadURL,
+		SHA256Sum:           dl.SHA256Sum,
+		SHASumsURL:          dl.SHASumsURL,
+		SHASumsSignatureURL: dl.SHASumsSignatureURL,
+	}
+	for _, key := range dl.SigningKeys.GPGPublicKeys {
+		info.SigningKeys = append(info.SigningKeys, SigningKey{
+			KeyID:          key.KeyID,
+			ASCIIArmor:     key.ASCIIArmor,
+			TrustSignature: key.TrustSignature,
+		})
+	}
+	return info, nil
 }
 
-// DownloadToPath downloads the provider archive to a local path.
+// DownloadToPath downloads the provider archive to a local path, verifying it
+// against info.SHA256Sum while streaming. A mismatch fails with
+// *ErrChecksumMismatch and leaves no file behind. Before downloading, the
+// release's signed SHA256SUMS file is checked against the trust policy and
+// must list info.SHA256Sum, or *ErrSignatureVerification is returned.
+// Progress is reported to the ProgressFunc set with ContextWithProgress.
+//
+// Interrupted downloads are resumed with HTTP range requests when the server
+// supports them. The data received so far is kept in destPath+".partial"
+// until the download completes, so a later call with the same destPath
+// resumes it too.
 func (r *TerraformRegistry) DownloadToPath(ctx context.Context, info *DownloadInfo, destPath string) error {
+	if err := r.verifySignature(ctx, info); err != nil {
+		return err
+	}
+
 	// Create directory if needed
 	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, info.DownloadURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := r.client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to download: %w", err)
-	}
-	defer resp.Body.Close()
+	partial := newPartialDownload(destPath, info)
+	for attempt := 1; ; attempt++ {
+		err := r.downloadPart(ctx, info, partial)
+		if err == nil {
+			return partial.commit(destPath)
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download returned status %d", resp.StatusCode)
+		// The connection may drop mid-download: resume, or start over if the
+		// partial download couldn't be resumed
+		var mismatch *ErrChecksumMismatch
+		if errors.As(err, &mismatch) && !errors.Is(err, errRangeMismatch) {
+			return err
+		}
+		retry := attempt < r.retry.MaxAttempts && ctx.Err() == nil
+		if !errors.Is(err, errRangeMismatch) {
+			var wait time.Duration
+			if wait, retry = r.retry.delay(ctx, attempt, nil, err); retry {
+				if err := sleep(ctx, wait); err != nil {
+					return err
+				}
+			}
+		}
+		if !retry {
+			return err
+		}
 	}
+}
 
-	out, err := os.Create(destPath)
+// writeVerified writes r to path, replacing its content, and verifies it
+// against info.SHA256Sum. The file is removed if verification fails.
+func writeVerified(path string, r io.Reader, info *DownloadInfo) error {
+	out, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
 	defer out.Close()
 
-	if _, err := io.Copy(out, resp.Body); err != nil {
+	if err := copyVerified(out, r, info.SHA256Sum, info.DownloadURL); err != nil {
+		out.Close()
+		os.Remove(path)
+		var mismatch *ErrChecksumMismatch
+		if errors.As(err, &mismatch) {
+			return err
+		}
 		return fmt.Errorf("failed to write file: %w", err)
 	}
-
-	return nil
-}
-
-// semverParts parses a semantic version string into major, minor, patch.
-func semverParts(v string) (int, int, int) {
-	// Remove leading 'v' if present
-	v = strings.TrimPrefix(v, "v")
-	// Remove any prerelease suffix (e.g., -beta, -rc1)
-	if idx := strings.IndexAny(v, "-+"); idx != -1 {
-		v = v[:idx]
-	}
-	parts := strings.Split(v, ".")
-	var major, minor, patch int
-	if len(parts) >= 1 {
-		major, _ = strconv.Atoi(parts[0])
-	}
-	if len(parts) >= 2 {
-		minor, _ = strconv.Atoi(parts[1])
-	}
-	if len(parts) >= 3 {
-		patch, _ = strconv.Atoi(parts[2])
-	}
-	return major, minor, patch
+	return out.Close()
 }
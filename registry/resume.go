@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// partialDownload is an archive download in progress at <dest>.partial, with
+// a <dest>.partial.json sidecar recording what it is a download of, so that a
+// later attempt, or a later DownloadToPath call with the same destination,
+// can resume it with an HTTP Range request.
+type partialDownload struct {
+	path     string
+	metaPath string
+	meta     partialMeta
+}
+
+type partialMeta struct {
+	URL       string `json:"url"`
+	SHA256Sum string `json:"sha256"`
+	Validator string `json:"validator,omitempty"` // ETag or Last-Modified, for If-Range
+}
+
+func newPartialDownload(destPath string, info *DownloadInfo) *partialDownload {
+	return &partialDownload{
+		path:     destPath + ".partial",
+		metaPath: destPath + ".partial.json",
+		meta:     partialMeta{URL: info.DownloadURL, SHA256Sum: info.SHA256Sum},
+	}
+}
+
+// offset returns the number of bytes already downloaded, discarding a
+// partial download of something else, or one this user doesn't own.
+func (p *partialDownload) offset() int64 {
+	data, err := readPrivate(p.metaPath)
+	if err != nil {
+		p.discard()
+		return 0
+	}
+	var meta partialMeta
+	if json.Unmarshal(data, &meta) != nil || meta.URL != p.meta.URL || meta.SHA256Sum != p.meta.SHA256Sum || p.meta.SHA256Sum == "" {
+		p.discard()
+		return 0
+	}
+	stat, err := os.Lstat(p.path)
+	if err != nil || !stat.Mode().IsRegular() || !ownedByUser(stat) {
+		p.discard()
+		return 0
+	}
+	p.meta.Validator = meta.Validator
+	return stat.Size()
+}
+
+// open returns the partial file positioned at offset, after writing the
+// sidecar, and a hash of its content so far.
+func (p *partialDownload) open(offset int64) (*os.File, hash.Hash, error) {
+	data, err := json.Marshal(p.meta)
+	if err != nil {
+		return nil, nil, err
+	}
+	meta, err := openPrivate(p.metaPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = meta.Write(data)
+	if cerr := meta.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := openPrivate(p.path, flags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, f, offset); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, h, nil
+}
+
+// commit moves the completed download to destPath.
+func (p *partialDownload) commit(destPath string) error {
+	os.Remove(p.metaPath)
+	if err := os.Rename(p.path, destPath); err != nil {
+		return fmt.Errorf("failed to move download into place: %w", err)
+	}
+	return nil
+}
+
+// openPrivate opens a file of a partial download, created readable by this
+// user only. It refuses symbolic links and files of other users, which could
+// feed the download data of their own.
+func openPrivate(path string, flags int) (*os.File, error) {
+	f, err := os.OpenFile(path, flags|noFollow, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() || !ownedByUser(stat) {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a file owned by the current user", path)
+	}
+	return f, nil
+}
+
+// readPrivate reads a file of a partial download, see openPrivate.
+func readPrivate(path string) ([]byte, error) {
+	f, err := openPrivate(path, os.O_RDONLY)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+func (p *partialDownload) discard() {
+	os.Remove(p.path)
+	os.Remove(p.metaPath)
+}
+
+// errRangeMismatch is returned when a partial download can't be resumed, or
+// resuming it didn't produce the expected archive; the next attempt starts
+// over.
+var errRangeMismatch = errors.New("server did not resume the download at the requested offset")
+
+// downloadPart downloads info's archive into p, resuming from where a previous
+// attempt stopped when the server supports range requests, and verifies the
+// complete file against info.SHA256Sum.
+func (r *TerraformRegistry) downloadPart(ctx context.Context, info *DownloadInfo, p *partialDownload) error {
+	offset := p.offset()
+	var header http.Header
+	if offset > 0 {
+		header = http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+		if p.meta.Validator != "" {
+			header.Set("If-Range", p.meta.Validator)
+		}
+	}
+
+	resp, err := r.doWithHeader(ctx, info.DownloadURL, header)
+	if err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Ranges unsupported, or the archive changed: start over
+		offset = 0
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp); !ok || start != offset {
+			p.discard()
+			return errRangeMismatch
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		p.discard()
+		return errRangeMismatch
+	default:
+		return fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	p.meta.Validator = resp.Header.Get("ETag")
+	if p.meta.Validator == "" {
+		p.meta.Validator = resp.Header.Get("Last-Modified")
+	}
+	f, h, err := p.open(offset)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	if _, err := io.Copy(io.MultiWriter(f, h), progressReader(ctx, resp.Body, offset, total)); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if actual := hex.EncodeToString(h.Sum(nil)); info.SHA256Sum != "" && !strings.EqualFold(actual, info.SHA256Sum) {
+		p.discard()
+		err := &ErrChecksumMismatch{URL: info.DownloadURL, Expected: info.SHA256Sum, Actual: actual}
+		if offset > 0 {
+			// The partial download may have been corrupted: try again from scratch
+			return fmt.Errorf("%w: %w", errRangeMismatch, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte position of a 206 response.
+func contentRangeStart(resp *http.Response) (int64, bool) {
+	// Content-Range: bytes <start>-<end>/<size>
+	value, ok := strings.CutPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
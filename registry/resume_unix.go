@@ -0,0 +1,17 @@
+//go:build unix
+
+package registry
+
+import (
+	"os"
+	"syscall"
+)
+
+// noFollow makes opening a partial download fail if it is a symbolic link.
+const noFollow = syscall.O_NOFOLLOW
+
+// ownedByUser reports whether info is of a file owned by the current user.
+func ownedByUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
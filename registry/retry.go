@@ -0,0 +1,133 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls retries of registry requests failing with a transient
+// network error, 429 Too Many Requests or a 5xx status.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts; 1 disables retries
+	MinBackoff  time.Duration // delay before the first retry, doubled after each one
+	MaxBackoff  time.Duration // delay cap; a longer Retry-After is not waited for
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// do sends a GET request, retrying transient failures. It returns the last
+// response or error once attempts are exhausted.
+func (r *TerraformRegistry) do(ctx context.Context, rawURL string) (*http.Response, error) {
+	return r.doWithHeader(ctx, rawURL, nil)
+}
+
+// doWithHeader is do with extra request headers.
+func (r *TerraformRegistry) doWithHeader(ctx context.Context, rawURL string, header http.Header) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		resp, err := r.client.Do(req)
+		wait, retry := r.retry.delay(ctx, attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// delay reports whether a request that failed on the given attempt should be
+// retried, and after how long.
+func (p RetryPolicy) delay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		if !isTransient(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return 0, false
+	}
+	if after, ok := retryAfter(resp); ok {
+		return after, after <= p.MaxBackoff
+	}
+	return p.backoff(attempt), true
+}
+
+// backoff returns the exponential delay before retrying after attempt, with
+// jitter so that clients failing together don't retry together.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff << (attempt - 1)
+	if d > p.MaxBackoff || d <= 0 {
+		d = p.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// isTransient reports whether err is a network failure worth retrying.
+func isTransient(err error) bool {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// retryAfter parses the Retry-After header, in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,199 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrSearchNotSupported is returned by Searcher implementations for registries
+// that don't offer provider search, such as those that only implement the
+// provider registry protocol.
+var ErrSearchNotSupported = errors.New("registry does not support provider search")
+
+// Searcher is implemented by registries that can list and search the
+// providers they serve.
+type Searcher interface {
+	// Search returns the providers matching query, most relevant first.
+	Search(ctx context.Context, query string) ([]ProviderSummary, error)
+
+	// ListProviders returns the providers published in a namespace.
+	ListProviders(ctx context.Context, namespace string) ([]ProviderSummary, error)
+}
+
+// ProviderSummary describes a provider found by a Searcher.
+type ProviderSummary struct {
+	Namespace   string
+	Name        string
+	Description string
+	Tier        string // "official", "partner" or "community", if known
+	Downloads   int64
+	Source      string // source repository URL, if known
+}
+
+// searchPageSize is the number of providers requested per page. Search only
+// returns the first page; ListProviders follows every page.
+const searchPageSize = 50
+
+type providersV2Response struct {
+	Data []struct {
+		Attributes struct {
+			Namespace   string `json:"namespace"`
+			Name        string `json:"name"`
+			Description string `json:"description"`
+			Tier        string `json:"tier"`
+			Downloads   int64  `json:"downloads"`
+			Source      string `json:"source"`
+			Unlisted    bool   `json:"unlisted"`
+		} `json:"attributes"`
+	} `json:"data"`
+	Meta struct {
+		Pagination struct {
+			NextPage *int `json:"next-page"`
+		} `json:"pagination"`
+	} `json:"meta"`
+}
+
+// Search returns the providers matching query, using the registry's v2
+// providers API.
+func (r *TerraformRegistry) Search(ctx context.Context, query string) ([]ProviderSummary, error) {
+	return r.listV2(ctx, url.Values{"filter[query]": {query}}, false)
+}
+
+// ListProviders returns the providers published in a namespace, using the
+// registry's v2 providers API.
+func (r *TerraformRegistry) ListProviders(ctx context.Context, namespace string) ([]ProviderSummary, error) {
+	return r.listV2(ctx, url.Values{"filter[namespace]": {namespace}}, true)
+}
+
+// listV2 queries the v2 providers endpoint next to the registry's providers.v1
+// endpoint, following pages if all is set.
+func (r *TerraformRegistry) listV2(ctx context.Context, query url.Values, all bool) ([]ProviderSummary, error) {
+	baseURL, err := r.providersURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid registry URL %q: %w", baseURL, err)
+	}
+	endpoint.Path = "/v2/providers"
+	query.Set("page[size]", strconv.Itoa(searchPageSize))
+
+	var result []ProviderSummary
+	for page := 1; ; page++ {
+		query.Set("page[number]", strconv.Itoa(page))
+		endpoint.RawQuery = query.Encode()
+
+		providers, err := r.fetchProvidersPage(ctx, endpoint.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range providers.Data {
+			if p.Attributes.Unlisted {
+				continue
+			}
+			result = append(result, ProviderSummary{
+				Namespace:   p.Attributes.Namespace,
+				Name:        p.Attributes.Name,
+				Description: p.Attributes.Description,
+				Tier:        p.Attributes.Tier,
+				Downloads:   p.Attributes.Downloads,
+				Source:      p.Attributes.Source,
+			})
+		}
+
+		next := providers.Meta.Pagination.NextPage
+		if !all || next == nil || *next <= page {
+			return result, nil
+		}
+	}
+}
+
+func (r *TerraformRegistry) fetchProvidersPage(ctx context.Context, url string) (*providersV2Response, error) {
+	resp, err := r.do(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search providers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSearchNotSupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned status %d for provider search", resp.StatusCode)
+	}
+
+	var providers providersV2Response
+	if err := json.NewDecoder(resp.Body).Decode(&providers); err != nil {
+		return nil, fmt.Errorf("failed to decode provider search response: %w", err)
+	}
+	return &providers, nil
+}
+
+// Search returns ErrSearchNotSupported: the OpenTofu registry has no search
+// API.
+func (r *OpenTofuRegistry) Search(ctx context.Context, query string) ([]ProviderSummary, error) {
+	return nil, ErrSearchNotSupported
+}
+
+// ListProviders returns ErrSearchNotSupported: the OpenTofu registry has no
+// search API.
+func (r *OpenTofuRegistry) ListProviders(ctx context.Context, namespace string) ([]ProviderSummary, error) {
+	return nil, ErrSearchNotSupported
+}
+
+// Search returns the providers in the mirror whose "namespace/name" contains
+// query, ignoring case.
+func (m *FilesystemMirror) Search(ctx context.Context, query string) ([]ProviderSummary, error) {
+	namespaces, err := os.ReadDir(filepath.Join(m.dir, m.host))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read mirror: %w", err)
+	}
+
+	query = strings.ToLower(query)
+	var result []ProviderSummary
+	for _, ns := range namespaces {
+		if !ns.IsDir() {
+			continue
+		}
+		providers, err := m.ListProviders(ctx, ns.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range providers {
+			if strings.Contains(strings.ToLower(p.Namespace+"/"+p.Name), query) {
+				result = append(result, p)
+			}
+		}
+	}
+	return result, nil
+}
+
+// ListProviders returns the providers of a namespace in the mirror.
+func (m *FilesystemMirror) ListProviders(ctx context.Context, namespace string) ([]ProviderSummary, error) {
+	entries, err := os.ReadDir(filepath.Join(m.dir, m.host, namespace))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror: %w", err)
+	}
+
+	var result []ProviderSummary
+	for _, entry := range entries {
+		if entry.IsDir() {
+			result = append(result, ProviderSummary{Namespace: namespace, Name: entry.Name()})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
@@ -8,9 +8,20 @@ type VersionInfo struct {
 
 // DownloadInfo contains information for downloading a provider.
 type DownloadInfo struct {
-	OS          string
-	Arch        string
-	Filename    string
-	DownloadURL string
-	SHA256Sum   string
+	Protocols           []string
+	OS                  string
+	Arch                string
+	Filename            string
+	DownloadURL         string
+	SHA256Sum           string
+	SHASumsURL          string // SHA256SUMS file covering every platform's archive
+	SHASumsSignatureURL string // detached GPG signature of the SHA256SUMS file
+	SigningKeys         []SigningKey
+}
+
+// SigningKey is a GPG public key that may have signed a provider's SHA256SUMS file.
+type SigningKey struct {
+	KeyID          string // may be empty; the armored key is authoritative
+	ASCIIArmor     string // one or more armored public keys
+	TrustSignature string // armored signature of ASCIIArmor by a partner key, if any
 }
@@ -0,0 +1,98 @@
+package tfclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+// Metadata keys identifying the target provider of a call proxied through an agent.
+const (
+	agentNamespaceKey = "tf-provider-namespace"
+	agentNameKey      = "tf-provider-name"
+	agentVersionKey   = "tf-provider-version"
+	agentAliasKey     = "tf-provider-alias"
+)
+
+// remoteAgent holds the settings used to reach a provider execution agent.
+type remoteAgent struct {
+	target string
+	creds  credentials.TransportCredentials
+}
+
+// remoteConn is a connection to a provider launched by a remote agent.
+type remoteConn struct {
+	cc  *grpc.ClientConn
+	key string // provider key, used as the health check service name
+}
+
+func (c *remoteConn) Exited() bool {
+	return c.cc.GetState() == connectivity.Shutdown
+}
+
+func (c *remoteConn) Ping(ctx context.Context) error {
+	resp, err := grpc_health_v1.NewHealthClient(c.cc).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: c.key})
+	if err != nil {
+		return err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("agent reports provider %s as %s", c.key, resp.Status)
+	}
+	return nil
+}
+
+func (c *remoteConn) Kill() {
+	c.cc.Close()
+}
+
+// Terminate closes the connection at once: the process is the agent's to
+// stop.
+func (c *remoteConn) Terminate(time.Duration) {
+	c.Kill()
+}
+
+// Pid always returns 0: the process runs on the agent's host.
+func (c *remoteConn) Pid() int {
+	return 0
+}
+
+// KilledBy always returns "": resource limits are enforced by the agent.
+func (c *remoteConn) KilledBy() string {
+	return ""
+}
+
+// launchRemote connects to the agent and returns an instance whose calls are
+// routed to the given provider. The agent downloads and launches the provider
+// on first use.
+func launchRemote(agent *remoteAgent, cfg ProviderConfig, dialOpts []grpc.DialOption) (*pluginInstance, error) {
+	md := metadata.Pairs(
+		agentNamespaceKey, cfg.Namespace,
+		agentNameKey, cfg.Name,
+		agentVersionKey, cfg.Version,
+		agentAliasKey, cfg.Alias,
+	)
+	withProvider := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+	}
+
+	opts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(agent.creds),
+		grpc.WithUnaryInterceptor(withProvider),
+	}, dialOpts...)
+	cc, err := grpc.NewClient(agent.target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent %s: %w", agent.target, err)
+	}
+
+	return &pluginInstance{
+		conn:       &remoteConn{cc: cc, key: cfg.String()},
+		grpcClient: tfplugin6.NewProviderClient(cc),
+	}, nil
+}
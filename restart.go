@@ -0,0 +1,178 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errProviderClosed is returned when a restart is attempted after Close.
+var errProviderClosed = errors.New("provider closed")
+
+// call invokes fn against the least busy plugin instance.
+func (p *provider) call(ctx context.Context, fn func(ctx context.Context, client tfplugin6.ProviderClient) error) error {
+	inst := p.acquire()
+	defer inst.release()
+	return p.callInstance(ctx, inst, fn)
+}
+
+// callInstance invokes fn against inst, through the circuit breaker of the
+// provider if any, bounded by the call timeout of the provider if any. When
+// auto-restart is enabled and fn failed because the provider process died,
+// the process is relaunched and fn is retried once against the replacement.
+// Calls that ran out of time fail with ErrCallTimeout.
+func (p *provider) callInstance(ctx context.Context, inst *pluginInstance, fn func(ctx context.Context, client tfplugin6.ProviderClient) error) error {
+	callCtx := ctx
+	if p.callTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, p.callTimeout)
+		defer cancel()
+	}
+	err := p.guard(ctx, func() error {
+		return p.supervise(callCtx, inst, fn)
+	})
+	if err == nil || !deadlineExceeded(err) {
+		return err
+	}
+	timeout := &ErrCallTimeout{Namespace: p.namespace, Name: p.name, Version: p.version, Err: err}
+	if ctx.Err() == nil {
+		timeout.Timeout = p.callTimeout
+	}
+	return timeout
+}
+
+// deadlineExceeded reports whether err, returned by a call, is the call
+// running out of time, locally or as reported by the provider over gRPC.
+func deadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
+
+// supervise invokes fn against inst, relaunching the process if it died, as
+// callInstance describes.
+func (p *provider) supervise(ctx context.Context, inst *pluginInstance, fn func(ctx context.Context, client tfplugin6.ProviderClient) error) error {
+	p.calls.Add(1)
+	p.touch()
+	err := fn(ctx, inst.grpcClient)
+	if err == nil || !inst.dead(err) {
+		return err
+	}
+	err = p.crashed(inst, err)
+	p.exited(inst, false, err)
+
+	// A process killed for exceeding its limits would likely be killed again,
+	// so report it instead of retrying. The next call gets a fresh process.
+	if reason := inst.conn.KilledBy(); reason != "" {
+		if p.autoRestart {
+			if _, rerr := p.restart(ctx, inst); rerr != nil {
+				p.logger.Error(rerr, "failed to restart provider", "provider", p.Config().String())
+			}
+		}
+		return &ErrProviderKilled{
+			Namespace: p.namespace,
+			Name:      p.name,
+			Version:   p.version,
+			Reason:    reason,
+		}
+	}
+
+	if !p.autoRestart {
+		return err
+	}
+
+	replacement, rerr := p.restart(ctx, inst)
+	if rerr != nil {
+		return fmt.Errorf("%w (restart failed: %v)", err, rerr)
+	}
+	// Mark the retry in flight on the replacement, as acquire does, so that
+	// eviction and retire wait for it
+	replacement.inflight.Add(1)
+	defer replacement.release()
+	return fn(ctx, replacement.grpcClient)
+}
+
+// dead reports whether err was caused by the plugin process going away.
+func (i *pluginInstance) dead(err error) bool {
+	return i.conn.Exited() || status.Code(err) == codes.Unavailable
+}
+
+// restart replaces the failed instance with a freshly launched process,
+// re-fetches the schema and replays the last Configure call. Concurrent callers
+// that observed the same failed instance share a single restart; all of them
+// get the replacement.
+func (p *provider) restart(ctx context.Context, failed *pluginInstance) (*pluginInstance, error) {
+	p.restartMu.Lock()
+	defer p.restartMu.Unlock()
+
+	if current := p.instances()[failed.slot]; current != failed {
+		return current, nil // already restarted by another caller
+	}
+	if p.closed() {
+		return nil, errProviderClosed
+	}
+
+	p.logger.Info("restarting provider", "provider", p.Config().String(), "slot", failed.slot)
+
+	inst, err := p.launch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to relaunch provider: %w", err)
+	}
+	inst.slot = failed.slot
+
+	// A schema deferred by WithLazySchemas is fetched when needed
+	var schema *tfplugin6.GetProviderSchema_Response
+	if p.providerSchema() != nil {
+		if schema, err = p.loadSchema(ctx, inst.grpcClient); err != nil {
+			inst.kill()
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	req := p.configureReq
+	p.mu.Unlock()
+
+	if req != nil {
+		resp, err := inst.grpcClient.ConfigureProvider(ctx, req)
+		if err == nil {
+			err = checkDiagnostics(resp.Diagnostics)
+		}
+		if err != nil {
+			inst.kill()
+			return nil, fmt.Errorf("failed to replay configure: %w", err)
+		}
+	}
+
+	p.mu.Lock()
+	p.insts[failed.slot] = inst
+	if schema != nil {
+		p.schema = schema
+	}
+	p.mu.Unlock()
+
+	failed.kill()
+
+	// Close may have run while we were launching; don't leak the new process.
+	if p.closed() {
+		inst.kill()
+		return nil, errProviderClosed
+	}
+
+	p.exited(failed, false, errProcessExited)
+	p.events.emit(&ProviderLaunched{Provider: p.Config(), Slot: inst.slot, Restart: true})
+	p.logger.Info("provider restarted", "provider", p.Config().String(), "slot", inst.slot)
+	return inst, nil
+}
+
+// closed reports whether Close has been called.
+func (p *provider) closed() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
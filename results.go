@@ -0,0 +1,319 @@
+package tfclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// resultsBucket is the bbolt bucket of persisted results.
+var resultsBucket = []byte("results")
+
+// resultPruneInterval is how often put drops the expired results, which are
+// otherwise only dropped when read again.
+const resultPruneInterval = time.Minute
+
+// ResultCacheOptions configure a ResultCache.
+type ResultCacheOptions struct {
+	// TTL is how long the results of data sources not in TTLs are reused.
+	TTL time.Duration
+	// TTLs override TTL by data source type, e.g. {"aws_ami": time.Hour}. A
+	// TTL of 0 disables caching.
+	TTLs map[string]time.Duration
+	// Path is a bbolt database file persisting results across restarts
+	// (optional, results are kept in memory only if empty).
+	Path string
+}
+
+// ResultCache is a read-through cache of data source states for
+// WithResultCache, so that identical reads in a short window don't call the
+// provider again. Results are keyed by provider version, provider
+// configuration, data source and data source configuration, and expire
+// after their TTL. It is safe for concurrent use, and can be shared by
+// several clients.
+type ResultCache struct {
+	ttl  time.Duration
+	ttls map[string]time.Duration
+	db   *bolt.DB // nil without Path
+
+	// writeMu serializes the changes to the cache, keeping the entries and
+	// the database in sync. Reads of the entries only take mu, so that cache
+	// hits don't wait for database writes.
+	writeMu   sync.Mutex
+	nextPrune time.Time // when put next drops the expired results
+
+	mu      sync.Mutex
+	entries map[string]*resultEntry
+}
+
+// resultEntry is a cached data source state.
+type resultEntry struct {
+	Provider   string         `json:"provider"` // namespace/name
+	DataSource string         `json:"data_source"`
+	State      map[string]any `json:"state"`
+	Sensitive  []string       `json:"sensitive,omitempty"` // see DataSourceResult.Sensitive
+	CachedAt   time.Time      `json:"cached_at"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+}
+
+// NewResultCache creates a ResultCache, opening its database if
+// opts.Path is set. Close it when done.
+func NewResultCache(opts ResultCacheOptions) (*ResultCache, error) {
+	rc := &ResultCache{
+		ttl:     opts.TTL,
+		ttls:    opts.TTLs,
+		entries: make(map[string]*resultEntry),
+	}
+	if opts.Path == "" {
+		return rc, nil
+	}
+
+	db, err := bolt.Open(opts.Path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open result cache %s: %w", opts.Path, err)
+	}
+	// Create the bucket and drop the results that expired while closed
+	now := time.Now()
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(resultsBucket)
+		if err != nil {
+			return err
+		}
+		return deleteEntries(b, func(e *resultEntry) bool { return !now.Before(e.ExpiresAt) })
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open result cache %s: %w", opts.Path, err)
+	}
+	rc.db = db
+	return rc, nil
+}
+
+// Close closes the database of the cache, if any.
+func (rc *ResultCache) Close() error {
+	if rc.db == nil {
+		return nil
+	}
+	return rc.db.Close()
+}
+
+// Invalidate removes the cached results of a data source of a provider,
+// returning how many were removed. Empty arguments match any namespace, name
+// or data source, so Invalidate("", "", "") empties the cache.
+func (rc *ResultCache) Invalidate(namespace, name, dataSource string) (int, error) {
+	match := func(e *resultEntry) bool {
+		ns, n, _ := strings.Cut(e.Provider, "/")
+		return (namespace == "" || ns == namespace) &&
+			(name == "" || n == name) &&
+			(dataSource == "" || e.DataSource == dataSource)
+	}
+
+	rc.writeMu.Lock()
+	defer rc.writeMu.Unlock()
+	rc.mu.Lock()
+	removed := 0
+	for key, e := range rc.entries {
+		if match(e) {
+			delete(rc.entries, key)
+			removed++
+		}
+	}
+	rc.mu.Unlock()
+	if rc.db == nil {
+		return removed, nil
+	}
+
+	// Entries loaded in memory are also in the database
+	removed = 0
+	err := rc.db.Update(func(tx *bolt.Tx) error {
+		return deleteEntries(tx.Bucket(resultsBucket), func(e *resultEntry) bool {
+			if match(e) {
+				removed++
+				return true
+			}
+			return false
+		})
+	})
+	return removed, err
+}
+
+// ttlFor returns the TTL of the results of a data source.
+func (rc *ResultCache) ttlFor(dataSource string) time.Duration {
+	if ttl, ok := rc.ttls[dataSource]; ok {
+		return ttl
+	}
+	return rc.ttl
+}
+
+// resultKey identifies a read by the provider version, the configuration it
+// was configured with and the data source configuration, both encoded as
+// msgpack.
+func resultKey(providerKey string, providerConfig []byte, dataSource string, config []byte) string {
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(providerKey), providerConfig, []byte(dataSource), config} {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the unexpired result cached under key, or nil.
+func (rc *ResultCache) get(key string) *resultEntry {
+	rc.mu.Lock()
+	e := rc.entries[key]
+	rc.mu.Unlock()
+
+	if e == nil && rc.db != nil {
+		rc.db.View(func(tx *bolt.Tx) error {
+			if data := tx.Bucket(resultsBucket).Get([]byte(key)); data != nil {
+				// Keep the precision of numbers, see copyState
+				var loaded resultEntry
+				dec := json.NewDecoder(bytes.NewReader(data))
+				dec.UseNumber()
+				if dec.Decode(&loaded) == nil {
+					e = &loaded
+				}
+			}
+			return nil
+		})
+		if e != nil && time.Now().Before(e.ExpiresAt) {
+			rc.mu.Lock()
+			if _, ok := rc.entries[key]; !ok {
+				rc.entries[key] = e
+			}
+			rc.mu.Unlock()
+		}
+	}
+	if e == nil {
+		return nil
+	}
+	if !time.Now().Before(e.ExpiresAt) {
+		rc.writeMu.Lock()
+		defer rc.writeMu.Unlock()
+		rc.mu.Lock()
+		current := rc.entries[key]
+		if current == e {
+			delete(rc.entries, key)
+		}
+		rc.mu.Unlock()
+		// Unless a fresh result replaced it meanwhile
+		if rc.db != nil && (current == e || current == nil) {
+			rc.db.Update(func(tx *bolt.Tx) error { return tx.Bucket(resultsBucket).Delete([]byte(key)) })
+		}
+		return nil
+	}
+	return e
+}
+
+// put caches a result under key, unless caching of its data source is
+// disabled, returning the entry or nil.
+func (rc *ResultCache) put(key, provider, dataSource string, state map[string]any, sensitive []string) (*resultEntry, error) {
+	ttl := rc.ttlFor(dataSource)
+	if ttl <= 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	e := &resultEntry{Provider: provider, DataSource: dataSource, State: state, Sensitive: sensitive, CachedAt: now, ExpiresAt: now.Add(ttl)}
+
+	rc.writeMu.Lock()
+	defer rc.writeMu.Unlock()
+	prune := !now.Before(rc.nextPrune)
+	if prune {
+		rc.nextPrune = now.Add(resultPruneInterval)
+	}
+	rc.mu.Lock()
+	rc.entries[key] = e
+	if prune {
+		for k, entry := range rc.entries {
+			if !now.Before(entry.ExpiresAt) {
+				delete(rc.entries, k)
+			}
+		}
+	}
+	rc.mu.Unlock()
+	if rc.db == nil {
+		return e, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	return e, rc.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(resultsBucket)
+		if prune {
+			if err := deleteEntries(b, func(e *resultEntry) bool { return !now.Before(e.ExpiresAt) }); err != nil {
+				return err
+			}
+		}
+		return b.Put([]byte(key), data)
+	})
+}
+
+// deleteEntries deletes the entries of b for which match returns true.
+func deleteEntries(b *bolt.Bucket, match func(*resultEntry) bool) error {
+	var keys [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var e resultEntry
+		if json.Unmarshal(v, &e) != nil || match(&e) {
+			keys = append(keys, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyState returns a deep copy of a state, so that callers modifying a
+// cached result don't alter the cache, with numbers as float64, or as
+// json.Number with exactNumbers.
+func copyState(state map[string]any, exactNumbers bool) map[string]any {
+	if state == nil {
+		return nil
+	}
+	return copyValue(state, exactNumbers).(map[string]any)
+}
+
+func copyValue(v any, exactNumbers bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = copyValue(e, exactNumbers)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = copyValue(e, exactNumbers)
+		}
+		return out
+	case float64:
+		if exactNumbers {
+			return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return v
+	case json.Number:
+		if !exactNumbers {
+			f, _ := v.Float64()
+			return f
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,45 @@
+package tfclient
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// SandboxPolicy restricts what a provider process may do. Providers are
+// arbitrary binaries downloaded from a registry, so sandboxing limits the damage
+// a compromised or misbehaving one can do on the host.
+//
+// On Linux the policy is enforced with Landlock (filesystem) and seccomp
+// (network); on macOS with sandbox-exec. Launching fails on other platforms or
+// when the kernel lacks the required features.
+type SandboxPolicy struct {
+	// WritablePaths are the files and directories the provider may write to.
+	// Everything else is read-only. The system temporary directory is always
+	// writable since go-plugin creates the provider socket there.
+	WritablePaths []string
+
+	// AllowNetwork permits TCP/UDP sockets. Most providers need it to reach
+	// their API. Raw and packet sockets are always denied.
+	AllowNetwork bool
+}
+
+// writablePaths returns the absolute paths the provider may write to.
+func (p SandboxPolicy) writablePaths() []string {
+	paths := []string{os.TempDir(), os.DevNull}
+	for _, path := range p.WritablePaths {
+		if abs, err := filepath.Abs(path); err == nil {
+			paths = append(paths, abs)
+		}
+	}
+	return paths
+}
+
+// prepareSandbox adjusts cmd for the policy. It returns the setup restricting
+// the thread the process is started from, or nil if none is needed.
+func prepareSandbox(cmd *exec.Cmd, policy *SandboxPolicy) (threadSetup, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	return platformSandbox(cmd, *policy)
+}
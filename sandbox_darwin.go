@@ -0,0 +1,46 @@
+package tfclient
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const sandboxExecPath = "/usr/bin/sandbox-exec"
+
+// platformSandbox wraps the provider command in sandbox-exec with a profile
+// generated from the policy.
+func platformSandbox(cmd *exec.Cmd, policy SandboxPolicy) (threadSetup, error) {
+	if _, err := exec.LookPath(sandboxExecPath); err != nil {
+		return nil, fmt.Errorf("sandbox-exec is not available: %w", err)
+	}
+
+	args := append([]string{sandboxExecPath, "-p", sandboxProfile(policy), cmd.Path}, cmd.Args[1:]...)
+	cmd.Path = sandboxExecPath
+	cmd.Args = args
+	return nil, nil
+}
+
+// sandboxProfile renders the policy as a Sandbox Profile Language document.
+func sandboxProfile(policy SandboxPolicy) string {
+	var b strings.Builder
+	b.WriteString("(version 1)\n(allow default)\n(deny file-write*)\n")
+
+	b.WriteString("(allow file-write*")
+	for _, path := range policy.writablePaths() {
+		// Profiles match resolved paths, e.g. /private/tmp rather than /tmp.
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		fmt.Fprintf(&b, " (subpath %s)", strconv.Quote(path))
+	}
+	b.WriteString(")\n")
+
+	if !policy.AllowNetwork {
+		// go-plugin talks to the provider over a unix socket.
+		b.WriteString("(deny network*)\n(allow network* (local unix-socket) (remote unix-socket))\n")
+	}
+	return b.String()
+}
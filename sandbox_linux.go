@@ -0,0 +1,211 @@
+package tfclient
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Landlock write rights, by ABI version.
+const (
+	landlockWriteV1 = unix.LANDLOCK_ACCESS_FS_WRITE_FILE |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_DIR |
+		unix.LANDLOCK_ACCESS_FS_REMOVE_FILE |
+		unix.LANDLOCK_ACCESS_FS_MAKE_CHAR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_DIR |
+		unix.LANDLOCK_ACCESS_FS_MAKE_REG |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_FIFO |
+		unix.LANDLOCK_ACCESS_FS_MAKE_BLOCK |
+		unix.LANDLOCK_ACCESS_FS_MAKE_SYM
+	landlockWriteV2 = landlockWriteV1 | unix.LANDLOCK_ACCESS_FS_REFER
+	landlockWriteV3 = landlockWriteV2 | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+
+	// Rights that apply to regular files, as opposed to directories.
+	landlockFileRights = unix.LANDLOCK_ACCESS_FS_WRITE_FILE | unix.LANDLOCK_ACCESS_FS_TRUNCATE
+)
+
+// Offsets in struct seccomp_data.
+const (
+	seccompNrOffset   = 0
+	seccompArchOffset = 4
+	seccompArg0Offset = 16
+	seccompArg1Offset = 24
+)
+
+const x32SyscallBit = 0x40000000
+
+// platformSandbox builds the Landlock ruleset and seccomp filter up front, and
+// returns a setup applying them to the thread the provider is started from.
+// Both are inherited by the child process and neither affects the rest of this
+// process.
+func platformSandbox(cmd *exec.Cmd, policy SandboxPolicy) (threadSetup, error) {
+	filter, err := seccompFilter(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	rulesetFD, err := landlockRuleset(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		defer unix.Close(rulesetFD)
+
+		if err := restrictThread(rulesetFD, filter); err != nil {
+			return fmt.Errorf("failed to apply sandbox: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// restrictThread applies the sandbox to the calling thread.
+func restrictThread(rulesetFD int, filter []unix.SockFilter) error {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_LANDLOCK_RESTRICT_SELF, uintptr(rulesetFD), 0, 0); errno != 0 {
+		return fmt.Errorf("landlock_restrict_self: %w", errno)
+	}
+
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if _, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, 0, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("seccomp: %w", errno)
+	}
+	return nil
+}
+
+// landlockRuleset creates a ruleset denying writes outside the policy's
+// writable paths and returns its file descriptor.
+func landlockRuleset(policy SandboxPolicy) (int, error) {
+	abi, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, 0, 0, unix.LANDLOCK_CREATE_RULESET_VERSION)
+	if errno != 0 {
+		return -1, fmt.Errorf("landlock is not available: %w", errno)
+	}
+
+	var handled uint64
+	switch {
+	case abi >= 3:
+		handled = landlockWriteV3
+	case abi == 2:
+		handled = landlockWriteV2
+	default:
+		handled = landlockWriteV1
+	}
+
+	attr := unix.LandlockRulesetAttr{Access_fs: handled}
+	fd, _, errno := unix.Syscall(unix.SYS_LANDLOCK_CREATE_RULESET, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr.Access_fs), 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("landlock_create_ruleset: %w", errno)
+	}
+	rulesetFD := int(fd)
+
+	for _, path := range policy.writablePaths() {
+		if err := landlockAllow(rulesetFD, path, handled); err != nil {
+			unix.Close(rulesetFD)
+			return -1, err
+		}
+	}
+	return rulesetFD, nil
+}
+
+// landlockAllow grants write access beneath path. Missing paths are skipped.
+func landlockAllow(rulesetFD int, path string, handled uint64) error {
+	pathFD, err := unix.Open(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+	if errors.Is(err, unix.ENOENT) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open sandbox path %s: %w", path, err)
+	}
+	defer unix.Close(pathFD)
+
+	var st unix.Stat_t
+	if err := unix.Fstat(pathFD, &st); err != nil {
+		return fmt.Errorf("failed to stat sandbox path %s: %w", path, err)
+	}
+
+	allowed := handled
+	if st.Mode&unix.S_IFMT != unix.S_IFDIR {
+		allowed &= landlockFileRights
+	}
+
+	rule := unix.LandlockPathBeneathAttr{Allowed_access: allowed, Parent_fd: int32(pathFD)}
+	if _, _, errno := unix.Syscall6(unix.SYS_LANDLOCK_ADD_RULE, uintptr(rulesetFD), unix.LANDLOCK_RULE_PATH_BENEATH,
+		uintptr(unsafe.Pointer(&rule)), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("landlock_add_rule %s: %w", path, errno)
+	}
+	return nil
+}
+
+// seccompFilter returns a BPF program denying packet and raw sockets, and all
+// IP sockets unless the policy allows network access.
+func seccompFilter(policy SandboxPolicy) ([]unix.SockFilter, error) {
+	var arch uint32
+	switch runtime.GOARCH {
+	case "amd64":
+		arch = unix.AUDIT_ARCH_X86_64
+	case "arm64":
+		arch = unix.AUDIT_ARCH_AARCH64
+	default:
+		return nil, fmt.Errorf("seccomp sandbox is not supported on %s", runtime.GOARCH)
+	}
+
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	const (
+		load   = unix.BPF_LD | unix.BPF_W | unix.BPF_ABS
+		jeq    = unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K
+		jge    = unix.BPF_JMP | unix.BPF_JGE | unix.BPF_K
+		ret    = unix.BPF_RET | unix.BPF_K
+		and    = unix.BPF_ALU | unix.BPF_AND | unix.BPF_K
+		allow  = unix.SECCOMP_RET_ALLOW
+		deny   = unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+		killed = unix.SECCOMP_RET_KILL_PROCESS
+	)
+
+	deniedFamilies := []uint32{unix.AF_PACKET}
+	if !policy.AllowNetwork {
+		deniedFamilies = append(deniedFamilies, unix.AF_INET, unix.AF_INET6)
+	}
+
+	filter := []unix.SockFilter{
+		// Kill on a foreign architecture, whose syscall numbers we can't check.
+		stmt(load, seccompArchOffset),
+		jump(jeq, arch, 1, 0),
+		stmt(ret, killed),
+
+		stmt(load, seccompNrOffset),
+	}
+	if arch == unix.AUDIT_ARCH_X86_64 {
+		// x32 syscalls would bypass the checks below.
+		filter = append(filter, jump(jge, x32SyscallBit, 0, 1), stmt(ret, deny))
+	}
+	filter = append(filter,
+		jump(jeq, unix.SYS_SOCKET, 1, 0),
+		stmt(ret, allow),
+
+		stmt(load, seccompArg0Offset),
+	)
+	for _, family := range deniedFamilies {
+		filter = append(filter, jump(jeq, family, 0, 1), stmt(ret, deny))
+	}
+	filter = append(filter,
+		stmt(load, seccompArg1Offset),
+		stmt(and, 0xf), // SOCK_TYPE_MASK, drops SOCK_NONBLOCK and SOCK_CLOEXEC
+		jump(jeq, unix.SOCK_RAW, 0, 1),
+		stmt(ret, deny),
+		stmt(ret, allow),
+	)
+	return filter, nil
+}
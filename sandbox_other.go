@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package tfclient
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+func platformSandbox(cmd *exec.Cmd, policy SandboxPolicy) (threadSetup, error) {
+	return nil, fmt.Errorf("provider sandboxing is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,281 @@
+package tfclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/infracollect/tf-data-client/internal/statediff"
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule is a data source read run periodically by a Scheduler, whose
+// results are delivered to sinks.
+type Schedule struct {
+	// Name identifies the schedule in results and in Remove.
+	Name string
+	// Spec is when to read: a cron expression such as "*/5 * * * *", or a
+	// descriptor such as "@hourly" or "@every 30s".
+	Spec string
+
+	Provider   Provider // configured
+	DataSource string
+	Config     map[string]any
+
+	// OnlyChanges delivers results only when the state differs from the
+	// previous read, or when the read fails differently, instead of after
+	// every read. The first read is always delivered.
+	OnlyChanges bool
+	// Filter is applied to every state before it is compared and delivered,
+	// e.g. to mask sensitive attributes (optional).
+	Filter func(state map[string]any) map[string]any
+
+	Sinks []Sink
+}
+
+// ScheduleResult is delivered to the sinks of a Schedule after a read.
+type ScheduleResult struct {
+	Schedule   string    `json:"schedule"`
+	Provider   string    `json:"provider"` // namespace/name@version
+	DataSource string    `json:"data_source"`
+	Time       time.Time `json:"time"`
+
+	State map[string]any `json:"state,omitempty"`
+	// Changes are the differences with the state of the previous read, nil
+	// for the first read.
+	Changes []StateChange `json:"changes,omitempty"`
+	Error   string        `json:"error,omitempty"` // set if the read failed
+}
+
+// StateChange is a difference between two states, as returned by DiffStates.
+type StateChange struct {
+	// Path locates the value, e.g. "items[0].name".
+	Path string `json:"path"`
+	// Op is "added", "removed" or "changed".
+	Op  string `json:"op"`
+	Old any    `json:"old,omitempty"`
+	New any    `json:"new,omitempty"`
+}
+
+// Sink receives the results of schedules. Deliver is called from one
+// goroutine per schedule, so implementations shared by schedules must be
+// safe for concurrent use. Adapt other services, e.g. SNS or a queue, with
+// SinkFunc.
+type Sink interface {
+	Deliver(ctx context.Context, result *ScheduleResult) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(ctx context.Context, result *ScheduleResult) error
+
+// Deliver calls f(ctx, result).
+func (f SinkFunc) Deliver(ctx context.Context, result *ScheduleResult) error {
+	return f(ctx, result)
+}
+
+// WriterSink writes results to w as JSON lines, e.g. to os.Stdout.
+func WriterSink(w io.Writer) Sink {
+	var mu sync.Mutex
+	return SinkFunc(func(ctx context.Context, result *ScheduleResult) error {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// WebhookSink posts results as JSON to url, with the extra headers, e.g.
+// Authorization. httpClient may be nil to use http.DefaultClient. Responses
+// other than 2xx are errors.
+func WebhookSink(url string, headers map[string]string, httpClient *http.Client) Sink {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return SinkFunc(func(ctx context.Context, result *ScheduleResult) error {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to post result to webhook: %w", err)
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook answered %s", resp.Status)
+		}
+		return nil
+	})
+}
+
+// Scheduler runs schedules, turning providers into a change detection
+// service for infrastructure facts. A read of a schedule is skipped while
+// its previous read is still running.
+type Scheduler struct {
+	logger logr.Logger
+	cron   *cron.Cron
+
+	mu        sync.Mutex
+	ctx       context.Context // of Run, nil before
+	schedules map[string]*scheduled
+}
+
+// scheduled is a Schedule with the outcome of its previous read.
+type scheduled struct {
+	Schedule
+	id cron.EntryID
+
+	read      bool // whether it was read before
+	lastState map[string]any
+	lastErr   string
+}
+
+// NewScheduler creates a Scheduler logging to logger. Add schedules, then
+// call Run.
+func NewScheduler(logger logr.Logger) *Scheduler {
+	return &Scheduler{
+		logger:    logger,
+		cron:      cron.New(cron.WithLogger(logger.V(1)), cron.WithChain(cron.SkipIfStillRunning(logger.V(1)))),
+		schedules: make(map[string]*scheduled),
+	}
+}
+
+// Add adds a schedule, which may be done while the scheduler runs.
+func (s *Scheduler) Add(schedule Schedule) error {
+	switch {
+	case schedule.Name == "":
+		return errors.New("schedule has no name")
+	case schedule.Provider == nil:
+		return fmt.Errorf("schedule %s has no provider", schedule.Name)
+	case schedule.DataSource == "":
+		return fmt.Errorf("schedule %s has no data source", schedule.Name)
+	case len(schedule.Sinks) == 0:
+		return fmt.Errorf("schedule %s has no sinks", schedule.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.schedules[schedule.Name]; ok {
+		return fmt.Errorf("schedule %s already exists", schedule.Name)
+	}
+	sc := &scheduled{Schedule: schedule}
+	id, err := s.cron.AddFunc(schedule.Spec, func() { s.run(sc) })
+	if err != nil {
+		return fmt.Errorf("schedule %s: invalid spec %q: %w", schedule.Name, schedule.Spec, err)
+	}
+	sc.id = id
+	s.schedules[schedule.Name] = sc
+	return nil
+}
+
+// Remove removes a schedule, returning whether it existed. A read in
+// progress completes.
+func (s *Scheduler) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sc, ok := s.schedules[name]
+	if ok {
+		s.cron.Remove(sc.id)
+		delete(s.schedules, name)
+	}
+	return ok
+}
+
+// Run runs the schedules until ctx is done, which cancels the reads in
+// progress, and returns once they have stopped.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return errors.New("scheduler is already running")
+	}
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	s.cron.Start()
+	<-ctx.Done()
+	<-s.cron.Stop().Done()
+	return nil
+}
+
+// run reads the data source of a schedule and delivers the result.
+func (s *Scheduler) run(sc *scheduled) {
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+	if ctx.Err() != nil {
+		return
+	}
+
+	result := &ScheduleResult{
+		Schedule:   sc.Name,
+		Provider:   sc.Provider.Config().String(),
+		DataSource: sc.DataSource,
+		Time:       time.Now(),
+	}
+	read, err := sc.Provider.ReadDataSource(ctx, sc.DataSource, sc.Config)
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Only this job touches the outcome of the previous read, as reads of a
+	// schedule don't overlap
+	changed := !sc.read
+	if err != nil {
+		s.logger.Error(err, "scheduled read failed", "schedule", sc.Name, "data_source", sc.DataSource)
+		result.Error = err.Error()
+		changed = changed || result.Error != sc.lastErr
+		sc.lastErr = result.Error
+	} else {
+		result.State = read.State
+		if sc.Filter != nil {
+			result.State = sc.Filter(result.State)
+		}
+		if sc.read && sc.lastErr == "" {
+			result.Changes = DiffStates(sc.lastState, result.State)
+		}
+		changed = changed || sc.lastErr != "" || len(result.Changes) > 0
+		sc.lastState, sc.lastErr = result.State, ""
+	}
+	sc.read = true
+
+	if sc.OnlyChanges && !changed {
+		s.logger.V(1).Info("scheduled read unchanged", "schedule", sc.Name)
+		return
+	}
+	for _, sink := range sc.Sinks {
+		if err := sink.Deliver(ctx, result); err != nil {
+			s.logger.Error(err, "failed to deliver scheduled read", "schedule", sc.Name)
+		}
+	}
+}
+
+// DiffStates returns the differences between two states, ordered by path.
+// Lists are compared element by element.
+func DiffStates(old, new map[string]any) []StateChange {
+	var changes []StateChange
+	for _, c := range statediff.Diff(old, new, nil) {
+		changes = append(changes, StateChange{Path: c.Path, Op: string(c.Op), Old: c.Old, New: c.New})
+	}
+	return changes
+}
@@ -3,6 +3,8 @@ package tfclient
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/infracollect/tf-data-client/internal/tfplugin6"
 	"github.com/zclconf/go-cty/cty"
@@ -103,42 +105,101 @@ func nestedObjectToType(obj *tfplugin6.Schema_Object) (cty.Type, error) {
 	}
 }
 
-// mapToCtyValue converts a Go map to a cty.Value using the given type
-func mapToCtyValue(m map[string]any, ty cty.Type) (cty.Value, error) {
-	if m == nil {
-		return cty.NullVal(ty), nil
-	}
+// valueOptions control the conversion of cty values to Go values.
+type valueOptions struct {
+	exactNumbers bool // numbers as json.Number instead of float64, see WithExactNumbers
+	sortSets     bool // see WithSortedSets
+}
 
-	jsonBytes, err := json.Marshal(m)
-	if err != nil {
-		return cty.NilVal, fmt.Errorf("failed to marshal map to JSON: %w", err)
+// ctyValueToMap converts a cty object value to a Go map.
+func ctyValueToMap(val cty.Value, opts valueOptions) (map[string]any, error) {
+	if val.IsNull() {
+		return nil, nil
 	}
 
-	val, err := ctyjson.Unmarshal(jsonBytes, ty)
+	val, _ = val.UnmarkDeep()
+	v, err := ctyToGo(val, opts)
 	if err != nil {
-		return cty.NilVal, fmt.Errorf("failed to unmarshal JSON to cty value: %w", err)
+		return nil, err
 	}
-
-	return val, nil
+	result, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an object, got %s", val.Type().FriendlyName())
+	}
+	return result, nil
 }
 
-// ctyValueToMap converts a cty.Value to a Go map
-func ctyValueToMap(val cty.Value) (map[string]any, error) {
+// ctyToGo converts a cty value to the Go value encoding/json decodes from its
+// JSON encoding, without going through JSON.
+func ctyToGo(val cty.Value, opts valueOptions) (any, error) {
+	if !val.IsKnown() {
+		return nil, fmt.Errorf("value is not known")
+	}
 	if val.IsNull() {
 		return nil, nil
 	}
 
-	jsonBytes, err := ctyjson.Marshal(val, val.Type())
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal cty value to JSON: %w", err)
+	ty := val.Type()
+	switch {
+	case ty == cty.String:
+		return val.AsString(), nil
+	case ty == cty.Bool:
+		return val.True(), nil
+	case ty == cty.Number:
+		bf := val.AsBigFloat()
+		if opts.exactNumbers {
+			return json.Number(bf.Text('f', -1)), nil
+		}
+		f, _ := bf.Float64()
+		return f, nil
+	case ty.IsListType() || ty.IsSetType() || ty.IsTupleType():
+		out := make([]any, 0, val.LengthInt())
+		for it := val.ElementIterator(); it.Next(); {
+			_, ev := it.Element()
+			v, err := ctyToGo(ev, opts)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		// cty orders sets of primitive values by value, but only promises
+		// a consistent order for other sets.
+		if opts.sortSets && ty.IsSetType() && !ty.ElementType().IsPrimitiveType() {
+			sortByJSON(out)
+		}
+		return out, nil
+	case ty.IsMapType() || ty.IsObjectType():
+		out := make(map[string]any)
+		for it := val.ElementIterator(); it.Next(); {
+			k, ev := it.Element()
+			v, err := ctyToGo(ev, opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k.AsString(), err)
+			}
+			out[k.AsString()] = v
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", ty.FriendlyName())
 	}
+}
 
-	var result map[string]any
-	if err := json.Unmarshal(jsonBytes, &result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal JSON to map: %w", err)
+// sortByJSON sorts values by their JSON encoding, in which map keys are
+// sorted.
+func sortByJSON(values []any) {
+	type keyed struct {
+		key   string
+		value any
+	}
+	elems := make([]keyed, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		elems[i] = keyed{string(data), v}
+	}
+	slices.SortFunc(elems, func(a, b keyed) int { return strings.Compare(a.key, b.key) })
+	for i, e := range elems {
+		values[i] = e.value
 	}
-
-	return result, nil
 }
 
 // decodeDynamicValue decodes a DynamicValue proto message to a cty.Value
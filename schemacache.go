@@ -0,0 +1,107 @@
+package tfclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/protobuf/proto"
+)
+
+// schemasDir returns the directory of the provider schemas of a cache
+// directory.
+func schemasDir(cacheDir string) string {
+	return filepath.Join(cacheDir, ".schemas")
+}
+
+// schemaFile is where the schema of a cached provider executable is kept, so
+// that it doesn't have to be fetched again on each launch.
+type schemaFile struct {
+	path     string // serialized GetProviderSchema response
+	execPath string // the provider executable, the schema is stale if newer
+	refresh  bool   // WithSchemaRefresh: ignore the file but update it
+}
+
+// newSchemaFile returns the schema file in dir of a provider version for the
+// current platform.
+func newSchemaFile(dir, namespace, name, version, execPath string, refresh bool) *schemaFile {
+	return &schemaFile{
+		path:     filepath.Join(dir, namespace, name, version, runtime.GOOS+"_"+runtime.GOARCH+".pb"),
+		execPath: execPath,
+		refresh:  refresh,
+	}
+}
+
+// load returns the stored schema, or nil if there is none usable.
+func (f *schemaFile) load() *tfplugin6.GetProviderSchema_Response {
+	if f.refresh {
+		return nil
+	}
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil
+	}
+	if exec, err := os.Stat(f.execPath); err != nil || exec.ModTime().After(info.ModTime()) {
+		return nil
+	}
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil
+	}
+	resp := &tfplugin6.GetProviderSchema_Response{}
+	if err := proto.Unmarshal(data, resp); err != nil {
+		return nil
+	}
+	return resp
+}
+
+// store writes a schema, replacing the stored one atomically.
+func (f *schemaFile) store(resp *tfplugin6.GetProviderSchema_Response) error {
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), ".schema-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// loadSchema returns the schema of the provider process behind client. Like
+// Terraform, the stored schema is only used instead of calling
+// GetProviderSchema when the provider announces that the call is optional;
+// otherwise it may expect it before other calls.
+func (p *provider) loadSchema(ctx context.Context, client tfplugin6.ProviderClient) (*tfplugin6.GetProviderSchema_Response, error) {
+	f := p.schemaFile
+	if f != nil {
+		if resp := f.load(); resp != nil && resp.GetServerCapabilities().GetGetProviderSchemaOptional() {
+			p.logger.V(1).Info("using cached provider schema", "provider", p.Config().String(), "path", f.path)
+			return resp, nil
+		}
+	}
+
+	resp, err := fetchSchema(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	if f != nil && resp.GetServerCapabilities().GetGetProviderSchemaOptional() {
+		if err := f.store(resp); err != nil {
+			p.logger.Error(err, "failed to cache provider schema", "provider", p.Config().String(), "path", f.path)
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,48 @@
+package tfclient
+
+import (
+	"context"
+
+	"github.com/infracollect/tf-data-client/registry"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SearchProviders searches the registry for providers matching query, most
+// relevant first, to discover the provider serving some data. It fails with
+// registry.ErrSearchNotSupported if the registry has no search API. In offline
+// mode, only a filesystem mirror is searched.
+func (c *Client) SearchProviders(ctx context.Context, query string) (_ []registry.ProviderSummary, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.Search", attribute.String("search.query", query))
+	defer func() { endSpan(span, err) }()
+
+	searcher, err := c.searcher()
+	if err != nil {
+		return nil, err
+	}
+	return searcher.Search(ctx, query)
+}
+
+// ListRegistryProviders returns the providers a namespace publishes in the
+// registry, e.g. "hashicorp". It fails as SearchProviders does.
+func (c *Client) ListRegistryProviders(ctx context.Context, namespace string) (_ []registry.ProviderSummary, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.ListProviders", attribute.String("provider.namespace", namespace))
+	defer func() { endSpan(span, err) }()
+
+	searcher, err := c.searcher()
+	if err != nil {
+		return nil, err
+	}
+	return searcher.ListProviders(ctx, namespace)
+}
+
+func (c *Client) searcher() (registry.Searcher, error) {
+	reg := c.registry
+	if offline, ok := reg.(*offlineRegistry); ok {
+		reg = offline.mirror
+	}
+	searcher, ok := reg.(registry.Searcher)
+	if !ok {
+		return nil, registry.ErrSearchNotSupported
+	}
+	return searcher, nil
+}
@@ -0,0 +1,332 @@
+package tfclient
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// SecretResolver returns the secret a reference such as
+// "vault://secret/aws#access_key" points to.
+//
+// ResolveSecret must be safe for concurrent use, and its errors must not
+// contain secrets.
+type SecretResolver interface {
+	ResolveSecret(ctx context.Context, ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface.
+type SecretResolverFunc func(ctx context.Context, ref string) (string, error)
+
+// ResolveSecret calls f(ctx, ref).
+func (f SecretResolverFunc) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	return f(ctx, ref)
+}
+
+// SecretsResolver returns a ConfigResolver replacing the configuration
+// strings that are references "<scheme>://<secret>[#<field>]" by the secrets
+// they point to, resolved by the resolver of their scheme in resolvers. Other
+// strings are left as is.
+//
+// Use it with WithConfigResolver: secrets are resolved when Configure or
+// ReadDataSource is called and only kept in memory.
+func SecretsResolver(resolvers map[string]SecretResolver) ConfigResolver {
+	return ConfigResolverFunc(func(ctx context.Context, config map[string]any) (map[string]any, error) {
+		return rewriteConfig(config, func(s, path string) (string, error) {
+			scheme, _, ok := strings.Cut(s, "://")
+			r := resolvers[scheme]
+			if !ok || r == nil {
+				return s, nil
+			}
+			secret, err := r.ResolveSecret(ctx, s)
+			if err != nil {
+				return "", fmt.Errorf("attribute %s: %w", path, err)
+			}
+			return secret, nil
+		})
+	})
+}
+
+// Secrets is a ConfigResolver resolving references to secrets with the
+// built-in resolvers: "vault://" with VaultResolver, "awssm://" with
+// AWSSecretsManagerResolver and "sops://" with SOPSResolver, each configured
+// from the environment.
+var Secrets = SecretsResolver(map[string]SecretResolver{
+	"vault": &VaultResolver{},
+	"awssm": &AWSSecretsManagerResolver{},
+	"sops":  &SOPSResolver{},
+})
+
+// splitSecretRef splits a reference "<scheme>://<secret>#<field>" into its
+// secret and field, empty if there is none.
+func splitSecretRef(ref string) (secret, field string) {
+	_, rest, _ := strings.Cut(ref, "://")
+	secret, field, _ = strings.Cut(rest, "#")
+	return secret, field
+}
+
+// secretField returns the field of a JSON document at a path of keys
+// separated by dots, or the whole document if field is empty. Strings are
+// returned as is, other values JSON-encoded.
+func secretField(ref string, doc []byte, field string) (string, error) {
+	var v any
+	if err := json.Unmarshal(doc, &v); err != nil {
+		if field == "" {
+			return string(doc), nil
+		}
+		return "", fmt.Errorf("secret %s is not a JSON object", ref)
+	}
+	if field != "" {
+		for _, key := range strings.Split(field, ".") {
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return "", fmt.Errorf("secret %s has no field %s", ref, field)
+			}
+			if v, ok = obj[key]; !ok {
+				return "", fmt.Errorf("secret %s has no field %s", ref, field)
+			}
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// VaultResolver resolves "vault://<path>#<field>" references by reading
+// secrets from HashiCorp Vault, e.g. "vault://secret/aws#access_key". Paths
+// in KV version 2 mounts are read without their "data/" segment, as with
+// "vault kv get".
+type VaultResolver struct {
+	Address    string       // defaults to VAULT_ADDR
+	Token      string       // defaults to VAULT_TOKEN, or ~/.vault-token
+	Namespace  string       // defaults to VAULT_NAMESPACE
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+// ResolveSecret reads the secret ref points to.
+func (v *VaultResolver) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	path, field := splitSecretRef(ref)
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", fmt.Errorf("invalid Vault reference %s: no path", ref)
+	}
+	addr := cmp.Or(v.Address, os.Getenv("VAULT_ADDR"))
+	if addr == "" {
+		return "", fmt.Errorf("failed to read %s: no Vault address, set VAULT_ADDR", ref)
+	}
+	token := cmp.Or(v.Token, os.Getenv("VAULT_TOKEN"))
+	if token == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			if data, err := os.ReadFile(filepath.Join(home, ".vault-token")); err == nil {
+				token = strings.TrimSpace(string(data))
+			}
+		}
+	}
+	if token == "" {
+		return "", fmt.Errorf("failed to read %s: no Vault token, set VAULT_TOKEN", ref)
+	}
+
+	var mount struct {
+		Path    string            `json:"path"`
+		Type    string            `json:"type"`
+		Options map[string]string `json:"options"`
+	}
+	kv2 := false
+	if v.get(ctx, addr, token, "sys/internal/ui/mounts/"+path, &mount) == nil {
+		kv2 = mount.Type == "kv" && mount.Options["version"] == "2" && strings.HasPrefix(path, mount.Path)
+		if kv2 {
+			path = mount.Path + "data/" + strings.TrimPrefix(path, mount.Path)
+		}
+	}
+
+	var data json.RawMessage
+	if err := v.get(ctx, addr, token, path, &data); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	if kv2 {
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &versioned); err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", ref, err)
+		}
+		data = versioned.Data
+	}
+	return secretField(ref, data, field)
+}
+
+// get reads a Vault path, decoding the data of the response into out.
+func (v *VaultResolver) get(ctx context.Context, addr, token, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if ns := cmp.Or(v.Namespace, os.Getenv("VAULT_NAMESPACE")); ns != "" {
+		req.Header.Set("X-Vault-Namespace", ns)
+	}
+	client := v.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusOK {
+		if len(body.Errors) > 0 {
+			return fmt.Errorf("vault returned status %d: %s", resp.StatusCode, strings.Join(body.Errors, "; "))
+		}
+		return fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("invalid Vault response: %w", decodeErr)
+	}
+	if len(body.Data) == 0 || string(body.Data) == "null" {
+		return fmt.Errorf("no secret at %s", path)
+	}
+	return json.Unmarshal(body.Data, out)
+}
+
+// AWSSecretsManagerResolver resolves "awssm://<secret>#<field>" references by
+// reading secrets from AWS Secrets Manager, where secret is a name or an ARN,
+// e.g. "awssm://prod/db#password". Without a field, the whole secret string
+// is used. Credentials come from the default AWS configuration chain.
+type AWSSecretsManagerResolver struct {
+	Region   string // defaults to the region of ARNs, then of the AWS configuration
+	Endpoint string // defaults to the regional Secrets Manager endpoint
+}
+
+// ResolveSecret reads the secret ref points to.
+func (r *AWSSecretsManagerResolver) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	id, field := splitSecretRef(ref)
+	if id == "" {
+		return "", fmt.Errorf("invalid AWS Secrets Manager reference %s: no secret", ref)
+	}
+	region := r.Region
+	if arn := strings.Split(id, ":"); region == "" && len(arn) > 3 && arn[0] == "arn" {
+		region = arn[3]
+	}
+	var opts []func(*awsconfig.LoadOptions) error
+	if region != "" {
+		opts = append(opts, awsconfig.WithRegion(region))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	if cfg.Region == "" {
+		return "", fmt.Errorf("failed to read %s: no AWS region, set AWS_REGION", ref)
+	}
+	creds, err := cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	endpoint := cmp.Or(r.Endpoint, aws.ToString(cfg.BaseEndpoint))
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + cfg.Region + ".amazonaws.com"
+	}
+	payload, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	hash := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "secretsmanager", cfg.Region, time.Now()); err != nil {
+		return "", fmt.Errorf("failed to sign request: %w", err)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+		Type         string  `json:"__type"`
+		Message      string  `json:"message"`
+		MessageUpper string  `json:"Message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to read %s: invalid response: %w", ref, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		errType := body.Type[strings.LastIndex(body.Type, "#")+1:]
+		return "", fmt.Errorf("failed to read %s: %s: %s", ref, errType, cmp.Or(body.Message, body.MessageUpper))
+	}
+	if body.SecretString == nil {
+		return secretField(ref, body.SecretBinary, field)
+	}
+	return secretField(ref, []byte(*body.SecretString), field)
+}
+
+// SOPSResolver resolves "sops://<file>#<field>" references by decrypting
+// files encrypted with SOPS, e.g. "sops://secrets.enc.yaml#db.password",
+// where field is a path of keys separated by dots. Without a field, the whole
+// decrypted file is used. Keys are found as the sops command finds them.
+type SOPSResolver struct {
+	Command string // defaults to "sops" in PATH
+}
+
+// ResolveSecret decrypts the file ref points to.
+func (r *SOPSResolver) ResolveSecret(ctx context.Context, ref string) (string, error) {
+	file, field := splitSecretRef(ref)
+	if file == "" {
+		return "", fmt.Errorf("invalid SOPS reference %s: no file", ref)
+	}
+	args := []string{"--decrypt"}
+	if field != "" {
+		args = append(args, "--output-type", "json")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cmp.Or(r.Command, "sops"), append(args, file)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("failed to decrypt %s: %w: %s", ref, err, msg)
+		}
+		return "", fmt.Errorf("failed to decrypt %s: %w", ref, err)
+	}
+	if field == "" {
+		return strings.TrimSuffix(stdout.String(), "\n"), nil
+	}
+	return secretField(ref, stdout.Bytes(), field)
+}
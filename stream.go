@@ -0,0 +1,148 @@
+package tfclient
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	ctymsgpack "github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// EachElement calls fn with each element of the list, set or tuple attribute
+// of the state, in order, decoding them one at a time from the msgpack the
+// provider returned, so that states of tens of thousands of elements are
+// never held in memory decoded. Elements are the values State would hold,
+// with numbers as json.Number as with WithExactNumbers. A null attribute has
+// no elements. An error from fn stops the iteration and is returned.
+//
+// States the provider encoded as JSON are decoded whole first.
+func (r *RawState) EachElement(attribute string, fn func(elem any) error) error {
+	ty, err := ctyjson.UnmarshalType(r.Type)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal state type: %w", err)
+	}
+	if !ty.IsObjectType() || !ty.HasAttribute(attribute) {
+		return fmt.Errorf("attribute %q not found in state", attribute)
+	}
+	attrType := ty.AttributeType(attribute)
+	if !attrType.IsListType() && !attrType.IsSetType() && !attrType.IsTupleType() {
+		return fmt.Errorf("attribute %q is not a list or set", attribute)
+	}
+	if len(r.Msgpack) == 0 {
+		return r.eachJSONElement(ty, attribute, fn)
+	}
+
+	dec := msgpack.NewDecoder(bytes.NewReader(r.Msgpack))
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return fmt.Errorf("failed to decode state: %w", err)
+	}
+	for range max(n, 0) {
+		name, err := dec.DecodeString()
+		if err != nil {
+			return fmt.Errorf("failed to decode state: %w", err)
+		}
+		if name != attribute {
+			if err := dec.Skip(); err != nil {
+				return fmt.Errorf("failed to decode state: %w", err)
+			}
+			continue
+		}
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", attribute, err)
+		}
+		for i := range max(count, 0) {
+			raw, err := dec.DecodeRaw()
+			if err != nil {
+				return fmt.Errorf("failed to decode %s[%d]: %w", attribute, i, err)
+			}
+			var ety cty.Type
+			if attrType.IsTupleType() {
+				ety = attrType.TupleElementType(i)
+			} else {
+				ety = attrType.ElementType()
+			}
+			val, err := ctymsgpack.Unmarshal(raw, ety)
+			if err != nil {
+				return fmt.Errorf("failed to decode %s[%d]: %w", attribute, i, err)
+			}
+			if err := emitElement(attribute, i, val, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("attribute %q not found in state", attribute)
+}
+
+// eachJSONElement is EachElement for states encoded as JSON.
+func (r *RawState) eachJSONElement(ty cty.Type, attribute string, fn func(elem any) error) error {
+	if len(r.JSON) == 0 {
+		return nil
+	}
+	state, err := ctyjson.Unmarshal(r.JSON, ty)
+	if err != nil {
+		return fmt.Errorf("failed to decode state: %w", err)
+	}
+	if state.IsNull() {
+		return nil
+	}
+	list := state.GetAttr(attribute)
+	if !list.IsKnown() {
+		return fmt.Errorf("unknown value at %s", attribute)
+	}
+	if list.IsNull() {
+		return nil
+	}
+	i := 0
+	for it := list.ElementIterator(); it.Next(); i++ {
+		_, val := it.Element()
+		if err := emitElement(attribute, i, val, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// emitElement converts the element i of attribute to its Go value and calls
+// fn with it.
+func emitElement(attribute string, i int, val cty.Value, fn func(elem any) error) error {
+	if paths := unknownPaths(val); len(paths) > 0 {
+		prefix := fmt.Sprintf("%s[%d]", attribute, i)
+		for j, p := range paths {
+			if p != "" && !strings.HasPrefix(p, "[") {
+				p = "." + p
+			}
+			paths[j] = prefix + p
+		}
+		return fmt.Errorf("unknown values at %s", strings.Join(paths, ", "))
+	}
+	val, _ = val.UnmarkDeep()
+	elem, err := ctyToGo(val, valueOptions{exactNumbers: true})
+	if err != nil {
+		return fmt.Errorf("%s[%d]: %w", attribute, i, err)
+	}
+	return fn(elem)
+}
+
+// WriteNDJSON writes each element of the list, set or tuple attribute of the
+// state to w as a line of JSON, decoding and encoding them one at a time, see
+// EachElement.
+func (r *RawState) WriteNDJSON(w io.Writer, attribute string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := r.EachElement(attribute, func(elem any) error {
+		return enc.Encode(elem)
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,116 @@
+package tfclienttest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// Client is a fake tfclient.ProviderManager handing out fake providers.
+type Client struct {
+	mu        sync.Mutex
+	providers []*Provider
+	created   []tfclient.ProviderConfig
+	createErr error
+}
+
+var _ tfclient.ProviderManager = (*Client)(nil)
+
+// NewClient returns a client serving the given providers.
+func NewClient(providers ...*Provider) *Client {
+	return &Client{providers: providers}
+}
+
+// WithCreateError makes CreateProvider fail with err, e.g. a
+// *tfclient.ErrDownloadFailed.
+func (c *Client) WithCreateError(err error) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.createErr = err
+	return c
+}
+
+// CreateProvider returns the provider matching cfg's namespace and name, and
+// its version or version constraint unless cfg.Version is empty. Closed providers are reopened, as
+// a real client would launch them again.
+func (c *Client) CreateProvider(ctx context.Context, cfg tfclient.ProviderConfig) (tfclient.Provider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.created = append(c.created, cfg)
+	if c.createErr != nil {
+		return nil, c.createErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p, err := c.find(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.closed = false
+	p.mu.Unlock()
+	return p, nil
+}
+
+// find returns the provider matching cfg. The caller must hold c.mu.
+func (c *Client) find(cfg tfclient.ProviderConfig) (*Provider, error) {
+	found := false
+	for _, p := range c.providers {
+		if p.namespace != cfg.Namespace || p.name != cfg.Name {
+			continue
+		}
+		found = true
+		if cfg.Version == "" || cfg.Version == p.version || matchesConstraint(p.version, cfg.Version) {
+			return p, nil
+		}
+	}
+	if found {
+		return nil, &tfclient.ErrVersionNotFound{Namespace: cfg.Namespace, Name: cfg.Name, Version: cfg.Version}
+	}
+	return nil, &tfclient.ErrProviderNotFound{Namespace: cfg.Namespace, Name: cfg.Name}
+}
+
+// matchesConstraint reports whether v satisfies constraint, e.g. "~> 2.1".
+func matchesConstraint(v, constraint string) bool {
+	c, err := version.NewConstraint(constraint)
+	if err != nil {
+		return false
+	}
+	parsed, err := version.NewVersion(v)
+	return err == nil && c.Check(parsed)
+}
+
+// StopProvider closes the matching provider, if any.
+func (c *Client) StopProvider(ctx context.Context, cfg tfclient.ProviderConfig) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if p, err := c.find(cfg); err == nil {
+		return p.Close()
+	}
+	return nil
+}
+
+// Close closes every provider.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, p := range c.providers {
+		p.Close()
+	}
+	return nil
+}
+
+// Created returns the configurations passed to CreateProvider so far.
+func (c *Client) Created() []tfclient.ProviderConfig {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]tfclient.ProviderConfig(nil), c.created...)
+}
@@ -0,0 +1,293 @@
+// Package tfclienttest provides fakes of the tfclient Provider and Client for
+// testing code that reads data sources without downloading real providers.
+package tfclienttest
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	tfclient "github.com/infracollect/tf-data-client"
+)
+
+// Call records a Configure, Reconfigure or ReadDataSource call made to a
+// Provider.
+type Call struct {
+	Operation  string // "Configure", "Reconfigure" or "ReadDataSource"
+	DataSource string // ReadDataSource only
+	Config     map[string]any
+}
+
+// DataSourceFunc computes the state of a data source from its configuration.
+type DataSourceFunc func(ctx context.Context, config map[string]any) (map[string]any, error)
+
+// Provider is a fake tfclient.Provider serving canned data source results.
+// Configure it before use; it is safe for concurrent use afterwards.
+type Provider struct {
+	namespace string
+	name      string
+	version   string
+
+	dataSources  map[string]DataSourceFunc
+	schemas      map[string]*tfclient.Schema
+	configSchema *tfclient.Schema
+	configureErr error
+	validateErr  error
+	pingErr      error
+	latency      time.Duration
+
+	mu         sync.Mutex
+	configured bool
+	config     map[string]any // of the last successful Configure
+	closed     bool
+	calls      []Call
+}
+
+var _ tfclient.Provider = (*Provider)(nil)
+
+// NewProvider returns a fake provider with no data sources.
+func NewProvider(namespace, name, version string) *Provider {
+	return &Provider{
+		namespace:   namespace,
+		name:        name,
+		version:     version,
+		dataSources: make(map[string]DataSourceFunc),
+		schemas:     make(map[string]*tfclient.Schema),
+	}
+}
+
+// WithDataSource adds a data source returning state for any configuration.
+func (p *Provider) WithDataSource(typeName string, state map[string]any) *Provider {
+	return p.WithDataSourceFunc(typeName, func(context.Context, map[string]any) (map[string]any, error) {
+		return maps.Clone(state), nil
+	})
+}
+
+// WithDataSourceError adds a data source whose reads fail with err.
+func (p *Provider) WithDataSourceError(typeName string, err error) *Provider {
+	return p.WithDataSourceFunc(typeName, func(context.Context, map[string]any) (map[string]any, error) {
+		return nil, err
+	})
+}
+
+// WithDataSourceFunc adds a data source whose state is computed by fn.
+func (p *Provider) WithDataSourceFunc(typeName string, fn DataSourceFunc) *Provider {
+	p.dataSources[typeName] = fn
+	return p
+}
+
+// WithDataSourceSchema sets the schema DataSourceSchema returns for a data
+// source, which otherwise has an empty schema.
+func (p *Provider) WithDataSourceSchema(typeName string, schema *tfclient.Schema) *Provider {
+	p.schemas[typeName] = schema
+	return p
+}
+
+// WithProviderSchema sets the schema ProviderSchema returns, which is
+// otherwise empty.
+func (p *Provider) WithProviderSchema(schema *tfclient.Schema) *Provider {
+	p.configSchema = schema
+	return p
+}
+
+// WithConfigureError makes Configure fail with err.
+func (p *Provider) WithConfigureError(err error) *Provider {
+	p.configureErr = err
+	return p
+}
+
+// WithValidateError makes ValidateProviderConfig and
+// ValidateDataSourceConfig fail with err.
+func (p *Provider) WithValidateError(err error) *Provider {
+	p.validateErr = err
+	return p
+}
+
+// WithPingError makes Ping fail with err and Healthy report false.
+func (p *Provider) WithPingError(err error) *Provider {
+	p.pingErr = err
+	return p
+}
+
+// WithLatency delays every Configure and ReadDataSource call by d, or until
+// the call's context is done.
+func (p *Provider) WithLatency(d time.Duration) *Provider {
+	p.latency = d
+	return p
+}
+
+// Calls returns the Configure and ReadDataSource calls made so far.
+func (p *Provider) Calls() []Call {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.calls)
+}
+
+// Closed reports whether Close was called.
+func (p *Provider) Closed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// begin records a call and applies the configured latency.
+func (p *Provider) begin(ctx context.Context, call Call) error {
+	p.mu.Lock()
+	closed := p.closed
+	p.calls = append(p.calls, call)
+	p.mu.Unlock()
+
+	if closed {
+		return errors.New("provider is closed")
+	}
+	if p.latency <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(p.latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Provider) Configure(ctx context.Context, config map[string]interface{}) error {
+	if err := p.begin(ctx, Call{Operation: "Configure", Config: config}); err != nil {
+		return err
+	}
+	if p.configureErr != nil {
+		return p.configureErr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.configured && !reflect.DeepEqual(p.config, config) {
+		return &tfclient.ErrProviderAlreadyConfigured{Namespace: p.namespace, Name: p.name, Version: p.version}
+	}
+	p.configured = true
+	p.config = config
+	return nil
+}
+
+func (p *Provider) Reconfigure(ctx context.Context, config map[string]interface{}) error {
+	if err := p.begin(ctx, Call{Operation: "Reconfigure", Config: config}); err != nil {
+		return err
+	}
+	if p.configureErr != nil {
+		return p.configureErr
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.configured = true
+	p.config = config
+	return nil
+}
+
+func (p *Provider) ReadDataSource(ctx context.Context, typeName string, config map[string]interface{}) (*tfclient.DataSourceResult, error) {
+	if err := p.begin(ctx, Call{Operation: "ReadDataSource", DataSource: typeName, Config: config}); err != nil {
+		return nil, err
+	}
+
+	fn, ok := p.dataSources[typeName]
+	if !ok {
+		return nil, &tfclient.ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+
+	state, err := fn(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return &tfclient.DataSourceResult{State: state}, nil
+}
+
+func (p *Provider) IsConfigured() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.configured
+}
+
+// ListDataSources returns the data source names in sorted order.
+func (p *Provider) ListDataSources() []string {
+	return slices.Sorted(maps.Keys(p.dataSources))
+}
+
+func (p *Provider) ProviderSchema() (*tfclient.Schema, error) {
+	if p.configSchema == nil {
+		return &tfclient.Schema{}, nil
+	}
+	return p.configSchema, nil
+}
+
+func (p *Provider) DataSourceSchema(typeName string) (*tfclient.Schema, error) {
+	if _, ok := p.dataSources[typeName]; !ok {
+		return nil, &tfclient.ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+	if schema := p.schemas[typeName]; schema != nil {
+		return schema, nil
+	}
+	return &tfclient.Schema{}, nil
+}
+
+func (p *Provider) ValidateProviderConfig(ctx context.Context, config map[string]interface{}) error {
+	if p.validateErr != nil {
+		return p.validateErr
+	}
+	return ctx.Err()
+}
+
+func (p *Provider) ValidateDataSourceConfig(ctx context.Context, typeName string, config map[string]interface{}) error {
+	if _, ok := p.dataSources[typeName]; !ok {
+		return &tfclient.ErrDataSourceNotFound{
+			TypeName:  typeName,
+			Namespace: p.namespace,
+			Name:      p.name,
+		}
+	}
+	if p.validateErr != nil {
+		return p.validateErr
+	}
+	return ctx.Err()
+}
+
+func (p *Provider) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return nil
+}
+
+func (p *Provider) Ping(ctx context.Context) error {
+	if p.pingErr != nil {
+		return &tfclient.ErrProviderUnhealthy{
+			Namespace: p.namespace,
+			Name:      p.name,
+			Version:   p.version,
+			Err:       p.pingErr,
+		}
+	}
+	return ctx.Err()
+}
+
+func (p *Provider) Healthy() bool {
+	return p.pingErr == nil && !p.Closed()
+}
+
+func (p *Provider) Config() tfclient.ProviderConfig {
+	return tfclient.ProviderConfig{Namespace: p.namespace, Name: p.name, Version: p.version}
+}
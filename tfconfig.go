@@ -0,0 +1,511 @@
+package tfclient
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/ext/typeexpr"
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+// TerraformVars are values of the input variables of a Terraform
+// configuration, as given to terraform plan.
+type TerraformVars struct {
+	Files  []string          // .tfvars or .tfvars.json files, as with -var-file
+	Values map[string]string // raw values by variable name, as with -var
+}
+
+// DecodeTerraformProviderConfig decodes the provider block of the Terraform
+// configuration in dir (its .tf and .tf.json files) referenced by provider,
+// e.g. "aws", or "aws.west" for the block with alias "west", against its
+// schema from ProviderSchema. The result can be passed to Configure.
+//
+// Expressions can refer to input variables, locals, path.module,
+// terraform.workspace, and call the functions of the cty standard library and
+// file. Variables take their values as in Terraform: from their default,
+// TF_VAR_ environment variables, terraform.tfvars, terraform.tfvars.json and
+// *.auto.tfvars files in dir, then vars. References to resources, data
+// sources or modules can't be evaluated.
+func DecodeTerraformProviderConfig(dir, provider string, schema *Schema, vars TerraformVars) (map[string]any, error) {
+	name, alias, _ := strings.Cut(provider, ".")
+	parser := hclparse.NewParser()
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	jsonFiles, err := filepath.Glob(filepath.Join(dir, "*.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files)+len(jsonFiles) == 0 {
+		return nil, fmt.Errorf("no Terraform files in %s", dir)
+	}
+
+	var bodies []*hcl.File
+	var diags hcl.Diagnostics
+	for _, f := range files {
+		file, fileDiags := parser.ParseHCLFile(f)
+		diags = append(diags, fileDiags...)
+		bodies = append(bodies, file)
+	}
+	for _, f := range jsonFiles {
+		file, fileDiags := parser.ParseJSONFile(f)
+		diags = append(diags, fileDiags...)
+		bodies = append(bodies, file)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse Terraform files in %s: %w", dir, diags)
+	}
+
+	content, _, diags := hcl.MergeFiles(bodies).PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "provider", LabelNames: []string{"name"}},
+			{Type: "variable", LabelNames: []string{"name"}},
+			{Type: "locals"},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to read Terraform files in %s: %w", dir, diags)
+	}
+
+	var body hcl.Body
+	for _, block := range content.Blocks.OfType("provider") {
+		if block.Labels[0] != name {
+			continue
+		}
+		meta, remain, diags := block.Body.PartialContent(&hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{{Name: "alias"}, {Name: "version"}},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to read provider %s: %w", provider, diags)
+		}
+		blockAlias := ""
+		if attr := meta.Attributes["alias"]; attr != nil {
+			if diags := stringValue(attr.Expr, &blockAlias); diags.HasErrors() {
+				return nil, fmt.Errorf("invalid alias of provider %s: %w", name, diags)
+			}
+		}
+		if blockAlias != alias {
+			continue
+		}
+		if body != nil {
+			return nil, fmt.Errorf("duplicate provider %s in %s", provider, dir)
+		}
+		body = remain
+	}
+	if body == nil {
+		return nil, fmt.Errorf("no provider %s in %s", provider, dir)
+	}
+
+	ctx, locals, err := terraformEvalContext(dir, content, vars)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := schemaSpec(schema)
+	if err != nil {
+		return nil, err
+	}
+	val, diags := hcldec.Decode(body, spec, ctx)
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to decode provider %s: %w", provider, diags)
+	}
+	if !val.IsWhollyKnown() {
+		missing := unsetVariables(hcldec.Variables(body, spec), ctx, locals, map[string]bool{})
+		slices.Sort(missing)
+		return nil, fmt.Errorf("provider %s uses variables without a value: %s; set them in a tfvars file or TerraformVars", provider, strings.Join(slices.Compact(missing), ", "))
+	}
+	return ctyValueToMap(val, valueOptions{})
+}
+
+// stringValue evaluates a static string expression into s.
+func stringValue(expr hcl.Expression, s *string) hcl.Diagnostics {
+	val, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return diags
+	}
+	val, err := convert.Convert(val, cty.String)
+	if err != nil || val.IsNull() {
+		return hcl.Diagnostics{{Severity: hcl.DiagError, Summary: "expected a string", Subject: expr.Range().Ptr()}}
+	}
+	*s = val.AsString()
+	return nil
+}
+
+// unsetVariables returns the names of the variables without a value that
+// traversals refer to, directly or through the locals not in seen.
+func unsetVariables(traversals []hcl.Traversal, ctx *hcl.EvalContext, locals map[string]*hcl.Attribute, seen map[string]bool) []string {
+	var names []string
+	for _, traversal := range traversals {
+		if len(traversal) < 2 {
+			continue
+		}
+		attr, ok := traversal[1].(hcl.TraverseAttr)
+		if !ok {
+			continue
+		}
+		switch traversal.RootName() {
+		case "var":
+			if v := ctx.Variables["var"]; v.Type().HasAttribute(attr.Name) && !v.GetAttr(attr.Name).IsKnown() {
+				names = append(names, attr.Name)
+			}
+		case "local":
+			if l := locals[attr.Name]; l != nil && !seen[attr.Name] {
+				seen[attr.Name] = true
+				names = append(names, unsetVariables(l.Expr.Variables(), ctx, locals, seen)...)
+			}
+		}
+	}
+	return names
+}
+
+// terraformEvalContext returns the evaluation context of the expressions of
+// a Terraform configuration, with variables, locals and functions, and the
+// expressions of its locals. Variables without a value are unknown.
+func terraformEvalContext(dir string, content *hcl.BodyContent, vars TerraformVars) (*hcl.EvalContext, map[string]*hcl.Attribute, error) {
+	variables, err := terraformVariables(dir, content.Blocks.OfType("variable"), vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
+	workspace := os.Getenv("TF_WORKSPACE")
+	if workspace == "" {
+		workspace = "default"
+	}
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"var": cty.ObjectVal(variables),
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal(dir),
+				"root":   cty.StringVal(dir),
+				"cwd":    cty.StringVal(cwd),
+			}),
+			"terraform": cty.ObjectVal(map[string]cty.Value{"workspace": cty.StringVal(workspace)}),
+		},
+		Functions: terraformFunctions(dir),
+	}
+
+	// Evaluate the locals whose references can be, in dependency order
+	pending := map[string]*hcl.Attribute{}
+	for _, block := range content.Blocks.OfType("locals") {
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			return nil, nil, fmt.Errorf("failed to read locals: %w", diags)
+		}
+		for name, attr := range attrs {
+			pending[name] = attr
+		}
+	}
+	all := maps.Clone(pending)
+	locals := map[string]cty.Value{}
+	ctx.Variables["local"] = cty.EmptyObjectVal
+	for progress := true; progress && len(pending) > 0; {
+		progress = false
+		for name, attr := range pending {
+			if !localReady(attr.Expr, locals) {
+				continue
+			}
+			val, diags := attr.Expr.Value(ctx)
+			if diags.HasErrors() {
+				return nil, nil, fmt.Errorf("failed to evaluate local.%s: %w", name, diags)
+			}
+			locals[name] = val
+			ctx.Variables["local"] = cty.ObjectVal(locals)
+			delete(pending, name)
+			progress = true
+		}
+	}
+	return ctx, all, nil
+}
+
+// localReady reports whether the references of a local's expression can be
+// evaluated: only to variables, paths, the workspace and evaluated locals.
+// Locals referring to resources are never ready, and are left out.
+func localReady(expr hcl.Expression, locals map[string]cty.Value) bool {
+	for _, traversal := range expr.Variables() {
+		switch traversal.RootName() {
+		case "var", "path", "terraform":
+		case "local":
+			if len(traversal) < 2 {
+				return false
+			}
+			attr, ok := traversal[1].(hcl.TraverseAttr)
+			if !ok {
+				return false
+			}
+			if _, ok := locals[attr.Name]; !ok {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// terraformVariables returns the values of the variables declared by blocks,
+// unknown for those without one.
+func terraformVariables(dir string, blocks hcl.Blocks, vars TerraformVars) (map[string]cty.Value, error) {
+	type variable struct {
+		ty       cty.Type
+		defaults *typeexpr.Defaults
+		value    cty.Value
+		set      bool
+	}
+	declared := map[string]*variable{}
+	for _, block := range blocks {
+		content, _, diags := block.Body.PartialContent(&hcl.BodySchema{
+			Attributes: []hcl.AttributeSchema{{Name: "type"}, {Name: "default"}},
+		})
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to read variable %s: %w", block.Labels[0], diags)
+		}
+		v := &variable{ty: cty.DynamicPseudoType}
+		if attr := content.Attributes["type"]; attr != nil {
+			if v.ty, v.defaults, diags = typeexpr.TypeConstraintWithDefaults(attr.Expr); diags.HasErrors() {
+				return nil, fmt.Errorf("invalid type of variable %s: %w", block.Labels[0], diags)
+			}
+		}
+		if attr := content.Attributes["default"]; attr != nil {
+			if v.value, diags = attr.Expr.Value(nil); diags.HasErrors() {
+				return nil, fmt.Errorf("invalid default of variable %s: %w", block.Labels[0], diags)
+			}
+			v.set = true
+		}
+		declared[block.Labels[0]] = v
+	}
+
+	// Raw values, from the environment or -var, are strings unless the
+	// variable has a complex type, when they're HCL expressions
+	setRaw := func(name, raw, source string) error {
+		v := declared[name]
+		if v == nil {
+			return nil
+		}
+		if v.ty.IsPrimitiveType() || v.ty == cty.DynamicPseudoType {
+			v.value, v.set = cty.StringVal(raw), true
+			return nil
+		}
+		expr, diags := hclsyntax.ParseExpression([]byte(raw), source, hcl.InitialPos)
+		if diags.HasErrors() {
+			return fmt.Errorf("invalid value of variable %s: %w", name, diags)
+		}
+		if v.value, diags = expr.Value(nil); diags.HasErrors() {
+			return fmt.Errorf("invalid value of variable %s: %w", name, diags)
+		}
+		v.set = true
+		return nil
+	}
+	setFile := func(path string) error {
+		values, err := readTFVars(path)
+		if err != nil {
+			return err
+		}
+		for name, val := range values {
+			if v := declared[name]; v != nil {
+				v.value, v.set = val, true
+			}
+		}
+		return nil
+	}
+
+	for _, env := range os.Environ() {
+		key, raw, _ := strings.Cut(env, "=")
+		if name, ok := strings.CutPrefix(key, "TF_VAR_"); ok {
+			if err := setRaw(name, raw, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var files []string
+	for _, name := range []string{"terraform.tfvars", "terraform.tfvars.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	auto, err := filepath.Glob(filepath.Join(dir, "*.auto.tfvars"))
+	if err != nil {
+		return nil, err
+	}
+	autoJSON, err := filepath.Glob(filepath.Join(dir, "*.auto.tfvars.json"))
+	if err != nil {
+		return nil, err
+	}
+	auto = append(auto, autoJSON...)
+	sort.Strings(auto)
+	files = append(append(files, auto...), vars.Files...)
+	for _, f := range files {
+		if err := setFile(f); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(vars.Values)) {
+		if declared[name] == nil {
+			return nil, fmt.Errorf("variable %s is not declared in %s", name, dir)
+		}
+		if err := setRaw(name, vars.Values[name], "-var "+name); err != nil {
+			return nil, err
+		}
+	}
+
+	values := map[string]cty.Value{}
+	for name, v := range declared {
+		if !v.set {
+			values[name] = cty.UnknownVal(v.ty)
+			continue
+		}
+		val := v.value
+		if v.defaults != nil {
+			val = v.defaults.Apply(val)
+		}
+		val, err := convert.Convert(val, v.ty)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of variable %s: %w", name, err)
+		}
+		values[name] = val
+	}
+	return values, nil
+}
+
+// values returns the variables of vars, which don't have declarations:
+// values from tfvars files are used as is, and raw values are strings.
+func (vars TerraformVars) values() (map[string]cty.Value, error) {
+	values := map[string]cty.Value{}
+	for _, f := range vars.Files {
+		fileValues, err := readTFVars(f)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(values, fileValues)
+	}
+	for name, raw := range vars.Values {
+		values[name] = cty.StringVal(raw)
+	}
+	return values, nil
+}
+
+// readTFVars reads the values of a .tfvars or .tfvars.json file.
+func readTFVars(path string) (map[string]cty.Value, error) {
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if strings.HasSuffix(path, ".json") {
+		file, diags = parser.ParseJSONFile(path)
+	} else {
+		file, diags = parser.ParseHCLFile(path)
+	}
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, diags)
+	}
+	attrs, diags := file.Body.JustAttributes()
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("failed to read %s: %w", path, diags)
+	}
+	values := make(map[string]cty.Value, len(attrs))
+	for name, attr := range attrs {
+		val, diags := attr.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to read %s: %w", path, diags)
+		}
+		values[name] = val
+	}
+	return values, nil
+}
+
+// terraformFunctions returns the functions available to expressions: those
+// of the cty standard library under their Terraform names, and file, reading
+// paths relative to dir.
+func terraformFunctions(dir string) map[string]function.Function {
+	return map[string]function.Function{
+		"abs":             stdlib.AbsoluteFunc,
+		"ceil":            stdlib.CeilFunc,
+		"chomp":           stdlib.ChompFunc,
+		"coalesce":        stdlib.CoalesceFunc,
+		"coalescelist":    stdlib.CoalesceListFunc,
+		"compact":         stdlib.CompactFunc,
+		"concat":          stdlib.ConcatFunc,
+		"contains":        stdlib.ContainsFunc,
+		"csvdecode":       stdlib.CSVDecodeFunc,
+		"distinct":        stdlib.DistinctFunc,
+		"element":         stdlib.ElementFunc,
+		"flatten":         stdlib.FlattenFunc,
+		"floor":           stdlib.FloorFunc,
+		"format":          stdlib.FormatFunc,
+		"formatdate":      stdlib.FormatDateFunc,
+		"formatlist":      stdlib.FormatListFunc,
+		"indent":          stdlib.IndentFunc,
+		"join":            stdlib.JoinFunc,
+		"jsondecode":      stdlib.JSONDecodeFunc,
+		"jsonencode":      stdlib.JSONEncodeFunc,
+		"keys":            stdlib.KeysFunc,
+		"length":          stdlib.LengthFunc,
+		"log":             stdlib.LogFunc,
+		"lookup":          stdlib.LookupFunc,
+		"lower":           stdlib.LowerFunc,
+		"max":             stdlib.MaxFunc,
+		"merge":           stdlib.MergeFunc,
+		"min":             stdlib.MinFunc,
+		"parseint":        stdlib.ParseIntFunc,
+		"pow":             stdlib.PowFunc,
+		"range":           stdlib.RangeFunc,
+		"regex":           stdlib.RegexFunc,
+		"regexall":        stdlib.RegexAllFunc,
+		"regexreplace":    stdlib.RegexReplaceFunc,
+		"replace":         stdlib.ReplaceFunc,
+		"reverse":         stdlib.ReverseListFunc,
+		"setintersection": stdlib.SetIntersectionFunc,
+		"setproduct":      stdlib.SetProductFunc,
+		"setsubtract":     stdlib.SetSubtractFunc,
+		"setunion":        stdlib.SetUnionFunc,
+		"signum":          stdlib.SignumFunc,
+		"slice":           stdlib.SliceFunc,
+		"sort":            stdlib.SortFunc,
+		"split":           stdlib.SplitFunc,
+		"strrev":          stdlib.ReverseFunc,
+		"substr":          stdlib.SubstrFunc,
+		"timeadd":         stdlib.TimeAddFunc,
+		"title":           stdlib.TitleFunc,
+		"trim":            stdlib.TrimFunc,
+		"trimprefix":      stdlib.TrimPrefixFunc,
+		"trimspace":       stdlib.TrimSpaceFunc,
+		"trimsuffix":      stdlib.TrimSuffixFunc,
+		"upper":           stdlib.UpperFunc,
+		"values":          stdlib.ValuesFunc,
+		"zipmap":          stdlib.ZipmapFunc,
+		"file": function.New(&function.Spec{
+			Params: []function.Parameter{{Name: "path", Type: cty.String}},
+			Type:   function.StaticReturnType(cty.String),
+			Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+				path := args[0].AsString()
+				if rest, ok := strings.CutPrefix(path, "~/"); ok {
+					home, err := os.UserHomeDir()
+					if err != nil {
+						return cty.NilVal, err
+					}
+					path = filepath.Join(home, rest)
+				} else if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return cty.NilVal, err
+				}
+				return cty.StringVal(string(data)), nil
+			},
+		}),
+	}
+}
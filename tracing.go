@@ -0,0 +1,38 @@
+package tfclient
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of the spans created by this package.
+const tracerName = "github.com/infracollect/tf-data-client"
+
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// providerAttrs returns the span attributes identifying a provider.
+func providerAttrs(namespace, name, version string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("provider.namespace", namespace),
+		attribute.String("provider.name", name),
+		attribute.String("provider.version", version),
+	}
+}
+
+// startSpan starts a span as a child of the span in ctx, if any.
+func startSpan(ctx context.Context, tracer trace.Tracer, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
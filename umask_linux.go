@@ -0,0 +1,21 @@
+package tfclient
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// umaskSetup sets the file mode creation mask of the thread the provider is
+// started from. The umask is shared by all threads of a process, so the thread
+// first gets its own copy of the filesystem attributes.
+func umaskSetup(mask os.FileMode) (threadSetup, error) {
+	return func() error {
+		if err := unix.Unshare(unix.CLONE_FS); err != nil {
+			return fmt.Errorf("failed to set umask: %w", err)
+		}
+		unix.Umask(int(mask.Perm()))
+		return nil
+	}, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package tfclient
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func umaskSetup(mask os.FileMode) (threadSetup, error) {
+	return nil, fmt.Errorf("setting the provider umask is not supported on %s", runtime.GOOS)
+}
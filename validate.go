@@ -0,0 +1,395 @@
+package tfclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"github.com/zclconf/go-cty/cty/msgpack"
+)
+
+// ValidateProviderConfig checks a provider configuration against the
+// provider schema, then has the provider validate it, without configuring
+// the provider. An invalid configuration fails with *ErrInvalidConfig.
+func (p *provider) ValidateProviderConfig(ctx context.Context, config map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.ValidateProviderConfig", providerAttrs(p.namespace, p.name, p.version)...)
+	defer func() { endSpan(span, err) }()
+
+	schema, err := p.currentSchema(ctx)
+	if err != nil {
+		return err
+	}
+	if schema.Provider == nil {
+		return fmt.Errorf("provider schema not found")
+	}
+
+	configBytes, err := p.checkConfig(ctx, "", schema.Provider.Block, config)
+	if err != nil {
+		return err
+	}
+
+	var resp *tfplugin6.ValidateProviderConfig_Response
+	err = p.call(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		var err error
+		resp, err = client.ValidateProviderConfig(ctx, &tfplugin6.ValidateProviderConfig_Request{
+			Config: &tfplugin6.DynamicValue{Msgpack: configBytes},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to validate provider config: %w", err)
+	}
+	return p.diagnosticsError("", resp.Diagnostics)
+}
+
+// ValidateDataSourceConfig checks a data source configuration against its
+// schema, then has the provider validate it (ValidateDataResourceConfig),
+// without reading the data source. The provider needn't be configured. An
+// invalid configuration fails with *ErrInvalidConfig.
+func (p *provider) ValidateDataSourceConfig(ctx context.Context, typeName string, config map[string]interface{}) (err error) {
+	ctx, span := startSpan(ctx, p.tracer, "tfclient.ValidateDataSourceConfig", providerAttrs(p.namespace, p.name, p.version)...)
+	defer func() { endSpan(span, err) }()
+
+	if !p.hasDataSource(typeName) {
+		return &ErrDataSourceNotFound{TypeName: typeName, Namespace: p.namespace, Name: p.name}
+	}
+	schema, err := p.currentSchema(ctx)
+	if err != nil {
+		return err
+	}
+	dataSourceSchema, ok := schema.DataSourceSchemas[typeName]
+	if !ok {
+		return &ErrDataSourceNotFound{TypeName: typeName, Namespace: p.namespace, Name: p.name}
+	}
+
+	configBytes, err := p.checkConfig(ctx, typeName, dataSourceSchema.Block, config)
+	if err != nil {
+		return err
+	}
+
+	var resp *tfplugin6.ValidateDataResourceConfig_Response
+	err = p.call(ctx, func(ctx context.Context, client tfplugin6.ProviderClient) error {
+		var err error
+		resp, err = client.ValidateDataResourceConfig(ctx, &tfplugin6.ValidateDataResourceConfig_Request{
+			TypeName: typeName,
+			Config:   &tfplugin6.DynamicValue{Msgpack: configBytes},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to validate data source config: %w", err)
+	}
+	return p.diagnosticsError(typeName, resp.Diagnostics)
+}
+
+// checkConfig checks a configuration against a schema block, returning it
+// encoded for the provider. Configure and ReadDataSource check
+// configurations too, so that mistakes are reported with their attribute
+// paths rather than by the provider, if at all. The configuration is first
+// rewritten by the resolvers of WithConfigResolver.
+func (p *provider) checkConfig(ctx context.Context, typeName string, block *tfplugin6.Schema_Block, config map[string]interface{}) ([]byte, error) {
+	schemaType, err := schemaBlockToType(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert schema to type: %w", err)
+	}
+	for _, r := range p.resolvers {
+		if config, err = r.ResolveConfig(ctx, config); err != nil {
+			return nil, fmt.Errorf("failed to resolve configuration: %w", err)
+		}
+	}
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	invalid := func(problems []string) error {
+		return &ErrInvalidConfig{Namespace: p.namespace, Name: p.name, TypeName: typeName, Problems: problems}
+	}
+
+	// Check the JSON form of the configuration first: converting it stops at
+	// the first error, without its path, and converts strings to numbers or
+	// booleans.
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, invalid([]string{err.Error()})
+	}
+	var decoded map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return nil, invalid([]string{err.Error()})
+	}
+	if problems := configProblems(block, decoded, ""); len(problems) > 0 {
+		return nil, invalid(problems)
+	}
+
+	configValue, err := ctyjson.Unmarshal(data, schemaType)
+	if err != nil {
+		return nil, invalid([]string{err.Error()})
+	}
+	if problems := blockProblems(block, configValue, ""); len(problems) > 0 {
+		return nil, invalid(problems)
+	}
+	return msgpack.Marshal(configValue, schemaType)
+}
+
+// configProblems lists the attributes of a JSON-decoded configuration of
+// block that the schema doesn't have, and those whose values don't have the
+// type of their attribute. prefix is the path of the block.
+func configProblems(block *tfplugin6.Schema_Block, config map[string]any, prefix string) []string {
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(config)) {
+		v, path := config[name], prefix+name
+		if attr := schemaAttribute(block.Attributes, name); attr != nil {
+			if obj := attr.NestedType; obj != nil {
+				nested := &tfplugin6.Schema_Block{Attributes: obj.Attributes}
+				problems = append(problems, nestedProblems(nested, obj.Nesting == tfplugin6.Schema_Object_SINGLE, obj.Nesting == tfplugin6.Schema_Object_MAP, v, path)...)
+				continue
+			}
+			var ty cty.Type
+			if err := json.Unmarshal(attr.Type, &ty); err != nil {
+				continue // reported by the conversion
+			}
+			problems = append(problems, typeProblems(ty, v, path)...)
+			continue
+		}
+		if nested := schemaBlockType(block.BlockTypes, name); nested != nil {
+			single := nested.Nesting == tfplugin6.Schema_NestedBlock_SINGLE || nested.Nesting == tfplugin6.Schema_NestedBlock_GROUP
+			problems = append(problems, nestedProblems(nested.Block, single, nested.Nesting == tfplugin6.Schema_NestedBlock_MAP, v, path)...)
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("attribute %s is not supported", path))
+	}
+	return problems
+}
+
+// nestedProblems lists the problems of the value of a nested attribute or
+// block: an object if single, else a map of objects if isMap, else a list of
+// objects.
+func nestedProblems(block *tfplugin6.Schema_Block, single, isMap bool, v any, path string) []string {
+	if v == nil || block == nil {
+		return nil
+	}
+	var problems []string
+	switch elems := v.(type) {
+	case map[string]any:
+		if single {
+			return configProblems(block, elems, path+".")
+		}
+		if isMap {
+			for _, key := range slices.Sorted(maps.Keys(elems)) {
+				problems = append(problems, nestedProblems(block, true, false, elems[key], fmt.Sprintf("%s[%q]", path, key))...)
+			}
+			return problems
+		}
+	case []any:
+		if !single && !isMap {
+			for i, elem := range elems {
+				problems = append(problems, nestedProblems(block, true, false, elem, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return problems
+		}
+	}
+	want := "a list of objects"
+	switch {
+	case single:
+		want = "an object"
+	case isMap:
+		want = "a map of objects"
+	}
+	return []string{fmt.Sprintf("attribute %s: expected %s, got %s", path, want, jsonKind(v))}
+}
+
+// typeProblems lists the values of a JSON-decoded value v that don't have
+// type ty. Numbers and booleans are accepted as strings, as in Terraform,
+// but strings aren't accepted as numbers or booleans.
+func typeProblems(ty cty.Type, v any, path string) []string {
+	if v == nil || ty == cty.DynamicPseudoType {
+		return nil
+	}
+	mismatch := []string{fmt.Sprintf("attribute %s: expected %s, got %s", path, ty.FriendlyNameForConstraint(), jsonKind(v))}
+	switch {
+	case ty == cty.String:
+		switch v.(type) {
+		case string, json.Number, bool:
+			return nil
+		}
+	case ty == cty.Number:
+		if _, ok := v.(json.Number); ok {
+			return nil
+		}
+	case ty == cty.Bool:
+		if _, ok := v.(bool); ok {
+			return nil
+		}
+	case ty.IsListType() || ty.IsSetType():
+		if elems, ok := v.([]any); ok {
+			var problems []string
+			for i, elem := range elems {
+				problems = append(problems, typeProblems(ty.ElementType(), elem, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return problems
+		}
+	case ty.IsTupleType():
+		if elems, ok := v.([]any); ok && len(elems) == ty.Length() {
+			var problems []string
+			for i, elem := range elems {
+				problems = append(problems, typeProblems(ty.TupleElementType(i), elem, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+			return problems
+		}
+	case ty.IsMapType():
+		if elems, ok := v.(map[string]any); ok {
+			var problems []string
+			for _, key := range slices.Sorted(maps.Keys(elems)) {
+				problems = append(problems, typeProblems(ty.ElementType(), elems[key], fmt.Sprintf("%s[%q]", path, key))...)
+			}
+			return problems
+		}
+	case ty.IsObjectType():
+		if elems, ok := v.(map[string]any); ok {
+			var problems []string
+			for _, key := range slices.Sorted(maps.Keys(elems)) {
+				if !ty.HasAttribute(key) {
+					problems = append(problems, fmt.Sprintf("attribute %s.%s is not supported", path, key))
+					continue
+				}
+				problems = append(problems, typeProblems(ty.AttributeType(key), elems[key], path+"."+key)...)
+			}
+			return problems
+		}
+	default:
+		return nil
+	}
+	return mismatch
+}
+
+// jsonKind names the kind of a JSON-decoded value in problems.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func schemaAttribute(attrs []*tfplugin6.Schema_Attribute, name string) *tfplugin6.Schema_Attribute {
+	for _, attr := range attrs {
+		if attr.Name == name {
+			return attr
+		}
+	}
+	return nil
+}
+
+func schemaBlockType(blocks []*tfplugin6.Schema_NestedBlock, name string) *tfplugin6.Schema_NestedBlock {
+	for _, nested := range blocks {
+		if nested.TypeName == name {
+			return nested
+		}
+	}
+	return nil
+}
+
+// diagnosticsError returns the error diagnostics of a validation as
+// *ErrInvalidConfig, or nil.
+func (p *provider) diagnosticsError(typeName string, diags []*tfplugin6.Diagnostic) error {
+	var problems []string
+	for _, diag := range diags {
+		if diag.Severity != tfplugin6.Diagnostic_ERROR {
+			continue
+		}
+		problem := diag.Summary
+		if diag.Detail != "" {
+			problem += ": " + diag.Detail
+		}
+		problems = append(problems, problem)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ErrInvalidConfig{Namespace: p.namespace, Name: p.name, TypeName: typeName, Problems: problems}
+}
+
+// blockProblems lists the missing required attributes, the read-only
+// attributes that are set and the nested blocks out of their bounds in a
+// configuration value of block. prefix is the path of the block.
+func blockProblems(block *tfplugin6.Schema_Block, val cty.Value, prefix string) []string {
+	if val.IsNull() || !val.IsKnown() {
+		return nil
+	}
+	var problems []string
+	for _, attr := range block.Attributes {
+		v := val.GetAttr(attr.Name)
+		switch {
+		case attr.Required && v.IsNull():
+			problems = append(problems, fmt.Sprintf("attribute %s%s is required", prefix, attr.Name))
+		case attr.Computed && !attr.Optional && !attr.Required && !v.IsNull():
+			problems = append(problems, fmt.Sprintf("attribute %s%s is read-only", prefix, attr.Name))
+		case attr.NestedType != nil:
+			nested := &tfplugin6.Schema_Block{Attributes: attr.NestedType.Attributes}
+			if attr.NestedType.Nesting == tfplugin6.Schema_Object_SINGLE {
+				problems = append(problems, blockProblems(nested, v, prefix+attr.Name+".")...)
+			} else if !v.IsNull() && v.IsKnown() {
+				for it := v.ElementIterator(); it.Next(); {
+					key, elem := it.Element()
+					problems = append(problems, blockProblems(nested, elem, elementPath(prefix+attr.Name, key)+".")...)
+				}
+			}
+		}
+	}
+
+	for _, nested := range block.BlockTypes {
+		v := val.GetAttr(nested.TypeName)
+		path := prefix + nested.TypeName
+		if nested.Nesting == tfplugin6.Schema_NestedBlock_SINGLE || nested.Nesting == tfplugin6.Schema_NestedBlock_GROUP {
+			if v.IsNull() && nested.MinItems > 0 {
+				problems = append(problems, fmt.Sprintf("block %s is required", path))
+			}
+			problems = append(problems, blockProblems(nested.Block, v, path+".")...)
+			continue
+		}
+		if v.IsNull() || !v.IsKnown() {
+			if nested.MinItems > 0 {
+				problems = append(problems, fmt.Sprintf("at least %d %s blocks are required", nested.MinItems, path))
+			}
+			continue
+		}
+		n := int64(v.LengthInt())
+		switch {
+		case n < nested.MinItems:
+			problems = append(problems, fmt.Sprintf("at least %d %s blocks are required, got %d", nested.MinItems, path, n))
+		case nested.MaxItems > 0 && n > nested.MaxItems:
+			problems = append(problems, fmt.Sprintf("at most %d %s blocks are allowed, got %d", nested.MaxItems, path, n))
+		}
+		for it := v.ElementIterator(); it.Next(); {
+			key, elem := it.Element()
+			problems = append(problems, blockProblems(nested.Block, elem, elementPath(path, key)+".")...)
+		}
+	}
+	return problems
+}
+
+// elementPath returns the path of the element of a collection at path with
+// key; set elements have no key.
+func elementPath(path string, key cty.Value) string {
+	switch {
+	case key.Type() == cty.String:
+		return path + fmt.Sprintf("[%q]", key.AsString())
+	case key.Type() == cty.Number:
+		return path + fmt.Sprintf("[%s]", key.AsBigFloat().String())
+	}
+	return path
+}
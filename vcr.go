@@ -0,0 +1,244 @@
+package tfclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/infracollect/tf-data-client/internal/tfplugin6"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// cassette holds the recorded interactions with one provider version. It is
+// stored as JSON at <dir>/<namespace>/<name>/<version>.json, with protocol
+// messages in their protobuf JSON encoding.
+type cassette struct {
+	path string
+
+	mu     sync.Mutex
+	Schema json.RawMessage `json:"schema,omitempty"`
+	Reads  []recordedRead  `json:"reads"`
+}
+
+// recordedRead is a ReadDataSource call and its response.
+type recordedRead struct {
+	TypeName string          `json:"type_name"`
+	Config   []byte          `json:"config"` // msgpack-encoded configuration
+	Response json.RawMessage `json:"response"`
+}
+
+func cassettePath(dir string, cfg ProviderConfig) string {
+	return filepath.Join(dir, cfg.Namespace, cfg.Name, cfg.Version+".json")
+}
+
+// loadCassette reads the cassette of cfg from dir. A missing file yields an
+// empty cassette if allowMissing is set.
+func loadCassette(dir string, cfg ProviderConfig, allowMissing bool) (*cassette, error) {
+	c := &cassette{path: cassettePath(dir, cfg)}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if allowMissing && errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse recording %s: %w", c.path, err)
+	}
+	return c, nil
+}
+
+// save writes the cassette to disk, readable by this user only: it holds
+// the states as the provider returned them, sensitive values included. The
+// caller must hold c.mu.
+func (c *cassette) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	os.Remove(tmp) // left over by an interrupted save, with its permissions
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+func (c *cassette) recordSchema(resp *tfplugin6.GetProviderSchema_Response) error {
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Schema = data
+	return c.save()
+}
+
+// recordRead stores a ReadDataSource response, replacing any earlier one for
+// the same request.
+func (c *cassette) recordRead(req *tfplugin6.ReadDataSource_Request, resp *tfplugin6.ReadDataSource_Response) error {
+	data, err := protojson.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	read := recordedRead{TypeName: req.TypeName, Config: req.GetConfig().GetMsgpack(), Response: data}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.find(req); i >= 0 {
+		c.Reads[i] = read
+	} else {
+		c.Reads = append(c.Reads, read)
+	}
+	return c.save()
+}
+
+// find returns the index of the recorded read matching req, or -1. The caller
+// must hold c.mu.
+func (c *cassette) find(req *tfplugin6.ReadDataSource_Request) int {
+	for i, read := range c.Reads {
+		if read.TypeName == req.TypeName && bytes.Equal(read.Config, req.GetConfig().GetMsgpack()) {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordingClient passes calls through to a provider and records schema and
+// ReadDataSource responses.
+type recordingClient struct {
+	tfplugin6.ProviderClient
+	cassette *cassette
+}
+
+func (r *recordingClient) GetProviderSchema(ctx context.Context, in *tfplugin6.GetProviderSchema_Request, opts ...grpc.CallOption) (*tfplugin6.GetProviderSchema_Response, error) {
+	resp, err := r.ProviderClient.GetProviderSchema(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cassette.recordSchema(resp); err != nil {
+		return nil, fmt.Errorf("failed to record provider schema: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *recordingClient) ReadDataSource(ctx context.Context, in *tfplugin6.ReadDataSource_Request, opts ...grpc.CallOption) (*tfplugin6.ReadDataSource_Response, error) {
+	resp, err := r.ProviderClient.ReadDataSource(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cassette.recordRead(in, resp); err != nil {
+		return nil, fmt.Errorf("failed to record data source read: %w", err)
+	}
+	return resp, nil
+}
+
+// withRecording wraps launch so that every instance records into the cassette
+// of cfg in dir.
+func withRecording(dir string, cfg ProviderConfig, launch func() (*pluginInstance, error)) (func() (*pluginInstance, error), error) {
+	c, err := loadCassette(dir, cfg, true)
+	if err != nil {
+		return nil, err
+	}
+	return func() (*pluginInstance, error) {
+		inst, err := launch()
+		if err != nil {
+			return nil, err
+		}
+		inst.grpcClient = &recordingClient{ProviderClient: inst.grpcClient, cassette: c}
+		return inst, nil
+	}, nil
+}
+
+// replayServer serves recorded interactions in place of a provider.
+type replayServer struct {
+	tfplugin6.UnimplementedProviderServer
+	cassette *cassette
+}
+
+func (s *replayServer) GetProviderSchema(context.Context, *tfplugin6.GetProviderSchema_Request) (*tfplugin6.GetProviderSchema_Response, error) {
+	if s.cassette.Schema == nil {
+		return nil, status.Errorf(codes.NotFound, "no provider schema recorded in %s", s.cassette.path)
+	}
+	resp := &tfplugin6.GetProviderSchema_Response{}
+	if err := protojson.Unmarshal(s.cassette.Schema, resp); err != nil {
+		return nil, status.Errorf(codes.DataLoss, "failed to decode recorded schema: %v", err)
+	}
+	return resp, nil
+}
+
+// ConfigureProvider accepts any configuration: recorded responses don't
+// depend on it.
+func (s *replayServer) ConfigureProvider(context.Context, *tfplugin6.ConfigureProvider_Request) (*tfplugin6.ConfigureProvider_Response, error) {
+	return &tfplugin6.ConfigureProvider_Response{}, nil
+}
+
+func (s *replayServer) ReadDataSource(_ context.Context, req *tfplugin6.ReadDataSource_Request) (*tfplugin6.ReadDataSource_Response, error) {
+	s.cassette.mu.Lock()
+	i := s.cassette.find(req)
+	var data json.RawMessage
+	if i >= 0 {
+		data = s.cassette.Reads[i].Response
+	}
+	s.cassette.mu.Unlock()
+
+	if i < 0 {
+		return nil, status.Errorf(codes.NotFound, "no recorded read of %s with this configuration in %s", req.TypeName, s.cassette.path)
+	}
+	resp := &tfplugin6.ReadDataSource_Response{}
+	if err := protojson.Unmarshal(data, resp); err != nil {
+		return nil, status.Errorf(codes.DataLoss, "failed to decode recorded read: %v", err)
+	}
+	return resp, nil
+}
+
+// launchReplay returns an instance serving the recorded interactions of cfg
+// from dir, without launching the provider.
+func launchReplay(dir string, cfg ProviderConfig, dialOpts []grpc.DialOption) (*pluginInstance, error) {
+	c, err := loadCassette(dir, cfg, false)
+	if err != nil {
+		return nil, err
+	}
+	return serveInMemory(&replayServer{cassette: c}, dialOpts)
+}
+
+// recordedVersion returns the only version of a provider recorded in dir.
+func recordedVersion(dir, namespace, name string) (string, error) {
+	versions, err := recordedVersions(dir, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	if len(versions) > 1 {
+		return "", fmt.Errorf("several versions of %s/%s recorded in %s, set ProviderConfig.Version", namespace, name, dir)
+	}
+	return versions[0], nil
+}
+
+// recordedVersions returns the versions of a provider recorded in dir.
+func recordedVersions(dir, namespace, name string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, namespace, name, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recording of %s/%s in %s", namespace, name, dir)
+	}
+	versions := make([]string, len(matches))
+	for i, m := range matches {
+		versions[i] = strings.TrimSuffix(filepath.Base(m), ".json")
+	}
+	return versions, nil
+}
@@ -0,0 +1,264 @@
+package tfclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/infracollect/tf-data-client/cache"
+	"github.com/infracollect/tf-data-client/registry"
+)
+
+// resolveRequest returns the exact version to use for cfg, pinned by the lock
+// file or resolved from an empty version or a constraint, along with its lock
+// file entry, if any.
+func (c *Client) resolveRequest(ctx context.Context, cfg ProviderConfig) (string, *lockedProvider, error) {
+	prereleases := c.allowPrereleases
+	if cfg.AllowPrereleases != nil {
+		prereleases = *cfg.AllowPrereleases
+	}
+
+	// Pin the version recorded in the lock file, if any
+	version := cfg.Version
+	locked := c.lockFile[c.providerAddress(cfg.Namespace, cfg.Name)]
+	_, devOverride := c.devOverrides[cfg.Namespace+"/"+cfg.Name]
+	_, inProcess := c.inProcess[cfg.Namespace+"/"+cfg.Name]
+	if devOverride || inProcess {
+		locked = nil
+	}
+	if locked != nil {
+		var err error
+		if version, err = lockedVersion(cfg, locked); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// Resolve version if not specified
+	if version == "" {
+		latest, err := c.latestVersion(ctx, cfg.Namespace, cfg.Name, prereleases)
+		if err != nil {
+			return "", nil, &ErrProviderNotFound{
+				Namespace: cfg.Namespace,
+				Name:      cfg.Name,
+				Err:       err,
+			}
+		}
+		version = latest
+	} else if isVersionConstraint(version) {
+		matched, err := c.resolveVersion(ctx, cfg.Namespace, cfg.Name, version, prereleases)
+		if err != nil {
+			var notFound *ErrVersionNotFound
+			if errors.As(err, &notFound) {
+				return "", nil, err
+			}
+			return "", nil, &ErrProviderNotFound{
+				Namespace: cfg.Namespace,
+				Name:      cfg.Name,
+				Err:       err,
+			}
+		}
+		version = matched
+	}
+	return version, locked, nil
+}
+
+// isVersionConstraint reports whether v is a version constraint such as
+// "~> 2.1" or ">= 4.0, < 5.0" rather than an exact version.
+func isVersionConstraint(v string) bool {
+	if v == "" {
+		return false
+	}
+	_, err := version.NewVersion(v)
+	return err != nil
+}
+
+// resolveVersion returns the newest version of a provider matching
+// constraint, or an *ErrVersionNotFound listing the candidates. Prereleases
+// are only candidates if prereleases is set or the constraint names one.
+func (c *Client) resolveVersion(ctx context.Context, namespace, name, constraint string, prereleases bool) (_ string, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.GetVersions", providerAttrs(namespace, name, "")...)
+	defer func() { endSpan(span, err) }()
+
+	constraints, err := version.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+	}
+
+	// Like Terraform, development overrides ignore version constraints.
+	if _, ok := c.devOverrides[namespace+"/"+name]; ok {
+		return devOverrideVersion, nil
+	}
+
+	candidates, err := c.availableVersions(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+
+	newest := newestVersion(candidates, constraints, prereleases)
+	if newest == "" {
+		return "", &ErrVersionNotFound{
+			Namespace: namespace,
+			Name:      name,
+			Version:   constraint,
+			Available: candidates,
+		}
+	}
+	return newest, nil
+}
+
+// newestVersion returns the newest of candidates matching constraints (all of
+// them if nil), or "" if none does. With prereleases set, a prerelease matches
+// if its release version does, so "~> 3.0" accepts "3.0.0-rc1".
+func newestVersion(candidates []string, constraints version.Constraints, prereleases bool) string {
+	var newest *version.Version
+	for _, candidate := range candidates {
+		v, err := version.NewVersion(candidate)
+		if err != nil {
+			continue
+		}
+		match := constraints == nil || constraints.Check(v)
+		if v.Prerelease() != "" {
+			if !prereleases {
+				match = match && constraints != nil
+			} else if !match {
+				match = constraints.Check(v.Core())
+			}
+		}
+		if match && (newest == nil || v.GreaterThan(newest)) {
+			newest = v
+		}
+	}
+	if newest == nil {
+		return ""
+	}
+	return newest.Original()
+}
+
+// availableVersions lists the versions of a provider this client can launch.
+func (c *Client) availableVersions(ctx context.Context, namespace, name string) ([]string, error) {
+	if ip, ok := c.inProcess[namespace+"/"+name]; ok {
+		return []string{ip.version}, nil
+	}
+	if c.replayDir != "" {
+		return recordedVersions(c.replayDir, namespace, name)
+	}
+
+	infos, err := c.registry.GetVersions(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]string, len(infos))
+	for i, info := range infos {
+		versions[i] = info.Version
+	}
+	return versions, nil
+}
+
+// ProviderVersions returns the versions of a provider the registry publishes,
+// newest first, with the plugin protocols each supports; this client only
+// launches protocol 6 providers. In offline mode, the cached and mirrored
+// versions are listed instead.
+func (c *Client) ProviderVersions(ctx context.Context, namespace, name string) (_ []registry.VersionInfo, err error) {
+	ctx, span := startSpan(ctx, c.tracer, "tfclient.Registry.GetVersions", providerAttrs(namespace, name, "")...)
+	defer func() { endSpan(span, err) }()
+
+	versions, err := c.registry.GetVersions(ctx, namespace, name)
+	if err != nil {
+		return nil, &ErrProviderNotFound{Namespace: namespace, Name: name, Err: err}
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		vi, erri := version.NewVersion(versions[i].Version)
+		vj, errj := version.NewVersion(versions[j].Version)
+		if erri != nil || errj != nil {
+			return erri == nil
+		}
+		return vi.GreaterThan(vj)
+	})
+	return versions, nil
+}
+
+// ProviderUpdate compares a provider version in use with the latest version
+// published.
+type ProviderUpdate struct {
+	Namespace string
+	Name      string
+	Current   string // version pinned by the lock file, or newest cached version
+	Locked    bool   // Current is pinned by the lock file
+	Latest    string // latest version published by the registry
+}
+
+// Outdated reports whether a newer version than Current is published.
+func (u ProviderUpdate) Outdated() bool {
+	current, err := version.NewVersion(u.Current)
+	if err != nil {
+		return false
+	}
+	latest, err := version.NewVersion(u.Latest)
+	return err == nil && latest.GreaterThan(current)
+}
+
+// CheckUpdates compares the providers pinned by the lock file loaded with
+// WithLockFile, and the newest cached version of the other cached providers,
+// with the latest versions the registry publishes, sorted by provider. The
+// errors of providers that can't be looked up are joined; the others are
+// still returned.
+func (c *Client) CheckUpdates(ctx context.Context) ([]ProviderUpdate, error) {
+	current := make(map[string]*ProviderUpdate)
+
+	prefix := c.providerAddress("", "")
+	prefix = strings.TrimSuffix(prefix, "/")
+	for address, locked := range c.lockFile {
+		rest, ok := strings.CutPrefix(address, prefix)
+		if !ok {
+			continue // another registry's provider
+		}
+		namespace, name, ok := strings.Cut(rest, "/")
+		if !ok {
+			continue
+		}
+		current[namespace+"/"+name] = &ProviderUpdate{Namespace: namespace, Name: name, Current: locked.version, Locked: true}
+	}
+
+	if reporter, ok := c.cache.(cache.StatsReporter); ok {
+		stats, err := reporter.Stats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range stats.Providers {
+			key := strings.ToLower(p.Namespace + "/" + p.Name)
+			if u := current[key]; u != nil && u.Locked {
+				continue
+			}
+			var versions []string
+			for _, v := range p.Versions {
+				versions = append(versions, v.Version)
+			}
+			if newest := newestVersion(versions, nil, true); newest != "" {
+				current[key] = &ProviderUpdate{Namespace: p.Namespace, Name: p.Name, Current: newest}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(current))
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	updates := make([]ProviderUpdate, 0, len(keys))
+	var errs []error
+	for _, key := range keys {
+		u := current[key]
+		latest, err := c.latestVersion(ctx, u.Namespace, u.Name, c.allowPrereleases)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to look up the latest version of %s/%s: %w", u.Namespace, u.Name, err))
+			continue
+		}
+		u.Latest = latest
+		updates = append(updates, *u)
+	}
+	return updates, errors.Join(errs...)
+}